@@ -1,15 +1,17 @@
 package deiz
 
 type ClinicianAccount struct {
-	Clinician        Clinician        `json:"clinician"`
-	Business         Business         `json:"business"`
-	OfficeAddresses  []Address        `json:"officeAddresses"`
-	StripePublicKey  string           `json:"stripePublicKey"`
-	OfficeHours      []OfficeHours    `json:"officeHours"`
-	BookingMotives   []BookingMotive  `json:"bookingMotives"`
-	CalendarSettings CalendarSettings `json:"calendarSettings"`
-	PaymentMethods   []PaymentMethod  `json:"paymentMethods"`
-	TaxExemptions    []TaxExemption   `json:"taxExemptions"`
+	Clinician           Clinician           `json:"clinician"`
+	Business            Business            `json:"business"`
+	OfficeAddresses     []Address           `json:"officeAddresses"`
+	StripePublicKey     string              `json:"stripePublicKey"`
+	StripeAccount       StripeAccount       `json:"stripeAccount"`
+	OfficeHours         []OfficeHours       `json:"officeHours"`
+	ExtraAvailabilities []ExtraAvailability `json:"extraAvailabilities"`
+	BookingMotives      []BookingMotive     `json:"bookingMotives"`
+	CalendarSettings    CalendarSettings    `json:"calendarSettings"`
+	PaymentMethods      []PaymentMethod     `json:"paymentMethods"`
+	TaxExemptions       []TaxExemption      `json:"taxExemptions"`
 }
 
 type LoginData struct {
@@ -0,0 +1,61 @@
+package address
+
+import (
+	"context"
+	"github.com/audrenbdb/deiz"
+)
+
+type (
+	roomCreater interface {
+		CreateRoom(ctx context.Context, room *deiz.Room) error
+	}
+	roomDeleter interface {
+		DeleteRoom(ctx context.Context, roomID, addressID int) error
+	}
+	roomsGetter interface {
+		GetAddressRooms(ctx context.Context, addressID int) ([]deiz.Room, error)
+	}
+)
+
+//RoomUsecase manages consultation rooms of clinician office addresses
+type RoomUsecase struct {
+	AccountGetter accountGetter
+	RoomCreater   roomCreater
+	RoomDeleter   roomDeleter
+	RoomsGetter   roomsGetter
+}
+
+func (u *RoomUsecase) AddRoom(ctx context.Context, room *deiz.Room, cred deiz.Credentials) error {
+	if !room.IsValid() {
+		return deiz.ErrorStructValidation
+	}
+	if err := u.authorizeAddress(ctx, room.AddressID, cred); err != nil {
+		return err
+	}
+	return u.RoomCreater.CreateRoom(ctx, room)
+}
+
+func (u *RoomUsecase) RemoveRoom(ctx context.Context, roomID, addressID int, cred deiz.Credentials) error {
+	if err := u.authorizeAddress(ctx, addressID, cred); err != nil {
+		return err
+	}
+	return u.RoomDeleter.DeleteRoom(ctx, roomID, addressID)
+}
+
+func (u *RoomUsecase) GetAddressRooms(ctx context.Context, addressID int, cred deiz.Credentials) ([]deiz.Room, error) {
+	if err := u.authorizeAddress(ctx, addressID, cred); err != nil {
+		return nil, err
+	}
+	return u.RoomsGetter.GetAddressRooms(ctx, addressID)
+}
+
+func (u *RoomUsecase) authorizeAddress(ctx context.Context, addressID int, cred deiz.Credentials) error {
+	authorized, err := isAddressToClinician(ctx, addressID, cred.UserID, u.AccountGetter)
+	if err != nil {
+		return err
+	}
+	if !authorized {
+		return deiz.ErrorUnauthorized
+	}
+	return nil
+}
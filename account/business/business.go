@@ -28,6 +28,9 @@ type Usecase struct {
 }
 
 func (u *Usecase) EditClinicianBusiness(ctx context.Context, b *deiz.Business, clinicianID int) error {
+	if !b.InvoiceNumbering.IsValid() {
+		return deiz.ErrorStructValidation
+	}
 	return u.BusinessUpdater.UpdateClinicianBusiness(ctx, b, clinicianID)
 }
 
@@ -18,10 +18,13 @@ func (u *GetDataUsecase) GetClinicianAccountPublicData(ctx context.Context, clin
 	return deiz.ClinicianAccount{
 		Clinician:       acc.Clinician,
 		StripePublicKey: acc.StripePublicKey,
+		StripeAccount:   acc.StripeAccount,
 		BookingMotives:  filterPublicMotives(acc.BookingMotives),
 		CalendarSettings: deiz.CalendarSettings{
 			RemoteAllowed:     acc.CalendarSettings.RemoteAllowed,
 			NewPatientAllowed: acc.CalendarSettings.NewPatientAllowed,
+			Prepayment:        acc.CalendarSettings.Prepayment,
+			Cancellation:      acc.CalendarSettings.Cancellation,
 		},
 	}, nil
 }
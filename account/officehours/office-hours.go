@@ -3,29 +3,196 @@ package officehours
 import (
 	"context"
 	"github.com/audrenbdb/deiz"
+	"time"
 )
 
 type (
 	creater interface {
 		CreateOfficeHours(ctx context.Context, h *deiz.OfficeHours, clinicianID int) error
 	}
+	updater interface {
+		UpdateOfficeHours(ctx context.Context, h *deiz.OfficeHours, clinicianID int) error
+	}
 	deleter interface {
 		DeleteOfficeHours(ctx context.Context, hoursID, clinicianID int) error
 	}
+	replacer interface {
+		ReplaceClinicianOfficeHours(ctx context.Context, hours []*deiz.OfficeHours, clinicianID int) error
+	}
+	getter interface {
+		GetClinicianOfficeHours(ctx context.Context, clinicianID int) ([]deiz.OfficeHours, error)
+	}
+	extraAvailabilityCreater interface {
+		CreateExtraAvailability(ctx context.Context, a *deiz.ExtraAvailability, clinicianID int) error
+	}
+	extraAvailabilityDeleter interface {
+		DeleteExtraAvailability(ctx context.Context, availabilityID, clinicianID int) error
+	}
+	extraAvailabilitiesGetter interface {
+		GetClinicianExtraAvailabilitiesInTimeRange(ctx context.Context, start, end time.Time, clinicianID int) ([]deiz.ExtraAvailability, error)
+		GetClinicianUpcomingExtraAvailabilities(ctx context.Context, clinicianID int) ([]deiz.ExtraAvailability, error)
+	}
+	timezoneGetter interface {
+		GetClinicianTimezone(ctx context.Context, clinicianID int) (deiz.Timezone, error)
+	}
 )
 
 type Usecase struct {
-	Creater creater
-	Deleter deleter
+	TimezoneGetter timezoneGetter
+
+	Creater  creater
+	Updater  updater
+	Deleter  deleter
+	Getter   getter
+	Replacer replacer
+
+	ExtraAvailabilityCreater  extraAvailabilityCreater
+	ExtraAvailabilityDeleter  extraAvailabilityDeleter
+	ExtraAvailabilitiesGetter extraAvailabilitiesGetter
 }
 
+//AddOfficeHours creates weekly office hours, which should not conflict with existing office hours
+//nor upcoming extra availabilities
 func (u *Usecase) AddOfficeHours(ctx context.Context, h *deiz.OfficeHours, clinicianID int) error {
 	if h.IsInvalid() {
 		return deiz.ErrorStructValidation
 	}
+	existingHours, err := u.Getter.GetClinicianOfficeHours(ctx, clinicianID)
+	if err != nil {
+		return err
+	}
+	if officeHoursOverlap(*h, existingHours) {
+		return deiz.ErrorOfficeHoursOverlap
+	}
+	if err := u.checkExtraAvailabilitiesOverlap(ctx, clinicianID, h); err != nil {
+		return err
+	}
 	return u.Creater.CreateOfficeHours(ctx, h, clinicianID)
 }
 
+//EditOfficeHours updates existing office hours, making sure they do not overlap other clinician office hours
+//nor upcoming extra availabilities
+func (u *Usecase) EditOfficeHours(ctx context.Context, h *deiz.OfficeHours, clinicianID int) error {
+	if h.IsInvalid() {
+		return deiz.ErrorStructValidation
+	}
+	existingHours, err := u.Getter.GetClinicianOfficeHours(ctx, clinicianID)
+	if err != nil {
+		return err
+	}
+	if !officeHoursExist(h.ID, existingHours) {
+		return deiz.ErrorUnauthorized
+	}
+	if officeHoursOverlap(*h, existingHours) {
+		return deiz.ErrorOfficeHoursOverlap
+	}
+	if err := u.checkExtraAvailabilitiesOverlap(ctx, clinicianID, h); err != nil {
+		return err
+	}
+	return u.Updater.UpdateOfficeHours(ctx, h, clinicianID)
+}
+
+//ReplaceWeeklySchedule swaps all clinician office hours for given ones at once
+func (u *Usecase) ReplaceWeeklySchedule(ctx context.Context, hours []*deiz.OfficeHours, clinicianID int) error {
+	for _, h := range hours {
+		if h == nil || h.IsInvalid() {
+			return deiz.ErrorStructValidation
+		}
+	}
+	for i, h := range hours {
+		for _, other := range hours[i+1:] {
+			if h.Overlaps(*other) {
+				return deiz.ErrorOfficeHoursOverlap
+			}
+		}
+	}
+	if err := u.checkExtraAvailabilitiesOverlap(ctx, clinicianID, hours...); err != nil {
+		return err
+	}
+	return u.Replacer.ReplaceClinicianOfficeHours(ctx, hours, clinicianID)
+}
+
 func (u *Usecase) RemoveOfficeHours(ctx context.Context, hoursID, clinicianID int) error {
 	return u.Deleter.DeleteOfficeHours(ctx, hoursID, clinicianID)
 }
+
+//AddExtraAvailability opens a one-off availability, which should not conflict with existing office hours
+func (u *Usecase) AddExtraAvailability(ctx context.Context, a *deiz.ExtraAvailability, clinicianID int) error {
+	if a.IsInvalid() {
+		return deiz.ErrorStructValidation
+	}
+	tz, err := u.TimezoneGetter.GetClinicianTimezone(ctx, clinicianID)
+	if err != nil {
+		return err
+	}
+	loc, err := tz.Location()
+	if err != nil {
+		return err
+	}
+	existingHours, err := u.Getter.GetClinicianOfficeHours(ctx, clinicianID)
+	if err != nil {
+		return err
+	}
+	for _, h := range existingHours {
+		if a.OverlapsOfficeHours(h, loc) {
+			return deiz.ErrorOfficeHoursOverlap
+		}
+	}
+	existingAvailabilities, err := u.ExtraAvailabilitiesGetter.GetClinicianExtraAvailabilitiesInTimeRange(ctx, a.Start, a.End, clinicianID)
+	if err != nil {
+		return err
+	}
+	for _, existing := range existingAvailabilities {
+		if a.Overlaps(existing) {
+			return deiz.ErrorOfficeHoursOverlap
+		}
+	}
+	return u.ExtraAvailabilityCreater.CreateExtraAvailability(ctx, a, clinicianID)
+}
+
+func (u *Usecase) RemoveExtraAvailability(ctx context.Context, availabilityID, clinicianID int) error {
+	return u.ExtraAvailabilityDeleter.DeleteExtraAvailability(ctx, availabilityID, clinicianID)
+}
+
+//checkExtraAvailabilitiesOverlap makes sure weekly office hours do not conflict with upcoming extra availabilities,
+//the same way extra availabilities are checked against office hours when added
+func (u *Usecase) checkExtraAvailabilitiesOverlap(ctx context.Context, clinicianID int, hours ...*deiz.OfficeHours) error {
+	availabilities, err := u.ExtraAvailabilitiesGetter.GetClinicianUpcomingExtraAvailabilities(ctx, clinicianID)
+	if err != nil || len(availabilities) == 0 {
+		return err
+	}
+	tz, err := u.TimezoneGetter.GetClinicianTimezone(ctx, clinicianID)
+	if err != nil {
+		return err
+	}
+	loc, err := tz.Location()
+	if err != nil {
+		return err
+	}
+	for _, a := range availabilities {
+		for _, h := range hours {
+			if a.OverlapsOfficeHours(*h, loc) {
+				return deiz.ErrorOfficeHoursOverlap
+			}
+		}
+	}
+	return nil
+}
+
+func officeHoursExist(hoursID int, existingHours []deiz.OfficeHours) bool {
+	for _, existing := range existingHours {
+		if existing.ID == hoursID {
+			return true
+		}
+	}
+	return false
+}
+
+func officeHoursOverlap(h deiz.OfficeHours, existingHours []deiz.OfficeHours) bool {
+	for _, existing := range existingHours {
+		if existing.ID != h.ID && h.Overlaps(existing) {
+			return true
+		}
+	}
+	return false
+}
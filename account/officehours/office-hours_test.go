@@ -0,0 +1,430 @@
+package officehours
+
+import (
+	"context"
+	"github.com/audrenbdb/deiz"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type mockCreater struct {
+	err error
+}
+
+func (m *mockCreater) CreateOfficeHours(ctx context.Context, h *deiz.OfficeHours, clinicianID int) error {
+	return m.err
+}
+
+type mockUpdater struct {
+	err error
+}
+
+func (m *mockUpdater) UpdateOfficeHours(ctx context.Context, h *deiz.OfficeHours, clinicianID int) error {
+	return m.err
+}
+
+type mockReplacer struct {
+	err error
+}
+
+func (m *mockReplacer) ReplaceClinicianOfficeHours(ctx context.Context, hours []*deiz.OfficeHours, clinicianID int) error {
+	return m.err
+}
+
+type mockGetter struct {
+	hours []deiz.OfficeHours
+	err   error
+}
+
+func (m *mockGetter) GetClinicianOfficeHours(ctx context.Context, clinicianID int) ([]deiz.OfficeHours, error) {
+	return m.hours, m.err
+}
+
+type mockTimezoneGetter struct {
+	tz  deiz.Timezone
+	err error
+}
+
+func (m *mockTimezoneGetter) GetClinicianTimezone(ctx context.Context, clinicianID int) (deiz.Timezone, error) {
+	return m.tz, m.err
+}
+
+type mockExtraAvailabilityCreater struct {
+	err error
+}
+
+func (m *mockExtraAvailabilityCreater) CreateExtraAvailability(ctx context.Context, a *deiz.ExtraAvailability, clinicianID int) error {
+	return m.err
+}
+
+type mockExtraAvailabilitiesGetter struct {
+	availabilities []deiz.ExtraAvailability
+	err            error
+}
+
+func (m *mockExtraAvailabilitiesGetter) GetClinicianExtraAvailabilitiesInTimeRange(ctx context.Context, start, end time.Time, clinicianID int) ([]deiz.ExtraAvailability, error) {
+	return m.availabilities, m.err
+}
+
+func (m *mockExtraAvailabilitiesGetter) GetClinicianUpcomingExtraAvailabilities(ctx context.Context, clinicianID int) ([]deiz.ExtraAvailability, error) {
+	return m.availabilities, m.err
+}
+
+func TestAddOfficeHours(t *testing.T) {
+	mondayMorning := deiz.OfficeHours{ID: 1, StartMn: 540, EndMn: 720, WeekDay: 1}
+	summerMondayMorning := deiz.OfficeHours{StartMn: 540, EndMn: 720, WeekDay: 1,
+		ValidFrom:  time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC),
+		ValidUntil: time.Date(2021, 8, 31, 0, 0, 0, 0, time.UTC),
+	}
+	regularMondayUntilSummer := deiz.OfficeHours{ID: 2, StartMn: 480, EndMn: 1080, WeekDay: 1,
+		ValidUntil: time.Date(2021, 6, 30, 0, 0, 0, 0, time.UTC),
+	}
+	//2021-07-05 is a monday
+	extraMonday := deiz.ExtraAvailability{
+		Start: time.Date(2021, 7, 5, 11, 0, 0, 0, time.UTC),
+		End:   time.Date(2021, 7, 5, 14, 0, 0, 0, time.UTC),
+	}
+
+	var tests = []struct {
+		description string
+
+		hoursInput  *deiz.OfficeHours
+		errorOutput error
+
+		usecase Usecase
+	}{
+		{
+			description: "should fail to validate office hours",
+
+			hoursInput:  &deiz.OfficeHours{StartMn: 720, EndMn: 540},
+			errorOutput: deiz.ErrorStructValidation,
+		},
+		{
+			description: "should fail to validate validity period ending before it starts",
+
+			hoursInput: &deiz.OfficeHours{StartMn: 540, EndMn: 720,
+				ValidFrom:  time.Date(2021, 8, 31, 0, 0, 0, 0, time.UTC),
+				ValidUntil: time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC),
+			},
+			errorOutput: deiz.ErrorStructValidation,
+		},
+		{
+			description: "should fail to get existing office hours",
+
+			hoursInput:  &mondayMorning,
+			errorOutput: deiz.GenericError,
+
+			usecase: Usecase{Getter: &mockGetter{err: deiz.GenericError}},
+		},
+		{
+			description: "should reject office hours overlapping existing ones",
+
+			hoursInput:  &deiz.OfficeHours{StartMn: 600, EndMn: 780, WeekDay: 1},
+			errorOutput: deiz.ErrorOfficeHoursOverlap,
+
+			usecase: Usecase{Getter: &mockGetter{hours: []deiz.OfficeHours{mondayMorning}}},
+		},
+		{
+			description: "should reject seasonal office hours overlapping permanent ones",
+
+			hoursInput:  &summerMondayMorning,
+			errorOutput: deiz.ErrorOfficeHoursOverlap,
+
+			usecase: Usecase{Getter: &mockGetter{hours: []deiz.OfficeHours{mondayMorning}}},
+		},
+		{
+			description: "should fail to get upcoming extra availabilities",
+
+			hoursInput:  &mondayMorning,
+			errorOutput: deiz.GenericError,
+
+			usecase: Usecase{
+				Getter:                    &mockGetter{},
+				ExtraAvailabilitiesGetter: &mockExtraAvailabilitiesGetter{err: deiz.GenericError},
+			},
+		},
+		{
+			description: "should reject office hours overlapping an upcoming extra availability",
+
+			hoursInput:  &mondayMorning,
+			errorOutput: deiz.ErrorOfficeHoursOverlap,
+
+			usecase: Usecase{
+				TimezoneGetter:            &mockTimezoneGetter{tz: deiz.Timezone{ID: 1, Name: "UTC"}},
+				Getter:                    &mockGetter{},
+				ExtraAvailabilitiesGetter: &mockExtraAvailabilitiesGetter{availabilities: []deiz.ExtraAvailability{extraMonday}},
+			},
+		},
+		{
+			description: "should accept seasonal office hours starting after existing ones end",
+
+			hoursInput: &summerMondayMorning,
+
+			usecase: Usecase{
+				Getter:                    &mockGetter{hours: []deiz.OfficeHours{regularMondayUntilSummer}},
+				ExtraAvailabilitiesGetter: &mockExtraAvailabilitiesGetter{},
+				Creater:                   &mockCreater{},
+			},
+		},
+		{
+			description: "should fail to create office hours",
+
+			hoursInput:  &mondayMorning,
+			errorOutput: deiz.GenericError,
+
+			usecase: Usecase{
+				Getter:                    &mockGetter{},
+				ExtraAvailabilitiesGetter: &mockExtraAvailabilitiesGetter{},
+				Creater:                   &mockCreater{err: deiz.GenericError},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			err := test.usecase.AddOfficeHours(context.Background(), test.hoursInput, 1)
+			assert.Equal(t, test.errorOutput, err)
+		})
+	}
+}
+
+func TestEditOfficeHours(t *testing.T) {
+	mondayMorning := deiz.OfficeHours{ID: 1, StartMn: 540, EndMn: 720, WeekDay: 1}
+	mondayAfternoon := deiz.OfficeHours{ID: 2, StartMn: 840, EndMn: 1080, WeekDay: 1}
+
+	var tests = []struct {
+		description string
+
+		hoursInput  *deiz.OfficeHours
+		errorOutput error
+
+		usecase Usecase
+	}{
+		{
+			description: "should fail to validate office hours ending after midnight",
+
+			hoursInput:  &deiz.OfficeHours{ID: 1, StartMn: 540, EndMn: 1500},
+			errorOutput: deiz.ErrorStructValidation,
+		},
+		{
+			description: "should fail to get existing office hours",
+
+			hoursInput:  &mondayMorning,
+			errorOutput: deiz.GenericError,
+
+			usecase: Usecase{Getter: &mockGetter{err: deiz.GenericError}},
+		},
+		{
+			description: "should refuse to edit office hours of another clinician",
+
+			hoursInput:  &deiz.OfficeHours{ID: 3, StartMn: 540, EndMn: 720, WeekDay: 1},
+			errorOutput: deiz.ErrorUnauthorized,
+
+			usecase: Usecase{Getter: &mockGetter{hours: []deiz.OfficeHours{mondayMorning}}},
+		},
+		{
+			description: "should reject edition overlapping other office hours",
+
+			hoursInput:  &deiz.OfficeHours{ID: 1, StartMn: 540, EndMn: 900, WeekDay: 1},
+			errorOutput: deiz.ErrorOfficeHoursOverlap,
+
+			usecase: Usecase{Getter: &mockGetter{hours: []deiz.OfficeHours{mondayMorning, mondayAfternoon}}},
+		},
+		{
+			description: "should extend office hours overlapping only their previous version",
+
+			hoursInput: &deiz.OfficeHours{ID: 1, StartMn: 480, EndMn: 780, WeekDay: 1},
+
+			usecase: Usecase{
+				Getter:                    &mockGetter{hours: []deiz.OfficeHours{mondayMorning, mondayAfternoon}},
+				ExtraAvailabilitiesGetter: &mockExtraAvailabilitiesGetter{},
+				Updater:                   &mockUpdater{},
+			},
+		},
+		{
+			description: "should fail to update office hours",
+
+			hoursInput:  &mondayMorning,
+			errorOutput: deiz.GenericError,
+
+			usecase: Usecase{
+				Getter:                    &mockGetter{hours: []deiz.OfficeHours{mondayMorning}},
+				ExtraAvailabilitiesGetter: &mockExtraAvailabilitiesGetter{},
+				Updater:                   &mockUpdater{err: deiz.GenericError},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			err := test.usecase.EditOfficeHours(context.Background(), test.hoursInput, 1)
+			assert.Equal(t, test.errorOutput, err)
+		})
+	}
+}
+
+func TestReplaceWeeklySchedule(t *testing.T) {
+	var tests = []struct {
+		description string
+
+		hoursInput  []*deiz.OfficeHours
+		errorOutput error
+
+		usecase Usecase
+	}{
+		{
+			description: "should fail to validate one of the office hours",
+
+			hoursInput: []*deiz.OfficeHours{
+				{StartMn: 540, EndMn: 720, WeekDay: 1},
+				{StartMn: 540, EndMn: 720, WeekDay: 7},
+			},
+			errorOutput: deiz.ErrorStructValidation,
+		},
+		{
+			description: "should reject a null entry",
+
+			hoursInput: []*deiz.OfficeHours{
+				{StartMn: 540, EndMn: 720, WeekDay: 1},
+				nil,
+			},
+			errorOutput: deiz.ErrorStructValidation,
+		},
+		{
+			description: "should reject overlapping windows on the same weekday",
+
+			hoursInput: []*deiz.OfficeHours{
+				{StartMn: 540, EndMn: 720, WeekDay: 1},
+				{StartMn: 840, EndMn: 1080, WeekDay: 2},
+				{StartMn: 700, EndMn: 900, WeekDay: 1},
+			},
+			errorOutput: deiz.ErrorOfficeHoursOverlap,
+		},
+		{
+			description: "should reject a week overlapping an upcoming extra availability",
+
+			hoursInput: []*deiz.OfficeHours{
+				{StartMn: 540, EndMn: 720, WeekDay: 1},
+			},
+			errorOutput: deiz.ErrorOfficeHoursOverlap,
+
+			usecase: Usecase{
+				TimezoneGetter: &mockTimezoneGetter{tz: deiz.Timezone{ID: 1, Name: "UTC"}},
+				ExtraAvailabilitiesGetter: &mockExtraAvailabilitiesGetter{availabilities: []deiz.ExtraAvailability{{
+					Start: time.Date(2021, 7, 5, 11, 0, 0, 0, time.UTC),
+					End:   time.Date(2021, 7, 5, 14, 0, 0, 0, time.UTC),
+				}}},
+			},
+		},
+		{
+			description: "should fail to replace office hours",
+
+			hoursInput: []*deiz.OfficeHours{
+				{StartMn: 540, EndMn: 720, WeekDay: 1},
+			},
+			errorOutput: deiz.GenericError,
+
+			usecase: Usecase{
+				ExtraAvailabilitiesGetter: &mockExtraAvailabilitiesGetter{},
+				Replacer:                  &mockReplacer{err: deiz.GenericError},
+			},
+		},
+		{
+			description: "should replace the whole week",
+
+			hoursInput: []*deiz.OfficeHours{
+				{StartMn: 540, EndMn: 720, WeekDay: 1},
+				{StartMn: 720, EndMn: 1080, WeekDay: 1},
+				{StartMn: 540, EndMn: 720, WeekDay: 2},
+			},
+
+			usecase: Usecase{
+				ExtraAvailabilitiesGetter: &mockExtraAvailabilitiesGetter{},
+				Replacer:                  &mockReplacer{},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			err := test.usecase.ReplaceWeeklySchedule(context.Background(), test.hoursInput, 1)
+			assert.Equal(t, test.errorOutput, err)
+		})
+	}
+}
+
+func TestAddExtraAvailability(t *testing.T) {
+	utc := &mockTimezoneGetter{tz: deiz.Timezone{ID: 1, Name: "UTC"}}
+	saturdayMorning := deiz.OfficeHours{StartMn: 540, EndMn: 720, WeekDay: 6}
+	//2021-07-03 is a saturday
+	extraSaturday := deiz.ExtraAvailability{
+		Start: time.Date(2021, 7, 3, 11, 0, 0, 0, time.UTC),
+		End:   time.Date(2021, 7, 3, 14, 0, 0, 0, time.UTC),
+	}
+
+	var tests = []struct {
+		description string
+
+		availabilityInput *deiz.ExtraAvailability
+		errorOutput       error
+
+		usecase Usecase
+	}{
+		{
+			description: "should fail to validate extra availability",
+
+			availabilityInput: &deiz.ExtraAvailability{},
+			errorOutput:       deiz.ErrorStructValidation,
+		},
+		{
+			description: "should fail to get clinician timezone",
+
+			availabilityInput: &extraSaturday,
+			errorOutput:       deiz.GenericError,
+
+			usecase: Usecase{TimezoneGetter: &mockTimezoneGetter{err: deiz.GenericError}},
+		},
+		{
+			description: "should reject extra availability overlapping weekly office hours",
+
+			availabilityInput: &extraSaturday,
+			errorOutput:       deiz.ErrorOfficeHoursOverlap,
+
+			usecase: Usecase{TimezoneGetter: utc, Getter: &mockGetter{hours: []deiz.OfficeHours{saturdayMorning}}},
+		},
+		{
+			description: "should reject extra availability overlapping another one",
+
+			availabilityInput: &extraSaturday,
+			errorOutput:       deiz.ErrorOfficeHoursOverlap,
+
+			usecase: Usecase{
+				TimezoneGetter:            utc,
+				Getter:                    &mockGetter{},
+				ExtraAvailabilitiesGetter: &mockExtraAvailabilitiesGetter{availabilities: []deiz.ExtraAvailability{extraSaturday}},
+			},
+		},
+		{
+			description: "should accept extra availability outside of seasonal office hours",
+
+			availabilityInput: &extraSaturday,
+
+			usecase: Usecase{
+				TimezoneGetter: utc,
+				Getter: &mockGetter{hours: []deiz.OfficeHours{{StartMn: 540, EndMn: 720, WeekDay: 6,
+					ValidFrom: time.Date(2021, 9, 1, 0, 0, 0, 0, time.UTC),
+				}}},
+				ExtraAvailabilitiesGetter: &mockExtraAvailabilitiesGetter{},
+				ExtraAvailabilityCreater:  &mockExtraAvailabilityCreater{},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			err := test.usecase.AddExtraAvailability(context.Background(), test.availabilityInput, 1)
+			assert.Equal(t, test.errorOutput, err)
+		})
+	}
+}
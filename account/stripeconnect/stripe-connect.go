@@ -0,0 +1,92 @@
+package stripeconnect
+
+import (
+	"context"
+	"github.com/audrenbdb/deiz"
+	"time"
+)
+
+//Usecase connects clinician Stripe account to the platform so that patients pay clinician online,
+//no clinician secret being stored
+type Usecase struct {
+	ClinicianGetter clinicianGetter
+	AccountGetter   accountGetter
+	AccountUpdater  accountUpdater
+	Connecter       connecter
+}
+
+type (
+	clinicianGetter interface {
+		GetClinicianByID(ctx context.Context, clinicianID int) (deiz.Clinician, error)
+	}
+	accountGetter interface {
+		GetClinicianStripeAccount(ctx context.Context, clinicianID int) (deiz.StripeAccount, error)
+	}
+	accountUpdater interface {
+		UpdateClinicianStripeAccount(ctx context.Context, a deiz.StripeAccount, clinicianID int) error
+	}
+	legacyKeysRemover interface {
+		RemoveSettledStripeKeys(ctx context.Context, connectedBefore time.Time, now time.Time) error
+	}
+	connecter interface {
+		CreateConnectedAccount(ctx context.Context, email string) (string, error)
+		CreateOnboardingLink(ctx context.Context, accountID string) (string, error)
+		GetConnectedAccount(ctx context.Context, accountID string) (deiz.StripeAccount, error)
+	}
+)
+
+//StartStripeOnboarding returns the Stripe url where clinician fills its connected account details.
+//Connected account is created on first onboarding, later calls resuming it.
+func (u *Usecase) StartStripeOnboarding(ctx context.Context, clinicianID int) (string, error) {
+	a, err := u.AccountGetter.GetClinicianStripeAccount(ctx, clinicianID)
+	if err != nil {
+		return "", err
+	}
+	if a.ID == "" {
+		c, err := u.ClinicianGetter.GetClinicianByID(ctx, clinicianID)
+		if err != nil {
+			return "", err
+		}
+		a.ID, err = u.Connecter.CreateConnectedAccount(ctx, c.Email)
+		if err != nil {
+			return "", err
+		}
+		if err := u.AccountUpdater.UpdateClinicianStripeAccount(ctx, a, clinicianID); err != nil {
+			return "", err
+		}
+	}
+	return u.Connecter.CreateOnboardingLink(ctx, a.ID)
+}
+
+//RefreshStripeAccount reads onboarding status of clinician connected account once back from Stripe.
+//Payments are made on behalf of the connected account as soon as it may be charged.
+//Keys a clinician pasted before Stripe Connect are kept, legacy payments being refunded
+//and legacy webhooks verified with them until removed by RemoveSettledLegacyKeys.
+func (u *Usecase) RefreshStripeAccount(ctx context.Context, clinicianID int) (deiz.StripeAccount, error) {
+	a, err := u.AccountGetter.GetClinicianStripeAccount(ctx, clinicianID)
+	if err != nil {
+		return deiz.StripeAccount{}, err
+	}
+	if a.ID == "" {
+		return deiz.StripeAccount{}, deiz.ErrorStripeAccountNotConnected
+	}
+	a, err = u.Connecter.GetConnectedAccount(ctx, a.ID)
+	if err != nil {
+		return deiz.StripeAccount{}, err
+	}
+	if err := u.AccountUpdater.UpdateClinicianStripeAccount(ctx, a, clinicianID); err != nil {
+		return deiz.StripeAccount{}, err
+	}
+	return a, nil
+}
+
+type LegacyKeysUsecase struct {
+	Remover legacyKeysRemover
+}
+
+//RemoveSettledLegacyKeys erases keys clinicians pasted before Stripe Connect once they are no longer needed:
+//account connected for deiz.LegacyStripeKeysRetention, and no booking paid with them left to take place
+func (u *LegacyKeysUsecase) RemoveSettledLegacyKeys(ctx context.Context) error {
+	now := time.Now().UTC()
+	return u.Remover.RemoveSettledStripeKeys(ctx, now.Add(-deiz.LegacyStripeKeysRetention), now)
+}
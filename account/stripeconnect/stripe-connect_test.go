@@ -0,0 +1,173 @@
+package stripeconnect
+
+import (
+	"context"
+	"github.com/audrenbdb/deiz"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type mockClinicianGetter struct {
+	err error
+}
+
+func (m *mockClinicianGetter) GetClinicianByID(ctx context.Context, clinicianID int) (deiz.Clinician, error) {
+	return deiz.Clinician{ID: clinicianID, Email: "jean@dupont.fr"}, m.err
+}
+
+type mockAccountRepo struct {
+	account deiz.StripeAccount
+	updated deiz.StripeAccount
+	getErr  error
+	err     error
+}
+
+func (m *mockAccountRepo) GetClinicianStripeAccount(ctx context.Context, clinicianID int) (deiz.StripeAccount, error) {
+	return m.account, m.getErr
+}
+
+func (m *mockAccountRepo) UpdateClinicianStripeAccount(ctx context.Context, a deiz.StripeAccount, clinicianID int) error {
+	m.updated = a
+	return m.err
+}
+
+type mockKeysRemover struct {
+	connectedBefore time.Time
+	now             time.Time
+	err             error
+}
+
+func (m *mockKeysRemover) RemoveSettledStripeKeys(ctx context.Context, connectedBefore time.Time, now time.Time) error {
+	m.connectedBefore = connectedBefore
+	m.now = now
+	return m.err
+}
+
+type mockConnecter struct {
+	createdFor     string
+	chargesEnabled bool
+	err            error
+}
+
+func (m *mockConnecter) CreateConnectedAccount(ctx context.Context, email string) (string, error) {
+	m.createdFor = email
+	return "acct_new", m.err
+}
+
+func (m *mockConnecter) CreateOnboardingLink(ctx context.Context, accountID string) (string, error) {
+	return "https://connect.stripe.com/setup/" + accountID, m.err
+}
+
+func (m *mockConnecter) GetConnectedAccount(ctx context.Context, accountID string) (deiz.StripeAccount, error) {
+	return deiz.StripeAccount{ID: accountID, ChargesEnabled: m.chargesEnabled}, m.err
+}
+
+func TestStartStripeOnboarding(t *testing.T) {
+	var tests = []struct {
+		description string
+
+		linkOutput    string
+		errorOutput   error
+		createdOutput string
+
+		usecase Usecase
+	}{
+		{
+			description: "should fail to get clinician stripe account",
+			errorOutput: deiz.GenericError,
+
+			usecase: Usecase{AccountGetter: &mockAccountRepo{getErr: deiz.GenericError}},
+		},
+		{
+			description:   "should fail to create connected account",
+			errorOutput:   deiz.GenericError,
+			createdOutput: "jean@dupont.fr",
+
+			usecase: Usecase{
+				AccountGetter:   &mockAccountRepo{},
+				ClinicianGetter: &mockClinicianGetter{},
+				Connecter:       &mockConnecter{err: deiz.GenericError},
+			},
+		},
+		{
+			description:   "should fail to save connected account",
+			errorOutput:   deiz.GenericError,
+			createdOutput: "jean@dupont.fr",
+
+			usecase: Usecase{
+				AccountGetter:   &mockAccountRepo{},
+				ClinicianGetter: &mockClinicianGetter{},
+				AccountUpdater:  &mockAccountRepo{err: deiz.GenericError},
+				Connecter:       &mockConnecter{},
+			},
+		},
+		{
+			description:   "should create connected account and return its onboarding link",
+			linkOutput:    "https://connect.stripe.com/setup/acct_new",
+			createdOutput: "jean@dupont.fr",
+
+			usecase: Usecase{
+				AccountGetter:   &mockAccountRepo{},
+				ClinicianGetter: &mockClinicianGetter{},
+				AccountUpdater:  &mockAccountRepo{},
+				Connecter:       &mockConnecter{},
+			},
+		},
+		{
+			description: "should resume onboarding of existing connected account",
+			linkOutput:  "https://connect.stripe.com/setup/acct_1",
+
+			usecase: Usecase{
+				AccountGetter: &mockAccountRepo{account: deiz.StripeAccount{ID: "acct_1"}},
+				Connecter:     &mockConnecter{},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			link, err := test.usecase.StartStripeOnboarding(context.Background(), 1)
+			assert.Equal(t, test.errorOutput, err)
+			assert.Equal(t, test.linkOutput, link)
+			if connecter, ok := test.usecase.Connecter.(*mockConnecter); ok {
+				assert.Equal(t, test.createdOutput, connecter.createdFor)
+			}
+		})
+	}
+}
+
+func TestRefreshStripeAccount(t *testing.T) {
+	t.Run("should fail when clinician never started onboarding", func(t *testing.T) {
+		u := Usecase{AccountGetter: &mockAccountRepo{}}
+		_, err := u.RefreshStripeAccount(context.Background(), 1)
+		assert.Equal(t, deiz.ErrorStripeAccountNotConnected, err)
+	})
+	t.Run("should record an account whose onboarding is incomplete", func(t *testing.T) {
+		repo := &mockAccountRepo{account: deiz.StripeAccount{ID: "acct_1"}}
+		u := Usecase{AccountGetter: repo, AccountUpdater: repo, Connecter: &mockConnecter{}}
+		a, err := u.RefreshStripeAccount(context.Background(), 1)
+		assert.NoError(t, err)
+		assert.Equal(t, deiz.StripeAccount{ID: "acct_1"}, a)
+		assert.Equal(t, a, repo.updated)
+	})
+	t.Run("should record an account that may be charged", func(t *testing.T) {
+		repo := &mockAccountRepo{account: deiz.StripeAccount{ID: "acct_1"}}
+		u := Usecase{AccountGetter: repo, AccountUpdater: repo, Connecter: &mockConnecter{chargesEnabled: true}}
+		a, err := u.RefreshStripeAccount(context.Background(), 1)
+		assert.NoError(t, err)
+		assert.True(t, a.IsConnected())
+		assert.Equal(t, a, repo.updated)
+	})
+}
+
+func TestRemoveSettledLegacyKeys(t *testing.T) {
+	remover := &mockKeysRemover{}
+	u := LegacyKeysUsecase{Remover: remover}
+
+	err := u.RemoveSettledLegacyKeys(context.Background())
+
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), remover.now, time.Minute)
+	assert.Equal(t, remover.now.Add(-deiz.LegacyStripeKeysRetention), remover.connectedBefore)
+}
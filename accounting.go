@@ -0,0 +1,115 @@
+package deiz
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"time"
+)
+
+//accounts of french chart of accounts (plan comptable général) invoices are booked to
+const (
+	RevenueAccount      = "706000"
+	VATCollectedAccount = "445710"
+	BankAccount         = "512000"
+	CashAccount         = "530000"
+)
+
+const (
+	salesJournalCode  = "VE"
+	salesJournalLabel = "Ventes"
+)
+
+//AccountingEntry is a line of the sales journal.
+//Lines sharing an entry number balance each other, debits equalling credits.
+type AccountingEntry struct {
+	JournalCode  string
+	JournalLabel string
+	EntryNumber  int
+	EntryDate    time.Time
+	Account      string
+	AccountLabel string
+	PieceRef     string
+	PieceDate    time.Time
+	Label        string
+	Debit        int64
+	Credit       int64
+}
+
+//JournalEntries books invoices in the sales journal, in issue order, dates being set in given location.
+//Invoices being paid when issued, amount paid is debited to the payment method account,
+//amount before tax and tax being credited to revenue and VAT accounts.
+//Credit notes are booked as negative entries.
+func JournalEntries(invoices []BookingInvoice, loc *time.Location) []AccountingEntry {
+	sorted := make([]BookingInvoice, len(invoices))
+	copy(sorted, invoices)
+	sort.SliceStable(sorted, func(a, b int) bool {
+		if sorted[a].CreatedAt.Equal(sorted[b].CreatedAt) {
+			return sorted[a].ID < sorted[b].ID
+		}
+		return sorted[a].CreatedAt.Before(sorted[b].CreatedAt)
+	})
+	entries := []AccountingEntry{}
+	for n, i := range sorted {
+		entry := AccountingEntry{
+			JournalCode:  salesJournalCode,
+			JournalLabel: salesJournalLabel,
+			EntryNumber:  n + 1,
+			EntryDate:    i.CreatedAt.In(loc),
+			PieceRef:     i.Identifier,
+			PieceDate:    i.CreatedAt.In(loc),
+			Label:        i.entryLabel(),
+		}
+		entries = append(entries,
+			entry.withAccount(i.PaymentMethod.AccountOrDefault(), i.PaymentMethod.Name, i.PriceAfterTax, 0),
+			entry.withAccount(RevenueAccount, "Prestations de services", 0, i.PriceBeforeTax))
+		if tax := i.PriceAfterTax - i.PriceBeforeTax; tax != 0 {
+			entries = append(entries, entry.withAccount(VATCollectedAccount, "T.V.A collectée", 0, tax))
+		}
+	}
+	return entries
+}
+
+func (e AccountingEntry) withAccount(account, label string, debit, credit int64) AccountingEntry {
+	e.Account = account
+	e.AccountLabel = label
+	e.Debit = debit
+	e.Credit = credit
+	return e
+}
+
+func (i *BookingInvoice) entryLabel() string {
+	if i.IsCreditNote() {
+		return fmt.Sprintf("Avoir %s", i.Identifier)
+	}
+	return fmt.Sprintf("Facture %s", i.Identifier)
+}
+
+//AccountOrDefault is the treasury account payments of method are booked to, bank by default
+func (m *PaymentMethod) AccountOrDefault() string {
+	if m.Account == "" {
+		return BankAccount
+	}
+	return m.Account
+}
+
+//ExportFormat of an accounting export
+type ExportFormat string
+
+const (
+	//FECFormat is the Fichier des Écritures Comptables required by french tax administration
+	FECFormat ExportFormat = "fec"
+	//CSVFormat is a plain journal readable by spreadsheets
+	CSVFormat ExportFormat = "csv"
+)
+
+func (f ExportFormat) IsValid() bool {
+	return f == FECFormat || f == CSVFormat
+}
+
+//AccountingExport is a journal file ready to be downloaded or mailed
+type AccountingExport struct {
+	Filename    string
+	ContentType string
+	Data        *bytes.Buffer
+}
@@ -0,0 +1,114 @@
+package accounting
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"github.com/audrenbdb/deiz"
+	"strings"
+)
+
+//Exporter formats journal entries as files accountants import
+type Exporter struct{}
+
+func NewService() *Exporter {
+	return &Exporter{}
+}
+
+//fecHeader lists mandatory columns of article A47 A-1 of Livre des procédures fiscales
+var fecHeader = []string{
+	"JournalCode", "JournalLib", "EcritureNum", "EcritureDate", "CompteNum", "CompteLib",
+	"CompAuxNum", "CompAuxLib", "PieceRef", "PieceDate", "EcritureLib", "Debit", "Credit",
+	"EcritureLet", "DateLet", "ValidDate", "Montantdevise", "Idevise",
+}
+
+const (
+	fecSeparator  = "|"
+	fecDateFormat = "20060102"
+)
+
+//CreateFEC writes entries as a pipe separated Fichier des Écritures Comptables
+func (e *Exporter) CreateFEC(entries []deiz.AccountingEntry) (*bytes.Buffer, error) {
+	var buffer bytes.Buffer
+	buffer.WriteString(strings.Join(fecHeader, fecSeparator) + "\r\n")
+	for _, entry := range entries {
+		entryDate := entry.EntryDate.Format(fecDateFormat)
+		fields := []string{
+			entry.JournalCode,
+			entry.JournalLabel,
+			fmt.Sprintf("%d", entry.EntryNumber),
+			entryDate,
+			entry.Account,
+			fecField(entry.AccountLabel),
+			"",
+			"",
+			fecField(entry.PieceRef),
+			entry.PieceDate.Format(fecDateFormat),
+			fecField(entry.Label),
+			formatAmount(entry.Debit),
+			formatAmount(entry.Credit),
+			"",
+			"",
+			entryDate,
+			"",
+			"",
+		}
+		buffer.WriteString(strings.Join(fields, fecSeparator) + "\r\n")
+	}
+	return &buffer, nil
+}
+
+//fecField removes characters breaking FEC columns
+func fecField(s string) string {
+	return strings.NewReplacer(fecSeparator, " ", "\r", " ", "\n", " ").Replace(s)
+}
+
+var csvHeader = []string{
+	"Journal", "N° écriture", "Date", "Compte", "Libellé compte", "Pièce", "Libellé", "Débit", "Crédit",
+}
+
+const (
+	csvDateFormat = "02/01/2006"
+	//utf8BOM lets spreadsheets detect file encoding
+	utf8BOM = "\ufeff"
+)
+
+//CreateJournalCSV writes entries as a semicolon separated journal, as expected by french spreadsheets
+func (e *Exporter) CreateJournalCSV(entries []deiz.AccountingEntry) (*bytes.Buffer, error) {
+	var buffer bytes.Buffer
+	buffer.WriteString(utf8BOM)
+	w := csv.NewWriter(&buffer)
+	w.Comma = ';'
+	w.UseCRLF = true
+	if err := w.Write(csvHeader); err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		err := w.Write([]string{
+			entry.JournalCode,
+			fmt.Sprintf("%d", entry.EntryNumber),
+			entry.EntryDate.Format(csvDateFormat),
+			entry.Account,
+			entry.AccountLabel,
+			entry.PieceRef,
+			entry.Label,
+			formatAmount(entry.Debit),
+			formatAmount(entry.Credit),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	return &buffer, w.Error()
+}
+
+//formatAmount formats cents with a decimal comma and no thousands separator
+func formatAmount(cents int64) string {
+	sign := ""
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+	return fmt.Sprintf("%s%d,%02d", sign, cents/100, cents%100)
+}
@@ -0,0 +1,33 @@
+package accounting
+
+import (
+	"github.com/audrenbdb/deiz"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+var entries = []deiz.AccountingEntry{{
+	JournalCode: "VE", JournalLabel: "Ventes", EntryNumber: 1,
+	EntryDate: time.Date(2021, 3, 2, 0, 0, 0, 0, time.UTC), PieceDate: time.Date(2021, 3, 2, 0, 0, 0, 0, time.UTC),
+	Account: deiz.BankAccount, AccountLabel: "Chèque | virement", PieceRef: "DEIZ-1-00000002",
+	Label: "Avoir DEIZ-1-00000002", Debit: -6005,
+}}
+
+func TestCreateFEC(t *testing.T) {
+	fec, err := NewService().CreateFEC(entries)
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(fec.String()), "\r\n")
+	assert.Len(t, lines, 2)
+	assert.Len(t, strings.Split(lines[0], "|"), 18)
+	assert.Equal(t, "VE|Ventes|1|20210302|512000|Chèque   virement|||DEIZ-1-00000002|20210302|Avoir DEIZ-1-00000002|-60,05|0,00|||20210302||", lines[1])
+}
+
+func TestCreateJournalCSV(t *testing.T) {
+	csv, err := NewService().CreateJournalCSV(entries)
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(csv.String()), "\r\n")
+	assert.Len(t, lines, 2)
+	assert.Equal(t, "VE;1;02/03/2021;512000;Chèque | virement;DEIZ-1-00000002;Avoir DEIZ-1-00000002;-60,05;0,00", lines[1])
+}
@@ -0,0 +1,58 @@
+package deiz_test
+
+import (
+	"github.com/audrenbdb/deiz"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestJournalEntries(t *testing.T) {
+	issued := deiz.BookingInvoice{
+		ID: 1, Identifier: "DEIZ-1-00000001", CreatedAt: time.Date(2021, 3, 1, 23, 30, 0, 0, time.UTC),
+		PriceBeforeTax: 5000, PriceAfterTax: 6000,
+		PaymentMethod: deiz.PaymentMethod{ID: 1, Name: "Espèces", Account: deiz.CashAccount},
+	}
+	creditNote := issued.CreditNote()
+	creditNote.ID = 2
+	creditNote.Identifier = "DEIZ-1-00000002"
+	creditNote.CreatedAt = issued.CreatedAt.Add(time.Hour)
+	paris, _ := time.LoadLocation("Europe/Paris")
+
+	entries := deiz.JournalEntries([]deiz.BookingInvoice{creditNote, issued}, paris)
+
+	t.Run("should balance each entry", func(t *testing.T) {
+		balance := map[int]int64{}
+		for _, e := range entries {
+			balance[e.EntryNumber] += e.Debit - e.Credit
+		}
+		assert.Equal(t, map[int]int64{1: 0, 2: 0}, balance)
+	})
+	t.Run("should book invoice to payment method, revenue and VAT accounts", func(t *testing.T) {
+		assert.Equal(t, deiz.CashAccount, entries[0].Account)
+		assert.Equal(t, int64(6000), entries[0].Debit)
+		assert.Equal(t, deiz.RevenueAccount, entries[1].Account)
+		assert.Equal(t, int64(5000), entries[1].Credit)
+		assert.Equal(t, deiz.VATCollectedAccount, entries[2].Account)
+		assert.Equal(t, int64(1000), entries[2].Credit)
+		assert.Equal(t, 2, entries[0].EntryDate.Day())
+	})
+	t.Run("should book credit note as negative entry", func(t *testing.T) {
+		assert.Len(t, entries, 6)
+		assert.Equal(t, "Avoir DEIZ-1-00000002", entries[3].Label)
+		assert.Equal(t, int64(-6000), entries[3].Debit)
+		assert.Equal(t, int64(-5000), entries[4].Credit)
+	})
+	t.Run("should book payments to bank by default", func(t *testing.T) {
+		entries := deiz.JournalEntries([]deiz.BookingInvoice{{PriceAfterTax: 6000, PriceBeforeTax: 6000}}, time.UTC)
+		assert.Len(t, entries, 2)
+		assert.Equal(t, deiz.BankAccount, entries[0].Account)
+	})
+}
+
+func TestBusinessSIREN(t *testing.T) {
+	b := deiz.Business{Identifier: "123 456 789 00012"}
+	assert.Equal(t, "123456789", b.SIREN())
+	b.Identifier = "1234"
+	assert.Equal(t, "", b.SIREN())
+}
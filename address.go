@@ -31,3 +31,18 @@ func (a *Address) ToString() string {
 	}
 	return fmt.Sprintf("%s, %d %s", a.Line, a.PostCode, a.City)
 }
+
+//Room is a consultation room of an office address, which clinicians sharing the office book in turn
+type Room struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	AddressID int    `json:"addressId"`
+}
+
+func (r *Room) IsSet() bool {
+	return r.ID != 0
+}
+
+func (r *Room) IsValid() bool {
+	return len(r.Name) > 0 && r.AddressID != 0
+}
@@ -0,0 +1,45 @@
+package deiz
+
+import "time"
+
+//AccountSummary describes a clinician account as listed in admin console
+type AccountSummary struct {
+	Clinician Clinician `json:"clinician"`
+	Role      Role      `json:"role"`
+	Disabled  bool      `json:"disabled"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+//UsageCount is the number of items created during the period starting at Period
+type UsageCount struct {
+	Period time.Time `json:"period"`
+	Count  int       `json:"count"`
+}
+
+//UsageStats aggregates app usage across every clinician
+type UsageStats struct {
+	BookingsPerWeek  []UsageCount `json:"bookingsPerWeek"`
+	InvoicesPerMonth []UsageCount `json:"invoicesPerMonth"`
+}
+
+type AuditAction string
+
+const (
+	AuditListAccounts        AuditAction = "list_accounts"
+	AuditEnableAccount       AuditAction = "enable_account"
+	AuditDisableAccount      AuditAction = "disable_account"
+	AuditImpersonateAccount  AuditAction = "impersonate_account"
+	AuditImpersonateCalendar AuditAction = "impersonate_calendar"
+	AuditViewUsage           AuditAction = "view_usage"
+	AuditViewAuditLogs       AuditAction = "view_audit_logs"
+)
+
+//AuditLog records an action taken by an admin
+type AuditLog struct {
+	ID      int         `json:"id"`
+	AdminID int         `json:"adminId"`
+	Action  AuditAction `json:"action"`
+	//TargetID is the account concerned by the action, 0 if none
+	TargetID  int       `json:"targetId"`
+	CreatedAt time.Time `json:"createdAt"`
+}
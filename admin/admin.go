@@ -0,0 +1,112 @@
+//Package admin implements support and monitoring actions reserved to admins.
+//Every action is written to the audit log before being executed.
+package admin
+
+import (
+	"context"
+	"github.com/audrenbdb/deiz"
+	"time"
+)
+
+//auditLogsLimit is the number of latest audit logs returned
+const auditLogsLimit = 200
+
+type (
+	AccountsLister interface {
+		ListClinicianAccounts(ctx context.Context) ([]deiz.AccountSummary, error)
+	}
+	AccountDisabler interface {
+		UpdateAccountDisabled(ctx context.Context, disabled bool, clinicianID int) error
+	}
+	AccountGetter interface {
+		GetClinicianAccount(ctx context.Context, clinicianID int) (deiz.ClinicianAccount, error)
+	}
+	CalendarReader interface {
+		GetCalendarSlots(ctx context.Context, start time.Time, defaultDuration int, clinicianID int) ([]deiz.Booking, error)
+	}
+	UsageGetter interface {
+		GetUsageStats(ctx context.Context, from, to time.Time) (deiz.UsageStats, error)
+	}
+	AuditLogger interface {
+		CreateAuditLog(ctx context.Context, l *deiz.AuditLog) error
+	}
+	AuditLogsGetter interface {
+		GetAuditLogs(ctx context.Context, limit int) ([]deiz.AuditLog, error)
+	}
+)
+
+type Usecase struct {
+	AccountsLister  AccountsLister
+	AccountDisabler AccountDisabler
+	AccountGetter   AccountGetter
+	CalendarReader  CalendarReader
+	UsageGetter     UsageGetter
+	AuditLogger     AuditLogger
+	AuditLogsGetter AuditLogsGetter
+}
+
+func (u *Usecase) ListAccounts(ctx context.Context, adminID int) ([]deiz.AccountSummary, error) {
+	if err := u.audit(ctx, deiz.AuditListAccounts, 0, adminID); err != nil {
+		return nil, err
+	}
+	return u.AccountsLister.ListClinicianAccounts(ctx)
+}
+
+func (u *Usecase) EnableAccount(ctx context.Context, clinicianID, adminID int) error {
+	if err := u.audit(ctx, deiz.AuditEnableAccount, clinicianID, adminID); err != nil {
+		return err
+	}
+	return u.AccountDisabler.UpdateAccountDisabled(ctx, false, clinicianID)
+}
+
+//DisableAccount prevents a clinician from login in, an admin cannot disable its own account
+func (u *Usecase) DisableAccount(ctx context.Context, clinicianID, adminID int) error {
+	if clinicianID == adminID {
+		return deiz.ErrorUnauthorized
+	}
+	if err := u.audit(ctx, deiz.AuditDisableAccount, clinicianID, adminID); err != nil {
+		return err
+	}
+	return u.AccountDisabler.UpdateAccountDisabled(ctx, true, clinicianID)
+}
+
+//ImpersonateAccount returns clinician account data as the clinician would see it, for support purpose
+func (u *Usecase) ImpersonateAccount(ctx context.Context, clinicianID, adminID int) (deiz.ClinicianAccount, error) {
+	if err := u.audit(ctx, deiz.AuditImpersonateAccount, clinicianID, adminID); err != nil {
+		return deiz.ClinicianAccount{}, err
+	}
+	return u.AccountGetter.GetClinicianAccount(ctx, clinicianID)
+}
+
+//ImpersonateCalendar returns clinician calendar slots as the clinician would see them, for support purpose
+func (u *Usecase) ImpersonateCalendar(ctx context.Context, start time.Time, defaultDuration int, clinicianID, adminID int) ([]deiz.Booking, error) {
+	if err := u.audit(ctx, deiz.AuditImpersonateCalendar, clinicianID, adminID); err != nil {
+		return nil, err
+	}
+	return u.CalendarReader.GetCalendarSlots(ctx, start, defaultDuration, clinicianID)
+}
+
+func (u *Usecase) GetUsageStats(ctx context.Context, from, to time.Time, adminID int) (deiz.UsageStats, error) {
+	if !from.Before(to) {
+		return deiz.UsageStats{}, deiz.ErrorStructValidation
+	}
+	if err := u.audit(ctx, deiz.AuditViewUsage, 0, adminID); err != nil {
+		return deiz.UsageStats{}, err
+	}
+	return u.UsageGetter.GetUsageStats(ctx, from, to)
+}
+
+func (u *Usecase) GetAuditLogs(ctx context.Context, adminID int) ([]deiz.AuditLog, error) {
+	if err := u.audit(ctx, deiz.AuditViewAuditLogs, 0, adminID); err != nil {
+		return nil, err
+	}
+	return u.AuditLogsGetter.GetAuditLogs(ctx, auditLogsLimit)
+}
+
+func (u *Usecase) audit(ctx context.Context, action deiz.AuditAction, targetID, adminID int) error {
+	return u.AuditLogger.CreateAuditLog(ctx, &deiz.AuditLog{
+		AdminID:  adminID,
+		Action:   action,
+		TargetID: targetID,
+	})
+}
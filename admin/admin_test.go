@@ -0,0 +1,93 @@
+package admin_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/audrenbdb/deiz"
+	"github.com/audrenbdb/deiz/admin"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockAuditLogger struct {
+	logs []deiz.AuditLog
+	err  error
+}
+
+func (m *mockAuditLogger) CreateAuditLog(ctx context.Context, l *deiz.AuditLog) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.logs = append(m.logs, *l)
+	return nil
+}
+
+type mockAccountDisabler struct {
+	disabled map[int]bool
+	err      error
+}
+
+func (m *mockAccountDisabler) UpdateAccountDisabled(ctx context.Context, disabled bool, clinicianID int) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.disabled[clinicianID] = disabled
+	return nil
+}
+
+func TestDisableAccount(t *testing.T) {
+	var tests = []struct {
+		description string
+
+		clinicianIDInput int
+		logger           *mockAuditLogger
+		disabler         *mockAccountDisabler
+
+		disabledOutput map[int]bool
+		logsOutput     []deiz.AuditLog
+		errorOutput    error
+	}{
+		{
+			description:      "should prevent admin from disabling its own account",
+			clinicianIDInput: 1,
+			logger:           &mockAuditLogger{},
+			disabler:         &mockAccountDisabler{disabled: map[int]bool{}},
+			disabledOutput:   map[int]bool{},
+			errorOutput:      deiz.ErrorUnauthorized,
+		},
+		{
+			description:      "should not disable account when audit log fails",
+			clinicianIDInput: 2,
+			logger:           &mockAuditLogger{err: deiz.GenericError},
+			disabler:         &mockAccountDisabler{disabled: map[int]bool{}},
+			disabledOutput:   map[int]bool{},
+			errorOutput:      deiz.GenericError,
+		},
+		{
+			description:      "should keep audit log of a failed attempt",
+			clinicianIDInput: 2,
+			logger:           &mockAuditLogger{},
+			disabler:         &mockAccountDisabler{err: deiz.GenericError},
+			logsOutput:       []deiz.AuditLog{{AdminID: 1, Action: deiz.AuditDisableAccount, TargetID: 2}},
+			errorOutput:      deiz.GenericError,
+		},
+		{
+			description:      "should disable account",
+			clinicianIDInput: 2,
+			logger:           &mockAuditLogger{},
+			disabler:         &mockAccountDisabler{disabled: map[int]bool{}},
+			disabledOutput:   map[int]bool{2: true},
+			logsOutput:       []deiz.AuditLog{{AdminID: 1, Action: deiz.AuditDisableAccount, TargetID: 2}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			u := admin.Usecase{AuditLogger: test.logger, AccountDisabler: test.disabler}
+			err := u.DisableAccount(context.Background(), test.clinicianIDInput, 1)
+			assert.Equal(t, test.errorOutput, err)
+			assert.Equal(t, test.disabledOutput, test.disabler.disabled)
+			assert.Equal(t, test.logsOutput, test.logger.logs)
+		})
+	}
+}
@@ -0,0 +1,269 @@
+package analytics
+
+import (
+	"context"
+	"github.com/audrenbdb/deiz"
+	"github.com/audrenbdb/deiz/interval"
+	"sort"
+	"time"
+)
+
+type (
+	timezoneGetter interface {
+		GetClinicianTimezone(ctx context.Context, clinicianID int) (deiz.Timezone, error)
+	}
+	periodInvoicesGetter interface {
+		GetPeriodBookingInvoices(ctx context.Context, start, end time.Time, clinicianID int) ([]deiz.BookingInvoice, error)
+	}
+	bookingsGetter interface {
+		GetNonRecurrentClinicianBookingsInTimeRange(ctx context.Context, start, end time.Time, clinicianID int) ([]deiz.Booking, error)
+	}
+	unpaidBookingsGetter interface {
+		GetUnpaidBookings(ctx context.Context, clinicianID int) ([]deiz.BookingBalance, error)
+	}
+	cancellationsGetter interface {
+		GetPeriodBookingCancellations(ctx context.Context, start, end time.Time, clinicianID int) ([]deiz.BookingCancellation, error)
+	}
+	officeHoursGetter interface {
+		GetClinicianOfficeHours(ctx context.Context, clinicianID int) ([]deiz.OfficeHours, error)
+	}
+	extraAvailabilitiesGetter interface {
+		GetClinicianExtraAvailabilitiesInTimeRange(ctx context.Context, start, end time.Time, clinicianID int) ([]deiz.ExtraAvailability, error)
+	}
+)
+
+type DashboardUsecase struct {
+	TimezoneGetter            timezoneGetter
+	InvoicesGetter            periodInvoicesGetter
+	BookingsGetter            bookingsGetter
+	UnpaidBookingsGetter      unpaidBookingsGetter
+	CancellationsGetter       cancellationsGetter
+	OfficeHoursGetter         officeHoursGetter
+	ExtraAvailabilitiesGetter extraAvailabilitiesGetter
+}
+
+//GetRevenueStats sums clinician revenue and activity of every period of given bucket within time range.
+//Periods follow clinician calendar, first and last ones extending to their calendar limits.
+//Weekly recurrent bookings, templates rather than actual sessions, are left out.
+func (d *DashboardUsecase) GetRevenueStats(ctx context.Context, start, end time.Time, bucket deiz.StatsBucket, clinicianID int) ([]deiz.PeriodStats, error) {
+	if !bucket.IsValid() || !start.Before(end) {
+		return nil, deiz.ErrorStructValidation
+	}
+	tz, err := d.TimezoneGetter.GetClinicianTimezone(ctx, clinicianID)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := tz.Location()
+	if err != nil {
+		return nil, err
+	}
+	periods := bucket.Periods(start, end, loc)
+	if len(periods) > deiz.MaxStatsPeriods {
+		return nil, deiz.ErrorStructValidation
+	}
+	limit := interval.Range{Start: periods[0].Start, End: periods[len(periods)-1].End}
+	stats := newPeriodsStats(periods)
+
+	invoices, err := d.InvoicesGetter.GetPeriodBookingInvoices(ctx, limit.Start, limit.End, clinicianID)
+	if err != nil {
+		return nil, err
+	}
+	stats.addRevenue(invoices)
+
+	bookings, err := d.BookingsGetter.GetNonRecurrentClinicianBookingsInTimeRange(ctx, limit.Start, limit.End, clinicianID)
+	if err != nil {
+		return nil, err
+	}
+	stats.addAppointments(bookings)
+
+	balances, err := d.UnpaidBookingsGetter.GetUnpaidBookings(ctx, clinicianID)
+	if err != nil {
+		return nil, err
+	}
+	stats.addOutstanding(balances)
+
+	cancellations, err := d.CancellationsGetter.GetPeriodBookingCancellations(ctx, limit.Start, limit.End, clinicianID)
+	if err != nil {
+		return nil, err
+	}
+	stats.addCancellations(cancellations)
+
+	officeHours, err := d.OfficeHoursGetter.GetClinicianOfficeHours(ctx, clinicianID)
+	if err != nil {
+		return nil, err
+	}
+	extraAvailabilities, err := d.ExtraAvailabilitiesGetter.GetClinicianExtraAvailabilitiesInTimeRange(ctx, limit.Start, limit.End, clinicianID)
+	if err != nil {
+		return nil, err
+	}
+	stats.addCapacity(officeHours, extraAvailabilities, loc)
+	return stats.list(), nil
+}
+
+//periodsStats accumulates statistics of consecutive periods
+type periodsStats struct {
+	periods []interval.Range
+	stats   []deiz.PeriodStats
+	shares  []revenueShares
+}
+
+//revenueShares indexes shares of a period by motive, payment method and meeting mode id
+type revenueShares struct {
+	byMotive        map[int]*deiz.RevenueShare
+	byPaymentMethod map[int]*deiz.RevenueShare
+	byMeetingMode   map[int]*deiz.RevenueShare
+}
+
+func newPeriodsStats(periods []interval.Range) *periodsStats {
+	s := &periodsStats{
+		periods: periods,
+		stats:   make([]deiz.PeriodStats, len(periods)),
+		shares:  make([]revenueShares, len(periods)),
+	}
+	for n, p := range periods {
+		s.stats[n].Start = p.Start
+		s.stats[n].End = p.End
+		s.shares[n] = revenueShares{
+			byMotive:        map[int]*deiz.RevenueShare{},
+			byPaymentMethod: map[int]*deiz.RevenueShare{},
+			byMeetingMode:   map[int]*deiz.RevenueShare{},
+		}
+	}
+	return s
+}
+
+//periodIndex returns index of the period containing date, -1 if none
+func (s *periodsStats) periodIndex(date time.Time) int {
+	n := sort.Search(len(s.periods), func(n int) bool {
+		return s.periods[n].End.After(date)
+	})
+	if n == len(s.periods) || date.Before(s.periods[n].Start) {
+		return -1
+	}
+	return n
+}
+
+const noMotiveName = "Sans motif"
+
+var meetingModeNames = map[deiz.MeetingMode]string{
+	deiz.RemoteMode:        "À distance",
+	deiz.InOfficeMode:      "Au cabinet",
+	deiz.AtExternalAddress: "À domicile",
+}
+
+//addRevenue books invoice lines in the period of their delivery date
+func (s *periodsStats) addRevenue(invoices []deiz.BookingInvoice) {
+	for _, i := range invoices {
+		for _, l := range i.Lines {
+			n := s.periodIndex(l.DeliveryDate)
+			if n < 0 {
+				continue
+			}
+			s.stats[n].Revenue += l.PriceAfterTax
+			s.stats[n].RevenueBeforeTax += l.PriceBeforeTax
+			motiveName := l.Booking.Motive.Name
+			if l.Booking.Motive.ID == 0 {
+				motiveName = noMotiveName
+			}
+			addShare(s.shares[n].byMotive, l.Booking.Motive.ID, motiveName, l.PriceAfterTax)
+			addShare(s.shares[n].byPaymentMethod, i.PaymentMethod.ID, i.PaymentMethod.Name, l.PriceAfterTax)
+			if l.Booking.ID != 0 {
+				mode := l.Booking.MeetingMode
+				addShare(s.shares[n].byMeetingMode, int(mode), meetingModeNames[mode], l.PriceAfterTax)
+			}
+		}
+	}
+}
+
+func addShare(shares map[int]*deiz.RevenueShare, id int, name string, revenue int64) {
+	share, ok := shares[id]
+	if !ok {
+		share = &deiz.RevenueShare{ID: id, Name: name}
+		shares[id] = share
+	}
+	share.Revenue += revenue
+	share.Count++
+}
+
+//addAppointments counts confirmed appointments and minutes booked in each period
+func (s *periodsStats) addAppointments(bookings []deiz.Booking) {
+	for _, b := range bookings {
+		if b.BookingType != deiz.AppointmentBooking || !b.Confirmed {
+			continue
+		}
+		if n := s.periodIndex(b.Start); n >= 0 {
+			s.stats[n].Appointments++
+			if b.NoShow {
+				s.stats[n].NoShows++
+			}
+		}
+		for n, p := range s.periods {
+			booked := p.Intersect(interval.Range{Start: b.Start, End: b.End})
+			if !booked.IsNull() {
+				s.stats[n].BookedMn += int(booked.End.Sub(booked.Start).Minutes())
+			}
+		}
+	}
+}
+
+func (s *periodsStats) addOutstanding(balances []deiz.BookingBalance) {
+	for _, b := range balances {
+		if n := s.periodIndex(b.Booking.Start); n >= 0 && b.Balance > 0 {
+			s.stats[n].Outstanding += b.Balance
+		}
+	}
+}
+
+func (s *periodsStats) addCancellations(cancellations []deiz.BookingCancellation) {
+	for _, c := range cancellations {
+		if n := s.periodIndex(c.Start); n >= 0 {
+			s.stats[n].Cancellations++
+		}
+	}
+}
+
+//addCapacity sums minutes of office hours and extra availabilities in each period,
+//overlapping ones being counted once
+func (s *periodsStats) addCapacity(officeHours []deiz.OfficeHours, extraAvailabilities []deiz.ExtraAvailability, loc *time.Location) {
+	for n, p := range s.periods {
+		available := []interval.Range{}
+		for _, h := range officeHours {
+			for _, occurrence := range interval.Weekly(p, h.WeekDay, h.StartMn, h.EndMn, loc) {
+				if h.IsActiveOn(occurrence.Start.In(loc)) {
+					available = append(available, p.Intersect(occurrence))
+				}
+			}
+		}
+		for _, a := range extraAvailabilities {
+			available = append(available, p.Intersect(interval.Range{Start: a.Start, End: a.End}))
+		}
+		for _, r := range interval.Union(available) {
+			s.stats[n].CapacityMn += int(r.End.Sub(r.Start).Minutes())
+		}
+	}
+}
+
+func (s *periodsStats) list() []deiz.PeriodStats {
+	for n := range s.stats {
+		s.stats[n].ByMotive = sortedShares(s.shares[n].byMotive)
+		s.stats[n].ByPaymentMethod = sortedShares(s.shares[n].byPaymentMethod)
+		s.stats[n].ByMeetingMode = sortedShares(s.shares[n].byMeetingMode)
+		s.stats[n].SetOccupancyRate()
+	}
+	return s.stats
+}
+
+//sortedShares lists shares by decreasing revenue
+func sortedShares(shares map[int]*deiz.RevenueShare) []deiz.RevenueShare {
+	list := make([]deiz.RevenueShare, 0, len(shares))
+	for _, share := range shares {
+		list = append(list, *share)
+	}
+	sort.Slice(list, func(a, b int) bool {
+		if list[a].Revenue == list[b].Revenue {
+			return list[a].ID < list[b].ID
+		}
+		return list[a].Revenue > list[b].Revenue
+	})
+	return list
+}
@@ -0,0 +1,203 @@
+package analytics
+
+import (
+	"context"
+	"github.com/audrenbdb/deiz"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type mockTimezoneGetter struct {
+	err error
+}
+
+func (m *mockTimezoneGetter) GetClinicianTimezone(ctx context.Context, clinicianID int) (deiz.Timezone, error) {
+	return deiz.Timezone{ID: 1, Name: "UTC"}, m.err
+}
+
+type mockPeriodInvoicesGetter struct {
+	invoices []deiz.BookingInvoice
+	err      error
+}
+
+func (m *mockPeriodInvoicesGetter) GetPeriodBookingInvoices(ctx context.Context, start, end time.Time, clinicianID int) ([]deiz.BookingInvoice, error) {
+	return m.invoices, m.err
+}
+
+type mockBookingsGetter struct {
+	bookings []deiz.Booking
+	err      error
+}
+
+func (m *mockBookingsGetter) GetNonRecurrentClinicianBookingsInTimeRange(ctx context.Context, start, end time.Time, clinicianID int) ([]deiz.Booking, error) {
+	return m.bookings, m.err
+}
+
+type mockUnpaidBookingsGetter struct {
+	balances []deiz.BookingBalance
+	err      error
+}
+
+func (m *mockUnpaidBookingsGetter) GetUnpaidBookings(ctx context.Context, clinicianID int) ([]deiz.BookingBalance, error) {
+	return m.balances, m.err
+}
+
+type mockCancellationsGetter struct {
+	cancellations []deiz.BookingCancellation
+	err           error
+}
+
+func (m *mockCancellationsGetter) GetPeriodBookingCancellations(ctx context.Context, start, end time.Time, clinicianID int) ([]deiz.BookingCancellation, error) {
+	return m.cancellations, m.err
+}
+
+type mockOfficeHoursGetter struct {
+	officeHours []deiz.OfficeHours
+	err         error
+}
+
+func (m *mockOfficeHoursGetter) GetClinicianOfficeHours(ctx context.Context, clinicianID int) ([]deiz.OfficeHours, error) {
+	return m.officeHours, m.err
+}
+
+type mockExtraAvailabilitiesGetter struct {
+	availabilities []deiz.ExtraAvailability
+	err            error
+}
+
+func (m *mockExtraAvailabilitiesGetter) GetClinicianExtraAvailabilitiesInTimeRange(ctx context.Context, start, end time.Time, clinicianID int) ([]deiz.ExtraAvailability, error) {
+	return m.availabilities, m.err
+}
+
+func validDashboard() DashboardUsecase {
+	return DashboardUsecase{
+		TimezoneGetter:            &mockTimezoneGetter{},
+		InvoicesGetter:            &mockPeriodInvoicesGetter{},
+		BookingsGetter:            &mockBookingsGetter{},
+		UnpaidBookingsGetter:      &mockUnpaidBookingsGetter{},
+		CancellationsGetter:       &mockCancellationsGetter{},
+		OfficeHoursGetter:         &mockOfficeHoursGetter{},
+		ExtraAvailabilitiesGetter: &mockExtraAvailabilitiesGetter{},
+	}
+}
+
+func TestGetRevenueStatsErrors(t *testing.T) {
+	start := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	failing := func(set func(u *DashboardUsecase)) DashboardUsecase {
+		u := validDashboard()
+		set(&u)
+		return u
+	}
+	var tests = []struct {
+		description string
+
+		bucket deiz.StatsBucket
+		start  time.Time
+
+		usecase DashboardUsecase
+	}{
+		{
+			description: "should refuse an unknown bucket",
+			bucket:      "quarter",
+			start:       start,
+			usecase:     validDashboard(),
+		},
+		{
+			description: "should refuse an empty time range",
+			bucket:      deiz.MonthBucket,
+			start:       end,
+			usecase:     validDashboard(),
+		},
+		{
+			description: "should refuse too many periods",
+			bucket:      deiz.DayBucket,
+			start:       start.AddDate(-2, 0, 0),
+			usecase:     validDashboard(),
+		},
+		{
+			description: "should fail to get invoices",
+			bucket:      deiz.MonthBucket,
+			start:       start,
+			usecase: failing(func(u *DashboardUsecase) {
+				u.InvoicesGetter = &mockPeriodInvoicesGetter{err: deiz.GenericError}
+			}),
+		},
+		{
+			description: "should fail to get cancellations",
+			bucket:      deiz.MonthBucket,
+			start:       start,
+			usecase: failing(func(u *DashboardUsecase) {
+				u.CancellationsGetter = &mockCancellationsGetter{err: deiz.GenericError}
+			}),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			_, err := test.usecase.GetRevenueStats(context.Background(), test.start, end, test.bucket, 1)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestGetRevenueStats(t *testing.T) {
+	june := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	july := june.AddDate(0, 1, 0)
+	at := func(day, hour int) time.Time {
+		return time.Date(2021, 6, day, hour, 0, 0, 0, time.UTC)
+	}
+	card := deiz.PaymentMethod{ID: 2, Name: "Carte bancaire"}
+	visit := deiz.Booking{ID: 1, MeetingMode: deiz.InOfficeMode, Motive: deiz.BookingMotive{ID: 3, Name: "Bilan"}}
+
+	u := validDashboard()
+	u.InvoicesGetter = &mockPeriodInvoicesGetter{invoices: []deiz.BookingInvoice{
+		{PaymentMethod: card, Lines: []deiz.InvoiceLine{
+			{Booking: visit, DeliveryDate: at(7, 9), PriceBeforeTax: 5000, PriceAfterTax: 6000},
+			{DeliveryDate: at(8, 9), PriceBeforeTax: 2000, PriceAfterTax: 2000},
+		}},
+		{PaymentMethod: card, Kind: deiz.CreditNoteDocument, Lines: []deiz.InvoiceLine{
+			{DeliveryDate: at(8, 9), PriceBeforeTax: -2000, PriceAfterTax: -2000},
+		}},
+		{PaymentMethod: card, Lines: []deiz.InvoiceLine{
+			{DeliveryDate: july.AddDate(0, 0, 1), PriceAfterTax: 9900},
+		}},
+	}}
+	u.BookingsGetter = &mockBookingsGetter{bookings: []deiz.Booking{
+		{BookingType: deiz.AppointmentBooking, Confirmed: true, Start: at(7, 9), End: at(7, 10)},
+		{BookingType: deiz.AppointmentBooking, Confirmed: true, NoShow: true, Start: at(14, 9), End: at(14, 10)},
+		{BookingType: deiz.AppointmentBooking, Start: at(21, 9), End: at(21, 10)},
+		{BookingType: deiz.BlockedBooking, Start: at(21, 10), End: at(21, 12)},
+	}}
+	u.UnpaidBookingsGetter = &mockUnpaidBookingsGetter{balances: []deiz.BookingBalance{
+		{Booking: deiz.Booking{Start: at(14, 9)}, Balance: 6000},
+		{Booking: deiz.Booking{Start: june.AddDate(0, -1, 0)}, Balance: 6000},
+	}}
+	u.CancellationsGetter = &mockCancellationsGetter{cancellations: []deiz.BookingCancellation{{Start: at(10, 9)}}}
+	//mondays 9:00 to 12:00, june 2021 counting 4 of them
+	u.OfficeHoursGetter = &mockOfficeHoursGetter{officeHours: []deiz.OfficeHours{{WeekDay: 1, StartMn: 540, EndMn: 720}}}
+	//overlapping extra availability is counted once
+	u.ExtraAvailabilitiesGetter = &mockExtraAvailabilitiesGetter{availabilities: []deiz.ExtraAvailability{{Start: at(7, 11), End: at(7, 13)}}}
+
+	stats, err := u.GetRevenueStats(context.Background(), june, july, deiz.MonthBucket, 1)
+	assert.NoError(t, err)
+	assert.Len(t, stats, 1)
+	s := stats[0]
+	assert.Equal(t, june, s.Start)
+	assert.Equal(t, july, s.End)
+	assert.Equal(t, int64(6000), s.Revenue)
+	assert.Equal(t, int64(5000), s.RevenueBeforeTax)
+	assert.Equal(t, []deiz.RevenueShare{
+		{ID: 3, Name: "Bilan", Revenue: 6000, Count: 1},
+		{ID: 0, Name: noMotiveName, Revenue: 0, Count: 2},
+	}, s.ByMotive)
+	assert.Equal(t, []deiz.RevenueShare{{ID: 2, Name: "Carte bancaire", Revenue: 6000, Count: 3}}, s.ByPaymentMethod)
+	assert.Equal(t, []deiz.RevenueShare{{ID: int(deiz.InOfficeMode), Name: "Au cabinet", Revenue: 6000, Count: 1}}, s.ByMeetingMode)
+	assert.Equal(t, int64(6000), s.Outstanding)
+	assert.Equal(t, 2, s.Appointments)
+	assert.Equal(t, 1, s.NoShows)
+	assert.Equal(t, 1, s.Cancellations)
+	assert.Equal(t, 120, s.BookedMn)
+	assert.Equal(t, 4*180+60, s.CapacityMn)
+	assert.InDelta(t, 120.0/780.0, s.OccupancyRate, 1e-9)
+}
@@ -5,45 +5,130 @@ import (
 	"context"
 	"fmt"
 	"github.com/audrenbdb/deiz"
+	"github.com/audrenbdb/deiz/blob"
 	"time"
 )
 
-//invoice identifier format
-const invoiceIDFormat = "DEIZ-%d-%08d"
-
 //common interface for billing usecases
 type (
-	invoicesCounter interface {
-		CountClinicianInvoices(ctx context.Context, clinicianID int) (int, error)
-	}
 	pdfInvoiceCreater interface {
 		CreateBookingInvoicePDF(i *deiz.BookingInvoice) (*bytes.Buffer, error)
 	}
+	invoiceGetter interface {
+		GetBookingInvoiceByID(ctx context.Context, invoiceID int, clinicianID int) (deiz.BookingInvoice, error)
+	}
 	periodInvoicesGetter interface {
 		GetPeriodBookingInvoices(ctx context.Context, start, end time.Time, clinicianID int) ([]deiz.BookingInvoice, error)
 	}
 )
 
-type mailInvoiceDeps struct {
-	mailer     invoiceMailer
+type (
+	blobStore interface {
+		PutBlob(ctx context.Context, key string, data []byte) error
+		GetBlob(ctx context.Context, key string) ([]byte, error)
+	}
+	invoiceArchiveSaver interface {
+		SaveInvoiceArchive(ctx context.Context, invoiceID int, clinicianID int, a deiz.InvoiceArchive) error
+	}
+)
+
+//archive key, hash being part of it so that a failed archiving attempt never blocks the next one
+const invoiceArchiveKeyFormat = "invoices/%d/%s-%s.pdf"
+
+type invoiceArchiveDeps struct {
 	pdfCreater pdfInvoiceCreater
-	invoice    *deiz.BookingInvoice
-	recipient  string
+	blobs      blobStore
+	saver      invoiceArchiveSaver
 }
 
-func mailInvoice(s mailInvoiceDeps) error {
-	pdf, err := s.pdfCreater.CreateBookingInvoicePDF(s.invoice)
+//archiveInvoicePDF renders invoice and stores the exact PDF bytes with their hash.
+//Archive is immutable, later sends and downloads serving the same PDF.
+func archiveInvoicePDF(ctx context.Context, invoice *deiz.BookingInvoice, s invoiceArchiveDeps) (*bytes.Buffer, error) {
+	pdf, archive, err := storeInvoicePDF(ctx, invoice, s)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return s.mailer.MailBookingInvoice(s.invoice, pdf, s.recipient)
+	if err := s.saver.SaveInvoiceArchive(ctx, invoice.ID, invoice.ClinicianID, archive); err != nil {
+		return nil, err
+	}
+	invoice.Archive = archive
+	return pdf, nil
+}
+
+//storeInvoicePDF renders invoice and puts the PDF bytes in the blob store, under a key holding their hash.
+//A blob already stored with the same hash, left by an attempt rolled back, is kept as is.
+func storeInvoicePDF(ctx context.Context, invoice *deiz.BookingInvoice, s invoiceArchiveDeps) (*bytes.Buffer, deiz.InvoiceArchive, error) {
+	pdf, err := s.pdfCreater.CreateBookingInvoicePDF(invoice)
+	if err != nil {
+		return nil, deiz.InvoiceArchive{}, err
+	}
+	hash := deiz.ArchiveHash(pdf.Bytes())
+	archive := deiz.InvoiceArchive{
+		Key:    fmt.Sprintf(invoiceArchiveKeyFormat, invoice.ClinicianID, invoice.Identifier, hash[:12]),
+		SHA256: hash,
+	}
+	err = s.blobs.PutBlob(ctx, archive.Key, pdf.Bytes())
+	if err == blob.ErrBlobExists {
+		err = checkStoredArchive(ctx, archive, s)
+	}
+	if err != nil {
+		return nil, deiz.InvoiceArchive{}, err
+	}
+	return pdf, archive, nil
 }
 
-func setInvoiceIdentifier(ctx context.Context, invoice *deiz.BookingInvoice, counter invoicesCounter) error {
-	count, err := counter.CountClinicianInvoices(ctx, invoice.ClinicianID)
+func checkStoredArchive(ctx context.Context, archive deiz.InvoiceArchive, s invoiceArchiveDeps) error {
+	stored, err := s.blobs.GetBlob(ctx, archive.Key)
 	if err != nil {
 		return err
 	}
-	invoice.Identifier = fmt.Sprintf(invoiceIDFormat, invoice.ClinicianID, count+1)
+	if !archive.Matches(stored) {
+		return blob.ErrBlobExists
+	}
 	return nil
 }
+
+//archiveWhenSaved returns archiving run by the repository once invoice is numbered,
+//within the transaction saving it. Rendered PDF is kept in pdf to be mailed.
+func archiveWhenSaved(ctx context.Context, pdf **bytes.Buffer, s invoiceArchiveDeps) func(i *deiz.BookingInvoice) error {
+	return func(i *deiz.BookingInvoice) error {
+		rendered, archive, err := storeInvoicePDF(ctx, i, s)
+		if err != nil {
+			return err
+		}
+		i.Archive = archive
+		*pdf = rendered
+		return nil
+	}
+}
+
+//getInvoicePDF serves archived PDF of an invoice after checking its integrity.
+//Invoices issued before archiving existed are archived on first access.
+func getInvoicePDF(ctx context.Context, invoice *deiz.BookingInvoice, s invoiceArchiveDeps) (*bytes.Buffer, error) {
+	if !invoice.Archive.IsSet() {
+		return archiveInvoicePDF(ctx, invoice, s)
+	}
+	data, err := s.blobs.GetBlob(ctx, invoice.Archive.Key)
+	if err != nil {
+		return nil, err
+	}
+	if !invoice.Archive.Matches(data) {
+		return nil, deiz.ErrorInvoiceArchiveAltered
+	}
+	return bytes.NewBuffer(data), nil
+}
+
+type mailInvoiceDeps struct {
+	mailer    invoiceMailer
+	archive   invoiceArchiveDeps
+	invoice   *deiz.BookingInvoice
+	recipient string
+}
+
+func mailInvoice(ctx context.Context, s mailInvoiceDeps) error {
+	pdf, err := getInvoicePDF(ctx, s.invoice, s.archive)
+	if err != nil {
+		return err
+	}
+	return s.mailer.MailBookingInvoice(s.invoice, pdf, s.recipient)
+}
@@ -4,23 +4,44 @@ import (
 	"bytes"
 	"context"
 	"github.com/audrenbdb/deiz"
+	"github.com/audrenbdb/deiz/blob"
 )
 
 var validInvoice = deiz.BookingInvoice{
-	TaxFee:        1,
+	TaxFee:        20,
 	PaymentMethod: deiz.PaymentMethod{ID: 1, Name: "A"},
 	CityAndDate:   "A",
 }
 
 var invalidInvoice = deiz.BookingInvoice{}
 
-type mockInvoicesCounter struct {
-	count int
-	err   error
+type mockBlobStore struct {
+	blobs  map[string][]byte
+	putErr error
+	getErr error
 }
 
-func (m *mockInvoicesCounter) CountClinicianInvoices(ctx context.Context, clinicianID int) (int, error) {
-	return m.count, m.err
+func (m *mockBlobStore) PutBlob(ctx context.Context, key string, data []byte) error {
+	if m.blobs == nil {
+		m.blobs = map[string][]byte{}
+	}
+	if _, ok := m.blobs[key]; ok {
+		return blob.ErrBlobExists
+	}
+	m.blobs[key] = data
+	return m.putErr
+}
+
+func (m *mockBlobStore) GetBlob(ctx context.Context, key string) ([]byte, error) {
+	return m.blobs[key], m.getErr
+}
+
+type mockArchiveSaver struct {
+	err error
+}
+
+func (m *mockArchiveSaver) SaveInvoiceArchive(ctx context.Context, invoiceID int, clinicianID int, a deiz.InvoiceArchive) error {
+	return m.err
 }
 
 type mockInvoiceSender struct {
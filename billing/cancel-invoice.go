@@ -1,27 +1,71 @@
 package billing
 
 import (
+	"bytes"
 	"context"
 	"github.com/audrenbdb/deiz"
+	"time"
 )
 
-//CancelInvoice creates a corrective invoice to erase a previous one with wrong data
+//CancelInvoice issues a credit note cancelling a stored invoice.
 //French invoice system does not allow deletion of invoice, only correction.
-func (c *CancelInvoiceUsecase) CancelInvoice(ctx context.Context, invoiceToCancel *deiz.BookingInvoice) error {
-	invoiceToCancel.RemoveBooking()
-	if err := setInvoiceIdentifier(ctx, invoiceToCancel, c.Counter); err != nil {
-		return err
+//Bookings billed go back to unpaid.
+func (c *CancelInvoiceUsecase) CancelInvoice(ctx context.Context, invoiceID int, clinicianID int) (deiz.BookingInvoice, error) {
+	invoice, err := c.getCancelable(ctx, invoiceID, clinicianID)
+	if err != nil {
+		return deiz.BookingInvoice{}, err
 	}
-	return c.Saver.SaveCorrectingBookingInvoice(ctx, invoiceToCancel)
+	return c.issue(ctx, invoice.CreditNote())
+}
+
+//CreditBooking issues a credit note paying back amount, tax included, on the line of a booking a stored invoice bills.
+//A booking cancelled once paid online is credited what patient was refunded, the rest standing as cancellation fee.
+//An invoice is credited once.
+func (c *CancelInvoiceUsecase) CreditBooking(ctx context.Context, invoiceID int, bookingID int, amount int64, clinicianID int) (deiz.BookingInvoice, error) {
+	invoice, err := c.getCancelable(ctx, invoiceID, clinicianID)
+	if err != nil {
+		return deiz.BookingInvoice{}, err
+	}
+	creditNote := invoice.PartialCreditNote(bookingID, amount)
+	if len(creditNote.Lines) == 0 || amount <= 0 {
+		return deiz.BookingInvoice{}, deiz.ErrorStructValidation
+	}
+	return c.issue(ctx, creditNote)
+}
+
+func (c *CancelInvoiceUsecase) getCancelable(ctx context.Context, invoiceID int, clinicianID int) (deiz.BookingInvoice, error) {
+	invoice, err := c.Getter.GetBookingInvoiceByID(ctx, invoiceID, clinicianID)
+	if err != nil {
+		return deiz.BookingInvoice{}, err
+	}
+	if !invoice.IsCancelable() {
+		return deiz.BookingInvoice{}, deiz.ErrorInvoiceAlreadyCanceled
+	}
+	return invoice, nil
+}
+
+func (c *CancelInvoiceUsecase) issue(ctx context.Context, creditNote deiz.BookingInvoice) (deiz.BookingInvoice, error) {
+	creditNote.CreatedAt = time.Now().UTC()
+	var pdf *bytes.Buffer
+	archive := archiveWhenSaved(ctx, &pdf, invoiceArchiveDeps{
+		pdfCreater: c.PdfCreater,
+		blobs:      c.Blobs,
+	})
+	if err := c.Saver.SaveCreditNote(ctx, &creditNote, archive); err != nil {
+		return deiz.BookingInvoice{}, err
+	}
+	return creditNote, nil
 }
 
 type (
-	correctiveInvoiceSaver interface {
-		SaveCorrectingBookingInvoice(ctx context.Context, correctiveInvoice *deiz.BookingInvoice) error
+	creditNoteSaver interface {
+		SaveCreditNote(ctx context.Context, creditNote *deiz.BookingInvoice, archive func(c *deiz.BookingInvoice) error) error
 	}
 )
 
 type CancelInvoiceUsecase struct {
-	Counter invoicesCounter
-	Saver   correctiveInvoiceSaver
+	Getter     invoiceGetter
+	Saver      creditNoteSaver
+	PdfCreater pdfInvoiceCreater
+	Blobs      blobStore
 }
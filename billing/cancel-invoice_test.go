@@ -7,67 +7,154 @@ import (
 	"testing"
 )
 
-type mockCorrectingInvoiceSaver struct {
-	err error
+type mockCreditNoteSaver struct {
+	creditNote deiz.BookingInvoice
+	err        error
 }
 
-func (m *mockCorrectingInvoiceSaver) SaveCorrectingBookingInvoice(ctx context.Context, correctiveInvoice *deiz.BookingInvoice) error {
-	return m.err
+func (m *mockCreditNoteSaver) SaveCreditNote(ctx context.Context, creditNote *deiz.BookingInvoice, archive func(c *deiz.BookingInvoice) error) error {
+	if m.err != nil {
+		return m.err
+	}
+	creditNote.ID = 2
+	m.creditNote = *creditNote
+	return archive(creditNote)
 }
+
 func TestCancelInvoice(t *testing.T) {
+	issued := deiz.BookingInvoice{
+		ID: 1, Identifier: "DEIZ-1-00000001", Booking: deiz.Booking{ID: 1},
+		PriceBeforeTax: 5000, PriceAfterTax: 6000, TaxFee: 20,
+		PaymentMethod: deiz.PaymentMethod{ID: 1, Name: "A"}, CityAndDate: "A",
+		Lines: []deiz.InvoiceLine{{Booking: deiz.Booking{ID: 1}, PriceBeforeTax: 5000, PriceAfterTax: 6000}},
+	}
+	canceled := issued
+	canceled.Canceled = true
+
 	var tests = []struct {
 		description string
 
-		invoiceInput *deiz.BookingInvoice
-		errorOutput  error
+		errorOutput error
 
 		usecase CancelInvoiceUsecase
 	}{
 		{
-			description: "Should remove the booking when canceling an invoice",
-
-			invoiceInput: &deiz.BookingInvoice{Booking: deiz.Booking{ID: 1}},
+			description: "should fail to get invoice to cancel",
+			errorOutput: deiz.GenericError,
 
 			usecase: CancelInvoiceUsecase{
-				Counter: &mockInvoicesCounter{},
-				Saver:   &mockCorrectingInvoiceSaver{},
+				Getter: &mockInvoiceGetter{err: deiz.GenericError},
 			},
 		},
 		{
-			description: "Should fail to generate an invoice identifier",
+			description: "should refuse to cancel an invoice twice",
+			errorOutput: deiz.ErrorInvoiceAlreadyCanceled,
 
-			invoiceInput: &validInvoice,
-			errorOutput:  deiz.GenericError,
+			usecase: CancelInvoiceUsecase{
+				Getter: &mockInvoiceGetter{invoice: canceled},
+			},
+		},
+		{
+			description: "should refuse to cancel a credit note",
+			errorOutput: deiz.ErrorInvoiceAlreadyCanceled,
 
 			usecase: CancelInvoiceUsecase{
-				Counter: &mockInvoicesCounter{err: deiz.GenericError},
+				Getter: &mockInvoiceGetter{invoice: issued.CreditNote()},
 			},
 		},
 		{
-			description: "should fail to save correcting invoice",
+			description: "should fail to save credit note",
+			errorOutput: deiz.GenericError,
 
-			invoiceInput: &validInvoice,
-			errorOutput:  deiz.GenericError,
+			usecase: CancelInvoiceUsecase{
+				Getter: &mockInvoiceGetter{invoice: issued},
+				Saver:  &mockCreditNoteSaver{err: deiz.GenericError},
+			},
+		},
+		{
+			description: "should fail to archive credit note",
+			errorOutput: deiz.GenericError,
 
 			usecase: CancelInvoiceUsecase{
-				Counter: &mockInvoicesCounter{},
-				Saver:   &mockCorrectingInvoiceSaver{err: deiz.GenericError},
+				Getter:     &mockInvoiceGetter{invoice: issued},
+				Saver:      &mockCreditNoteSaver{},
+				PdfCreater: &mockPDFCreater{err: deiz.GenericError},
 			},
 		},
 		{
 			description: "should pass",
 
-			invoiceInput: &validInvoice,
 			usecase: CancelInvoiceUsecase{
-				Counter: &mockInvoicesCounter{},
-				Saver:   &mockCorrectingInvoiceSaver{},
+				Getter:     &mockInvoiceGetter{invoice: issued},
+				Saver:      &mockCreditNoteSaver{},
+				PdfCreater: &mockPDFCreater{},
+				Blobs:      &mockBlobStore{},
 			},
 		},
 	}
 
 	for _, test := range tests {
-		err := test.usecase.CancelInvoice(context.Background(), test.invoiceInput)
-		assert.Equal(t, test.errorOutput, err)
-		assert.True(t, test.invoiceInput.Booking.ID == 0)
+		t.Run(test.description, func(t *testing.T) {
+			_, err := test.usecase.CancelInvoice(context.Background(), 1, 1)
+			assert.Equal(t, test.errorOutput, err)
+		})
 	}
+
+	t.Run("should issue a credit note offsetting invoice", func(t *testing.T) {
+		saver := &mockCreditNoteSaver{}
+		u := CancelInvoiceUsecase{
+			Getter:     &mockInvoiceGetter{invoice: issued},
+			Saver:      saver,
+			PdfCreater: &mockPDFCreater{},
+			Blobs:      &mockBlobStore{},
+		}
+		creditNote, err := u.CancelInvoice(context.Background(), 1, 1)
+		assert.NoError(t, err)
+		assert.True(t, creditNote.IsCreditNote())
+		assert.Equal(t, "DEIZ-1-00000001", creditNote.CanceledInvoice.Identifier)
+		assert.Equal(t, int64(-6000), saver.creditNote.PriceAfterTax)
+		assert.Equal(t, []int{1}, saver.creditNote.BookingIDs())
+		assert.False(t, creditNote.CreatedAt.IsZero())
+		assert.True(t, creditNote.Archive.IsSet())
+	})
+}
+
+func TestCreditBooking(t *testing.T) {
+	issued := deiz.BookingInvoice{
+		ID: 1, Identifier: "DEIZ-1-00000001", Booking: deiz.Booking{ID: 1},
+		PriceBeforeTax: 5000, PriceAfterTax: 5000,
+		PaymentMethod: deiz.PaymentMethod{ID: 1, Name: "A"}, CityAndDate: "A",
+		Lines: []deiz.InvoiceLine{{Booking: deiz.Booking{ID: 1}, PriceBeforeTax: 5000, PriceAfterTax: 5000}},
+	}
+	newUsecase := func(invoice deiz.BookingInvoice) (CancelInvoiceUsecase, *mockCreditNoteSaver) {
+		saver := &mockCreditNoteSaver{}
+		return CancelInvoiceUsecase{
+			Getter:     &mockInvoiceGetter{invoice: invoice},
+			Saver:      saver,
+			PdfCreater: &mockPDFCreater{},
+			Blobs:      &mockBlobStore{},
+		}, saver
+	}
+
+	t.Run("should credit amount refunded on booking line", func(t *testing.T) {
+		u, saver := newUsecase(issued)
+		creditNote, err := u.CreditBooking(context.Background(), 1, 1, 2500, 1)
+		assert.NoError(t, err)
+		assert.True(t, creditNote.IsCreditNote())
+		assert.Equal(t, int64(-2500), saver.creditNote.PriceAfterTax)
+		assert.True(t, creditNote.Archive.IsSet())
+	})
+	t.Run("should refuse to credit a booking invoice does not bill", func(t *testing.T) {
+		u, saver := newUsecase(issued)
+		_, err := u.CreditBooking(context.Background(), 1, 2, 2500, 1)
+		assert.Equal(t, deiz.ErrorStructValidation, err)
+		assert.Equal(t, deiz.BookingInvoice{}, saver.creditNote)
+	})
+	t.Run("should refuse to credit an invoice twice", func(t *testing.T) {
+		canceled := issued
+		canceled.Canceled = true
+		u, _ := newUsecase(canceled)
+		_, err := u.CreditBooking(context.Background(), 1, 1, 2500, 1)
+		assert.Equal(t, deiz.ErrorInvoiceAlreadyCanceled, err)
+	})
 }
@@ -7,33 +7,66 @@ import (
 )
 
 func (i *CreateInvoiceUsecase) CreateInvoice(ctx context.Context, invoice *deiz.BookingInvoice, sendToPatient bool) error {
+	invoice.SetLines()
 	if invoice.IsInvalid() {
 		return deiz.ErrorStructValidation
 	}
-	if err := setInvoiceIdentifier(ctx, invoice, i.Counter); err != nil {
+	if err := i.computeAmounts(ctx, invoice); err != nil {
 		return err
 	}
-	if err := i.Saver.SaveBookingInvoice(ctx, invoice); err != nil {
+	//identifier is set when saving, within the transaction taking the next invoice number,
+	//which archives the PDF so that no invoice is issued without it
+	var pdf *bytes.Buffer
+	archive := archiveWhenSaved(ctx, &pdf, invoiceArchiveDeps{
+		pdfCreater: i.PdfCreater,
+		blobs:      i.Blobs,
+	})
+	if err := i.Saver.SaveBookingInvoice(ctx, invoice, archive); err != nil {
 		return err
 	}
-	if sendToPatient && invoice.Booking.Patient.IsEmailSet() {
-		return i.send(invoice)
+	patient := invoice.Patient()
+	if sendToPatient && patient.IsEmailSet() {
+		return i.Mailer.MailBookingInvoice(invoice, pdf, patient.Email)
 	}
 	return nil
 }
 
-func (i *CreateInvoiceUsecase) send(invoice *deiz.BookingInvoice) error {
-	return mailInvoice(mailInvoiceDeps{
-		pdfCreater: i.PdfCreater,
-		mailer:     i.Mailer,
-		invoice:    invoice,
-		recipient:  invoice.Booking.Patient.Email,
-	})
+//computeAmounts sets invoice amounts from bookings price and clinician tax regime.
+//A line not related to a booking is charged the price given by clinician.
+func (i *CreateInvoiceUsecase) computeAmounts(ctx context.Context, invoice *deiz.BookingInvoice) error {
+	prices := make([]int64, len(invoice.Lines))
+	for n, l := range invoice.Lines {
+		prices[n] = l.PriceAfterTax
+		if l.Booking.ID == 0 {
+			continue
+		}
+		b, err := i.BookingGetter.GetBookingByID(ctx, l.Booking.ID)
+		if err != nil {
+			return err
+		}
+		if b.Clinician.ID != invoice.ClinicianID {
+			return deiz.ErrorUnauthorized
+		}
+		invoice.Lines[n].Booking = b
+		prices[n] = b.Price
+	}
+	invoice.SetLines()
+	business, err := i.BusinessGetter.GetClinicianBusiness(ctx, invoice.ClinicianID)
+	if err != nil {
+		return err
+	}
+	return invoice.ApplyTax(prices, business)
 }
 
 type (
 	invoiceSaver interface {
-		SaveBookingInvoice(ctx context.Context, i *deiz.BookingInvoice) error
+		SaveBookingInvoice(ctx context.Context, i *deiz.BookingInvoice, archive func(i *deiz.BookingInvoice) error) error
+	}
+	bookingGetter interface {
+		GetBookingByID(ctx context.Context, bookingID int) (deiz.Booking, error)
+	}
+	businessGetter interface {
+		GetClinicianBusiness(ctx context.Context, clinicianID int) (deiz.Business, error)
 	}
 	invoiceMailer interface {
 		MailBookingInvoice(invoice *deiz.BookingInvoice, invoicePDF *bytes.Buffer, recipient string) error
@@ -41,8 +74,10 @@ type (
 )
 
 type CreateInvoiceUsecase struct {
-	Counter    invoicesCounter
-	Saver      invoiceSaver
-	Mailer     invoiceMailer
-	PdfCreater pdfInvoiceCreater
+	Saver          invoiceSaver
+	Mailer         invoiceMailer
+	PdfCreater     pdfInvoiceCreater
+	Blobs          blobStore
+	BookingGetter  bookingGetter
+	BusinessGetter businessGetter
 }
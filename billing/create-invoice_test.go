@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"github.com/audrenbdb/deiz"
+	"github.com/audrenbdb/deiz/blob"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
@@ -12,8 +13,11 @@ type mockInvoiceSaver struct {
 	err error
 }
 
-func (m *mockInvoiceSaver) SaveBookingInvoice(ctx context.Context, i *deiz.BookingInvoice) error {
-	return m.err
+func (m *mockInvoiceSaver) SaveBookingInvoice(ctx context.Context, i *deiz.BookingInvoice, archive func(i *deiz.BookingInvoice) error) error {
+	if m.err != nil {
+		return m.err
+	}
+	return archive(i)
 }
 
 type mockPDFCreater struct {
@@ -21,7 +25,25 @@ type mockPDFCreater struct {
 }
 
 func (m *mockPDFCreater) CreateBookingInvoicePDF(i *deiz.BookingInvoice) (*bytes.Buffer, error) {
-	return nil, m.err
+	return bytes.NewBufferString("pdf"), m.err
+}
+
+type mockBookingGetter struct {
+	booking deiz.Booking
+	err     error
+}
+
+func (m *mockBookingGetter) GetBookingByID(ctx context.Context, bookingID int) (deiz.Booking, error) {
+	return m.booking, m.err
+}
+
+type mockBusinessGetter struct {
+	business deiz.Business
+	err      error
+}
+
+func (m *mockBusinessGetter) GetClinicianBusiness(ctx context.Context, clinicianID int) (deiz.Business, error) {
+	return m.business, m.err
 }
 
 func TestGenerate(t *testing.T) {
@@ -42,13 +64,45 @@ func TestGenerate(t *testing.T) {
 			usecase: CreateInvoiceUsecase{},
 		},
 		{
-			description: "should fail to set identifier because count fail",
+			description: "should fail to get clinician business",
 
 			invoiceInput: &validInvoice,
 			errorOutput:  deiz.GenericError,
 
 			usecase: CreateInvoiceUsecase{
-				Counter: &mockInvoicesCounter{err: deiz.GenericError},
+				BusinessGetter: &mockBusinessGetter{err: deiz.GenericError},
+			},
+		},
+		{
+			description: "should refuse to invoice a booking of another clinician",
+
+			invoiceInput: &deiz.BookingInvoice{
+				ClinicianID:   1,
+				Booking:       deiz.Booking{ID: 1},
+				PaymentMethod: deiz.PaymentMethod{ID: 1, Name: "A"},
+				CityAndDate:   "A",
+			},
+			errorOutput: deiz.ErrorUnauthorized,
+
+			usecase: CreateInvoiceUsecase{
+				BookingGetter: &mockBookingGetter{booking: deiz.Booking{ID: 1, Clinician: deiz.Clinician{ID: 2}}},
+			},
+		},
+		{
+			description: "should reject amounts inconsistent with booking price",
+
+			invoiceInput: &deiz.BookingInvoice{
+				ClinicianID:   1,
+				Booking:       deiz.Booking{ID: 1},
+				PriceAfterTax: 5000,
+				PaymentMethod: deiz.PaymentMethod{ID: 1, Name: "A"},
+				CityAndDate:   "A",
+			},
+			errorOutput: deiz.ErrorInvoiceAmountsMismatch,
+
+			usecase: CreateInvoiceUsecase{
+				BookingGetter:  &mockBookingGetter{booking: deiz.Booking{ID: 1, Price: 6000, Clinician: deiz.Clinician{ID: 1}}},
+				BusinessGetter: &mockBusinessGetter{},
 			},
 		},
 		{
@@ -58,8 +112,8 @@ func TestGenerate(t *testing.T) {
 			errorOutput:  deiz.GenericError,
 
 			usecase: CreateInvoiceUsecase{
-				Counter: &mockInvoicesCounter{},
-				Saver:   &mockInvoiceSaver{err: deiz.GenericError},
+				BusinessGetter: &mockBusinessGetter{},
+				Saver:          &mockInvoiceSaver{err: deiz.GenericError},
 			},
 		},
 		{
@@ -71,24 +125,47 @@ func TestGenerate(t *testing.T) {
 			errorOutput: deiz.GenericError,
 
 			usecase: CreateInvoiceUsecase{
-				Counter:    &mockInvoicesCounter{},
-				Saver:      &mockInvoiceSaver{},
-				PdfCreater: &mockPDFCreater{err: deiz.GenericError},
+				BusinessGetter: &mockBusinessGetter{},
+				Saver:          &mockInvoiceSaver{},
+				PdfCreater:     &mockPDFCreater{err: deiz.GenericError},
+			},
+		},
+		{
+			description: "should fail to archive invoice pdf",
+
+			invoiceInput: &validInvoice,
+
+			errorOutput: deiz.GenericError,
+
+			usecase: CreateInvoiceUsecase{
+				BusinessGetter: &mockBusinessGetter{},
+				Saver:          &mockInvoiceSaver{},
+				PdfCreater:     &mockPDFCreater{},
+				Blobs:          &mockBlobStore{putErr: deiz.GenericError},
 			},
 		},
 		{
 			description: "should fail to send invoice pdf",
 
 			sendToPatientInput: true,
-			invoiceInput:       &validInvoice,
+			invoiceInput: &deiz.BookingInvoice{
+				ClinicianID:   1,
+				Booking:       deiz.Booking{ID: 1},
+				PaymentMethod: deiz.PaymentMethod{ID: 1, Name: "A"},
+				CityAndDate:   "A",
+			},
 
 			errorOutput: deiz.GenericError,
 
 			usecase: CreateInvoiceUsecase{
-				Counter:    &mockInvoicesCounter{},
-				Saver:      &mockInvoiceSaver{},
-				PdfCreater: &mockPDFCreater{},
-				Mailer:     &mockInvoiceSender{err: deiz.GenericError},
+				BookingGetter: &mockBookingGetter{booking: deiz.Booking{
+					ID: 1, Clinician: deiz.Clinician{ID: 1}, Patient: deiz.Patient{ID: 1, Email: "patient@deiz.fr"},
+				}},
+				BusinessGetter: &mockBusinessGetter{},
+				Saver:          &mockInvoiceSaver{},
+				PdfCreater:     &mockPDFCreater{},
+				Blobs:          &mockBlobStore{},
+				Mailer:         &mockInvoiceSender{err: deiz.GenericError},
 			},
 		},
 	}
@@ -99,3 +176,22 @@ func TestGenerate(t *testing.T) {
 		assert.Equal(t, test.errorOutput, err)
 	}
 }
+
+func TestStoreInvoicePDF(t *testing.T) {
+	invoice := &deiz.BookingInvoice{ID: 1, ClinicianID: 7, Identifier: "DEIZ-7-00000001"}
+	hash := deiz.ArchiveHash([]byte("pdf"))
+	key := "invoices/7/DEIZ-7-00000001-" + hash[:12] + ".pdf"
+
+	t.Run("should keep pdf stored by an attempt rolled back", func(t *testing.T) {
+		blobs := &mockBlobStore{blobs: map[string][]byte{key: []byte("pdf")}}
+		_, archive, err := storeInvoicePDF(context.Background(), invoice, invoiceArchiveDeps{pdfCreater: &mockPDFCreater{}, blobs: blobs})
+		assert.NoError(t, err)
+		assert.Equal(t, deiz.InvoiceArchive{Key: key, SHA256: hash}, archive)
+	})
+	t.Run("should refuse to replace another pdf stored under the same key", func(t *testing.T) {
+		blobs := &mockBlobStore{blobs: map[string][]byte{key: []byte("forged")}}
+		_, _, err := storeInvoicePDF(context.Background(), invoice, invoiceArchiveDeps{pdfCreater: &mockPDFCreater{}, blobs: blobs})
+		assert.Equal(t, blob.ErrBlobExists, err)
+		assert.Equal(t, []byte("forged"), blobs.blobs[key])
+	})
+}
@@ -0,0 +1,26 @@
+package billing
+
+import (
+	"bytes"
+	"context"
+)
+
+//GetInvoicePDF returns archived PDF of a stored invoice of the clinician, as sent by email
+func (d *DownloadInvoiceUsecase) GetInvoicePDF(ctx context.Context, invoiceID int, clinicianID int) (*bytes.Buffer, error) {
+	invoice, err := d.Getter.GetBookingInvoiceByID(ctx, invoiceID, clinicianID)
+	if err != nil {
+		return nil, err
+	}
+	return getInvoicePDF(ctx, &invoice, invoiceArchiveDeps{
+		pdfCreater: d.PdfCreater,
+		blobs:      d.Blobs,
+		saver:      d.ArchiveSaver,
+	})
+}
+
+type DownloadInvoiceUsecase struct {
+	Getter       invoiceGetter
+	PdfCreater   pdfInvoiceCreater
+	Blobs        blobStore
+	ArchiveSaver invoiceArchiveSaver
+}
@@ -0,0 +1,47 @@
+package billing
+
+import (
+	"context"
+	"github.com/audrenbdb/deiz"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestGetInvoicePDF(t *testing.T) {
+	archived := deiz.BookingInvoice{ID: 1, Archive: deiz.InvoiceArchive{Key: "a", SHA256: deiz.ArchiveHash([]byte("issued"))}}
+
+	t.Run("should serve archived pdf instead of rendering it again", func(t *testing.T) {
+		u := DownloadInvoiceUsecase{
+			Getter:     &mockInvoiceGetter{invoice: archived},
+			PdfCreater: &mockPDFCreater{err: deiz.GenericError},
+			Blobs:      &mockBlobStore{blobs: map[string][]byte{"a": []byte("issued")}},
+		}
+		pdf, err := u.GetInvoicePDF(context.Background(), 1, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, "issued", pdf.String())
+	})
+	t.Run("should refuse to serve an altered archive", func(t *testing.T) {
+		u := DownloadInvoiceUsecase{
+			Getter: &mockInvoiceGetter{invoice: archived},
+			Blobs:  &mockBlobStore{blobs: map[string][]byte{"a": []byte("forged")}},
+		}
+		_, err := u.GetInvoicePDF(context.Background(), 1, 1)
+		assert.Equal(t, deiz.ErrorInvoiceArchiveAltered, err)
+	})
+	t.Run("should archive an invoice issued before archiving on first download", func(t *testing.T) {
+		blobs := &mockBlobStore{}
+		u := DownloadInvoiceUsecase{
+			Getter:       &mockInvoiceGetter{invoice: deiz.BookingInvoice{ID: 1, ClinicianID: 7, Identifier: "DEIZ-7-00000001"}},
+			PdfCreater:   &mockPDFCreater{},
+			Blobs:        blobs,
+			ArchiveSaver: &mockArchiveSaver{},
+		}
+		pdf, err := u.GetInvoicePDF(context.Background(), 1, 7)
+		assert.NoError(t, err)
+		assert.Len(t, blobs.blobs, 1)
+		for key, data := range blobs.blobs {
+			assert.Contains(t, key, "invoices/7/DEIZ-7-00000001-")
+			assert.Equal(t, pdf.Bytes(), data)
+		}
+	})
+}
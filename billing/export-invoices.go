@@ -0,0 +1,96 @@
+package billing
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/audrenbdb/deiz"
+	"time"
+)
+
+//ExportInvoices writes invoices of a period as an accounting journal file for clinician accountant
+func (e *ExportInvoicesUsecase) ExportInvoices(ctx context.Context, start, end time.Time, clinicianID int, format deiz.ExportFormat) (deiz.AccountingExport, error) {
+	if !format.IsValid() {
+		return deiz.AccountingExport{}, deiz.ErrorStructValidation
+	}
+	invoices, err := e.InvoicesGetter.GetPeriodBookingInvoices(ctx, start, end, clinicianID)
+	if err != nil {
+		return deiz.AccountingExport{}, err
+	}
+	tz, err := e.TimezoneGetter.GetClinicianTimezone(ctx, clinicianID)
+	if err != nil {
+		return deiz.AccountingExport{}, err
+	}
+	loc, err := tz.Location()
+	if err != nil {
+		return deiz.AccountingExport{}, err
+	}
+	return exportInvoices(ctx, invoices, exportInvoicesDeps{
+		exporter:       e.Exporter,
+		businessGetter: e.BusinessGetter,
+		start:          start,
+		end:            end,
+		loc:            loc,
+		clinicianID:    clinicianID,
+		format:         format,
+	})
+}
+
+const (
+	fecFilenameFormat     = "%sFEC%s.txt"
+	journalFilenameFormat = "journal-%s-%s.csv"
+	filenameDateFormat    = "20060102"
+)
+
+type exportInvoicesDeps struct {
+	exporter       accountingExporter
+	businessGetter businessGetter
+	start, end     time.Time
+	loc            *time.Location
+	clinicianID    int
+	format         deiz.ExportFormat
+}
+
+func exportInvoices(ctx context.Context, invoices []deiz.BookingInvoice, s exportInvoicesDeps) (deiz.AccountingExport, error) {
+	entries := deiz.JournalEntries(invoices, s.loc)
+	end := s.end.In(s.loc).Format(filenameDateFormat)
+	if s.format == deiz.CSVFormat {
+		data, err := s.exporter.CreateJournalCSV(entries)
+		if err != nil {
+			return deiz.AccountingExport{}, err
+		}
+		return deiz.AccountingExport{
+			Filename:    fmt.Sprintf(journalFilenameFormat, s.start.In(s.loc).Format(filenameDateFormat), end),
+			ContentType: "text/csv; charset=utf-8",
+			Data:        data,
+		}, nil
+	}
+	//FEC file is named after company SIREN and fiscal period closing date
+	business, err := s.businessGetter.GetClinicianBusiness(ctx, s.clinicianID)
+	if err != nil {
+		return deiz.AccountingExport{}, err
+	}
+	data, err := s.exporter.CreateFEC(entries)
+	if err != nil {
+		return deiz.AccountingExport{}, err
+	}
+	return deiz.AccountingExport{
+		Filename:    fmt.Sprintf(fecFilenameFormat, business.SIREN(), end),
+		ContentType: "text/plain; charset=utf-8",
+		Data:        data,
+	}, nil
+}
+
+type (
+	accountingExporter interface {
+		CreateFEC(entries []deiz.AccountingEntry) (*bytes.Buffer, error)
+		CreateJournalCSV(entries []deiz.AccountingEntry) (*bytes.Buffer, error)
+	}
+)
+
+type ExportInvoicesUsecase struct {
+	InvoicesGetter periodInvoicesGetter
+	TimezoneGetter timezoneGetter
+	BusinessGetter businessGetter
+	Exporter       accountingExporter
+}
@@ -0,0 +1,120 @@
+package billing
+
+import (
+	"bytes"
+	"context"
+	"github.com/audrenbdb/deiz"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type mockAccountingExporter struct {
+	entries []deiz.AccountingEntry
+	err     error
+}
+
+func (m *mockAccountingExporter) CreateFEC(entries []deiz.AccountingEntry) (*bytes.Buffer, error) {
+	m.entries = entries
+	return bytes.NewBufferString("fec"), m.err
+}
+
+func (m *mockAccountingExporter) CreateJournalCSV(entries []deiz.AccountingEntry) (*bytes.Buffer, error) {
+	m.entries = entries
+	return bytes.NewBufferString("csv"), m.err
+}
+
+func TestExportInvoices(t *testing.T) {
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2021, 12, 31, 0, 0, 0, 0, time.UTC)
+	invoices := []deiz.BookingInvoice{{ID: 1, Identifier: "DEIZ-1-00000001", PriceBeforeTax: 5000, PriceAfterTax: 6000}}
+
+	var tests = []struct {
+		description string
+
+		formatInput    deiz.ExportFormat
+		filenameOutput string
+		errorOutput    error
+
+		usecase ExportInvoicesUsecase
+	}{
+		{
+			description: "should refuse an unknown format",
+			formatInput: "xls",
+			errorOutput: deiz.ErrorStructValidation,
+		},
+		{
+			description: "should fail to get invoices",
+			formatInput: deiz.FECFormat,
+			errorOutput: deiz.GenericError,
+
+			usecase: ExportInvoicesUsecase{
+				InvoicesGetter: &mockPeriodInvoicesGetter{err: deiz.GenericError},
+			},
+		},
+		{
+			description: "should fail to get clinician timezone",
+			formatInput: deiz.FECFormat,
+			errorOutput: deiz.GenericError,
+
+			usecase: ExportInvoicesUsecase{
+				InvoicesGetter: &mockPeriodInvoicesGetter{},
+				TimezoneGetter: &mockTimezoneGetter{err: deiz.GenericError},
+			},
+		},
+		{
+			description: "should fail to get clinician business numbering FEC file",
+			formatInput: deiz.FECFormat,
+			errorOutput: deiz.GenericError,
+
+			usecase: ExportInvoicesUsecase{
+				InvoicesGetter: &mockPeriodInvoicesGetter{},
+				TimezoneGetter: &mockTimezoneGetter{},
+				BusinessGetter: &mockBusinessGetter{err: deiz.GenericError},
+			},
+		},
+		{
+			description: "should fail to create FEC file",
+			formatInput: deiz.FECFormat,
+			errorOutput: deiz.GenericError,
+
+			usecase: ExportInvoicesUsecase{
+				InvoicesGetter: &mockPeriodInvoicesGetter{},
+				TimezoneGetter: &mockTimezoneGetter{},
+				BusinessGetter: &mockBusinessGetter{},
+				Exporter:       &mockAccountingExporter{err: deiz.GenericError},
+			},
+		},
+		{
+			description:    "should name FEC file after business SIREN and period end",
+			formatInput:    deiz.FECFormat,
+			filenameOutput: "123456789FEC20211231.txt",
+
+			usecase: ExportInvoicesUsecase{
+				InvoicesGetter: &mockPeriodInvoicesGetter{invoices: invoices},
+				TimezoneGetter: &mockTimezoneGetter{},
+				BusinessGetter: &mockBusinessGetter{business: deiz.Business{Identifier: "12345678900012"}},
+				Exporter:       &mockAccountingExporter{},
+			},
+		},
+		{
+			description:    "should name CSV journal after period",
+			formatInput:    deiz.CSVFormat,
+			filenameOutput: "journal-20210101-20211231.csv",
+
+			usecase: ExportInvoicesUsecase{
+				InvoicesGetter: &mockPeriodInvoicesGetter{invoices: invoices},
+				TimezoneGetter: &mockTimezoneGetter{},
+				Exporter:       &mockAccountingExporter{},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			export, err := test.usecase.ExportInvoices(context.Background(), start, end, 1, test.formatInput)
+			assert.Equal(t, test.errorOutput, err)
+			assert.Equal(t, test.filenameOutput, export.Filename)
+		})
+	}
+}
@@ -7,7 +7,7 @@ import (
 
 type (
 	unpaidBookingsGetter interface {
-		GetUnpaidBookings(ctx context.Context, clinicianID int) ([]deiz.Booking, error)
+		GetUnpaidBookings(ctx context.Context, clinicianID int) ([]deiz.BookingBalance, error)
 	}
 )
 
@@ -15,6 +15,6 @@ type GetUnpaidBookingsUsecase struct {
 	Getter unpaidBookingsGetter
 }
 
-func (u *GetUnpaidBookingsUsecase) GetUnpaidBookings(ctx context.Context, clinicianID int) ([]deiz.Booking, error) {
+func (u *GetUnpaidBookingsUsecase) GetUnpaidBookings(ctx context.Context, clinicianID int) ([]deiz.BookingBalance, error) {
 	return u.Getter.GetUnpaidBookings(ctx, clinicianID)
 }
@@ -7,40 +7,87 @@ import (
 	"time"
 )
 
-func (m *MailInvoiceUsecase) MailInvoice(invoice *deiz.BookingInvoice, recipient string) error {
-	return mailInvoice(mailInvoiceDeps{
-		invoice:    invoice,
-		mailer:     m.InvoiceMailer,
-		pdfCreater: m.PdfInvoiceCreater,
-		recipient:  recipient,
+//MailInvoice sends archived PDF of a stored invoice of the clinician
+func (m *MailInvoiceUsecase) MailInvoice(ctx context.Context, invoiceID int, clinicianID int, recipient string) error {
+	invoice, err := m.InvoiceGetter.GetBookingInvoiceByID(ctx, invoiceID, clinicianID)
+	if err != nil {
+		return err
+	}
+	return mailInvoice(ctx, mailInvoiceDeps{
+		invoice: &invoice,
+		mailer:  m.InvoiceMailer,
+		archive: invoiceArchiveDeps{
+			pdfCreater: m.PdfInvoiceCreater,
+			blobs:      m.Blobs,
+			saver:      m.ArchiveSaver,
+		},
+		recipient: recipient,
 	})
 }
 
-func (m *MailInvoiceUsecase) MailInvoicesSummary(ctx context.Context, start, end time.Time, recipient string, clinicianID int) error {
+//MailInvoicesSummary sends PDF summary of a period invoices.
+//An accounting export of given format is attached as well, none if format is empty.
+func (m *MailInvoiceUsecase) MailInvoicesSummary(ctx context.Context, start, end time.Time, recipient string, clinicianID int, format deiz.ExportFormat) error {
+	if format != "" && !format.IsValid() {
+		return deiz.ErrorStructValidation
+	}
 	invoices, err := m.InvoicesGetter.GetPeriodBookingInvoices(ctx, start, end, clinicianID)
 	if err != nil {
 		return err
 	}
-	invoicesPDF, err := m.PdfInvoicesSummaryCreater.CreateInvoicesSummaryPDF(invoices, start, end)
+	tz, err := m.TimezoneGetter.GetClinicianTimezone(ctx, clinicianID)
+	if err != nil {
+		return err
+	}
+	loc, err := tz.Location()
+	if err != nil {
+		return err
+	}
+	invoicesPDF, err := m.PdfInvoicesSummaryCreater.CreateInvoicesSummaryPDF(invoices, start, end, loc)
 	if err != nil {
 		return err
 	}
-	return m.InvoicesSummaryMailer.MailInvoicesSummary(invoicesPDF, start, end, recipient)
+	var export *deiz.AccountingExport
+	if format != "" {
+		e, err := exportInvoices(ctx, invoices, exportInvoicesDeps{
+			exporter:       m.Exporter,
+			businessGetter: m.BusinessGetter,
+			start:          start,
+			end:            end,
+			loc:            loc,
+			clinicianID:    clinicianID,
+			format:         format,
+		})
+		if err != nil {
+			return err
+		}
+		export = &e
+	}
+	return m.InvoicesSummaryMailer.MailInvoicesSummary(invoicesPDF, export, start, end, loc, recipient)
 }
 
 type (
 	invoicesSummaryPDFCreater interface {
-		CreateInvoicesSummaryPDF(i []deiz.BookingInvoice, start, end time.Time) (*bytes.Buffer, error)
+		CreateInvoicesSummaryPDF(i []deiz.BookingInvoice, start, end time.Time, loc *time.Location) (*bytes.Buffer, error)
 	}
 	invoicesSummaryMailer interface {
-		MailInvoicesSummary(summaryPDF *bytes.Buffer, start, end time.Time, sendTo string) error
+		MailInvoicesSummary(summaryPDF *bytes.Buffer, export *deiz.AccountingExport, start, end time.Time, loc *time.Location, sendTo string) error
+	}
+	timezoneGetter interface {
+		GetClinicianTimezone(ctx context.Context, clinicianID int) (deiz.Timezone, error)
 	}
 )
 
 type MailInvoiceUsecase struct {
+	InvoiceGetter             invoiceGetter
 	InvoiceMailer             invoiceMailer
 	PdfInvoiceCreater         pdfInvoiceCreater
+	Blobs                     blobStore
+	ArchiveSaver              invoiceArchiveSaver
 	PdfInvoicesSummaryCreater invoicesSummaryPDFCreater
 	InvoicesGetter            periodInvoicesGetter
 	InvoicesSummaryMailer     invoicesSummaryMailer
+	TimezoneGetter            timezoneGetter
+	Exporter                  accountingExporter
+	BusinessGetter            businessGetter
 }
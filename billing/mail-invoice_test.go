@@ -22,35 +22,65 @@ type mockInvoicesSummaryPDFCreater struct {
 	err error
 }
 
-func (m *mockInvoicesSummaryPDFCreater) CreateInvoicesSummaryPDF(i []deiz.BookingInvoice, start, end time.Time) (*bytes.Buffer, error) {
+func (m *mockInvoicesSummaryPDFCreater) CreateInvoicesSummaryPDF(i []deiz.BookingInvoice, start, end time.Time, loc *time.Location) (*bytes.Buffer, error) {
 	return nil, m.err
 }
 
 type mockInvoicesSummaryMailer struct {
-	err error
+	export *deiz.AccountingExport
+	err    error
 }
 
-func (m *mockInvoicesSummaryMailer) MailInvoicesSummary(summaryPDF *bytes.Buffer, start, end time.Time, sendTo string) error {
+func (m *mockInvoicesSummaryMailer) MailInvoicesSummary(summaryPDF *bytes.Buffer, export *deiz.AccountingExport, start, end time.Time, loc *time.Location, sendTo string) error {
+	m.export = export
 	return m.err
 }
 
+type mockTimezoneGetter struct {
+	err error
+}
+
+func (m *mockTimezoneGetter) GetClinicianTimezone(ctx context.Context, clinicianID int) (deiz.Timezone, error) {
+	return deiz.Timezone{ID: 1, Name: "UTC"}, m.err
+}
+
+type mockInvoiceGetter struct {
+	invoice deiz.BookingInvoice
+	err     error
+}
+
+func (m *mockInvoiceGetter) GetBookingInvoiceByID(ctx context.Context, invoiceID int, clinicianID int) (deiz.BookingInvoice, error) {
+	return m.invoice, m.err
+}
+
 func TestMailInvoice(t *testing.T) {
 	var tests = []struct {
 		description string
 
-		invoiceInput   *deiz.BookingInvoice
 		recipientInput string
 		errorOutput    error
 
 		usecase MailInvoiceUsecase
 	}{
+		{
+			description: "should fail to load stored invoice",
+
+			errorOutput: deiz.GenericError,
+
+			usecase: MailInvoiceUsecase{
+				InvoiceGetter: &mockInvoiceGetter{err: deiz.GenericError},
+			},
+		},
 		{
 			description: "should fail to send email",
 
 			errorOutput: deiz.GenericError,
 
 			usecase: MailInvoiceUsecase{
+				InvoiceGetter:     &mockInvoiceGetter{},
 				PdfInvoiceCreater: &mockPDFCreater{},
+				Blobs:             &mockBlobStore{},
+				ArchiveSaver:      &mockArchiveSaver{},
 				InvoiceMailer:     &mockInvoiceSender{err: deiz.GenericError},
 			},
 		},
@@ -60,6 +90,7 @@ func TestMailInvoice(t *testing.T) {
 			errorOutput: deiz.GenericError,
 
 			usecase: MailInvoiceUsecase{
+				InvoiceGetter:     &mockInvoiceGetter{},
 				PdfInvoiceCreater: &mockPDFCreater{err: deiz.GenericError},
 				InvoiceMailer:     &mockInvoiceSender{},
 			},
@@ -68,13 +99,16 @@ func TestMailInvoice(t *testing.T) {
 			description: "should succeed",
 
 			usecase: MailInvoiceUsecase{
+				InvoiceGetter:     &mockInvoiceGetter{},
 				PdfInvoiceCreater: &mockPDFCreater{},
+				Blobs:             &mockBlobStore{},
+				ArchiveSaver:      &mockArchiveSaver{},
 				InvoiceMailer:     &mockInvoiceSender{},
 			},
 		},
 	}
 	for _, test := range tests {
-		err := test.usecase.MailInvoice(&validInvoice, test.recipientInput)
+		err := test.usecase.MailInvoice(context.Background(), 1, 1, test.recipientInput)
 		assert.Equal(t, test.errorOutput, err)
 	}
 }
@@ -87,6 +121,7 @@ func TestMailInvoicesSummary(t *testing.T) {
 		endInput         time.Time
 		recipientInput   string
 		clinicianIDInput int
+		formatInput      deiz.ExportFormat
 		errorOutput      error
 
 		usecase MailInvoiceUsecase
@@ -99,12 +134,22 @@ func TestMailInvoicesSummary(t *testing.T) {
 				InvoicesGetter: &mockPeriodInvoicesGetter{err: deiz.GenericError},
 			},
 		},
+		{
+			description: "should fail to get clinician timezone",
+			errorOutput: deiz.GenericError,
+
+			usecase: MailInvoiceUsecase{
+				InvoicesGetter: &mockPeriodInvoicesGetter{},
+				TimezoneGetter: &mockTimezoneGetter{err: deiz.GenericError},
+			},
+		},
 		{
 			description: "should fail to create summary pdf",
 			errorOutput: deiz.GenericError,
 
 			usecase: MailInvoiceUsecase{
 				InvoicesGetter:            &mockPeriodInvoicesGetter{},
+				TimezoneGetter:            &mockTimezoneGetter{},
 				PdfInvoicesSummaryCreater: &mockInvoicesSummaryPDFCreater{err: deiz.GenericError},
 			},
 		},
@@ -114,14 +159,60 @@ func TestMailInvoicesSummary(t *testing.T) {
 
 			usecase: MailInvoiceUsecase{
 				InvoicesGetter:            &mockPeriodInvoicesGetter{},
+				TimezoneGetter:            &mockTimezoneGetter{},
 				PdfInvoicesSummaryCreater: &mockInvoicesSummaryPDFCreater{},
 				InvoicesSummaryMailer:     &mockInvoicesSummaryMailer{err: deiz.GenericError},
 			},
 		},
+		{
+			description: "should refuse an unknown export format",
+			formatInput: "xls",
+			errorOutput: deiz.ErrorStructValidation,
+		},
+		{
+			description: "should fail to export invoices",
+			formatInput: deiz.CSVFormat,
+			errorOutput: deiz.GenericError,
+
+			usecase: MailInvoiceUsecase{
+				InvoicesGetter:            &mockPeriodInvoicesGetter{},
+				TimezoneGetter:            &mockTimezoneGetter{},
+				PdfInvoicesSummaryCreater: &mockInvoicesSummaryPDFCreater{},
+				Exporter:                  &mockAccountingExporter{err: deiz.GenericError},
+			},
+		},
+		{
+			description: "should pass",
+
+			usecase: MailInvoiceUsecase{
+				InvoicesGetter:            &mockPeriodInvoicesGetter{},
+				TimezoneGetter:            &mockTimezoneGetter{},
+				PdfInvoicesSummaryCreater: &mockInvoicesSummaryPDFCreater{},
+				InvoicesSummaryMailer:     &mockInvoicesSummaryMailer{},
+			},
+		},
 	}
 
 	for _, test := range tests {
-		err := test.usecase.MailInvoicesSummary(context.Background(), test.startInput, test.endInput, test.recipientInput, test.clinicianIDInput)
-		assert.Equal(t, test.errorOutput, err)
+		t.Run(test.description, func(t *testing.T) {
+			err := test.usecase.MailInvoicesSummary(context.Background(), test.startInput, test.endInput, test.recipientInput, test.clinicianIDInput, test.formatInput)
+			assert.Equal(t, test.errorOutput, err)
+		})
 	}
+
+	t.Run("should attach accounting export", func(t *testing.T) {
+		mailer := &mockInvoicesSummaryMailer{}
+		u := MailInvoiceUsecase{
+			InvoicesGetter:            &mockPeriodInvoicesGetter{},
+			TimezoneGetter:            &mockTimezoneGetter{},
+			PdfInvoicesSummaryCreater: &mockInvoicesSummaryPDFCreater{},
+			InvoicesSummaryMailer:     mailer,
+			Exporter:                  &mockAccountingExporter{},
+			BusinessGetter:            &mockBusinessGetter{business: deiz.Business{Identifier: "123 456 789 00012"}},
+		}
+		end := time.Date(2021, 12, 31, 0, 0, 0, 0, time.UTC)
+		err := u.MailInvoicesSummary(context.Background(), time.Time{}, end, "a@b.fr", 1, deiz.FECFormat)
+		assert.NoError(t, err)
+		assert.Equal(t, "123456789FEC20211231.txt", mailer.export.Filename)
+	})
 }
@@ -0,0 +1,113 @@
+package billing
+
+import (
+	"context"
+	"errors"
+	"github.com/audrenbdb/deiz"
+	"time"
+)
+
+type (
+	paymentLinkSigner interface {
+		Sign(bookingID int, expiresAt time.Time) (string, error)
+	}
+	paymentReminderMailer interface {
+		MailPaymentReminder(balance *deiz.BookingBalance, paymentToken string) error
+	}
+)
+
+var errBookingNotUnpaid = errors.New("booking is not awaiting payment")
+
+//PaymentLinkUsecase issues signed links for patients to pay a booking online from their phone,
+//and mails them to patients who left without paying
+type PaymentLinkUsecase struct {
+	Crypter         crypter
+	SecretKeyGetter stripeSecretKeyGetter
+	AccountGetter   stripeAccountGetter
+	UnpaidGetter    unpaidBookingsGetter
+	Signer          paymentLinkSigner
+	Mailer          paymentReminderMailer
+}
+
+//IssuePaymentLink returns the token of a link paying booking online.
+//It is empty when clinician cannot be paid online, and fails with
+//deiz.ErrorNothingToPay when booking has nothing left to pay.
+func (u *PaymentLinkUsecase) IssuePaymentLink(ctx context.Context, b *deiz.Booking) (string, error) {
+	if !b.HasBalance() {
+		return "", deiz.ErrorNothingToPay
+	}
+	err := u.checkPaidOnline(ctx, b.Clinician.ID)
+	if err == deiz.ErrorStripeAccountNotConnected {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return u.sign(b.ID)
+}
+
+//SendPaymentReminder mails patient of an unpaid booking a link to pay its balance
+func (u *PaymentLinkUsecase) SendPaymentReminder(ctx context.Context, bookingID int, clinicianID int) error {
+	if err := u.checkPaidOnline(ctx, clinicianID); err != nil {
+		return err
+	}
+	balances, err := u.UnpaidGetter.GetUnpaidBookings(ctx, clinicianID)
+	if err != nil {
+		return err
+	}
+	for _, b := range balances {
+		if b.Booking.ID != bookingID || b.Balance <= 0 {
+			continue
+		}
+		if !b.Booking.Patient.IsEmailSet() {
+			return deiz.ErrorPatientEmailNotSet
+		}
+		return u.remind(&b)
+	}
+	return errBookingNotUnpaid
+}
+
+//SendPaymentReminders mails a payment link to every patient with a booking unpaid,
+//returning how many reminders were sent. Patients without email are left to clinician.
+func (u *PaymentLinkUsecase) SendPaymentReminders(ctx context.Context, clinicianID int) (int, error) {
+	if err := u.checkPaidOnline(ctx, clinicianID); err != nil {
+		return 0, err
+	}
+	balances, err := u.UnpaidGetter.GetUnpaidBookings(ctx, clinicianID)
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for _, b := range balances {
+		if b.Balance <= 0 || !b.Booking.Patient.IsEmailSet() {
+			continue
+		}
+		if err := u.remind(&b); err != nil {
+			return sent, err
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+func (u *PaymentLinkUsecase) remind(b *deiz.BookingBalance) error {
+	token, err := u.sign(b.Booking.ID)
+	if err != nil {
+		return err
+	}
+	return u.Mailer.MailPaymentReminder(b, token)
+}
+
+func (u *PaymentLinkUsecase) sign(bookingID int) (string, error) {
+	return u.Signer.Sign(bookingID, time.Now().Add(deiz.PaymentLinkValidity))
+}
+
+//checkPaidOnline fails with deiz.ErrorStripeAccountNotConnected when clinician has no Stripe account to be paid on
+func (u *PaymentLinkUsecase) checkPaidOnline(ctx context.Context, clinicianID int) error {
+	_, err := getStripePayee(ctx, stripePayeeDeps{
+		crypter:       u.Crypter,
+		keyGetter:     u.SecretKeyGetter,
+		accountGetter: u.AccountGetter,
+	}, clinicianID)
+	return err
+}
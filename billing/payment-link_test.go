@@ -0,0 +1,188 @@
+package billing
+
+import (
+	"context"
+	"github.com/audrenbdb/deiz"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type mockUnpaidBookingsGetter struct {
+	balances []deiz.BookingBalance
+	err      error
+}
+
+func (m *mockUnpaidBookingsGetter) GetUnpaidBookings(ctx context.Context, clinicianID int) ([]deiz.BookingBalance, error) {
+	return m.balances, m.err
+}
+
+type mockPaymentLinkSigner struct {
+	bookingIDs []int
+	expiresAt  time.Time
+}
+
+func (m *mockPaymentLinkSigner) Sign(bookingID int, expiresAt time.Time) (string, error) {
+	m.bookingIDs = append(m.bookingIDs, bookingID)
+	m.expiresAt = expiresAt
+	return "token", nil
+}
+
+type mockPaymentReminderMailer struct {
+	reminded []int
+	err      error
+}
+
+func (m *mockPaymentReminderMailer) MailPaymentReminder(balance *deiz.BookingBalance, paymentToken string) error {
+	m.reminded = append(m.reminded, balance.Booking.ID)
+	return m.err
+}
+
+func TestIssuePaymentLink(t *testing.T) {
+	unpaidBooking := &deiz.Booking{ID: 4, Clinician: deiz.Clinician{ID: 1}, Price: 5000}
+	connected := &mockStripeAccountGetter{account: deiz.StripeAccount{ID: "acct_1", ChargesEnabled: true}}
+	var tests = []struct {
+		description string
+
+		bookingInput *deiz.Booking
+
+		tokenOutput string
+		errorOutput error
+
+		usecase PaymentLinkUsecase
+	}{
+		{
+			description:  "should issue no link for a booking already paid",
+			bookingInput: &deiz.Booking{ID: 4, Price: 5000, Paid: true},
+			errorOutput:  deiz.ErrorNothingToPay,
+		},
+		{
+			description:  "should issue no link for a free booking",
+			bookingInput: &deiz.Booking{ID: 4},
+			errorOutput:  deiz.ErrorNothingToPay,
+		},
+		{
+			description:  "should issue no link when clinician cannot be paid online",
+			bookingInput: unpaidBooking,
+			usecase: PaymentLinkUsecase{
+				AccountGetter:   &mockStripeAccountGetter{},
+				SecretKeyGetter: &mockStripeKeyGetter{},
+			},
+		},
+		{
+			description:  "should fail to get stripe account of the clinician",
+			bookingInput: unpaidBooking,
+			errorOutput:  deiz.GenericError,
+			usecase: PaymentLinkUsecase{
+				AccountGetter: &mockStripeAccountGetter{err: deiz.GenericError},
+			},
+		},
+		{
+			description:  "should issue a link valid for the payment link validity",
+			bookingInput: unpaidBooking,
+			tokenOutput:  "token",
+			usecase: PaymentLinkUsecase{
+				AccountGetter: connected,
+				Signer:        &mockPaymentLinkSigner{},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			token, err := test.usecase.IssuePaymentLink(context.Background(), test.bookingInput)
+			assert.Equal(t, test.errorOutput, err)
+			assert.Equal(t, test.tokenOutput, token)
+			if signer, ok := test.usecase.Signer.(*mockPaymentLinkSigner); ok {
+				assert.Equal(t, []int{4}, signer.bookingIDs)
+				assert.WithinDuration(t, time.Now().Add(deiz.PaymentLinkValidity), signer.expiresAt, time.Minute)
+			}
+		})
+	}
+}
+
+func TestSendPaymentReminder(t *testing.T) {
+	connected := &mockStripeAccountGetter{account: deiz.StripeAccount{ID: "acct_1", ChargesEnabled: true}}
+	balances := []deiz.BookingBalance{
+		{Booking: deiz.Booking{ID: 4, Patient: deiz.Patient{Email: "patient@deiz.fr"}}, Balance: 5000},
+		{Booking: deiz.Booking{ID: 5}, Balance: 5000},
+	}
+	var tests = []struct {
+		description string
+
+		bookingIDInput int
+
+		errorOutput    error
+		remindedOutput []int
+
+		usecase PaymentLinkUsecase
+	}{
+		{
+			description:    "should fail because clinician cannot be paid online",
+			bookingIDInput: 4,
+			errorOutput:    deiz.ErrorStripeAccountNotConnected,
+			usecase: PaymentLinkUsecase{
+				AccountGetter:   &mockStripeAccountGetter{},
+				SecretKeyGetter: &mockStripeKeyGetter{},
+			},
+		},
+		{
+			description:    "should fail because booking is not unpaid",
+			bookingIDInput: 6,
+			errorOutput:    errBookingNotUnpaid,
+			usecase: PaymentLinkUsecase{
+				AccountGetter: connected,
+				UnpaidGetter:  &mockUnpaidBookingsGetter{balances: balances},
+			},
+		},
+		{
+			description:    "should fail because patient has no email",
+			bookingIDInput: 5,
+			errorOutput:    deiz.ErrorPatientEmailNotSet,
+			usecase: PaymentLinkUsecase{
+				AccountGetter: connected,
+				UnpaidGetter:  &mockUnpaidBookingsGetter{balances: balances},
+			},
+		},
+		{
+			description:    "should mail a payment link to patient",
+			bookingIDInput: 4,
+			remindedOutput: []int{4},
+			usecase: PaymentLinkUsecase{
+				AccountGetter: connected,
+				UnpaidGetter:  &mockUnpaidBookingsGetter{balances: balances},
+				Signer:        &mockPaymentLinkSigner{},
+				Mailer:        &mockPaymentReminderMailer{},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			err := test.usecase.SendPaymentReminder(context.Background(), test.bookingIDInput, 1)
+			assert.Equal(t, test.errorOutput, err)
+			if mailer, ok := test.usecase.Mailer.(*mockPaymentReminderMailer); ok {
+				assert.Equal(t, test.remindedOutput, mailer.reminded)
+			}
+		})
+	}
+}
+
+func TestSendPaymentReminders(t *testing.T) {
+	mailer := &mockPaymentReminderMailer{}
+	u := PaymentLinkUsecase{
+		AccountGetter: &mockStripeAccountGetter{account: deiz.StripeAccount{ID: "acct_1", ChargesEnabled: true}},
+		UnpaidGetter: &mockUnpaidBookingsGetter{balances: []deiz.BookingBalance{
+			{Booking: deiz.Booking{ID: 4, Patient: deiz.Patient{Email: "patient@deiz.fr"}}, Balance: 5000},
+			{Booking: deiz.Booking{ID: 5}, Balance: 5000},
+			{Booking: deiz.Booking{ID: 6, Patient: deiz.Patient{Email: "patient@deiz.fr"}}, Balance: 0},
+			{Booking: deiz.Booking{ID: 7, Patient: deiz.Patient{Email: "patient@deiz.fr"}}, Balance: 2000},
+		}},
+		Signer: &mockPaymentLinkSigner{},
+		Mailer: mailer,
+	}
+
+	sent, err := u.SendPaymentReminders(context.Background(), 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, sent)
+	assert.Equal(t, []int{4, 7}, mailer.reminded)
+}
@@ -0,0 +1,54 @@
+package billing
+
+import (
+	"context"
+	"github.com/audrenbdb/deiz"
+	"time"
+)
+
+type (
+	paymentSaver interface {
+		SavePayment(ctx context.Context, p *deiz.Payment, clinicianID int) error
+	}
+	paymentDeleter interface {
+		DeletePayment(ctx context.Context, paymentID int, clinicianID int) error
+	}
+	bookingPaymentsGetter interface {
+		GetBookingPayments(ctx context.Context, bookingID int, clinicianID int) ([]deiz.Payment, error)
+	}
+)
+
+//PaymentUsecase manages clinician payment ledger
+type PaymentUsecase struct {
+	Saver   paymentSaver
+	Deleter paymentDeleter
+	Getter  bookingPaymentsGetter
+}
+
+//RecordPayment adds an instalment paid against a booking or an invoice
+func (u *PaymentUsecase) RecordPayment(ctx context.Context, p *deiz.Payment, clinicianID int) error {
+	if !p.IsValid() {
+		return deiz.ErrorStructValidation
+	}
+	if p.PaidAt.IsZero() {
+		p.PaidAt = time.Now().UTC()
+	}
+	return u.Saver.SavePayment(ctx, p, clinicianID)
+}
+
+func (u *PaymentUsecase) RemovePayment(ctx context.Context, paymentID int, clinicianID int) error {
+	return u.Deleter.DeletePayment(ctx, paymentID, clinicianID)
+}
+
+func (u *PaymentUsecase) GetBookingPayments(ctx context.Context, bookingID int, clinicianID int) ([]deiz.Payment, error) {
+	return u.Getter.GetBookingPayments(ctx, bookingID, clinicianID)
+}
+
+//sumPayments is the amount received, refunds being deducted
+func sumPayments(payments []deiz.Payment) int64 {
+	var amount int64
+	for _, p := range payments {
+		amount += p.Amount
+	}
+	return amount
+}
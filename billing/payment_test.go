@@ -0,0 +1,41 @@
+package billing
+
+import (
+	"context"
+	"github.com/audrenbdb/deiz"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type mockPaymentSaver struct {
+	saved *deiz.Payment
+	all   []deiz.Payment
+	err   error
+}
+
+func (m *mockPaymentSaver) SavePayment(ctx context.Context, p *deiz.Payment, clinicianID int) error {
+	m.saved = p
+	m.all = append(m.all, *p)
+	return m.err
+}
+
+func TestRecordPayment(t *testing.T) {
+	cash := deiz.PaymentMethod{ID: 1, Name: "Espèces"}
+	t.Run("should reject a payment without amount", func(t *testing.T) {
+		u := PaymentUsecase{Saver: &mockPaymentSaver{}}
+		err := u.RecordPayment(context.Background(), &deiz.Payment{BookingID: 1, Method: cash}, 1)
+		assert.Equal(t, deiz.ErrorStructValidation, err)
+	})
+	t.Run("should reject a payment not related to a booking nor an invoice", func(t *testing.T) {
+		u := PaymentUsecase{Saver: &mockPaymentSaver{}}
+		err := u.RecordPayment(context.Background(), &deiz.Payment{Amount: 2000, Method: cash}, 1)
+		assert.Equal(t, deiz.ErrorStructValidation, err)
+	})
+	t.Run("should date payment of today by default", func(t *testing.T) {
+		saver := &mockPaymentSaver{}
+		u := PaymentUsecase{Saver: saver}
+		err := u.RecordPayment(context.Background(), &deiz.Payment{BookingID: 1, Amount: 2000, Method: cash}, 1)
+		assert.NoError(t, err)
+		assert.False(t, saver.saved.PaidAt.IsZero())
+	})
+}
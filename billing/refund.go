@@ -0,0 +1,130 @@
+package billing
+
+import (
+	"context"
+	"github.com/audrenbdb/deiz"
+	"time"
+)
+
+type (
+	stripeRefunder interface {
+		Refund(ctx context.Context, paymentIntentID string, amount int64, payee deiz.StripePayee) (string, error)
+	}
+	cancellationPolicyGetter interface {
+		GetClinicianCalendarSettings(ctx context.Context, clinicianID int) (deiz.CalendarSettings, error)
+	}
+	bookingCrediter interface {
+		CreditBooking(ctx context.Context, invoiceID int, bookingID int, amount int64, clinicianID int) (deiz.BookingInvoice, error)
+	}
+)
+
+//RefundUsecase gives back what patients paid online for bookings cancelled
+type RefundUsecase struct {
+	Crypter         crypter
+	SecretKeyGetter stripeSecretKeyGetter
+	SettingsGetter  cancellationPolicyGetter
+	PaymentsGetter  bookingPaymentsGetter
+	PaymentSaver    paymentSaver
+	Refunder        stripeRefunder
+	InvoiceCrediter bookingCrediter
+}
+
+//RefundCanceledBooking refunds payments made online for a booking about to be cancelled.
+//A clinician cancelling refunds in full, a patient is refunded according to clinician cancellation policy.
+//Each refund is recorded in the ledger as a negative payment,
+//and the invoice already issued for the booking is credited the amount refunded.
+func (u *RefundUsecase) RefundCanceledBooking(ctx context.Context, b *deiz.Booking, byClinician bool) error {
+	payments, err := u.PaymentsGetter.GetBookingPayments(ctx, b.ID, b.Clinician.ID)
+	if err != nil {
+		return err
+	}
+	online := onlinePayments(payments)
+	if len(online) == 0 {
+		return nil
+	}
+	var paid int64
+	for _, p := range online {
+		paid += p.Amount
+	}
+	amount := paid
+	if !byClinician {
+		settings, err := u.SettingsGetter.GetClinicianCalendarSettings(ctx, b.Clinician.ID)
+		if err != nil {
+			return err
+		}
+		amount = settings.Cancellation.RefundAmount(paid, b.Start, time.Now())
+	}
+	if amount <= 0 {
+		return nil
+	}
+	if err := u.refund(ctx, b, online, amount); err != nil {
+		return err
+	}
+	return u.creditInvoices(ctx, b, payments, amount)
+}
+
+//refund spreads amount over payment intents of the booking, each refunded up to what it charged
+//on the Stripe account it was made on
+func (u *RefundUsecase) refund(ctx context.Context, b *deiz.Booking, online []deiz.Payment, amount int64) error {
+	for _, p := range online {
+		if amount <= 0 {
+			break
+		}
+		part := p.Amount
+		if part > amount {
+			part = amount
+		}
+		payee, err := getPaymentPayee(ctx, stripePayeeDeps{
+			crypter:   u.Crypter,
+			keyGetter: u.SecretKeyGetter,
+		}, p.AccountID, b.Clinician.ID)
+		if err != nil {
+			return err
+		}
+		refundID, err := u.Refunder.Refund(ctx, p.Reference, part, payee)
+		if err != nil {
+			return err
+		}
+		err = u.PaymentSaver.SavePayment(ctx, &deiz.Payment{
+			BookingID: b.ID,
+			Amount:    -part,
+			Method:    p.Method,
+			PaidAt:    time.Now().UTC(),
+			Reference: refundID,
+			AccountID: p.AccountID,
+		}, b.Clinician.ID)
+		if err != nil && err != deiz.ErrorPaymentAlreadyRecorded {
+			return err
+		}
+		amount -= part
+	}
+	return nil
+}
+
+//creditInvoices credits amount refunded on each invoice the booking payments were recorded with,
+//an invoice already credited being left as is
+func (u *RefundUsecase) creditInvoices(ctx context.Context, b *deiz.Booking, payments []deiz.Payment, amount int64) error {
+	credited := map[int]bool{}
+	for _, p := range payments {
+		if p.InvoiceID == 0 || p.Amount <= 0 || credited[p.InvoiceID] {
+			continue
+		}
+		_, err := u.InvoiceCrediter.CreditBooking(ctx, p.InvoiceID, b.ID, amount, b.Clinician.ID)
+		if err != nil && err != deiz.ErrorInvoiceAlreadyCanceled {
+			return err
+		}
+		credited[p.InvoiceID] = true
+	}
+	return nil
+}
+
+//onlinePayments are payments charged through a provider, refunds being excluded
+func onlinePayments(payments []deiz.Payment) []deiz.Payment {
+	online := []deiz.Payment{}
+	for _, p := range payments {
+		if p.Reference != "" && p.Amount > 0 {
+			online = append(online, p)
+		}
+	}
+	return online
+}
@@ -0,0 +1,153 @@
+package billing
+
+import (
+	"context"
+	"github.com/audrenbdb/deiz"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type mockStripeRefunder struct {
+	refunds map[string]int64
+	err     error
+}
+
+func (m *mockStripeRefunder) Refund(ctx context.Context, paymentIntentID string, amount int64, payee deiz.StripePayee) (string, error) {
+	if m.refunds == nil {
+		m.refunds = map[string]int64{}
+	}
+	m.refunds[paymentIntentID] = amount
+	return "re_" + paymentIntentID, m.err
+}
+
+type mockPayeeRefunder struct {
+	payees map[string]deiz.StripePayee
+}
+
+func (m *mockPayeeRefunder) Refund(ctx context.Context, paymentIntentID string, amount int64, payee deiz.StripePayee) (string, error) {
+	if m.payees == nil {
+		m.payees = map[string]deiz.StripePayee{}
+	}
+	m.payees[paymentIntentID] = payee
+	return "re_" + paymentIntentID, nil
+}
+
+type mockCancellationPolicyGetter struct {
+	policy deiz.CancellationPolicy
+	err    error
+}
+
+func (m *mockCancellationPolicyGetter) GetClinicianCalendarSettings(ctx context.Context, clinicianID int) (deiz.CalendarSettings, error) {
+	return deiz.CalendarSettings{Cancellation: m.policy}, m.err
+}
+
+type mockBookingCrediter struct {
+	credited map[int]int64
+	err      error
+}
+
+func (m *mockBookingCrediter) CreditBooking(ctx context.Context, invoiceID int, bookingID int, amount int64, clinicianID int) (deiz.BookingInvoice, error) {
+	if m.credited == nil {
+		m.credited = map[int]int64{}
+	}
+	m.credited[invoiceID] = amount
+	return deiz.BookingInvoice{}, m.err
+}
+
+func TestRefundCanceledBooking(t *testing.T) {
+	card := deiz.PaymentMethod{ID: 5, Name: deiz.OnlinePaymentMethodName}
+	cash := deiz.PaymentMethod{ID: 1, Name: "Espèces"}
+	deposit := deiz.Payment{ID: 1, BookingID: 4, Amount: 1500, Method: card, Reference: "pi_1"}
+	balance := deiz.Payment{ID: 2, BookingID: 4, InvoiceID: 9, Amount: 3500, Method: card, Reference: "pi_2"}
+	policy := deiz.CancellationPolicy{NoticeHours: 24, LateRefundPercent: 50}
+	lateBooking := deiz.Booking{ID: 4, Clinician: deiz.Clinician{ID: 1}, Price: 5000, Start: time.Now().Add(2 * time.Hour)}
+	earlyBooking := deiz.Booking{ID: 4, Clinician: deiz.Clinician{ID: 1}, Price: 5000, Start: time.Now().Add(48 * time.Hour)}
+
+	newUsecase := func(payments []deiz.Payment) (RefundUsecase, *mockStripeRefunder, *mockPaymentSaver, *mockBookingCrediter) {
+		refunder := &mockStripeRefunder{}
+		saver := &mockPaymentSaver{}
+		crediter := &mockBookingCrediter{}
+		return RefundUsecase{
+			Crypter:         &mockCrypter{key: "sk_test"},
+			SecretKeyGetter: &mockStripeKeyGetter{key: []byte("sk")},
+			SettingsGetter:  &mockCancellationPolicyGetter{policy: policy},
+			PaymentsGetter:  &mockBookingPaymentsGetter{payments: payments},
+			PaymentSaver:    saver,
+			Refunder:        refunder,
+			InvoiceCrediter: crediter,
+		}, refunder, saver, crediter
+	}
+
+	t.Run("should do nothing when nothing was paid online", func(t *testing.T) {
+		u, refunder, saver, crediter := newUsecase([]deiz.Payment{{BookingID: 4, InvoiceID: 9, Amount: 5000, Method: cash}})
+		err := u.RefundCanceledBooking(context.Background(), &lateBooking, true)
+		assert.NoError(t, err)
+		assert.Empty(t, refunder.refunds)
+		assert.Empty(t, saver.all)
+		assert.Empty(t, crediter.credited)
+	})
+	t.Run("should refund in full when clinician cancels, and credit invoice in full", func(t *testing.T) {
+		u, refunder, saver, crediter := newUsecase([]deiz.Payment{deposit, balance})
+		err := u.RefundCanceledBooking(context.Background(), &lateBooking, true)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]int64{"pi_1": 1500, "pi_2": 3500}, refunder.refunds)
+		assert.Len(t, saver.all, 2)
+		assert.Equal(t, int64(-1500), saver.all[0].Amount)
+		assert.Equal(t, "re_pi_1", saver.all[0].Reference)
+		assert.Equal(t, card, saver.all[0].Method)
+		assert.Equal(t, map[int]int64{9: 5000}, crediter.credited)
+	})
+	t.Run("should refund in full a patient cancelling with notice", func(t *testing.T) {
+		u, refunder, _, _ := newUsecase([]deiz.Payment{deposit})
+		err := u.RefundCanceledBooking(context.Background(), &earlyBooking, false)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]int64{"pi_1": 1500}, refunder.refunds)
+	})
+	t.Run("should refund part of what a patient cancelling late paid, and credit invoice that part only", func(t *testing.T) {
+		u, refunder, saver, crediter := newUsecase([]deiz.Payment{deposit, balance})
+		err := u.RefundCanceledBooking(context.Background(), &lateBooking, false)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]int64{"pi_1": 1500, "pi_2": 1000}, refunder.refunds)
+		assert.Len(t, saver.all, 2)
+		assert.Equal(t, map[int]int64{9: 2500}, crediter.credited)
+	})
+	t.Run("should not refund a patient cancelling late when policy keeps everything", func(t *testing.T) {
+		u, refunder, saver, crediter := newUsecase([]deiz.Payment{deposit, balance})
+		u.SettingsGetter = &mockCancellationPolicyGetter{policy: deiz.CancellationPolicy{NoticeHours: 24}}
+		err := u.RefundCanceledBooking(context.Background(), &lateBooking, false)
+		assert.NoError(t, err)
+		assert.Empty(t, refunder.refunds)
+		assert.Empty(t, saver.all)
+		assert.Empty(t, crediter.credited)
+	})
+	t.Run("should refund each payment on the Stripe account it was made on", func(t *testing.T) {
+		connected := deiz.Payment{ID: 3, BookingID: 4, InvoiceID: 9, Amount: 3500, Method: card, Reference: "pi_3", AccountID: "acct_1"}
+		u, _, saver, _ := newUsecase([]deiz.Payment{deposit, connected})
+		refunder := &mockPayeeRefunder{}
+		u.Refunder = refunder
+		err := u.RefundCanceledBooking(context.Background(), &lateBooking, true)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]deiz.StripePayee{
+			"pi_1": {LegacySecretKey: "sk_test"},
+			"pi_3": {AccountID: "acct_1"},
+		}, refunder.payees)
+		assert.Equal(t, "", saver.all[0].AccountID)
+		assert.Equal(t, "acct_1", saver.all[1].AccountID)
+	})
+	t.Run("should fail when refund is declined", func(t *testing.T) {
+		u, _, saver, crediter := newUsecase([]deiz.Payment{deposit})
+		u.Refunder = &mockStripeRefunder{err: deiz.GenericError}
+		err := u.RefundCanceledBooking(context.Background(), &lateBooking, true)
+		assert.Equal(t, deiz.GenericError, err)
+		assert.Empty(t, saver.all)
+		assert.Empty(t, crediter.credited)
+	})
+	t.Run("should accept a refund already recorded and an invoice already cancelled", func(t *testing.T) {
+		u, _, _, _ := newUsecase([]deiz.Payment{balance})
+		u.PaymentSaver = &mockPaymentSaver{err: deiz.ErrorPaymentAlreadyRecorded}
+		u.InvoiceCrediter = &mockBookingCrediter{err: deiz.ErrorInvoiceAlreadyCanceled}
+		err := u.RefundCanceledBooking(context.Background(), &lateBooking, true)
+		assert.NoError(t, err)
+	})
+}
@@ -4,25 +4,127 @@ import (
 	"context"
 	"errors"
 	"github.com/audrenbdb/deiz"
+	"time"
 )
 
-func (u *CreateStripeSessionUsecase) CreateStripePaymentSession(ctx context.Context, amount int64, clinicianID int) (string, error) {
+//CreateStripePaymentSession opens a checkout session charging booking balance to patient,
+//session metadata linking the payment back to the booking.
+func (u *CreateStripeSessionUsecase) CreateStripePaymentSession(ctx context.Context, bookingID int, clinicianID int) (string, error) {
+	b, err := u.BookingGetter.GetBookingByID(ctx, bookingID)
+	if err != nil {
+		return "", err
+	}
+	if b.Clinician.ID != clinicianID {
+		return "", deiz.ErrorUnauthorized
+	}
+	return u.createBalanceSession(ctx, &b)
+}
+
+//CreateStripePaymentLinkSession opens a checkout session charging booking balance to a patient following a payment link.
+//Token signature and expiry are checked, link being sent without login.
+func (u *CreateStripeSessionUsecase) CreateStripePaymentLinkSession(ctx context.Context, token string) (string, error) {
+	bookingID, err := u.LinkVerifier.Verify(token, time.Now())
+	if err != nil {
+		return "", err
+	}
+	b, err := u.BookingGetter.GetBookingByID(ctx, bookingID)
+	if err != nil {
+		return "", err
+	}
+	return u.createBalanceSession(ctx, &b)
+}
+
+//createBalanceSession charges what is left to pay of a booking, instalments recorded in the ledger being deducted
+func (u *CreateStripeSessionUsecase) createBalanceSession(ctx context.Context, b *deiz.Booking) (string, error) {
+	if b.Paid {
+		return "", deiz.ErrorBookingAlreadyPaid
+	}
+	payments, err := u.PaymentsGetter.GetBookingPayments(ctx, b.ID, b.Clinician.ID)
+	if err != nil {
+		return "", err
+	}
+	balance := deiz.BookingBalance{Booking: *b}
+	balance.SetBalance(sumPayments(payments))
+	if balance.Balance <= 0 {
+		return "", deiz.ErrorBookingAlreadyPaid
+	}
+	return u.createSession(ctx, b, balance.Balance)
+}
+
+//CreateStripePrepaymentSession opens a checkout session charging the prepayment of a booking held for patient.
+//Amount may be a deposit, part of booking price.
+func (u *CreateStripeSessionUsecase) CreateStripePrepaymentSession(ctx context.Context, b *deiz.Booking, amount int64) (string, error) {
+	if amount > b.Price {
+		return "", deiz.ErrorStructValidation
+	}
+	return u.createSession(ctx, b, amount)
+}
+
+func (u *CreateStripeSessionUsecase) createSession(ctx context.Context, b *deiz.Booking, amount int64) (string, error) {
 	if amount <= 0 {
 		return "", deiz.ErrorStructValidation
 	}
-	key, err := u.getDecryptedStripeKey(ctx, clinicianID)
+	payee, err := getStripePayee(ctx, stripePayeeDeps{
+		crypter:       u.Crypter,
+		keyGetter:     u.SecretKeyGetter,
+		accountGetter: u.AccountGetter,
+	}, b.Clinician.ID)
 	if err != nil {
 		return "", err
 	}
-	return u.StripeSessionCreater.CreateSession(ctx, amount, key)
+	payment := deiz.CheckoutPayment{
+		BookingID:   b.ID,
+		ClinicianID: b.Clinician.ID,
+		Amount:      amount,
+	}
+	if b.AwaitsPrepayment() {
+		payment.ExpiresAt = b.PrepaymentCheckoutExpiry()
+	}
+	return u.StripeSessionCreater.CreateSession(ctx, payment, payee)
 }
 
-func (u *CreateStripeSessionUsecase) getDecryptedStripeKey(ctx context.Context, clinicianID int) (string, error) {
-	k, err := u.SecretKeyGetter.GetClinicianStripeSecretKey(ctx, clinicianID)
+type stripePayeeDeps struct {
+	crypter       crypter
+	keyGetter     stripeSecretKeyGetter
+	accountGetter stripeAccountGetter
+}
+
+//getStripePayee resolves the Stripe account clinician is paid on.
+//A connected account prevails, the secret key a clinician pasted before Stripe Connect being used until then.
+func getStripePayee(ctx context.Context, deps stripePayeeDeps, clinicianID int) (deiz.StripePayee, error) {
+	a, err := deps.accountGetter.GetClinicianStripeAccount(ctx, clinicianID)
 	if err != nil {
-		return "", err
+		return deiz.StripePayee{}, err
+	}
+	if a.IsConnected() {
+		return deiz.StripePayee{AccountID: a.ID}, nil
 	}
-	return decryptKey(u.Crypter, k)
+	return getLegacyStripePayee(ctx, deps, clinicianID)
+}
+
+//getPaymentPayee resolves the Stripe account a payment was made on, refunds going through it.
+//A payment without connected account was made with the keys clinician pasted before Stripe Connect.
+func getPaymentPayee(ctx context.Context, deps stripePayeeDeps, accountID string, clinicianID int) (deiz.StripePayee, error) {
+	if accountID != "" {
+		return deiz.StripePayee{AccountID: accountID}, nil
+	}
+	return getLegacyStripePayee(ctx, deps, clinicianID)
+}
+
+//getLegacyStripePayee resolves the Stripe account of the secret key a clinician pasted before Stripe Connect
+func getLegacyStripePayee(ctx context.Context, deps stripePayeeDeps, clinicianID int) (deiz.StripePayee, error) {
+	k, err := deps.keyGetter.GetClinicianStripeSecretKey(ctx, clinicianID)
+	if err != nil {
+		return deiz.StripePayee{}, err
+	}
+	if len(k) == 0 {
+		return deiz.StripePayee{}, deiz.ErrorStripeAccountNotConnected
+	}
+	key, err := decryptKey(deps.crypter, k)
+	if err != nil {
+		return deiz.StripePayee{}, err
+	}
+	return deiz.StripePayee{LegacySecretKey: key}, nil
 }
 
 func decryptKey(crypter crypter, k []byte) (string, error) {
@@ -39,6 +141,10 @@ func decryptKey(crypter crypter, k []byte) (string, error) {
 type CreateStripeSessionUsecase struct {
 	Crypter              crypter
 	SecretKeyGetter      stripeSecretKeyGetter
+	AccountGetter        stripeAccountGetter
+	BookingGetter        bookingGetter
+	PaymentsGetter       bookingPaymentsGetter
+	LinkVerifier         paymentLinkVerifier
 	StripeSessionCreater stripeSessionCreater
 }
 
@@ -49,7 +155,13 @@ type (
 	stripeSecretKeyGetter interface {
 		GetClinicianStripeSecretKey(ctx context.Context, clinicianID int) ([]byte, error)
 	}
+	stripeAccountGetter interface {
+		GetClinicianStripeAccount(ctx context.Context, clinicianID int) (deiz.StripeAccount, error)
+	}
+	paymentLinkVerifier interface {
+		Verify(token string, now time.Time) (int, error)
+	}
 	stripeSessionCreater interface {
-		CreateSession(ctx context.Context, amount int64, sk string) (string, error)
+		CreateSession(ctx context.Context, p deiz.CheckoutPayment, payee deiz.StripePayee) (string, error)
 	}
 )
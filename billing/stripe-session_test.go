@@ -5,52 +5,337 @@ import (
 	"github.com/audrenbdb/deiz"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 type mockStripeKeyGetter struct {
+	key []byte
 	err error
 }
 
 func (m *mockStripeKeyGetter) GetClinicianStripeSecretKey(ctx context.Context, clinicianID int) ([]byte, error) {
-	return nil, m.err
+	return m.key, m.err
+}
+
+type mockStripeAccountGetter struct {
+	account deiz.StripeAccount
+	err     error
+}
+
+func (m *mockStripeAccountGetter) GetClinicianStripeAccount(ctx context.Context, clinicianID int) (deiz.StripeAccount, error) {
+	return m.account, m.err
+}
+
+type mockCrypter struct {
+	key string
+	err error
+}
+
+func (m *mockCrypter) BytesToString(strBytes []byte) (string, error) {
+	return m.key, m.err
+}
+
+type mockStripeSessionCreater struct {
+	payment deiz.CheckoutPayment
+	payee   deiz.StripePayee
+	err     error
+}
+
+func (m *mockStripeSessionCreater) CreateSession(ctx context.Context, p deiz.CheckoutPayment, payee deiz.StripePayee) (string, error) {
+	m.payment = p
+	m.payee = payee
+	return "cs_1", m.err
 }
 
 func TestCreateStripePaymentSession(t *testing.T) {
+	unpaidBooking := deiz.Booking{ID: 4, Clinician: deiz.Clinician{ID: 1}, Price: 5000}
 	var tests = []struct {
 		description string
 
-		amountInput      int64
+		bookingIDInput   int
 		clinicianIDInput int
 
 		sessionOutput string
 		errorOutput   error
+		paymentOutput deiz.CheckoutPayment
+		payeeOutput   deiz.StripePayee
 
 		usecase CreateStripeSessionUsecase
 	}{
 		{
-			description: "should fail because wrong amount input",
+			description: "should fail to get booking",
 
-			amountInput: -5,
-			errorOutput: deiz.ErrorStructValidation,
+			errorOutput: deiz.GenericError,
 
-			usecase: CreateStripeSessionUsecase{},
+			usecase: CreateStripeSessionUsecase{
+				BookingGetter: &mockBookingGetter{err: deiz.GenericError},
+			},
 		},
 		{
-			description: "should fail to get stripe key of the clinician",
+			description: "should fail because booking belongs to another clinician",
 
-			amountInput: 1,
-			errorOutput: deiz.GenericError,
+			clinicianIDInput: 2,
+			errorOutput:      deiz.ErrorUnauthorized,
+
+			usecase: CreateStripeSessionUsecase{
+				BookingGetter: &mockBookingGetter{booking: unpaidBooking},
+			},
+		},
+		{
+			description: "should fail because booking is already paid",
+
+			clinicianIDInput: 1,
+			errorOutput:      deiz.ErrorBookingAlreadyPaid,
 
 			usecase: CreateStripeSessionUsecase{
+				BookingGetter: &mockBookingGetter{booking: deiz.Booking{ID: 4, Clinician: deiz.Clinician{ID: 1}, Price: 5000, Paid: true}},
+			},
+		},
+		{
+			description: "should fail to get booking payments",
+
+			clinicianIDInput: 1,
+			errorOutput:      deiz.GenericError,
+
+			usecase: CreateStripeSessionUsecase{
+				BookingGetter:  &mockBookingGetter{booking: unpaidBooking},
+				PaymentsGetter: &mockBookingPaymentsGetter{err: deiz.GenericError},
+			},
+		},
+		{
+			description: "should fail because booking has no price",
+
+			clinicianIDInput: 1,
+			errorOutput:      deiz.ErrorBookingAlreadyPaid,
+
+			usecase: CreateStripeSessionUsecase{
+				BookingGetter:  &mockBookingGetter{booking: deiz.Booking{ID: 4, Clinician: deiz.Clinician{ID: 1}}},
+				PaymentsGetter: &mockBookingPaymentsGetter{},
+			},
+		},
+		{
+			description: "should fail because instalments already cover booking price",
+
+			clinicianIDInput: 1,
+			errorOutput:      deiz.ErrorBookingAlreadyPaid,
+
+			usecase: CreateStripeSessionUsecase{
+				BookingGetter:  &mockBookingGetter{booking: unpaidBooking},
+				PaymentsGetter: &mockBookingPaymentsGetter{payments: []deiz.Payment{{Amount: 5000}}},
+			},
+		},
+		{
+			description: "should fail to get stripe account of the clinician",
+
+			clinicianIDInput: 1,
+			errorOutput:      deiz.GenericError,
+
+			usecase: CreateStripeSessionUsecase{
+				BookingGetter:  &mockBookingGetter{booking: unpaidBooking},
+				PaymentsGetter: &mockBookingPaymentsGetter{},
+				AccountGetter:  &mockStripeAccountGetter{err: deiz.GenericError},
+			},
+		},
+		{
+			description: "should fail to get stripe key of a clinician not connected",
+
+			clinicianIDInput: 1,
+			errorOutput:      deiz.GenericError,
+
+			usecase: CreateStripeSessionUsecase{
+				BookingGetter:   &mockBookingGetter{booking: unpaidBooking},
+				PaymentsGetter:  &mockBookingPaymentsGetter{},
+				AccountGetter:   &mockStripeAccountGetter{account: deiz.StripeAccount{ID: "acct_1"}},
 				SecretKeyGetter: &mockStripeKeyGetter{err: deiz.GenericError},
 			},
 		},
+		{
+			description: "should fail because clinician neither connected nor pasted a key",
+
+			clinicianIDInput: 1,
+			errorOutput:      deiz.ErrorStripeAccountNotConnected,
+
+			usecase: CreateStripeSessionUsecase{
+				BookingGetter:   &mockBookingGetter{booking: unpaidBooking},
+				PaymentsGetter:  &mockBookingPaymentsGetter{},
+				AccountGetter:   &mockStripeAccountGetter{},
+				SecretKeyGetter: &mockStripeKeyGetter{},
+			},
+		},
+		{
+			description: "should open a session charging booking price with legacy key",
+
+			bookingIDInput:   4,
+			clinicianIDInput: 1,
+			sessionOutput:    "cs_1",
+			paymentOutput:    deiz.CheckoutPayment{BookingID: 4, ClinicianID: 1, Amount: 5000},
+			payeeOutput:      deiz.StripePayee{LegacySecretKey: "sk_test"},
+
+			usecase: CreateStripeSessionUsecase{
+				BookingGetter:        &mockBookingGetter{booking: unpaidBooking},
+				PaymentsGetter:       &mockBookingPaymentsGetter{},
+				AccountGetter:        &mockStripeAccountGetter{},
+				SecretKeyGetter:      &mockStripeKeyGetter{key: []byte("sk")},
+				Crypter:              &mockCrypter{key: "sk_test"},
+				StripeSessionCreater: &mockStripeSessionCreater{},
+			},
+		},
+		{
+			description: "should open a session charging booking balance after an instalment",
+
+			bookingIDInput:   4,
+			clinicianIDInput: 1,
+			sessionOutput:    "cs_1",
+			paymentOutput:    deiz.CheckoutPayment{BookingID: 4, ClinicianID: 1, Amount: 3000},
+			payeeOutput:      deiz.StripePayee{AccountID: "acct_1"},
+
+			usecase: CreateStripeSessionUsecase{
+				BookingGetter:        &mockBookingGetter{booking: unpaidBooking},
+				PaymentsGetter:       &mockBookingPaymentsGetter{payments: []deiz.Payment{{Amount: 2000}}},
+				AccountGetter:        &mockStripeAccountGetter{account: deiz.StripeAccount{ID: "acct_1", ChargesEnabled: true}},
+				StripeSessionCreater: &mockStripeSessionCreater{},
+			},
+		},
+		{
+			description: "should open a session on clinician connected account",
+
+			bookingIDInput:   4,
+			clinicianIDInput: 1,
+			sessionOutput:    "cs_1",
+			paymentOutput:    deiz.CheckoutPayment{BookingID: 4, ClinicianID: 1, Amount: 5000},
+			payeeOutput:      deiz.StripePayee{AccountID: "acct_1"},
+
+			usecase: CreateStripeSessionUsecase{
+				BookingGetter:        &mockBookingGetter{booking: unpaidBooking},
+				PaymentsGetter:       &mockBookingPaymentsGetter{},
+				AccountGetter:        &mockStripeAccountGetter{account: deiz.StripeAccount{ID: "acct_1", ChargesEnabled: true}},
+				SecretKeyGetter:      &mockStripeKeyGetter{key: []byte("sk")},
+				StripeSessionCreater: &mockStripeSessionCreater{},
+			},
+		},
 	}
 
 	for _, test := range tests {
 		session, err := test.usecase.CreateStripePaymentSession(
-			context.Background(), test.amountInput, test.clinicianIDInput)
+			context.Background(), test.bookingIDInput, test.clinicianIDInput)
 		assert.Equal(t, test.errorOutput, err)
 		assert.Equal(t, test.sessionOutput, session)
+		if creater, ok := test.usecase.StripeSessionCreater.(*mockStripeSessionCreater); ok {
+			assert.Equal(t, test.paymentOutput, creater.payment)
+			assert.Equal(t, test.payeeOutput, creater.payee)
+		}
+	}
+}
+
+func TestCreateStripePrepaymentSession(t *testing.T) {
+	held := &deiz.Booking{ID: 4, Clinician: deiz.Clinician{ID: 1}, Price: 5000}
+	t.Run("should refuse a prepayment above booking price", func(t *testing.T) {
+		u := CreateStripeSessionUsecase{}
+		_, err := u.CreateStripePrepaymentSession(context.Background(), held, 6000)
+		assert.Equal(t, deiz.ErrorStructValidation, err)
+	})
+	t.Run("should open a session charging deposit", func(t *testing.T) {
+		creater := &mockStripeSessionCreater{}
+		u := CreateStripeSessionUsecase{
+			AccountGetter:        &mockStripeAccountGetter{},
+			SecretKeyGetter:      &mockStripeKeyGetter{key: []byte("sk")},
+			Crypter:              &mockCrypter{key: "sk_test"},
+			StripeSessionCreater: creater,
+		}
+		session, err := u.CreateStripePrepaymentSession(context.Background(), held, 1500)
+		assert.NoError(t, err)
+		assert.Equal(t, "cs_1", session)
+		assert.Equal(t, deiz.CheckoutPayment{BookingID: 4, ClinicianID: 1, Amount: 1500}, creater.payment)
+	})
+	t.Run("should close session of a held slot before its payment deadline", func(t *testing.T) {
+		creater := &mockStripeSessionCreater{}
+		u := CreateStripeSessionUsecase{
+			AccountGetter:        &mockStripeAccountGetter{account: deiz.StripeAccount{ID: "acct_1", ChargesEnabled: true}},
+			StripeSessionCreater: creater,
+		}
+		deadline := time.Now().Add(deiz.PrepaymentHoldDuration)
+		_, err := u.CreateStripePrepaymentSession(context.Background(), &deiz.Booking{ID: 4, Clinician: deiz.Clinician{ID: 1}, Price: 5000, PaymentDeadline: deadline}, 1500)
+		assert.NoError(t, err)
+		assert.Equal(t, deadline.Add(-deiz.PrepaymentCheckoutMargin), creater.payment.ExpiresAt)
+		//Stripe refuses sessions expiring in less than 30 minutes
+		assert.True(t, creater.payment.ExpiresAt.After(time.Now().Add(30*time.Minute)))
+	})
+}
+
+type mockPaymentLinkVerifier struct {
+	bookingID int
+	err       error
+}
+
+func (m *mockPaymentLinkVerifier) Verify(token string, now time.Time) (int, error) {
+	return m.bookingID, m.err
+}
+
+func TestCreateStripePaymentLinkSession(t *testing.T) {
+	unpaidBooking := deiz.Booking{ID: 4, Clinician: deiz.Clinician{ID: 1}, Price: 5000}
+	var tests = []struct {
+		description string
+
+		sessionOutput string
+		errorOutput   error
+		paymentOutput deiz.CheckoutPayment
+
+		usecase CreateStripeSessionUsecase
+	}{
+		{
+			description: "should fail because link expired",
+
+			errorOutput: deiz.ErrorPaymentLinkExpired,
+
+			usecase: CreateStripeSessionUsecase{
+				LinkVerifier: &mockPaymentLinkVerifier{err: deiz.ErrorPaymentLinkExpired},
+			},
+		},
+		{
+			description: "should fail because booking is already paid",
+
+			errorOutput: deiz.ErrorBookingAlreadyPaid,
+
+			usecase: CreateStripeSessionUsecase{
+				LinkVerifier:  &mockPaymentLinkVerifier{bookingID: 4},
+				BookingGetter: &mockBookingGetter{booking: deiz.Booking{ID: 4, Clinician: deiz.Clinician{ID: 1}, Price: 5000, Paid: true}},
+			},
+		},
+		{
+			description: "should fail because payments recorded settle booking",
+
+			errorOutput: deiz.ErrorBookingAlreadyPaid,
+
+			usecase: CreateStripeSessionUsecase{
+				LinkVerifier:   &mockPaymentLinkVerifier{bookingID: 4},
+				BookingGetter:  &mockBookingGetter{booking: unpaidBooking},
+				PaymentsGetter: &mockBookingPaymentsGetter{payments: []deiz.Payment{{Amount: 5000}}},
+			},
+		},
+		{
+			description: "should open a session charging booking balance",
+
+			sessionOutput: "cs_1",
+			paymentOutput: deiz.CheckoutPayment{BookingID: 4, ClinicianID: 1, Amount: 3000},
+
+			usecase: CreateStripeSessionUsecase{
+				LinkVerifier:         &mockPaymentLinkVerifier{bookingID: 4},
+				BookingGetter:        &mockBookingGetter{booking: unpaidBooking},
+				PaymentsGetter:       &mockBookingPaymentsGetter{payments: []deiz.Payment{{Amount: 2000}}},
+				AccountGetter:        &mockStripeAccountGetter{account: deiz.StripeAccount{ID: "acct_1", ChargesEnabled: true}},
+				StripeSessionCreater: &mockStripeSessionCreater{},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			session, err := test.usecase.CreateStripePaymentLinkSession(context.Background(), "token")
+			assert.Equal(t, test.errorOutput, err)
+			assert.Equal(t, test.sessionOutput, session)
+			if creater, ok := test.usecase.StripeSessionCreater.(*mockStripeSessionCreater); ok {
+				assert.Equal(t, test.paymentOutput, creater.payment)
+			}
+		})
 	}
 }
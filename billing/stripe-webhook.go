@@ -0,0 +1,257 @@
+package billing
+
+import (
+	"context"
+	"errors"
+	"github.com/audrenbdb/deiz"
+	"strconv"
+	"time"
+)
+
+type (
+	webhookSecretGetter interface {
+		GetClinicianStripeWebhookSecret(ctx context.Context, clinicianID int) ([]byte, error)
+	}
+	checkoutEventParser interface {
+		ParseCheckoutEvent(payload []byte, signature string, webhookSecret string) (deiz.CheckoutPayment, error)
+		ParseConnectCheckoutEvent(payload []byte, signature string) (deiz.CheckoutPayment, error)
+	}
+	stripeAccountClinicianGetter interface {
+		GetClinicianIDByStripeAccount(ctx context.Context, accountID string) (int, error)
+	}
+	paymentMethodsGetter interface {
+		GetPaymentMethods(ctx context.Context) ([]deiz.PaymentMethod, error)
+	}
+	invoiceCreater interface {
+		CreateInvoice(ctx context.Context, invoice *deiz.BookingInvoice, sendToPatient bool) error
+	}
+	prepaidBookingConfirmer interface {
+		ConfirmPrepaidBooking(ctx context.Context, b *deiz.Booking) error
+	}
+)
+
+var errOnlinePaymentMethodNotFound = errors.New("online payment method not found")
+
+//StripeWebhookUsecase handles events Stripe sends to platform endpoint for connected accounts,
+//or to endpoint of a clinician who pasted keys before Stripe Connect
+type StripeWebhookUsecase struct {
+	Crypter              crypter
+	WebhookSecretGetter  webhookSecretGetter
+	EventParser          checkoutEventParser
+	AccountGetter        stripeAccountClinicianGetter
+	BookingGetter        bookingGetter
+	PaymentMethodsGetter paymentMethodsGetter
+	BusinessGetter       businessGetter
+	InvoiceCreater       invoiceCreater
+	PaymentsGetter       bookingPaymentsGetter
+	PaymentSaver         paymentSaver
+	BookingConfirmer     prepaidBookingConfirmer
+	SecretKeyGetter      stripeSecretKeyGetter
+	Refunder             stripeRefunder
+}
+
+//HandleStripeConnectWebhook verifies event signature with platform secret,
+//the connected account checkout was made on telling which clinician is paid.
+func (u *StripeWebhookUsecase) HandleStripeConnectWebhook(ctx context.Context, payload []byte, signature string) error {
+	p, err := u.EventParser.ParseConnectCheckoutEvent(payload, signature)
+	if err != nil {
+		return err
+	}
+	if !p.Paid {
+		return nil
+	}
+	clinicianID, err := u.AccountGetter.GetClinicianIDByStripeAccount(ctx, p.AccountID)
+	if err != nil {
+		return err
+	}
+	return u.handlePaidCheckout(ctx, p, clinicianID)
+}
+
+//HandleStripeWebhook verifies event signature with endpoint secret of a clinician not connected yet
+func (u *StripeWebhookUsecase) HandleStripeWebhook(ctx context.Context, payload []byte, signature string, clinicianID int) error {
+	k, err := u.WebhookSecretGetter.GetClinicianStripeWebhookSecret(ctx, clinicianID)
+	if err != nil {
+		return err
+	}
+	secret, err := decryptKey(u.Crypter, k)
+	if err != nil {
+		return err
+	}
+	p, err := u.EventParser.ParseCheckoutEvent(payload, signature, secret)
+	if err != nil {
+		return err
+	}
+	if !p.Paid {
+		return nil
+	}
+	return u.handlePaidCheckout(ctx, p, clinicianID)
+}
+
+//handlePaidCheckout settles the booking of a checkout paid to clinician.
+//A slot held until patient prepays it is confirmed once paid.
+//A checkout settling booking balance is invoiced with online card payment method,
+//saving the invoice marking it paid, and invoice is mailed to patient.
+//A deposit, or a payment of a booking already paid, is only recorded in the ledger.
+//Stripe retrying events until acknowledged, a payment already recorded is left untouched.
+func (u *StripeWebhookUsecase) handlePaidCheckout(ctx context.Context, p deiz.CheckoutPayment, clinicianID int) error {
+	if p.ClinicianID != clinicianID {
+		return deiz.ErrorUnauthorized
+	}
+	b, err := u.BookingGetter.GetBookingByID(ctx, p.BookingID)
+	if err == deiz.ErrorBookingNotFound {
+		return u.refundOrphanCheckout(ctx, p)
+	}
+	if err != nil {
+		return err
+	}
+	if b.Clinician.ID != clinicianID {
+		return deiz.ErrorUnauthorized
+	}
+	if b.AwaitsPrepayment() {
+		if err := u.BookingConfirmer.ConfirmPrepaidBooking(ctx, &b); err != nil {
+			return err
+		}
+	}
+	payments, err := u.PaymentsGetter.GetBookingPayments(ctx, b.ID, clinicianID)
+	if err != nil {
+		return err
+	}
+	var amountPaid int64
+	for _, payment := range payments {
+		if p.PaymentIntentID != "" && payment.Reference == p.PaymentIntentID {
+			return nil
+		}
+		amountPaid += payment.Amount
+	}
+	method, err := u.getOnlinePaymentMethod(ctx)
+	if err != nil {
+		return err
+	}
+	if b.Paid || p.Amount < b.Price-amountPaid {
+		return u.recordPayment(ctx, p, method)
+	}
+	business, err := u.BusinessGetter.GetClinicianBusiness(ctx, clinicianID)
+	if err != nil {
+		return err
+	}
+	loc, err := b.Clinician.Location()
+	if err != nil {
+		return err
+	}
+	invoice := onlineBookingInvoice(b, business, method, time.Now().UTC(), loc)
+	invoice.PaymentReference = p.PaymentIntentID
+	invoice.PaymentAccountID = p.AccountID
+	err = u.InvoiceCreater.CreateInvoice(ctx, &invoice, true)
+	if err == deiz.ErrorPaymentAlreadyRecorded {
+		return nil
+	}
+	return err
+}
+
+//refundOrphanCheckout gives back a checkout paid once its booking no longer exists, such as a slot released.
+//Payment and its refund are both recorded in the ledger, mirroring money actually moved.
+//Refunds being replayed by Stripe for the same payment intent, retries refund once.
+func (u *StripeWebhookUsecase) refundOrphanCheckout(ctx context.Context, p deiz.CheckoutPayment) error {
+	method, err := u.getOnlinePaymentMethod(ctx)
+	if err != nil {
+		return err
+	}
+	err = u.saveLedgerEntry(ctx, &deiz.Payment{
+		Amount:    p.Amount,
+		Method:    method,
+		PaidAt:    time.Now().UTC(),
+		Reference: p.PaymentIntentID,
+		AccountID: p.AccountID,
+	}, p.ClinicianID)
+	if err != nil {
+		return err
+	}
+	payee, err := getPaymentPayee(ctx, stripePayeeDeps{
+		crypter:   u.Crypter,
+		keyGetter: u.SecretKeyGetter,
+	}, p.AccountID, p.ClinicianID)
+	if err != nil {
+		return err
+	}
+	refundID, err := u.Refunder.Refund(ctx, p.PaymentIntentID, p.Amount, payee)
+	if err != nil {
+		return err
+	}
+	return u.saveLedgerEntry(ctx, &deiz.Payment{
+		Amount:    -p.Amount,
+		Method:    method,
+		PaidAt:    time.Now().UTC(),
+		Reference: refundID,
+		AccountID: p.AccountID,
+	}, p.ClinicianID)
+}
+
+func (u *StripeWebhookUsecase) recordPayment(ctx context.Context, p deiz.CheckoutPayment, method deiz.PaymentMethod) error {
+	return u.saveLedgerEntry(ctx, &deiz.Payment{
+		BookingID: p.BookingID,
+		Amount:    p.Amount,
+		Method:    method,
+		PaidAt:    time.Now().UTC(),
+		Reference: p.PaymentIntentID,
+		AccountID: p.AccountID,
+	}, p.ClinicianID)
+}
+
+//saveLedgerEntry records a provider payment or refund, an entry already recorded by a previous event being kept
+func (u *StripeWebhookUsecase) saveLedgerEntry(ctx context.Context, payment *deiz.Payment, clinicianID int) error {
+	err := u.PaymentSaver.SavePayment(ctx, payment, clinicianID)
+	if err == deiz.ErrorPaymentAlreadyRecorded {
+		return nil
+	}
+	return err
+}
+
+func (u *StripeWebhookUsecase) getOnlinePaymentMethod(ctx context.Context) (deiz.PaymentMethod, error) {
+	methods, err := u.PaymentMethodsGetter.GetPaymentMethods(ctx)
+	if err != nil {
+		return deiz.PaymentMethod{}, err
+	}
+	for _, m := range methods {
+		if m.Name == deiz.OnlinePaymentMethodName {
+			return m, nil
+		}
+	}
+	return deiz.PaymentMethod{}, errOnlinePaymentMethodNotFound
+}
+
+//onlineBookingInvoice fills invoice of a booking paid online, as a clinician would from its calendar.
+//Amounts are left to invoice creation, computed from booking price.
+func onlineBookingInvoice(b deiz.Booking, business deiz.Business, method deiz.PaymentMethod, now time.Time, loc *time.Location) deiz.BookingInvoice {
+	sender := []string{b.Clinician.FullName()}
+	if business.Name != "" {
+		sender = append(sender, business.Name)
+	}
+	if business.Address.Line != "" {
+		sender = append(sender, business.Address.Line, strconv.Itoa(business.Address.PostCode)+" "+business.Address.City)
+	}
+	if business.Identifier != "" {
+		sender = append(sender, "SIRET : "+business.Identifier)
+	}
+	recipient := []string{b.Patient.FullName()}
+	if b.Patient.IsEmailSet() {
+		recipient = append(recipient, b.Patient.Email)
+	}
+	cityAndDate := "Le " + now.In(loc).Format("02/01/2006")
+	if business.Address.City != "" {
+		cityAndDate = business.Address.City + ", le " + now.In(loc).Format("02/01/2006")
+	}
+	return deiz.BookingInvoice{
+		ClinicianID:   b.Clinician.ID,
+		CreatedAt:     now,
+		Sender:        sender,
+		Recipient:     recipient,
+		CityAndDate:   cityAndDate,
+		PaymentMethod: method,
+		Lines: []deiz.InvoiceLine{{
+			Booking:         b,
+			DeliveryDate:    b.Start,
+			DeliveryDateStr: b.Start.In(loc).Format("02/01/2006"),
+			Label:           "Consultation",
+		}},
+	}
+}
@@ -0,0 +1,413 @@
+package billing
+
+import (
+	"context"
+	"github.com/audrenbdb/deiz"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type mockWebhookSecretGetter struct {
+	err error
+}
+
+func (m *mockWebhookSecretGetter) GetClinicianStripeWebhookSecret(ctx context.Context, clinicianID int) ([]byte, error) {
+	return []byte("whsec"), m.err
+}
+
+type mockCheckoutEventParser struct {
+	payment deiz.CheckoutPayment
+	err     error
+}
+
+func (m *mockCheckoutEventParser) ParseCheckoutEvent(payload []byte, signature string, webhookSecret string) (deiz.CheckoutPayment, error) {
+	return m.payment, m.err
+}
+
+func (m *mockCheckoutEventParser) ParseConnectCheckoutEvent(payload []byte, signature string) (deiz.CheckoutPayment, error) {
+	return m.payment, m.err
+}
+
+type mockStripeAccountClinicianGetter struct {
+	clinicianID int
+	err         error
+}
+
+func (m *mockStripeAccountClinicianGetter) GetClinicianIDByStripeAccount(ctx context.Context, accountID string) (int, error) {
+	return m.clinicianID, m.err
+}
+
+type mockPaymentMethodsGetter struct {
+	methods []deiz.PaymentMethod
+	err     error
+}
+
+func (m *mockPaymentMethodsGetter) GetPaymentMethods(ctx context.Context) ([]deiz.PaymentMethod, error) {
+	return m.methods, m.err
+}
+
+type mockInvoiceCreater struct {
+	invoice       *deiz.BookingInvoice
+	sendToPatient bool
+	err           error
+}
+
+func (m *mockInvoiceCreater) CreateInvoice(ctx context.Context, invoice *deiz.BookingInvoice, sendToPatient bool) error {
+	m.invoice = invoice
+	m.sendToPatient = sendToPatient
+	return m.err
+}
+
+type mockBookingPaymentsGetter struct {
+	payments []deiz.Payment
+	err      error
+}
+
+func (m *mockBookingPaymentsGetter) GetBookingPayments(ctx context.Context, bookingID int, clinicianID int) ([]deiz.Payment, error) {
+	return m.payments, m.err
+}
+
+type mockPrepaidBookingConfirmer struct {
+	confirmed bool
+	err       error
+}
+
+func (m *mockPrepaidBookingConfirmer) ConfirmPrepaidBooking(ctx context.Context, b *deiz.Booking) error {
+	m.confirmed = true
+	return m.err
+}
+
+func TestHandleStripeWebhook(t *testing.T) {
+	paid := deiz.CheckoutPayment{SessionID: "cs_1", PaymentIntentID: "pi_1", BookingID: 4, ClinicianID: 1, Amount: 5000, Paid: true}
+	booking := deiz.Booking{
+		ID:        4,
+		Clinician: deiz.Clinician{ID: 1, Name: "Jean", Surname: "Dupont"},
+		Patient:   deiz.Patient{ID: 2, Name: "Marie", Surname: "Martin", Email: "marie@example.com"},
+		Price:     5000,
+	}
+	onlineCard := deiz.PaymentMethod{ID: 5, Name: deiz.OnlinePaymentMethodName}
+	methods := &mockPaymentMethodsGetter{methods: []deiz.PaymentMethod{{ID: 1, Name: "Chèque"}, onlineCard}}
+	heldBooking := booking
+	heldBooking.PaymentDeadline = time.Date(2021, 1, 4, 10, 30, 0, 0, time.UTC)
+	business := &mockBusinessGetter{business: deiz.Business{Address: deiz.Address{Line: "1 rue du port", PostCode: 29200, City: "Brest"}}}
+
+	var tests = []struct {
+		description string
+
+		clinicianIDInput int
+
+		errorOutput   error
+		invoiceOutput bool
+
+		usecase StripeWebhookUsecase
+	}{
+		{
+			description: "should fail to get webhook secret",
+
+			errorOutput: deiz.GenericError,
+
+			usecase: StripeWebhookUsecase{
+				WebhookSecretGetter: &mockWebhookSecretGetter{err: deiz.GenericError},
+			},
+		},
+		{
+			description: "should reject an event not signed with clinician secret",
+
+			clinicianIDInput: 1,
+			errorOutput:      deiz.ErrorUnauthorized,
+
+			usecase: StripeWebhookUsecase{
+				WebhookSecretGetter: &mockWebhookSecretGetter{},
+				Crypter:             &mockCrypter{key: "whsec_test"},
+				EventParser:         &mockCheckoutEventParser{err: deiz.ErrorUnauthorized},
+			},
+		},
+		{
+			description: "should ignore a checkout not paid",
+
+			clinicianIDInput: 1,
+
+			usecase: StripeWebhookUsecase{
+				WebhookSecretGetter: &mockWebhookSecretGetter{},
+				Crypter:             &mockCrypter{key: "whsec_test"},
+				EventParser:         &mockCheckoutEventParser{payment: deiz.CheckoutPayment{BookingID: 4, ClinicianID: 1}},
+			},
+		},
+		{
+			description: "should reject a checkout of another clinician",
+
+			clinicianIDInput: 2,
+			errorOutput:      deiz.ErrorUnauthorized,
+
+			usecase: StripeWebhookUsecase{
+				WebhookSecretGetter: &mockWebhookSecretGetter{},
+				Crypter:             &mockCrypter{key: "whsec_test"},
+				EventParser:         &mockCheckoutEventParser{payment: paid},
+			},
+		},
+		{
+			description: "should leave a payment already recorded untouched",
+
+			clinicianIDInput: 1,
+
+			usecase: StripeWebhookUsecase{
+				WebhookSecretGetter: &mockWebhookSecretGetter{},
+				Crypter:             &mockCrypter{key: "whsec_test"},
+				EventParser:         &mockCheckoutEventParser{payment: paid},
+				BookingGetter:       &mockBookingGetter{booking: deiz.Booking{ID: 4, Clinician: deiz.Clinician{ID: 1}, Price: 5000, Paid: true}},
+				PaymentsGetter:      &mockBookingPaymentsGetter{payments: []deiz.Payment{{Amount: 5000, Reference: "pi_1"}}},
+				PaymentSaver:        &mockPaymentSaver{},
+				InvoiceCreater:      &mockInvoiceCreater{},
+			},
+		},
+		{
+			description: "should fail to confirm a slot held for prepayment",
+
+			clinicianIDInput: 1,
+			errorOutput:      deiz.GenericError,
+
+			usecase: StripeWebhookUsecase{
+				WebhookSecretGetter: &mockWebhookSecretGetter{},
+				Crypter:             &mockCrypter{key: "whsec_test"},
+				EventParser:         &mockCheckoutEventParser{payment: paid},
+				BookingGetter:       &mockBookingGetter{booking: heldBooking},
+				BookingConfirmer:    &mockPrepaidBookingConfirmer{err: deiz.GenericError},
+				InvoiceCreater:      &mockInvoiceCreater{},
+			},
+		},
+		{
+			description: "should fail when online payment method is missing",
+
+			clinicianIDInput: 1,
+			errorOutput:      errOnlinePaymentMethodNotFound,
+
+			usecase: StripeWebhookUsecase{
+				WebhookSecretGetter:  &mockWebhookSecretGetter{},
+				Crypter:              &mockCrypter{key: "whsec_test"},
+				EventParser:          &mockCheckoutEventParser{payment: paid},
+				BookingGetter:        &mockBookingGetter{booking: booking},
+				PaymentsGetter:       &mockBookingPaymentsGetter{},
+				PaymentMethodsGetter: &mockPaymentMethodsGetter{methods: []deiz.PaymentMethod{{ID: 1, Name: "Chèque"}}},
+				InvoiceCreater:       &mockInvoiceCreater{},
+			},
+		},
+		{
+			description: "should fail to create invoice",
+
+			clinicianIDInput: 1,
+			errorOutput:      deiz.GenericError,
+			invoiceOutput:    true,
+
+			usecase: StripeWebhookUsecase{
+				WebhookSecretGetter:  &mockWebhookSecretGetter{},
+				Crypter:              &mockCrypter{key: "whsec_test"},
+				EventParser:          &mockCheckoutEventParser{payment: paid},
+				BookingGetter:        &mockBookingGetter{booking: booking},
+				PaymentsGetter:       &mockBookingPaymentsGetter{},
+				PaymentMethodsGetter: methods,
+				BusinessGetter:       business,
+				InvoiceCreater:       &mockInvoiceCreater{err: deiz.GenericError},
+			},
+		},
+		{
+			description: "should acknowledge a payment invoiced by a concurrent notification",
+
+			clinicianIDInput: 1,
+			invoiceOutput:    true,
+
+			usecase: StripeWebhookUsecase{
+				WebhookSecretGetter:  &mockWebhookSecretGetter{},
+				Crypter:              &mockCrypter{key: "whsec_test"},
+				EventParser:          &mockCheckoutEventParser{payment: paid},
+				BookingGetter:        &mockBookingGetter{booking: booking},
+				PaymentsGetter:       &mockBookingPaymentsGetter{},
+				PaymentMethodsGetter: methods,
+				BusinessGetter:       business,
+				InvoiceCreater:       &mockInvoiceCreater{err: deiz.ErrorPaymentAlreadyRecorded},
+			},
+		},
+		{
+			description: "should invoice booking paid online and mail it to patient",
+
+			clinicianIDInput: 1,
+			invoiceOutput:    true,
+
+			usecase: StripeWebhookUsecase{
+				WebhookSecretGetter:  &mockWebhookSecretGetter{},
+				Crypter:              &mockCrypter{key: "whsec_test"},
+				EventParser:          &mockCheckoutEventParser{payment: paid},
+				BookingGetter:        &mockBookingGetter{booking: booking},
+				PaymentsGetter:       &mockBookingPaymentsGetter{},
+				PaymentMethodsGetter: methods,
+				BusinessGetter:       business,
+				InvoiceCreater:       &mockInvoiceCreater{},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			err := test.usecase.HandleStripeWebhook(context.Background(), []byte("{}"), "t=1,v1=sig", test.clinicianIDInput)
+			assert.Equal(t, test.errorOutput, err)
+			creater, ok := test.usecase.InvoiceCreater.(*mockInvoiceCreater)
+			if !ok {
+				return
+			}
+			if !test.invoiceOutput {
+				assert.Nil(t, creater.invoice)
+				return
+			}
+			assert.True(t, creater.sendToPatient)
+			assert.Equal(t, "pi_1", creater.invoice.PaymentReference)
+			assert.Equal(t, onlineCard, creater.invoice.PaymentMethod)
+			assert.Equal(t, 1, creater.invoice.ClinicianID)
+			assert.Equal(t, []string{"Dupont Jean", "1 rue du port", "29200 Brest"}, creater.invoice.Sender)
+			assert.Equal(t, []string{"Martin Marie", "marie@example.com"}, creater.invoice.Recipient)
+			assert.Contains(t, creater.invoice.CityAndDate, "Brest, le ")
+			assert.Len(t, creater.invoice.Lines, 1)
+			assert.Equal(t, 4, creater.invoice.Lines[0].Booking.ID)
+		})
+	}
+}
+
+func TestHandleStripeWebhookPrepayment(t *testing.T) {
+	deposit := deiz.CheckoutPayment{PaymentIntentID: "pi_2", BookingID: 4, ClinicianID: 1, Amount: 1500, Paid: true}
+	onlineCard := deiz.PaymentMethod{ID: 5, Name: deiz.OnlinePaymentMethodName}
+	newUsecase := func(b deiz.Booking, payments []deiz.Payment) (StripeWebhookUsecase, *mockPrepaidBookingConfirmer, *mockPaymentSaver, *mockInvoiceCreater) {
+		confirmer := &mockPrepaidBookingConfirmer{}
+		saver := &mockPaymentSaver{}
+		creater := &mockInvoiceCreater{}
+		return StripeWebhookUsecase{
+			WebhookSecretGetter:  &mockWebhookSecretGetter{},
+			Crypter:              &mockCrypter{key: "whsec_test"},
+			EventParser:          &mockCheckoutEventParser{payment: deposit},
+			BookingGetter:        &mockBookingGetter{booking: b},
+			PaymentsGetter:       &mockBookingPaymentsGetter{payments: payments},
+			PaymentMethodsGetter: &mockPaymentMethodsGetter{methods: []deiz.PaymentMethod{onlineCard}},
+			BusinessGetter:       &mockBusinessGetter{},
+			PaymentSaver:         saver,
+			InvoiceCreater:       creater,
+			BookingConfirmer:     confirmer,
+		}, confirmer, saver, creater
+	}
+
+	t.Run("should confirm slot held and record deposit without invoicing", func(t *testing.T) {
+		held := deiz.Booking{ID: 4, Clinician: deiz.Clinician{ID: 1}, Price: 5000, PaymentDeadline: time.Now().Add(time.Minute)}
+		u, confirmer, saver, creater := newUsecase(held, nil)
+		err := u.HandleStripeWebhook(context.Background(), []byte("{}"), "t=1,v1=sig", 1)
+		assert.NoError(t, err)
+		assert.True(t, confirmer.confirmed)
+		assert.Nil(t, creater.invoice)
+		assert.Equal(t, int64(1500), saver.saved.Amount)
+		assert.Equal(t, 4, saver.saved.BookingID)
+		assert.Equal(t, "pi_2", saver.saved.Reference)
+		assert.Equal(t, onlineCard, saver.saved.Method)
+	})
+	t.Run("should invoice a checkout settling booking balance", func(t *testing.T) {
+		confirmed := deiz.Booking{ID: 4, Clinician: deiz.Clinician{ID: 1}, Price: 5000, Confirmed: true}
+		u, confirmer, saver, creater := newUsecase(confirmed, []deiz.Payment{{Amount: 3500, Reference: "pi_1"}})
+		err := u.HandleStripeWebhook(context.Background(), []byte("{}"), "t=1,v1=sig", 1)
+		assert.NoError(t, err)
+		assert.False(t, confirmer.confirmed)
+		assert.Nil(t, saver.saved)
+		assert.Equal(t, "pi_2", creater.invoice.PaymentReference)
+	})
+	t.Run("should only record payment of a booking already paid", func(t *testing.T) {
+		paid := deiz.Booking{ID: 4, Clinician: deiz.Clinician{ID: 1}, Price: 1500, Confirmed: true, Paid: true}
+		u, _, saver, creater := newUsecase(paid, []deiz.Payment{{Amount: 1500}})
+		err := u.HandleStripeWebhook(context.Background(), []byte("{}"), "t=1,v1=sig", 1)
+		assert.NoError(t, err)
+		assert.Nil(t, creater.invoice)
+		assert.Equal(t, int64(1500), saver.saved.Amount)
+	})
+	t.Run("should ignore a payment recorded meanwhile", func(t *testing.T) {
+		held := deiz.Booking{ID: 4, Clinician: deiz.Clinician{ID: 1}, Price: 5000, Confirmed: true}
+		u, _, saver, _ := newUsecase(held, nil)
+		saver.err = deiz.ErrorPaymentAlreadyRecorded
+		err := u.HandleStripeWebhook(context.Background(), []byte("{}"), "t=1,v1=sig", 1)
+		assert.NoError(t, err)
+	})
+}
+
+func TestHandleStripeConnectWebhook(t *testing.T) {
+	paid := deiz.CheckoutPayment{SessionID: "cs_1", AccountID: "acct_1", PaymentIntentID: "pi_1", BookingID: 4, ClinicianID: 1, Amount: 5000, Paid: true}
+	booking := deiz.Booking{ID: 4, Clinician: deiz.Clinician{ID: 1}, Patient: deiz.Patient{ID: 2}, Price: 5000}
+	onlineCard := deiz.PaymentMethod{ID: 5, Name: deiz.OnlinePaymentMethodName}
+
+	t.Run("should reject an event not signed with platform secret", func(t *testing.T) {
+		u := StripeWebhookUsecase{EventParser: &mockCheckoutEventParser{err: deiz.ErrorUnauthorized}}
+		err := u.HandleStripeConnectWebhook(context.Background(), []byte("{}"), "t=1,v1=sig")
+		assert.Equal(t, deiz.ErrorUnauthorized, err)
+	})
+	t.Run("should ignore a checkout not paid", func(t *testing.T) {
+		u := StripeWebhookUsecase{EventParser: &mockCheckoutEventParser{payment: deiz.CheckoutPayment{AccountID: "acct_1"}}}
+		err := u.HandleStripeConnectWebhook(context.Background(), []byte("{}"), "t=1,v1=sig")
+		assert.NoError(t, err)
+	})
+	t.Run("should fail to find clinician of connected account", func(t *testing.T) {
+		u := StripeWebhookUsecase{
+			EventParser:   &mockCheckoutEventParser{payment: paid},
+			AccountGetter: &mockStripeAccountClinicianGetter{err: deiz.GenericError},
+		}
+		err := u.HandleStripeConnectWebhook(context.Background(), []byte("{}"), "t=1,v1=sig")
+		assert.Equal(t, deiz.GenericError, err)
+	})
+	t.Run("should reject a checkout claiming a booking of another clinician", func(t *testing.T) {
+		u := StripeWebhookUsecase{
+			EventParser:   &mockCheckoutEventParser{payment: paid},
+			AccountGetter: &mockStripeAccountClinicianGetter{clinicianID: 2},
+		}
+		err := u.HandleStripeConnectWebhook(context.Background(), []byte("{}"), "t=1,v1=sig")
+		assert.Equal(t, deiz.ErrorUnauthorized, err)
+	})
+	t.Run("should record and refund a checkout paid once its booking is gone", func(t *testing.T) {
+		saver := &mockPaymentSaver{}
+		refunder := &mockStripeRefunder{}
+		u := StripeWebhookUsecase{
+			EventParser:          &mockCheckoutEventParser{payment: paid},
+			AccountGetter:        &mockStripeAccountClinicianGetter{clinicianID: 1},
+			BookingGetter:        &mockBookingGetter{err: deiz.ErrorBookingNotFound},
+			PaymentMethodsGetter: &mockPaymentMethodsGetter{methods: []deiz.PaymentMethod{onlineCard}},
+			PaymentSaver:         saver,
+			Refunder:             refunder,
+		}
+		err := u.HandleStripeConnectWebhook(context.Background(), []byte("{}"), "t=1,v1=sig")
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]int64{"pi_1": 5000}, refunder.refunds)
+		assert.Len(t, saver.all, 2)
+		assert.Equal(t, deiz.Payment{Amount: 5000, Method: onlineCard, PaidAt: saver.all[0].PaidAt, Reference: "pi_1", AccountID: "acct_1"}, saver.all[0])
+		assert.Equal(t, deiz.Payment{Amount: -5000, Method: onlineCard, PaidAt: saver.all[1].PaidAt, Reference: "re_pi_1", AccountID: "acct_1"}, saver.all[1])
+	})
+	t.Run("should refund an orphan checkout on retry once recorded", func(t *testing.T) {
+		refunder := &mockStripeRefunder{}
+		u := StripeWebhookUsecase{
+			EventParser:          &mockCheckoutEventParser{payment: paid},
+			AccountGetter:        &mockStripeAccountClinicianGetter{clinicianID: 1},
+			BookingGetter:        &mockBookingGetter{err: deiz.ErrorBookingNotFound},
+			PaymentMethodsGetter: &mockPaymentMethodsGetter{methods: []deiz.PaymentMethod{onlineCard}},
+			PaymentSaver:         &mockPaymentSaver{err: deiz.ErrorPaymentAlreadyRecorded},
+			Refunder:             refunder,
+		}
+		err := u.HandleStripeConnectWebhook(context.Background(), []byte("{}"), "t=1,v1=sig")
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]int64{"pi_1": 5000}, refunder.refunds)
+	})
+	t.Run("should invoice booking paid on clinician connected account", func(t *testing.T) {
+		creater := &mockInvoiceCreater{}
+		u := StripeWebhookUsecase{
+			EventParser:          &mockCheckoutEventParser{payment: paid},
+			AccountGetter:        &mockStripeAccountClinicianGetter{clinicianID: 1},
+			BookingGetter:        &mockBookingGetter{booking: booking},
+			PaymentsGetter:       &mockBookingPaymentsGetter{},
+			PaymentMethodsGetter: &mockPaymentMethodsGetter{methods: []deiz.PaymentMethod{onlineCard}},
+			BusinessGetter:       &mockBusinessGetter{},
+			InvoiceCreater:       creater,
+		}
+		err := u.HandleStripeConnectWebhook(context.Background(), []byte("{}"), "t=1,v1=sig")
+		assert.NoError(t, err)
+		assert.NotNil(t, creater.invoice)
+		assert.Equal(t, "pi_1", creater.invoice.PaymentReference)
+		assert.Equal(t, "acct_1", creater.invoice.PaymentAccountID)
+	})
+}
@@ -0,0 +1,14 @@
+//Package blob stores immutable binary objects, such as archived invoices.
+//Objects are written once: storing a key already taken fails instead of overwriting it.
+package blob
+
+const (
+	ErrBlobExists   Error = "blob already exists"
+	ErrBlobNotFound Error = "blob not found"
+)
+
+type Error string
+
+func (e Error) Error() string {
+	return string(e)
+}
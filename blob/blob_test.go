@@ -0,0 +1,129 @@
+package blob
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//fakeS3 mimics a MinIO server holding a single bucket in memory.
+//It checks every request signature with its own copy of the secret key.
+type fakeS3 struct {
+	mu        sync.Mutex
+	bucket    string
+	secretKey string
+	objects   map[string][]byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	if !f.validSignature(r, body) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	prefix := "/" + f.bucket + "/"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, prefix)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		if _, ok := f.objects[key]; ok && r.Header.Get("If-None-Match") == "*" {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		f.objects[key] = body
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet:
+		data, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(data)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (f *fakeS3) validSignature(r *http.Request, body []byte) bool {
+	hash := sha256.Sum256(body)
+	if r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(hash[:]) {
+		return false
+	}
+	signer := &S3{secretKey: f.secretKey, region: "fr-par", accessKey: "access"}
+	amzDate, err := time.Parse(amzDateFormat, r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return false
+	}
+	signer.now = func() time.Time { return amzDate }
+	expected := r.Clone(context.Background())
+	expected.URL.Host = r.Host
+	signer.sign(expected, body)
+	return r.Header.Get("Authorization") == expected.Header.Get("Authorization")
+}
+
+func newFakeS3Store(t *testing.T, secretKey string) *S3 {
+	fake := &fakeS3{bucket: "invoices", secretKey: "secret", objects: map[string][]byte{}}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	return NewS3(S3Deps{
+		Endpoint:  server.URL,
+		Bucket:    "invoices",
+		Region:    "fr-par",
+		AccessKey: "access",
+		SecretKey: secretKey,
+	})
+}
+
+type store interface {
+	PutBlob(ctx context.Context, key string, data []byte) error
+	GetBlob(ctx context.Context, key string) ([]byte, error)
+}
+
+func testStore(t *testing.T, s store) {
+	ctx := context.Background()
+	t.Run("should read stored blob", func(t *testing.T) {
+		assert.NoError(t, s.PutBlob(ctx, "7/DEIZ-7-00000001.pdf", []byte("pdf")))
+		data, err := s.GetBlob(ctx, "7/DEIZ-7-00000001.pdf")
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("pdf"), data)
+	})
+	t.Run("should refuse to overwrite a blob", func(t *testing.T) {
+		assert.NoError(t, s.PutBlob(ctx, "7/DEIZ-7-00000002.pdf", []byte("pdf")))
+		assert.Equal(t, ErrBlobExists, s.PutBlob(ctx, "7/DEIZ-7-00000002.pdf", []byte("forged")))
+		data, _ := s.GetBlob(ctx, "7/DEIZ-7-00000002.pdf")
+		assert.Equal(t, []byte("pdf"), data)
+	})
+	t.Run("should not find a missing blob", func(t *testing.T) {
+		_, err := s.GetBlob(ctx, "7/missing.pdf")
+		assert.Equal(t, ErrBlobNotFound, err)
+	})
+}
+
+func TestFileSystem(t *testing.T) {
+	testStore(t, NewFileSystem(t.TempDir()))
+}
+
+func TestS3(t *testing.T) {
+	testStore(t, newFakeS3Store(t, "secret"))
+}
+
+func TestS3WrongCredentials(t *testing.T) {
+	s := newFakeS3Store(t, "wrong")
+	err := s.PutBlob(context.Background(), "7/DEIZ-7-00000001.pdf", []byte("pdf"))
+	assert.Error(t, err)
+	assert.NotEqual(t, ErrBlobExists, err)
+}
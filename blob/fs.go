@@ -0,0 +1,56 @@
+package blob
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+//FileSystem stores blobs as files under a root directory, keys being relative paths
+type FileSystem struct {
+	dir string
+}
+
+func NewFileSystem(dir string) *FileSystem {
+	return &FileSystem{dir: dir}
+}
+
+//PutBlob writes a new read only file, failing if key already exists
+func (f *FileSystem) PutBlob(ctx context.Context, key string, data []byte) error {
+	path := f.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0440)
+	if os.IsExist(err) {
+		return ErrBlobExists
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		os.Remove(path)
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		os.Remove(path)
+		return err
+	}
+	return file.Close()
+}
+
+func (f *FileSystem) GetBlob(ctx context.Context, key string) ([]byte, error) {
+	data, err := ioutil.ReadFile(f.path(key))
+	if os.IsNotExist(err) {
+		return nil, ErrBlobNotFound
+	}
+	return data, err
+}
+
+//path cleans key so that it never leaves root directory
+func (f *FileSystem) path(key string) string {
+	return filepath.Join(f.dir, filepath.FromSlash(filepath.Clean("/"+key)))
+}
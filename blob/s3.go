@@ -0,0 +1,176 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+//S3 stores blobs in a bucket of an S3 compatible service, such as AWS or MinIO.
+//Requests are path-style and signed with AWS signature version 4.
+type S3 struct {
+	endpoint  string
+	bucket    string
+	region    string
+	accessKey string
+	secretKey string
+	client    *http.Client
+	now       func() time.Time
+}
+
+type S3Deps struct {
+	//Endpoint is the service base URL, such as https://s3.fr-par.scw.cloud
+	Endpoint  string
+	Bucket    string
+	Region    string
+	AccessKey string
+	SecretKey string
+	Client    *http.Client
+}
+
+func NewS3(deps S3Deps) *S3 {
+	client := deps.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &S3{
+		endpoint:  strings.TrimRight(deps.Endpoint, "/"),
+		bucket:    deps.Bucket,
+		region:    deps.Region,
+		accessKey: deps.AccessKey,
+		secretKey: deps.SecretKey,
+		client:    client,
+		now:       time.Now,
+	}
+}
+
+//PutBlob uploads a new object, the conditional write failing if key already exists
+func (s *S3) PutBlob(ctx context.Context, key string, data []byte) error {
+	req, err := s.newRequest(ctx, http.MethodPut, key, data)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("If-None-Match", "*")
+	res, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	switch res.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusNoContent:
+		return nil
+	case http.StatusPreconditionFailed, http.StatusConflict:
+		return ErrBlobExists
+	}
+	return responseError(res)
+}
+
+func (s *S3) GetBlob(ctx context.Context, key string) ([]byte, error) {
+	req, err := s.newRequest(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	switch res.StatusCode {
+	case http.StatusOK:
+		return ioutil.ReadAll(res.Body)
+	case http.StatusNotFound:
+		return nil, ErrBlobNotFound
+	}
+	return nil, responseError(res)
+}
+
+func responseError(res *http.Response) error {
+	body, _ := ioutil.ReadAll(res.Body)
+	return fmt.Errorf("s3: unexpected status %d: %s", res.StatusCode, body)
+}
+
+func (s *S3) newRequest(ctx context.Context, method, key string, body []byte) (*http.Request, error) {
+	u, err := url.Parse(s.endpoint + "/" + s.bucket + "/" + strings.TrimLeft(key, "/"))
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	s.sign(req, body)
+	return req, nil
+}
+
+const (
+	signingAlgorithm = "AWS4-HMAC-SHA256"
+	amzDateFormat    = "20060102T150405Z"
+	amzDayFormat     = "20060102"
+)
+
+//sign adds AWS signature version 4 headers to request
+func (s *S3) sign(req *http.Request, body []byte) {
+	now := s.now().UTC()
+	amzDate := now.Format(amzDateFormat)
+	payloadHash := sha256Hex(body)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		"host:" + req.URL.Host + "\n" +
+			"x-amz-content-sha256:" + payloadHash + "\n" +
+			"x-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := strings.Join([]string{now.Format(amzDayFormat), s.region, "s3", "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{signingAlgorithm, amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), now.Format(amzDayFormat))
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		signingAlgorithm, s.accessKey, scope, signedHeaders, signature))
+}
+
+func canonicalQuery(values url.Values) string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := []string{}
+	for _, k := range keys {
+		for _, v := range values[k] {
+			pairs = append(pairs, url.QueryEscape(k)+"="+url.QueryEscape(v))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+func sha256Hex(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -16,10 +16,18 @@ type Booking struct {
 	Clinician   Clinician `json:"clinician"`
 	Patient     Patient   `json:"patient"`
 	Address     string    `json:"address"`
+	Room        Room      `json:"room"`
 	Paid        bool      `json:"paid"`
 	Confirmed   bool      `json:"confirmed"`
 	Note        string    `json:"note"`
 	Price       int64     `json:"price"`
+	//NoShow is set when patient did not attend a confirmed appointment
+	NoShow bool `json:"noShow"`
+	//PaymentDeadline of a slot held from public calendar until patient prepays it,
+	//booking being released when unpaid by then. Zero when no prepayment is awaited.
+	PaymentDeadline time.Time `json:"paymentDeadline"`
+	//Motive booked, if any
+	Motive BookingMotive `json:"motive"`
 	//Title of the booking
 	//Can either be :
 	//"block", "appointment", "event"
@@ -30,6 +38,13 @@ type Booking struct {
 	Recurrence  BookingRecurrence `json:"recurrence"`
 }
 
+//BookingCancellation records a confirmed appointment cancelled, cancelled bookings being deleted
+type BookingCancellation struct {
+	Start      time.Time     `json:"start"`
+	CanceledAt time.Time     `json:"canceledAt"`
+	Motive     BookingMotive `json:"motive"`
+}
+
 type BookingType uint8
 type BookingRecurrence uint8
 
@@ -57,6 +72,11 @@ func (b *Booking) setAsEvent() {
 	b.MeetingMode = AtExternalAddress
 }
 
+//UsesRoom checks if booking holds a room that other clinicians of the office cannot book meanwhile
+func (b *Booking) UsesRoom() bool {
+	return b.MeetingMode == InOfficeMode && b.Room.IsSet()
+}
+
 func (b *Booking) IsValid(clinicianID int) bool {
 	if b.Start.After(b.End) {
 		return false
@@ -104,6 +124,21 @@ func (b *Booking) Assigned() bool {
 	return b.Confirmed || b.PreRegistered()
 }
 
+//HasBalance tells if booking still has something to pay
+func (b *Booking) HasBalance() bool {
+	return !b.Paid && b.Price > 0
+}
+
+//AwaitsPrepayment tells if booking is a slot held until patient prepays it
+func (b *Booking) AwaitsPrepayment() bool {
+	return !b.Confirmed && !b.PaymentDeadline.IsZero()
+}
+
+//PrepaymentCheckoutExpiry is when checkout paying a held slot closes, ahead of its payment deadline
+func (b *Booking) PrepaymentCheckoutExpiry() time.Time {
+	return b.PaymentDeadline.Add(-PrepaymentCheckoutMargin)
+}
+
 func (b *Booking) PreRegistered() bool {
 	return b.ID != 0 && !b.Confirmed
 }
@@ -3,6 +3,7 @@ package booking
 import (
 	"context"
 	"github.com/audrenbdb/deiz"
+	"github.com/audrenbdb/deiz/interval"
 	"time"
 )
 
@@ -14,13 +15,37 @@ type (
 		MailBookingToClinician(b *deiz.Booking) error
 		MailBookingToPatient(b *deiz.Booking) error
 	}
+	timezoneGetter interface {
+		GetClinicianTimezone(ctx context.Context, clinicianID int) (deiz.Timezone, error)
+	}
+	roomBookingsGetter interface {
+		GetRoomBookingsInTimeRange(ctx context.Context, start, end time.Time, roomID int) ([]deiz.Booking, error)
+		GetRoomWeeklyRecurrentBookings(ctx context.Context, roomID int) ([]deiz.Booking, error)
+	}
 )
 
+//getClinicianLocation resolves the location of clinician calendar, in which weekly events are projected
+func getClinicianLocation(ctx context.Context, getter timezoneGetter, clinicianID int) (deiz.Timezone, *time.Location, error) {
+	tz, err := getter.GetClinicianTimezone(ctx, clinicianID)
+	if err != nil {
+		return deiz.Timezone{}, nil, err
+	}
+	loc, err := tz.Location()
+	if err != nil {
+		return deiz.Timezone{}, nil, err
+	}
+	return tz, loc, nil
+}
+
 func bookingsOverlap(booking1, booking2 *deiz.Booking) bool {
-	return booking1.Start.Before(booking2.End) && booking2.Start.Before(booking1.End)
+	return bookingRange(booking1).Overlaps(bookingRange(booking2))
+}
+
+func bookingRange(b *deiz.Booking) interval.Range {
+	return interval.Range{Start: b.Start, End: b.End}
 }
 
-func bookingSlotAvailable(ctx context.Context, b *deiz.Booking, getter bookingGetter, loc *time.Location) (bool, error) {
+func bookingSlotAvailable(ctx context.Context, b *deiz.Booking, getter bookingGetter, roomGetter roomBookingsGetter, loc *time.Location) (bool, error) {
 	overlapExistingBookings, err := bookingOverlapExistingBookings(ctx, b, getter)
 	if err != nil {
 		return false, err
@@ -35,7 +60,84 @@ func bookingSlotAvailable(ctx context.Context, b *deiz.Booking, getter bookingGe
 	if overlapRecurrentBookings {
 		return false, nil
 	}
-	return true, nil
+	roomTaken, err := bookingRoomTaken(ctx, b, roomGetter, loc)
+	if err != nil {
+		return false, err
+	}
+	return !roomTaken, nil
+}
+
+//setBookingRoom sets the room a booking holds from the office hours covering it at booking address,
+//so that a room cannot be picked by client. A room given that office hours do not hold is rejected.
+func setBookingRoom(ctx context.Context, b *deiz.Booking, getter officeHoursGetter, loc *time.Location) error {
+	room := deiz.Room{}
+	if b.MeetingMode == deiz.InOfficeMode {
+		officeHours, err := getter.GetClinicianOfficeHours(ctx, b.Clinician.ID)
+		if err != nil {
+			return err
+		}
+		room = officeHoursRoom(officeHours, b, loc)
+	}
+	if b.Room.IsSet() && b.Room.ID != room.ID {
+		return deiz.ErrorStructValidation
+	}
+	b.Room = room
+	return nil
+}
+
+//officeHoursRoom returns the room of office hours covering an in office booking, if any
+func officeHoursRoom(officeHours []deiz.OfficeHours, b *deiz.Booking, loc *time.Location) deiz.Room {
+	for _, h := range officeHours {
+		if h.MeetingMode != deiz.InOfficeMode || !h.Room.IsSet() || h.Room.AddressID != h.Address.ID {
+			continue
+		}
+		if h.Address.ToString() == b.Address && officeHoursCoverBooking(h, b, loc) {
+			return h.Room
+		}
+	}
+	return deiz.Room{}
+}
+
+//bookingRoomTaken checks if another booking, from any clinician, holds the booking room meanwhile
+func bookingRoomTaken(ctx context.Context, b *deiz.Booking, getter roomBookingsGetter, loc *time.Location) (bool, error) {
+	if !b.UsesRoom() {
+		return false, nil
+	}
+	roomBookings, err := getRoomBookingsInTimeRange(ctx, timeRange{start: b.Start, end: b.End}, getter, loc, b.Room.ID)
+	if err != nil {
+		return true, err
+	}
+	for _, booking := range roomBookings {
+		if bookingsOverlap(b, &booking) && booking.ID != b.ID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+//getRoomBookingsInTimeRange returns bookings of every clinician holding given room within time range,
+//weekly recurrent ones being projected in their own clinician location
+func getRoomBookingsInTimeRange(ctx context.Context, tr timeRange, getter roomBookingsGetter, defaultLoc *time.Location, roomID int) ([]deiz.Booking, error) {
+	bookings, err := getter.GetRoomBookingsInTimeRange(ctx, tr.start, tr.end, roomID)
+	if err != nil {
+		return nil, err
+	}
+	recurrentBookings, err := getter.GetRoomWeeklyRecurrentBookings(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range recurrentBookings {
+		loc, err := b.Clinician.Location()
+		if err != nil {
+			loc = defaultLoc
+		}
+		for _, occurrence := range recurrentBookingOccurrences(tr, b, loc) {
+			b.Start = occurrence.Start
+			b.End = occurrence.End
+			bookings = append(bookings, b)
+		}
+	}
+	return bookings, nil
 }
 
 func bookingOverlapRecurrentBookings(ctx context.Context, b *deiz.Booking, getter bookingGetter, loc *time.Location) (bool, error) {
@@ -64,21 +166,35 @@ func bookingOverlapExistingBookings(ctx context.Context, b *deiz.Booking, getter
 
 func recurrentBookingsOverlapTimeRange(tr timeRange, rb []deiz.Booking, loc *time.Location) bool {
 	for _, r := range rb {
-		if recurrentBookingInTimeRange(tr, r, loc) {
+		if len(recurrentBookingOccurrences(tr, r, loc)) > 0 {
 			return true
 		}
 	}
 	return false
 }
 
-func recurrentBookingInTimeRange(tr timeRange, r deiz.Booking, loc *time.Location) bool {
-	tr = convertCalEventToTimeRange(tr, calEvent{
-		weekday: int(r.Start.In(loc).Weekday()),
-		startMn: r.Start.In(loc).Hour()*60 + r.Start.In(loc).Minute(),
-		endMn:   r.End.In(loc).Hour()*60 + r.End.In(loc).Minute(),
-		loc:     loc,
-	}, true)
-	return !tr.isNull()
+//bookingMotiveAllowed checks that office hours covering the booking do not restrict its motive
+func bookingMotiveAllowed(ctx context.Context, b *deiz.Booking, getter officeHoursGetter, loc *time.Location) (bool, error) {
+	officeHours, err := getter.GetClinicianOfficeHours(ctx, b.Clinician.ID)
+	if err != nil {
+		return false, err
+	}
+	for _, h := range officeHours {
+		if officeHoursCoverBooking(h, b, loc) && !h.AllowsMotive(b.Motive.ID) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func officeHoursCoverBooking(h deiz.OfficeHours, b *deiz.Booking, loc *time.Location) bool {
+	start := b.Start.In(loc)
+	if !h.IsWithinDate(start) || !h.IsActiveOn(start) {
+		return false
+	}
+	startMn := start.Hour()*60 + start.Minute()
+	endMn := startMn + int(b.End.Sub(b.Start).Minutes())
+	return startMn < h.EndMn && h.StartMn < endMn
 }
 
 func filterNonRecurrentBookings(bookings []deiz.Booking) []deiz.Booking {
@@ -0,0 +1,164 @@
+package booking
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/audrenbdb/deiz"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockRoomBookingsGetter struct {
+	bookings          []deiz.Booking
+	recurrentBookings []deiz.Booking
+	err               error
+}
+
+func (m *mockRoomBookingsGetter) GetRoomBookingsInTimeRange(ctx context.Context, start, end time.Time, roomID int) ([]deiz.Booking, error) {
+	return m.bookings, m.err
+}
+
+func (m *mockRoomBookingsGetter) GetRoomWeeklyRecurrentBookings(ctx context.Context, roomID int) ([]deiz.Booking, error) {
+	return m.recurrentBookings, m.err
+}
+
+func TestBookingRoomTaken(t *testing.T) {
+	room := deiz.Room{ID: 3, Name: "Salle 1", AddressID: 1}
+	//2021-01-04 is a monday
+	mondayAt := func(hour int, clinicianID int) deiz.Booking {
+		return deiz.Booking{
+			ID:          hour*10 + clinicianID,
+			Start:       time.Date(2021, 1, 4, hour, 0, 0, 0, time.UTC),
+			End:         time.Date(2021, 1, 4, hour+1, 0, 0, 0, time.UTC),
+			Clinician:   deiz.Clinician{ID: clinicianID},
+			MeetingMode: deiz.InOfficeMode,
+			Room:        room,
+		}
+	}
+	inOffice := mondayAt(10, 1)
+	inOffice.ID = 0
+	remote := mondayAt(10, 1)
+	remote.MeetingMode = deiz.RemoteMode
+	recurrentFromLastWeek := mondayAt(10, 2)
+	recurrentFromLastWeek.Start = recurrentFromLastWeek.Start.AddDate(0, 0, -7)
+	recurrentFromLastWeek.End = recurrentFromLastWeek.End.AddDate(0, 0, -7)
+	recurrentFromLastWeek.Recurrence = deiz.WeeklyRecurrence
+
+	var tests = []struct {
+		description string
+
+		bookingInput deiz.Booking
+		getter       *mockRoomBookingsGetter
+
+		takenOutput bool
+		errorOutput error
+	}{
+		{
+			description:  "should not check room of a remote booking",
+			bookingInput: remote,
+		},
+		{
+			description:  "should fail to get room bookings",
+			bookingInput: inOffice,
+			getter:       &mockRoomBookingsGetter{err: deiz.GenericError},
+			takenOutput:  true,
+			errorOutput:  deiz.GenericError,
+		},
+		{
+			description:  "should find room free when other clinician booked it later",
+			bookingInput: inOffice,
+			getter:       &mockRoomBookingsGetter{bookings: []deiz.Booking{mondayAt(11, 2)}},
+		},
+		{
+			description:  "should find room taken by other clinician",
+			bookingInput: inOffice,
+			getter:       &mockRoomBookingsGetter{bookings: []deiz.Booking{mondayAt(10, 2)}},
+			takenOutput:  true,
+		},
+		{
+			description:  "should find room taken by a weekly recurrent booking of other clinician",
+			bookingInput: inOffice,
+			getter:       &mockRoomBookingsGetter{recurrentBookings: []deiz.Booking{recurrentFromLastWeek}},
+			takenOutput:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			taken, err := bookingRoomTaken(context.Background(), &test.bookingInput, test.getter, time.UTC)
+			assert.Equal(t, test.errorOutput, err)
+			assert.Equal(t, test.takenOutput, taken)
+		})
+	}
+}
+
+func TestSetBookingRoom(t *testing.T) {
+	address := deiz.Address{ID: 1, Line: "1 rue de Brest", PostCode: 29000, City: "Quimper"}
+	room := deiz.Room{ID: 3, Name: "Salle 1", AddressID: 1}
+	//2021-01-04 is a monday
+	mondayHours := deiz.OfficeHours{StartMn: 540, EndMn: 720, WeekDay: 1, Address: address, Room: room, MeetingMode: deiz.InOfficeMode}
+	bookingWith := func(mode deiz.MeetingMode, addr string, r deiz.Room) deiz.Booking {
+		return deiz.Booking{
+			Start:       time.Date(2021, 1, 4, 10, 0, 0, 0, time.UTC),
+			End:         time.Date(2021, 1, 4, 11, 0, 0, 0, time.UTC),
+			MeetingMode: mode,
+			Address:     addr,
+			Room:        r,
+		}
+	}
+	var tests = []struct {
+		description string
+
+		bookingInput deiz.Booking
+		getter       *mockOfficeHoursGetter
+
+		roomOutput  deiz.Room
+		errorOutput error
+	}{
+		{
+			description:  "should fail to get office hours",
+			bookingInput: bookingWith(deiz.InOfficeMode, address.ToString(), deiz.Room{}),
+			getter:       &mockOfficeHoursGetter{err: deiz.GenericError},
+			errorOutput:  deiz.GenericError,
+		},
+		{
+			description:  "should hold room of office hours even when client gave none",
+			bookingInput: bookingWith(deiz.InOfficeMode, address.ToString(), deiz.Room{}),
+			getter:       &mockOfficeHoursGetter{hours: []deiz.OfficeHours{mondayHours}},
+			roomOutput:   room,
+		},
+		{
+			description:  "should reject a room office hours do not hold",
+			bookingInput: bookingWith(deiz.InOfficeMode, address.ToString(), deiz.Room{ID: 9}),
+			getter:       &mockOfficeHoursGetter{hours: []deiz.OfficeHours{mondayHours}},
+			errorOutput:  deiz.ErrorStructValidation,
+		},
+		{
+			description:  "should reject a room for a booking at another address",
+			bookingInput: bookingWith(deiz.InOfficeMode, "2 rue de Paris, 75000 Paris", room),
+			getter:       &mockOfficeHoursGetter{hours: []deiz.OfficeHours{mondayHours}},
+			errorOutput:  deiz.ErrorStructValidation,
+		},
+		{
+			description:  "should reject a room for a remote booking",
+			bookingInput: bookingWith(deiz.RemoteMode, "", room),
+			errorOutput:  deiz.ErrorStructValidation,
+		},
+		{
+			description:  "should hold no room outside office hours",
+			bookingInput: bookingWith(deiz.InOfficeMode, address.ToString(), deiz.Room{}),
+			getter:       &mockOfficeHoursGetter{hours: []deiz.OfficeHours{{StartMn: 840, EndMn: 1080, WeekDay: 1, Address: address, Room: room, MeetingMode: deiz.InOfficeMode}}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			err := setBookingRoom(context.Background(), &test.bookingInput, test.getter, time.UTC)
+			assert.Equal(t, test.errorOutput, err)
+			if err == nil {
+				assert.Equal(t, test.roomOutput, test.bookingInput.Room)
+			}
+		})
+	}
+}
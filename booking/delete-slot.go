@@ -20,12 +20,16 @@ type (
 		MailCancelBookingToClinician(b *deiz.Booking) error
 		MailCancelBookingToPatient(b *deiz.Booking) error
 	}
+	bookingRefunder interface {
+		RefundCanceledBooking(ctx context.Context, b *deiz.Booking, byClinician bool) error
+	}
 )
 
 type DeleteSlotUsecase struct {
 	BookingGetter  bookingGetter
 	BookingDeleter bookingDeleter
 	CancelMailer   cancelMailer
+	Refunder       bookingRefunder
 }
 
 func (d *DeleteSlotUsecase) DeleteBlockedSlot(ctx context.Context, bookingID, clinicianID int) error {
@@ -36,22 +40,34 @@ func (d *DeleteSlotUsecase) DeletePreRegisteredSlot(ctx context.Context, booking
 	return d.BookingDeleter.DeleteBooking(ctx, bookingID, clinicianID)
 }
 
+//DeleteBookedSlotFromPatient cancels a booking from the link sent to patient.
+//What patient paid online is refunded according to clinician cancellation policy.
 func (d *DeleteSlotUsecase) DeleteBookedSlotFromPatient(ctx context.Context, deleteID string) error {
 	booking, err := d.BookingGetter.GetBookingByDeleteID(ctx, deleteID)
 	if err != nil {
 		return err
 	}
+	if err := d.Refunder.RefundCanceledBooking(ctx, &booking, false); err != nil {
+		return err
+	}
 	if err := d.BookingDeleter.DeleteBooking(ctx, booking.ID, booking.Clinician.ID); err != nil {
 		return err
 	}
 	return d.CancelMailer.MailCancelBookingToClinician(&booking)
 }
 
+//DeleteBookedSlotFromClinician cancels a booking, refunding in full what patient paid online
 func (d *DeleteSlotUsecase) DeleteBookedSlotFromClinician(ctx context.Context, bookingID int, notifyPatient bool, clinicianID int) error {
 	booking, err := d.BookingGetter.GetBookingByID(ctx, bookingID)
 	if err != nil {
 		return err
 	}
+	if booking.Clinician.ID != clinicianID {
+		return deiz.ErrorUnauthorized
+	}
+	if err := d.Refunder.RefundCanceledBooking(ctx, &booking, true); err != nil {
+		return err
+	}
 	if err := d.BookingDeleter.DeleteBooking(ctx, booking.ID, clinicianID); err != nil {
 		return err
 	}
@@ -0,0 +1,83 @@
+package booking
+
+import (
+	"context"
+	"testing"
+
+	"github.com/audrenbdb/deiz"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockBookingRefunder struct {
+	byClinician bool
+	refunded    bool
+	err         error
+}
+
+func (m *mockBookingRefunder) RefundCanceledBooking(ctx context.Context, b *deiz.Booking, byClinician bool) error {
+	m.refunded = m.err == nil
+	m.byClinician = byClinician
+	return m.err
+}
+
+type mockCancelMailer struct {
+	toPatient, toClinician int
+}
+
+func (m *mockCancelMailer) MailCancelBookingToClinician(b *deiz.Booking) error {
+	m.toClinician++
+	return nil
+}
+
+func (m *mockCancelMailer) MailCancelBookingToPatient(b *deiz.Booking) error {
+	m.toPatient++
+	return nil
+}
+
+func TestDeleteBookedSlotFromClinician(t *testing.T) {
+	booking := deiz.Booking{ID: 8, Clinician: deiz.Clinician{ID: 1}, Price: 5000}
+	t.Run("should refuse to cancel booking of another clinician", func(t *testing.T) {
+		refunder := &mockBookingRefunder{}
+		deleter := &mockBookingDeleter{}
+		u := DeleteSlotUsecase{BookingGetter: &mockBookingGetter{booking: booking}, BookingDeleter: deleter, Refunder: refunder}
+		err := u.DeleteBookedSlotFromClinician(context.Background(), 8, true, 2)
+		assert.Equal(t, deiz.ErrorUnauthorized, err)
+		assert.False(t, refunder.refunded)
+		assert.Equal(t, 0, deleter.deletedID)
+	})
+	t.Run("should keep booking when refund fails", func(t *testing.T) {
+		deleter := &mockBookingDeleter{}
+		u := DeleteSlotUsecase{BookingGetter: &mockBookingGetter{booking: booking}, BookingDeleter: deleter, Refunder: &mockBookingRefunder{err: deiz.GenericError}}
+		err := u.DeleteBookedSlotFromClinician(context.Background(), 8, true, 1)
+		assert.Equal(t, deiz.GenericError, err)
+		assert.Equal(t, 0, deleter.deletedID)
+	})
+	t.Run("should refund in full and cancel booking", func(t *testing.T) {
+		refunder := &mockBookingRefunder{}
+		deleter := &mockBookingDeleter{}
+		mailer := &mockCancelMailer{}
+		u := DeleteSlotUsecase{BookingGetter: &mockBookingGetter{booking: booking}, BookingDeleter: deleter, Refunder: refunder, CancelMailer: mailer}
+		err := u.DeleteBookedSlotFromClinician(context.Background(), 8, true, 1)
+		assert.NoError(t, err)
+		assert.True(t, refunder.refunded)
+		assert.True(t, refunder.byClinician)
+		assert.Equal(t, 8, deleter.deletedID)
+		assert.Equal(t, 1, mailer.toPatient)
+	})
+}
+
+func TestDeleteBookedSlotFromPatient(t *testing.T) {
+	booking := deiz.Booking{ID: 8, Clinician: deiz.Clinician{ID: 1}, Price: 5000}
+	t.Run("should refund according to cancellation policy and cancel booking", func(t *testing.T) {
+		refunder := &mockBookingRefunder{}
+		deleter := &mockBookingDeleter{}
+		mailer := &mockCancelMailer{}
+		u := DeleteSlotUsecase{BookingGetter: &mockBookingGetter{booking: booking}, BookingDeleter: deleter, Refunder: refunder, CancelMailer: mailer}
+		err := u.DeleteBookedSlotFromPatient(context.Background(), "delete-id")
+		assert.NoError(t, err)
+		assert.True(t, refunder.refunded)
+		assert.False(t, refunder.byClinician)
+		assert.Equal(t, 8, deleter.deletedID)
+		assert.Equal(t, 1, mailer.toClinician)
+	})
+}
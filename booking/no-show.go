@@ -0,0 +1,18 @@
+package booking
+
+import "context"
+
+type (
+	noShowUpdater interface {
+		UpdateBookingNoShow(ctx context.Context, bookingID int, noShow bool, clinicianID int) error
+	}
+)
+
+type NoShowUsecase struct {
+	Updater noShowUpdater
+}
+
+//MarkNoShow flags a past confirmed appointment as not attended by patient, or clears the flag
+func (n *NoShowUsecase) MarkNoShow(ctx context.Context, bookingID int, noShow bool, clinicianID int) error {
+	return n.Updater.UpdateBookingNoShow(ctx, bookingID, noShow, clinicianID)
+}
@@ -0,0 +1,66 @@
+package booking
+
+import (
+	"context"
+	"github.com/audrenbdb/deiz"
+	"time"
+)
+
+type (
+	prepaymentSessionCreater interface {
+		CreateStripePrepaymentSession(ctx context.Context, b *deiz.Booking, amount int64) (string, error)
+	}
+	heldBookingConfirmer interface {
+		ConfirmHeldBooking(ctx context.Context, bookingID int, clinicianID int) error
+	}
+	expiredHoldsDeleter interface {
+		DeleteExpiredBookingHolds(ctx context.Context, d time.Time) error
+	}
+)
+
+//holdBookingForPrepayment pre-registers slot until patient pays it and opens the checkout session to do so.
+//No one is notified until payment succeeds.
+func (r *RegisterUsecase) holdBookingForPrepayment(ctx context.Context, b *deiz.Booking, policy deiz.PrepaymentPolicy) (string, error) {
+	b.Confirmed = false
+	b.PaymentDeadline = time.Now().Add(deiz.PrepaymentHoldDuration).UTC()
+	err := registerBookings(
+		ctx, registrationDependencies{timezoneGetter: r.TimezoneGetter,
+			getter: r.BookingGetter, roomGetter: r.RoomBookingsGetter, officeHoursGetter: r.OfficeHoursGetter,
+			creater: r.BookingCreater},
+		[]*deiz.Booking{b}, b.Clinician.ID, false, false)
+	if err != nil {
+		return "", err
+	}
+	session, err := r.PrepaymentSessionCreater.CreateStripePrepaymentSession(ctx, b, policy.Amount(b.Price))
+	if err != nil {
+		//slot is released right away rather than held for a payment that cannot be made
+		if deleteErr := r.BookingDeleter.DeleteBooking(ctx, b.ID, b.Clinician.ID); deleteErr != nil {
+			return "", deleteErr
+		}
+		return "", err
+	}
+	return session, nil
+}
+
+//ConfirmPrepaidBooking confirms a slot held until patient prepaid it,
+//notifying patient and clinician as any booking from public calendar
+func (r *RegisterUsecase) ConfirmPrepaidBooking(ctx context.Context, b *deiz.Booking) error {
+	if !b.AwaitsPrepayment() {
+		return deiz.ErrorStructValidation
+	}
+	if err := r.HoldConfirmer.ConfirmHeldBooking(ctx, b.ID, b.Clinician.ID); err != nil {
+		return err
+	}
+	b.Confirmed = true
+	b.PaymentDeadline = time.Time{}
+	return notifyRegistration(b, r.BookingMailer, true, true)
+}
+
+type ReleaseHoldsUsecase struct {
+	Deleter expiredHoldsDeleter
+}
+
+//ReleaseExpiredHolds frees slots held for a prepayment not made before its deadline
+func (r *ReleaseHoldsUsecase) ReleaseExpiredHolds(ctx context.Context) error {
+	return r.Deleter.DeleteExpiredBookingHolds(ctx, time.Now().UTC())
+}
@@ -0,0 +1,212 @@
+package booking
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/audrenbdb/deiz"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockBookingGetter struct {
+	bookings []deiz.Booking
+	booking  deiz.Booking
+	err      error
+}
+
+func (m *mockBookingGetter) GetNonRecurrentClinicianBookingsInTimeRange(ctx context.Context, start, end time.Time, clinicianID int) ([]deiz.Booking, error) {
+	return m.bookings, m.err
+}
+
+func (m *mockBookingGetter) GetClinicianWeeklyRecurrentBookings(ctx context.Context, clinicianID int) ([]deiz.Booking, error) {
+	return nil, m.err
+}
+
+func (m *mockBookingGetter) GetBookingByDeleteID(ctx context.Context, deleteID string) (deiz.Booking, error) {
+	return m.booking, m.err
+}
+
+func (m *mockBookingGetter) GetBookingByID(ctx context.Context, bookingID int) (deiz.Booking, error) {
+	return m.booking, m.err
+}
+
+type mockBookingCreater struct {
+	created *deiz.Booking
+}
+
+func (m *mockBookingCreater) CreateBooking(ctx context.Context, b *deiz.Booking) error {
+	b.ID = 8
+	m.created = b
+	return nil
+}
+
+type mockBookingMailer struct {
+	toPatient, toClinician int
+}
+
+func (m *mockBookingMailer) MailBookingToClinician(b *deiz.Booking) error {
+	m.toClinician++
+	return nil
+}
+
+func (m *mockBookingMailer) MailBookingToPatient(b *deiz.Booking) error {
+	m.toPatient++
+	return nil
+}
+
+type mockSettingsGetter struct {
+	settings deiz.CalendarSettings
+	err      error
+}
+
+func (m *mockSettingsGetter) GetClinicianCalendarSettings(ctx context.Context, clinicianID int) (deiz.CalendarSettings, error) {
+	return m.settings, m.err
+}
+
+type mockPrepaymentSessionCreater struct {
+	amount int64
+	err    error
+}
+
+func (m *mockPrepaymentSessionCreater) CreateStripePrepaymentSession(ctx context.Context, b *deiz.Booking, amount int64) (string, error) {
+	m.amount = amount
+	return "cs_1", m.err
+}
+
+type mockHoldConfirmer struct {
+	err error
+}
+
+func (m *mockHoldConfirmer) ConfirmHeldBooking(ctx context.Context, bookingID int, clinicianID int) error {
+	return m.err
+}
+
+type mockBookingDeleter struct {
+	deletedID int
+}
+
+func (m *mockBookingDeleter) DeleteBooking(ctx context.Context, bookingID, clinicianID int) error {
+	m.deletedID = bookingID
+	return nil
+}
+
+func TestRegisterBookingFromPatientPrepayment(t *testing.T) {
+	knownPatient := deiz.Patient{ID: 2, Name: "DUPONT", Surname: "Jean", Email: "jean@dupont.fr"}
+	newBooking := func() *deiz.Booking {
+		return &deiz.Booking{
+			Start:       time.Date(2021, 1, 4, 10, 0, 0, 0, time.UTC),
+			End:         time.Date(2021, 1, 4, 11, 0, 0, 0, time.UTC),
+			Clinician:   deiz.Clinician{ID: 1},
+			Patient:     deiz.Patient{Email: "jean@dupont.fr"},
+			BookingType: deiz.AppointmentBooking,
+			Confirmed:   true,
+			Price:       5000,
+		}
+	}
+	firstConsultation := deiz.BookingMotive{ID: 10, Price: 5000, Public: true}
+	newUsecase := func(policy deiz.PrepaymentPolicy, sessionErr error) (RegisterUsecase, *mockBookingCreater, *mockBookingMailer, *mockPrepaymentSessionCreater, *mockBookingDeleter) {
+		creater := &mockBookingCreater{}
+		mailer := &mockBookingMailer{}
+		sessionCreater := &mockPrepaymentSessionCreater{err: sessionErr}
+		deleter := &mockBookingDeleter{}
+		return RegisterUsecase{
+			TimezoneGetter:           &mockTimezoneGetter{tz: deiz.Timezone{ID: 1, Name: "UTC"}},
+			OfficeHoursGetter:        &mockOfficeHoursGetter{},
+			PatientGetter:            &mockPatientGetter{patient: knownPatient},
+			BookingGetter:            &mockBookingGetter{},
+			RoomBookingsGetter:       &mockRoomBookingsGetter{},
+			BookingCreater:           creater,
+			BookingMailer:            mailer,
+			SettingsGetter:           &mockSettingsGetter{settings: deiz.CalendarSettings{Prepayment: policy, DefaultMotive: firstConsultation}},
+			MotivesGetter:            &mockMotivesGetter{motives: []deiz.BookingMotive{firstConsultation}},
+			PrepaymentSessionCreater: sessionCreater,
+			BookingDeleter:           deleter,
+		}, creater, mailer, sessionCreater, deleter
+	}
+
+	t.Run("should confirm booking right away without prepayment", func(t *testing.T) {
+		u, creater, mailer, _, _ := newUsecase(deiz.PrepaymentPolicy{}, nil)
+		session, err := u.RegisterBookingFromPatient(context.Background(), newBooking())
+		assert.NoError(t, err)
+		assert.Equal(t, "", session)
+		assert.True(t, creater.created.Confirmed)
+		assert.Equal(t, 1, mailer.toPatient)
+		assert.Equal(t, 1, mailer.toClinician)
+	})
+	t.Run("should hold slot and open a checkout of the deposit", func(t *testing.T) {
+		u, creater, mailer, sessionCreater, _ := newUsecase(deiz.PrepaymentPolicy{Mode: deiz.DepositPrepayment, DepositPercent: 30}, nil)
+		session, err := u.RegisterBookingFromPatient(context.Background(), newBooking())
+		assert.NoError(t, err)
+		assert.Equal(t, "cs_1", session)
+		assert.True(t, creater.created.AwaitsPrepayment())
+		assert.True(t, creater.created.PaymentDeadline.After(time.Now()))
+		assert.Equal(t, int64(1500), sessionCreater.amount)
+		assert.Equal(t, 0, mailer.toPatient)
+		assert.Equal(t, 0, mailer.toClinician)
+	})
+	t.Run("should charge motive price whatever price patient sent", func(t *testing.T) {
+		for _, price := range []int64{0, 1} {
+			u, creater, _, sessionCreater, _ := newUsecase(deiz.PrepaymentPolicy{Mode: deiz.FullPrepayment}, nil)
+			b := newBooking()
+			b.Price = price
+			session, err := u.RegisterBookingFromPatient(context.Background(), b)
+			assert.NoError(t, err)
+			assert.Equal(t, "cs_1", session)
+			assert.Equal(t, int64(5000), creater.created.Price)
+			assert.Equal(t, int64(5000), sessionCreater.amount)
+		}
+	})
+	t.Run("should ignore paid status and deadline sent by patient", func(t *testing.T) {
+		u, creater, mailer, _, _ := newUsecase(deiz.PrepaymentPolicy{}, nil)
+		b := newBooking()
+		b.Paid = true
+		b.Confirmed = false
+		b.PaymentDeadline = time.Now().Add(time.Hour)
+		_, err := u.RegisterBookingFromPatient(context.Background(), b)
+		assert.NoError(t, err)
+		assert.False(t, creater.created.Paid)
+		assert.True(t, creater.created.Confirmed)
+		assert.True(t, creater.created.PaymentDeadline.IsZero())
+		assert.Equal(t, 1, mailer.toClinician)
+	})
+	t.Run("should release slot when checkout cannot be opened", func(t *testing.T) {
+		u, _, _, _, deleter := newUsecase(deiz.PrepaymentPolicy{Mode: deiz.FullPrepayment}, deiz.GenericError)
+		_, err := u.RegisterBookingFromPatient(context.Background(), newBooking())
+		assert.Equal(t, deiz.GenericError, err)
+		assert.Equal(t, 8, deleter.deletedID)
+	})
+}
+
+func TestConfirmPrepaidBooking(t *testing.T) {
+	held := func() *deiz.Booking {
+		return &deiz.Booking{
+			ID:              8,
+			Clinician:       deiz.Clinician{ID: 1},
+			Patient:         deiz.Patient{ID: 2, Email: "jean@dupont.fr"},
+			BookingType:     deiz.AppointmentBooking,
+			PaymentDeadline: time.Now().Add(time.Minute),
+		}
+	}
+	t.Run("should refuse a booking not held for prepayment", func(t *testing.T) {
+		u := RegisterUsecase{}
+		err := u.ConfirmPrepaidBooking(context.Background(), &deiz.Booking{ID: 8, Confirmed: true})
+		assert.Equal(t, deiz.ErrorStructValidation, err)
+	})
+	t.Run("should fail to confirm held booking", func(t *testing.T) {
+		u := RegisterUsecase{HoldConfirmer: &mockHoldConfirmer{err: deiz.GenericError}}
+		err := u.ConfirmPrepaidBooking(context.Background(), held())
+		assert.Equal(t, deiz.GenericError, err)
+	})
+	t.Run("should confirm held booking and notify patient and clinician", func(t *testing.T) {
+		mailer := &mockBookingMailer{}
+		u := RegisterUsecase{HoldConfirmer: &mockHoldConfirmer{}, BookingMailer: mailer}
+		b := held()
+		err := u.ConfirmPrepaidBooking(context.Background(), b)
+		assert.NoError(t, err)
+		assert.True(t, b.Confirmed)
+		assert.False(t, b.AwaitsPrepayment())
+		assert.Equal(t, 1, mailer.toPatient)
+		assert.Equal(t, 1, mailer.toClinician)
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/audrenbdb/deiz"
+	"github.com/audrenbdb/deiz/interval"
 	"time"
 )
 
@@ -11,189 +12,276 @@ type (
 	officeHoursGetter interface {
 		GetClinicianOfficeHours(ctx context.Context, clinicianID int) ([]deiz.OfficeHours, error)
 	}
+	extraAvailabilitiesGetter interface {
+		GetClinicianExtraAvailabilitiesInTimeRange(ctx context.Context, start, end time.Time, clinicianID int) ([]deiz.ExtraAvailability, error)
+	}
+	calendarSettingsGetter interface {
+		GetClinicianCalendarSettings(ctx context.Context, clinicianID int) (deiz.CalendarSettings, error)
+	}
 )
 
 type ReadCalendarUsecase struct {
-	Loc               *time.Location
-	OfficeHoursGetter officeHoursGetter
+	TimezoneGetter            timezoneGetter
+	OfficeHoursGetter         officeHoursGetter
+	ExtraAvailabilitiesGetter extraAvailabilitiesGetter
+	SettingsGetter            calendarSettingsGetter
 
-	BookingsGetter bookingGetter
+	BookingsGetter     bookingGetter
+	RoomBookingsGetter roomBookingsGetter
 }
 
 func (r *ReadCalendarUsecase) GetCalendarSlots(ctx context.Context, start time.Time, defaultDuration int, clinicianID int) ([]deiz.Booking, error) {
-	existingBookings, freeBookingSlots, err := r.getBookingSlots(ctx, start, defaultDuration, clinicianID)
+	existingBookings, freeBookingSlots, err := r.getBookingSlots(ctx, start, slotsOptions{duration: defaultDuration}, clinicianID)
 	if err != nil {
 		return nil, fmt.Errorf("unable to get booking slots: %s", err)
 	}
 	return append(existingBookings, freeBookingSlots...), nil
 }
 
-func (r *ReadCalendarUsecase) GetCalendarFreeSlots(ctx context.Context, start time.Time, defaultDuration int, clinicianID int) ([]deiz.Booking, error) {
-	_, freeBookingSlots, err := r.getBookingSlots(ctx, start, defaultDuration, clinicianID)
+//GetCalendarFreeSlots returns free slots in the week starting at given date.
+//If a motive is given, only slots in office hours allowing that motive are returned.
+//Slots start according to clinician slot granularity and may overlap each other.
+func (r *ReadCalendarUsecase) GetCalendarFreeSlots(ctx context.Context, start time.Time, defaultDuration int, motiveID int, clinicianID int) ([]deiz.Booking, error) {
+	settings, err := r.SettingsGetter.GetClinicianCalendarSettings(ctx, clinicianID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get calendar settings: %s", err)
+	}
+	_, freeBookingSlots, err := r.getBookingSlots(ctx, start, slotsOptions{
+		duration:    defaultDuration,
+		granularity: settings.SlotGranularity,
+		motiveID:    motiveID,
+	}, clinicianID)
 	if err != nil {
 		return nil, fmt.Errorf("unable to get booking slots: %s", err)
 	}
 	return freeBookingSlots, nil
 }
 
-func (r *ReadCalendarUsecase) getBookingSlots(ctx context.Context, start time.Time, defaultDuration int, clinicianID int) ([]deiz.Booking, []deiz.Booking, error) {
+//slotsOptions describes how availabilities are cut into free slots
+type slotsOptions struct {
+	//duration of a slot in mn
+	duration int
+	//granularity in mn between two slot starts, 0 meaning slots follow each other
+	granularity int
+	//motiveID to book, 0 meaning any
+	motiveID int
+}
 
+func (r *ReadCalendarUsecase) getBookingSlots(ctx context.Context, start time.Time, opts slotsOptions, clinicianID int) ([]deiz.Booking, []deiz.Booking, error) {
+	_, loc, err := getClinicianLocation(ctx, r.TimezoneGetter, clinicianID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to get clinician location: %s", err)
+	}
 	end := start.AddDate(0, 0, 7)
 	existingBookings, err := r.BookingsGetter.GetNonRecurrentClinicianBookingsInTimeRange(ctx, start, end, clinicianID)
 	if err != nil {
 		return nil, nil, fmt.Errorf("unable to get bookings in given timerange: %s", err)
 	}
-	recurrentBookings, err := r.getRecurrentBookingsInTimeRange(ctx, clinicianID)
+	recurrentBookings, err := r.getRecurrentBookingsInTimeRange(ctx, timeRange{start, end}, loc, clinicianID)
 	if err != nil {
 		return nil, nil, fmt.Errorf("unable to get existing recurrent bookings: %s", err)
 	}
 	existingBookings = append(existingBookings, recurrentBookings...)
-	freeBookingSlots, err := r.getFreeBookingSlots(ctx, timeRange{start, end}, deiz.SortBookingByDate(existingBookings), defaultDuration, clinicianID)
+	freeBookingSlots, err := r.getFreeBookingSlots(ctx, timeRange{start, end}, deiz.SortBookingByDate(existingBookings), opts, loc, clinicianID)
 	if err != nil {
 		return nil, nil, fmt.Errorf("unable to get free booking slots: %s", err)
 	}
 	return existingBookings, freeBookingSlots, nil
 }
 
-func (r *ReadCalendarUsecase) getRecurrentBookingsInTimeRange(ctx context.Context, clinicianID int) ([]deiz.Booking, error) {
+//getRecurrentBookingsInTimeRange projects weekly recurrent bookings on every occurrence within given time range
+func (r *ReadCalendarUsecase) getRecurrentBookingsInTimeRange(ctx context.Context, tr timeRange, loc *time.Location, clinicianID int) ([]deiz.Booking, error) {
 	recurrentBookings, err := r.BookingsGetter.GetClinicianWeeklyRecurrentBookings(ctx, clinicianID)
 	if err != nil {
 		return nil, err
 	}
+	projectedBookings := []deiz.Booking{}
 	for _, b := range recurrentBookings {
-		r.setRecurrentBookingInTimeRange(&b)
-	}
-	return recurrentBookings, nil
-}
-
-func (r *ReadCalendarUsecase) setRecurrentBookingInTimeRange(b *deiz.Booking) {
-	bookingTr := timeRange{
-		start: b.Start.In(r.Loc),
-		end:   b.End.In(r.Loc),
+		for _, occurrence := range recurrentBookingOccurrences(tr, b, loc) {
+			b.Start = occurrence.Start
+			b.End = occurrence.End
+			projectedBookings = append(projectedBookings, b)
+		}
 	}
-	tr := convertCalEventToTimeRange(bookingTr, calEvent{
-		weekday: int(bookingTr.start.Weekday()),
-		startMn: bookingTr.start.Hour()*60 + bookingTr.start.Minute(),
-		endMn:   bookingTr.end.Hour()*60 + bookingTr.end.Minute(),
-		loc:     r.Loc,
-	}, false)
-	b.Start = tr.start
-	b.End = tr.end
+	return projectedBookings, nil
 }
 
-func (r *ReadCalendarUsecase) getFreeBookingSlots(ctx context.Context, timeRange timeRange, existingBookings []deiz.Booking, defaultDuration int, clinicianID int) ([]deiz.Booking, error) {
-	availabilities, err := r.getOfficeHoursAvailabilities(ctx, timeRange, clinicianID)
+func (r *ReadCalendarUsecase) getFreeBookingSlots(ctx context.Context, timeRange timeRange, existingBookings []deiz.Booking, opts slotsOptions, loc *time.Location, clinicianID int) ([]deiz.Booking, error) {
+	availabilities, err := r.getOfficeHoursAvailabilities(ctx, timeRange, loc, clinicianID)
 	if err != nil {
 		return nil, fmt.Errorf("unable to get clinician availabilities: %s", err)
 	}
+	roomBookings := map[int][]deiz.Booking{}
 	bookingSlots := []deiz.Booking{}
-	for _, availability := range availabilities {
+	for _, availability := range filterAvailabilitiesByMotive(availabilities, opts.motiveID) {
+		takenSlots := existingBookings
+		if availability.usesRoom() {
+			roomID := availability.hours.Room.ID
+			if _, ok := roomBookings[roomID]; !ok {
+				roomBookings[roomID], err = getRoomBookingsInTimeRange(ctx, timeRange, r.RoomBookingsGetter, loc, roomID)
+				if err != nil {
+					return nil, fmt.Errorf("unable to get room bookings: %s", err)
+				}
+			}
+			takenSlots = append(append([]deiz.Booking{}, existingBookings...), roomBookings[roomID]...)
+		}
+		if opts.granularity > 0 {
+			bookingSlots = append(bookingSlots,
+				splitAvailabilityInGranularFreeBookingSlots(availability, takenSlots, opts.duration, opts.granularity)...)
+			continue
+		}
 		bookingSlots = append(bookingSlots,
-			splitAvailabilityInFreeBookingSlots(availability, existingBookings,
-				defaultDuration, []deiz.Booking{})...)
+			splitAvailabilityInFreeBookingSlots(availability, takenSlots, opts.duration)...)
 	}
-	return bookingSlots, nil
+	return setBookingsMotive(bookingSlots, opts.motiveID), nil
 }
 
-func splitAvailabilityInFreeBookingSlots(availability officeHoursAvailability, existingBookings []deiz.Booking, defaultDuration int, freeBookings []deiz.Booking) []deiz.Booking {
-	nextFreeBooking := deiz.Booking{
-		BookingType: deiz.AppointmentBooking,
-		Start:       availability.availableTimeRange.start,
-		End:         availability.availableTimeRange.start.Add(time.Minute * time.Duration(defaultDuration)),
-		Address:     availability.hours.Address.ToString(),
-		MeetingMode: availability.hours.MeetingMode,
+//filterAvailabilitiesByMotive keeps availabilities where given motive can be booked, motive 0 meaning any
+func filterAvailabilitiesByMotive(availabilities []officeHoursAvailability, motiveID int) []officeHoursAvailability {
+	if motiveID == 0 {
+		return availabilities
 	}
-	//make sure next free booking time range do not overlaps with existing bookings
-	for _, booking := range existingBookings {
-		if bookingsOverlap(&nextFreeBooking, &booking) {
-			nextFreeBooking.Start = booking.End
-			nextFreeBooking.End = nextFreeBooking.Start.Add(time.Minute * time.Duration(defaultDuration))
+	filtered := []officeHoursAvailability{}
+	for _, a := range availabilities {
+		if a.hours.AllowsMotive(motiveID) {
+			filtered = append(filtered, a)
 		}
 	}
-	if nextFreeBooking.End.After(availability.availableTimeRange.end) {
-		return freeBookings
+	return filtered
+}
+
+func setBookingsMotive(bookings []deiz.Booking, motiveID int) []deiz.Booking {
+	for i := range bookings {
+		bookings[i].Motive.ID = motiveID
+	}
+	return bookings
+}
+
+//splitAvailabilityInFreeBookingSlots cuts an availability in consecutive slots, skipping existing bookings
+func splitAvailabilityInFreeBookingSlots(availability officeHoursAvailability, existingBookings []deiz.Booking, defaultDuration int) []deiz.Booking {
+	duration := time.Minute * time.Duration(defaultDuration)
+	freeBookings := []deiz.Booking{}
+	for _, free := range freeRanges(availability, existingBookings) {
+		freeBookings = append(freeBookings,
+			slotsToBookings(interval.Split(free, duration, duration), availability)...)
+	}
+	return freeBookings
+}
+
+//splitAvailabilityInGranularFreeBookingSlots cuts an availability in slots starting every granularity mn.
+//Slots may overlap each other, but never overlap existing bookings.
+func splitAvailabilityInGranularFreeBookingSlots(availability officeHoursAvailability, existingBookings []deiz.Booking, defaultDuration int, granularity int) []deiz.Booking {
+	duration := time.Minute * time.Duration(defaultDuration)
+	step := time.Minute * time.Duration(granularity)
+	freeBookings := []deiz.Booking{}
+	for _, free := range freeRanges(availability, existingBookings) {
+		freeBookings = append(freeBookings,
+			slotsToBookings(interval.SplitAligned(free, availability.availableTimeRange.start, duration, step), availability)...)
 	}
-	availability.availableTimeRange.start = nextFreeBooking.End
-	return splitAvailabilityInFreeBookingSlots(
-		availability,
-		existingBookings,
-		defaultDuration, append(freeBookings, nextFreeBooking))
+	return freeBookings
 }
 
-func (r *ReadCalendarUsecase) getOfficeHoursAvailabilities(ctx context.Context, timeRange timeRange, clinicianID int) ([]officeHoursAvailability, error) {
+//freeRanges returns parts of availability not taken by existing bookings
+func freeRanges(availability officeHoursAvailability, existingBookings []deiz.Booking) []interval.Range {
+	taken := make([]interval.Range, 0, len(existingBookings))
+	for _, b := range existingBookings {
+		taken = append(taken, interval.Range{Start: b.Start, End: b.End})
+	}
+	return interval.Subtract([]interval.Range{availability.availableTimeRange.toRange()}, taken)
+}
+
+func slotsToBookings(slots []interval.Range, availability officeHoursAvailability) []deiz.Booking {
+	bookings := make([]deiz.Booking, 0, len(slots))
+	for _, slot := range slots {
+		bookings = append(bookings, deiz.Booking{
+			BookingType: deiz.AppointmentBooking,
+			Start:       slot.Start,
+			End:         slot.End,
+			Address:     availability.hours.Address.ToString(),
+			Room:        availability.hours.Room,
+			MeetingMode: availability.hours.MeetingMode,
+		})
+	}
+	return bookings
+}
+
+func (r *ReadCalendarUsecase) getOfficeHoursAvailabilities(ctx context.Context, timeRange timeRange, loc *time.Location, clinicianID int) ([]officeHoursAvailability, error) {
 	officeHours, err := r.OfficeHoursGetter.GetClinicianOfficeHours(ctx, clinicianID)
 	if err != nil {
 		return nil, err
 	}
+	extraAvailabilities, err := r.ExtraAvailabilitiesGetter.GetClinicianExtraAvailabilitiesInTimeRange(ctx, timeRange.start, timeRange.end, clinicianID)
+	if err != nil {
+		return nil, err
+	}
 	var officeHoursRanges []officeHoursAvailability
 	for _, h := range officeHours {
+		for _, tr := range convertOfficeHoursToTimeRanges(timeRange, h, loc) {
+			officeHoursRanges = append(officeHoursRanges,
+				officeHoursAvailability{
+					hours:              h,
+					availableTimeRange: tr,
+				})
+		}
+	}
+	for _, a := range extraAvailabilities {
+		tr := constraintTimeRangeWithinLimit(timeRange, timeRangeFromExtraAvailability(a))
+		if tr.isNull() {
+			continue
+		}
 		officeHoursRanges = append(officeHoursRanges,
 			officeHoursAvailability{
-				hours: h,
-				availableTimeRange: convertCalEventToTimeRange(timeRange, calEvent{
-					weekday: h.WeekDay,
-					startMn: h.StartMn,
-					endMn:   h.EndMn,
-					loc:     r.Loc,
-				}, true),
+				hours:              deiz.OfficeHours{Address: a.Address, MeetingMode: a.MeetingMode},
+				availableTimeRange: tr,
 			})
 	}
 	return officeHoursRanges, nil
 }
 
-//check if given dates in an array have same weekday
-func datesShareSameWeekday(dates []time.Time, weekday int) bool {
-	for _, d := range dates {
-		if int(d.Weekday()) != weekday {
-			return false
+//convertOfficeHoursToTimeRanges projects weekly office hours on every day of given time range
+//where they are active, cutting them to time range limits
+func convertOfficeHoursToTimeRanges(limit timeRange, h deiz.OfficeHours, loc *time.Location) []timeRange {
+	timeRanges := []timeRange{}
+	for _, occurrence := range interval.Weekly(limit.toRange(), h.WeekDay, h.StartMn, h.EndMn, loc) {
+		tr := constraintTimeRangeWithinLimit(limit, timeRangeFromRange(occurrence))
+		if tr.isNull() || !h.IsActiveOn(occurrence.Start.In(loc)) {
+			continue
 		}
+		timeRanges = append(timeRanges, tr)
 	}
-	return true
+	return timeRanges
 }
 
-type calEvent struct {
-	weekday int
-	startMn int
-	endMn   int
-	loc     *time.Location
+func timeRangeFromExtraAvailability(a deiz.ExtraAvailability) timeRange {
+	return timeRange{start: a.Start.UTC(), end: a.End.UTC()}
 }
 
-func convertCalEventToTimeRange(limit timeRange, ev calEvent, shouldConstraintWithingLimits bool) timeRange {
-	y, m, d := limit.start.In(ev.loc).Date()
-	if datesShareSameWeekday([]time.Time{limit.start.In(ev.loc)}, ev.weekday) {
-		officeOpensAt := time.Date(y, m, d, ev.startMn/60, ev.startMn%60, 0, 0, ev.loc).UTC()
-		officeClosesAt := time.Date(y, m, d, ev.endMn/60, ev.endMn%60, 0, 0, ev.loc).UTC()
-		if shouldConstraintWithingLimits {
-			return constraintTimeRangeWithinLimit(limit, timeRange{start: officeOpensAt, end: officeClosesAt})
+//recurrentBookingOccurrences projects a weekly recurrent booking on given time range,
+//ignoring occurrences before the booking was first made
+func recurrentBookingOccurrences(tr timeRange, b deiz.Booking, loc *time.Location) []interval.Range {
+	start := b.Start.In(loc)
+	end := b.End.In(loc)
+	startMn := start.Hour()*60 + start.Minute()
+	endMn := startMn + int(end.Sub(start).Minutes())
+	occurrences := []interval.Range{}
+	for _, occurrence := range interval.Weekly(tr.toRange(), int(start.Weekday()), startMn, endMn, loc) {
+		if occurrence.Overlaps(tr.toRange()) && !occurrence.Start.Before(b.Start) {
+			occurrences = append(occurrences, occurrence)
 		}
-		return timeRange{start: officeOpensAt, end: officeClosesAt}
-	}
-	if limit.start.After(limit.end) {
-		return timeRange{}
 	}
-	return convertCalEventToTimeRange(timeRange{
-		start: time.Date(y, m, d+1, 0, 0, 0, 0, time.UTC),
-		end:   limit.end}, ev, shouldConstraintWithingLimits)
+	return occurrences
 }
 
 func constraintTimeRangeWithinLimit(limit timeRange, tr timeRange) timeRange {
-	if !timeRangesOverlaps(timeRange{limit.start, limit.end}, tr) {
-		return timeRange{}
-	}
-	if tr.start.Before(limit.start) {
-		tr.start = limit.start
-	}
-	if tr.end.After(limit.end) {
-		tr.end = limit.end
-	}
-	return tr
-}
-
-func timeRangesOverlaps(trA, trB timeRange) bool {
-	return trA.start.Before(trB.end) && trB.start.Before(trA.end)
+	return timeRangeFromRange(limit.toRange().Intersect(tr.toRange()))
 }
 
 type officeHoursAvailability struct {
 	hours              deiz.OfficeHours
 	availableTimeRange timeRange
 }
+
+//usesRoom checks if slots of the availability hold a room shared with other clinicians
+func (a officeHoursAvailability) usesRoom() bool {
+	return a.hours.MeetingMode == deiz.InOfficeMode && a.hours.Room.IsSet()
+}
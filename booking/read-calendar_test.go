@@ -41,17 +41,18 @@ func TestConstraintTimeRangeWithinLimit(t *testing.T) {
 	}
 }
 
-func TestConvertOfficeHoursToTimeRange(t *testing.T) {
-	u := ReadCalendarUsecase{Loc: time.UTC}
+func TestConvertOfficeHoursToTimeRanges(t *testing.T) {
+	paris, _ := time.LoadLocation("Europe/Paris")
 
 	var tests = []struct {
 		description string
 
+		loc   *time.Location
 		start time.Time
 		end   time.Time
 		h     deiz.OfficeHours
 
-		outputTimerange [2]time.Time
+		outputTimeranges []timeRange
 	}{
 		{
 			description: "should return time range limited by start value",
@@ -60,10 +61,13 @@ func TestConvertOfficeHoursToTimeRange(t *testing.T) {
 			end:   time.Date(2021, 1, 8, 10, 0, 0, 0, time.UTC),
 			h:     deiz.OfficeHours{StartMn: 540, EndMn: 720, WeekDay: 5},
 
-			outputTimerange: [2]time.Time{
+			outputTimeranges: []timeRange{{
 				time.Date(2021, 1, 1, 10, 0, 0, 0, time.UTC),
 				time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC),
-			},
+			}, {
+				time.Date(2021, 1, 8, 9, 0, 0, 0, time.UTC),
+				time.Date(2021, 1, 8, 10, 0, 0, 0, time.UTC),
+			}},
 		},
 		{
 			description: "should return time range limited by end value",
@@ -72,18 +76,163 @@ func TestConvertOfficeHoursToTimeRange(t *testing.T) {
 			end:   time.Date(2021, 1, 8, 10, 0, 0, 0, time.UTC),
 			h:     deiz.OfficeHours{StartMn: 480, EndMn: 720, WeekDay: 5},
 
-			outputTimerange: [2]time.Time{
+			outputTimeranges: []timeRange{{
 				time.Date(2021, 1, 8, 8, 0, 0, 0, time.UTC),
 				time.Date(2021, 1, 8, 10, 0, 0, 0, time.UTC),
-			},
+			}},
+		},
+		{
+			description: "should return null time range when office hours are not yet valid",
+
+			start: time.Date(2021, 1, 1, 10, 0, 0, 0, time.UTC),
+			end:   time.Date(2021, 1, 8, 10, 0, 0, 0, time.UTC),
+			h: deiz.OfficeHours{StartMn: 540, EndMn: 720, WeekDay: 1,
+				ValidFrom: time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)},
+
+			outputTimeranges: []timeRange{},
+		},
+		{
+			description: "should return null time range when office hours validity expired",
+
+			start: time.Date(2021, 1, 1, 10, 0, 0, 0, time.UTC),
+			end:   time.Date(2021, 1, 8, 10, 0, 0, 0, time.UTC),
+			h: deiz.OfficeHours{StartMn: 540, EndMn: 720, WeekDay: 1,
+				ValidUntil: time.Date(2021, 1, 3, 0, 0, 0, 0, time.UTC)},
+
+			outputTimeranges: []timeRange{},
+		},
+		{
+			description: "should return time range on the last day of validity",
+
+			start: time.Date(2021, 1, 1, 10, 0, 0, 0, time.UTC),
+			end:   time.Date(2021, 1, 8, 10, 0, 0, 0, time.UTC),
+			h: deiz.OfficeHours{StartMn: 540, EndMn: 720, WeekDay: 1,
+				ValidUntil: time.Date(2021, 1, 4, 0, 0, 0, 0, time.UTC)},
+
+			outputTimeranges: []timeRange{{
+				time.Date(2021, 1, 4, 9, 0, 0, 0, time.UTC),
+				time.Date(2021, 1, 4, 12, 0, 0, 0, time.UTC),
+			}},
+		},
+		{
+			description: "should keep local opening hours on a DST transition day",
+
+			loc:   paris,
+			start: time.Date(2021, 3, 27, 0, 0, 0, 0, time.UTC),
+			end:   time.Date(2021, 4, 3, 0, 0, 0, 0, time.UTC),
+			h:     deiz.OfficeHours{StartMn: 540, EndMn: 720, WeekDay: 0},
+
+			outputTimeranges: []timeRange{{
+				time.Date(2021, 3, 28, 7, 0, 0, 0, time.UTC),
+				time.Date(2021, 3, 28, 10, 0, 0, 0, time.UTC),
+			}},
 		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.description, func(t *testing.T) {
-			timeRange := u.convertOfficeHoursToTimeRange(timeRange{test.start, test.end}, test.h)
-			assert.Equal(t, test.outputTimerange, timeRange, "expected : %s, got : %s", test.outputTimerange, timeRange)
+			loc := time.UTC
+			if test.loc != nil {
+				loc = test.loc
+			}
+			timeRanges := convertOfficeHoursToTimeRanges(timeRange{test.start, test.end}, test.h, loc)
+			assert.Equal(t, test.outputTimeranges, timeRanges)
 		})
 	}
 
 }
+
+func TestFilterAvailabilitiesByMotive(t *testing.T) {
+	unrestricted := officeHoursAvailability{hours: deiz.OfficeHours{ID: 1}}
+	firstConsultationOnly := officeHoursAvailability{hours: deiz.OfficeHours{ID: 2, MotiveIDs: []int{10}}}
+	availabilities := []officeHoursAvailability{unrestricted, firstConsultationOnly}
+
+	var tests = []struct {
+		description string
+
+		motiveID int
+
+		outputAvailabilities []officeHoursAvailability
+	}{
+		{
+			description: "should keep every availability when no motive is requested",
+
+			motiveID:             0,
+			outputAvailabilities: availabilities,
+		},
+		{
+			description: "should keep availability restricted to requested motive",
+
+			motiveID:             10,
+			outputAvailabilities: availabilities,
+		},
+		{
+			description: "should drop availability restricted to another motive",
+
+			motiveID:             11,
+			outputAvailabilities: []officeHoursAvailability{unrestricted},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			filtered := filterAvailabilitiesByMotive(availabilities, test.motiveID)
+			assert.Equal(t, test.outputAvailabilities, filtered)
+		})
+	}
+}
+
+func TestSplitAvailabilityInGranularFreeBookingSlots(t *testing.T) {
+	at := func(h, m int) time.Time {
+		return time.Date(2021, 7, 5, h, m, 0, 0, time.UTC)
+	}
+	availability := officeHoursAvailability{availableTimeRange: timeRange{at(9, 0), at(11, 0)}}
+
+	var tests = []struct {
+		description string
+
+		existingBookings []deiz.Booking
+		duration         int
+		granularity      int
+
+		outputStarts []time.Time
+	}{
+		{
+			description: "should offer overlapping slots every granularity minutes",
+
+			duration:    45,
+			granularity: 15,
+
+			outputStarts: []time.Time{at(9, 0), at(9, 15), at(9, 30), at(9, 45), at(10, 0), at(10, 15)},
+		},
+		{
+			description: "should drop candidate slots overlapping an existing booking",
+
+			existingBookings: []deiz.Booking{{Start: at(9, 45), End: at(10, 15)}},
+			duration:         30,
+			granularity:      15,
+
+			outputStarts: []time.Time{at(9, 0), at(9, 15), at(10, 15), at(10, 30)},
+		},
+		{
+			description: "should not offer slot ending after availability",
+
+			duration:    60,
+			granularity: 45,
+
+			outputStarts: []time.Time{at(9, 0), at(9, 45)},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			slots := splitAvailabilityInGranularFreeBookingSlots(availability, test.existingBookings, test.duration, test.granularity)
+			starts := []time.Time{}
+			for _, s := range slots {
+				starts = append(starts, s.Start)
+				assert.Equal(t, test.duration, int(s.End.Sub(s.Start).Minutes()))
+			}
+			assert.Equal(t, test.outputStarts, starts)
+		})
+	}
+}
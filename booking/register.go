@@ -16,43 +16,120 @@ type (
 	patientCreater interface {
 		CreatePatient(ctx context.Context, p *deiz.Patient, clinicianID int) error
 	}
+	patientTimezoneUpdater interface {
+		UpdatePatientTimezone(ctx context.Context, patientID int, timezone string, clinicianID int) error
+	}
+	bookingMotivesGetter interface {
+		GetClinicianBookingMotives(ctx context.Context, clinicianID int) ([]deiz.BookingMotive, error)
+	}
 )
 
 type RegisterUsecase struct {
-	Loc *time.Location
+	TimezoneGetter timezoneGetter
+
+	OfficeHoursGetter officeHoursGetter
 
-	PatientGetter  patientGetter
-	PatientCreater patientCreater
+	PatientGetter          patientGetter
+	PatientCreater         patientCreater
+	PatientTimezoneUpdater patientTimezoneUpdater
 
 	BookingCreater bookingCreater
 	BookingUpdater bookingUpdater
 	BookingGetter  bookingGetter
 
+	RoomBookingsGetter roomBookingsGetter
+
 	BookingMailer bookingMailer
+
+	SettingsGetter           calendarSettingsGetter
+	MotivesGetter            bookingMotivesGetter
+	PrepaymentSessionCreater prepaymentSessionCreater
+	HoldConfirmer            heldBookingConfirmer
+	BookingDeleter           bookingDeleter
 }
 
-func (r *RegisterUsecase) RegisterBookingFromPatient(ctx context.Context, b *deiz.Booking) error {
+//RegisterBookingFromPatient books a slot from public calendar.
+//Booking is priced from its motive, patients choosing neither price nor status of what they book.
+//When clinician requires a prepayment, slot is held until paid and the Stripe checkout session to pay it is returned,
+//booking being confirmed once payment succeeds. Session is empty otherwise.
+func (r *RegisterUsecase) RegisterBookingFromPatient(ctx context.Context, b *deiz.Booking) (string, error) {
+	_, loc, err := getClinicianLocation(ctx, r.TimezoneGetter, b.Clinician.ID)
+	if err != nil {
+		return "", err
+	}
+	settings, err := r.SettingsGetter.GetClinicianCalendarSettings(ctx, b.Clinician.ID)
+	if err != nil {
+		return "", err
+	}
+	motives, err := r.MotivesGetter.GetClinicianBookingMotives(ctx, b.Clinician.ID)
+	if err != nil {
+		return "", err
+	}
+	if err := setPublicBookingTerms(b, motives, settings.DefaultMotive); err != nil {
+		return "", err
+	}
+	motiveAllowed, err := bookingMotiveAllowed(ctx, b, r.OfficeHoursGetter, loc)
+	if err != nil {
+		return "", err
+	}
+	if !motiveAllowed {
+		return "", deiz.ErrorMotiveNotAllowed
+	}
 	if err := r.setBookingPatient(ctx, b); err != nil {
-		return err
+		return "", err
 	}
-	return registerBookings(
-		ctx, registrationDependencies{loc: r.Loc,
-			getter: r.BookingGetter, creater: r.BookingCreater, mailer: r.BookingMailer},
+	if settings.Prepayment.IsRequired() && b.Price > 0 {
+		return r.holdBookingForPrepayment(ctx, b, settings.Prepayment)
+	}
+	return "", registerBookings(
+		ctx, registrationDependencies{timezoneGetter: r.TimezoneGetter,
+			getter: r.BookingGetter, roomGetter: r.RoomBookingsGetter, officeHoursGetter: r.OfficeHoursGetter,
+			creater: r.BookingCreater, mailer: r.BookingMailer},
 		[]*deiz.Booking{b}, b.Clinician.ID, true, true)
 }
 
+//setPublicBookingTerms sets what a patient booking from public calendar cannot choose.
+//Booking is a confirmed appointment of a public motive, or of clinician default one when none is picked,
+//charged the motive price.
+func setPublicBookingTerms(b *deiz.Booking, motives []deiz.BookingMotive, defaultMotive deiz.BookingMotive) error {
+	motive := defaultMotive
+	if b.Motive.ID != 0 && b.Motive.ID != defaultMotive.ID {
+		found := false
+		for _, m := range motives {
+			if m.ID == b.Motive.ID && m.Public {
+				motive, found = m, true
+			}
+		}
+		if !found {
+			return deiz.ErrorMotiveNotAllowed
+		}
+	}
+	b.Motive = motive
+	b.Price = motive.Price
+	b.BookingType = deiz.AppointmentBooking
+	b.Confirmed = true
+	b.Paid = false
+	b.NoShow = false
+	b.PaymentDeadline = time.Time{}
+	return nil
+}
+
 func (r *RegisterUsecase) RegisterBookingsFromClinician(ctx context.Context, bookings []*deiz.Booking, clinicianID int, notifyPatient bool) error {
 	return registerBookings(
-		ctx, registrationDependencies{loc: r.Loc,
-			getter: r.BookingGetter, creater: r.BookingCreater, mailer: r.BookingMailer},
+		ctx, registrationDependencies{timezoneGetter: r.TimezoneGetter,
+			getter: r.BookingGetter, roomGetter: r.RoomBookingsGetter, officeHoursGetter: r.OfficeHoursGetter,
+			creater: r.BookingCreater, mailer: r.BookingMailer},
 		bookings, clinicianID, notifyPatient, false)
 }
 
 type registrationDependencies struct {
-	getter  bookingGetter
-	creater bookingCreater
-	mailer  bookingMailer
-	loc     *time.Location
+	getter            bookingGetter
+	roomGetter        roomBookingsGetter
+	officeHoursGetter officeHoursGetter
+	creater           bookingCreater
+	mailer            bookingMailer
+
+	timezoneGetter timezoneGetter
 }
 
 func registerBookings(
@@ -61,8 +138,16 @@ func registerBookings(
 	if areBookingsInvalid(bookings, clinicianID) {
 		return deiz.ErrorStructValidation
 	}
+	tz, loc, err := getClinicianLocation(ctx, deps.timezoneGetter, clinicianID)
+	if err != nil {
+		return err
+	}
 	for _, b := range bookings {
-		available, err := bookingSlotAvailable(ctx, b, deps.getter, deps.loc)
+		b.Clinician.Timezone = tz
+		if err := setBookingRoom(ctx, b, deps.officeHoursGetter, loc); err != nil {
+			return err
+		}
+		available, err := bookingSlotAvailable(ctx, b, deps.getter, deps.roomGetter, loc)
 		if err != nil {
 			return err
 		}
@@ -85,7 +170,15 @@ func (r *RegisterUsecase) RegisterPreRegisteredBooking(ctx context.Context, b *d
 	if areBookingsInvalid([]*deiz.Booking{b}, clinicianID) {
 		return deiz.ErrorStructValidation
 	}
-	available, err := bookingSlotAvailable(ctx, b, r.BookingGetter, r.Loc)
+	tz, loc, err := getClinicianLocation(ctx, r.TimezoneGetter, clinicianID)
+	if err != nil {
+		return err
+	}
+	b.Clinician.Timezone = tz
+	if err := setBookingRoom(ctx, b, r.OfficeHoursGetter, loc); err != nil {
+		return err
+	}
+	available, err := bookingSlotAvailable(ctx, b, r.BookingGetter, r.RoomBookingsGetter, loc)
 	if err != nil {
 		return err
 	}
@@ -114,11 +207,26 @@ func (r *RegisterUsecase) setBookingPatient(ctx context.Context, b *deiz.Booking
 			return err
 		}
 	} else {
+		if err := r.updatePatientTimezone(ctx, &patient, b.Patient.Timezone, b.Clinician.ID); err != nil {
+			return err
+		}
 		b.Patient = patient
 	}
 	return nil
 }
 
+//updatePatientTimezone keeps the timezone a known patient booked from, so that emails show their local time
+func (r *RegisterUsecase) updatePatientTimezone(ctx context.Context, p *deiz.Patient, timezone string, clinicianID int) error {
+	if timezone == "" || timezone == p.Timezone {
+		return nil
+	}
+	p.Timezone = timezone
+	if !p.IsTimezoneValid() {
+		return deiz.ErrorStructValidation
+	}
+	return r.PatientTimezoneUpdater.UpdatePatientTimezone(ctx, p.ID, timezone, clinicianID)
+}
+
 func notifyRegistration(b *deiz.Booking, mailer bookingMailer, notifyPatient, notifyClinician bool) error {
 	if notifyClinician {
 		if err := mailer.MailBookingToClinician(b); err != nil {
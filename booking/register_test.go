@@ -0,0 +1,228 @@
+package booking
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/audrenbdb/deiz"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockOfficeHoursGetter struct {
+	hours []deiz.OfficeHours
+	err   error
+}
+
+func (m *mockOfficeHoursGetter) GetClinicianOfficeHours(ctx context.Context, clinicianID int) ([]deiz.OfficeHours, error) {
+	return m.hours, m.err
+}
+
+type mockTimezoneGetter struct {
+	tz  deiz.Timezone
+	err error
+}
+
+func (m *mockTimezoneGetter) GetClinicianTimezone(ctx context.Context, clinicianID int) (deiz.Timezone, error) {
+	return m.tz, m.err
+}
+
+type mockPatientGetter struct {
+	patient deiz.Patient
+	err     error
+}
+
+func (m *mockPatientGetter) GetPatientByEmail(ctx context.Context, email string, clinicianID int) (deiz.Patient, error) {
+	return m.patient, m.err
+}
+
+type mockPatientTimezoneUpdater struct {
+	err error
+}
+
+func (m *mockPatientTimezoneUpdater) UpdatePatientTimezone(ctx context.Context, patientID int, timezone string, clinicianID int) error {
+	return m.err
+}
+
+type mockMotivesGetter struct {
+	motives []deiz.BookingMotive
+	err     error
+}
+
+func (m *mockMotivesGetter) GetClinicianBookingMotives(ctx context.Context, clinicianID int) ([]deiz.BookingMotive, error) {
+	return m.motives, m.err
+}
+
+func TestRegisterBookingFromPatient(t *testing.T) {
+	//2021-01-04 is a monday
+	mondayMorningFirstConsultation := deiz.OfficeHours{StartMn: 540, EndMn: 720, WeekDay: 1, MotiveIDs: []int{10}}
+	mondayBooking := func(motiveID int) *deiz.Booking {
+		return &deiz.Booking{
+			Start:     time.Date(2021, 1, 4, 10, 0, 0, 0, time.UTC),
+			End:       time.Date(2021, 1, 4, 11, 0, 0, 0, time.UTC),
+			Clinician: deiz.Clinician{ID: 1},
+			Motive:    deiz.BookingMotive{ID: motiveID},
+		}
+	}
+
+	utc := &mockTimezoneGetter{tz: deiz.Timezone{ID: 1, Name: "UTC"}}
+	settings := &mockSettingsGetter{}
+	motives := &mockMotivesGetter{motives: []deiz.BookingMotive{
+		{ID: 10, Price: 5000, Public: true},
+		{ID: 11, Price: 6000, Public: true},
+		{ID: 12, Price: 7000},
+	}}
+
+	var tests = []struct {
+		description string
+
+		bookingInput *deiz.Booking
+		errorOutput  error
+
+		usecase RegisterUsecase
+	}{
+		{
+			description: "should fail to get clinician timezone",
+
+			bookingInput: mondayBooking(10),
+			errorOutput:  deiz.GenericError,
+
+			usecase: RegisterUsecase{TimezoneGetter: &mockTimezoneGetter{err: deiz.GenericError}},
+		},
+		{
+			description: "should fail to get office hours",
+
+			bookingInput: mondayBooking(10),
+			errorOutput:  deiz.GenericError,
+
+			usecase: RegisterUsecase{
+				TimezoneGetter:    utc,
+				SettingsGetter:    settings,
+				MotivesGetter:     motives,
+				OfficeHoursGetter: &mockOfficeHoursGetter{err: deiz.GenericError},
+			},
+		},
+		{
+			description: "should fail to get clinician motives",
+
+			bookingInput: mondayBooking(10),
+			errorOutput:  deiz.GenericError,
+
+			usecase: RegisterUsecase{
+				TimezoneGetter: utc,
+				SettingsGetter: settings,
+				MotivesGetter:  &mockMotivesGetter{err: deiz.GenericError},
+			},
+		},
+		{
+			description: "should refuse a motive patients cannot book",
+
+			bookingInput: mondayBooking(12),
+			errorOutput:  deiz.ErrorMotiveNotAllowed,
+
+			usecase: RegisterUsecase{TimezoneGetter: utc, SettingsGetter: settings, MotivesGetter: motives},
+		},
+		{
+			description: "should refuse a motive of another clinician",
+
+			bookingInput: mondayBooking(20),
+			errorOutput:  deiz.ErrorMotiveNotAllowed,
+
+			usecase: RegisterUsecase{TimezoneGetter: utc, SettingsGetter: settings, MotivesGetter: motives},
+		},
+		{
+			description: "should refuse a motive not allowed in office hours",
+
+			bookingInput: mondayBooking(11),
+			errorOutput:  deiz.ErrorMotiveNotAllowed,
+
+			usecase: RegisterUsecase{TimezoneGetter: utc, SettingsGetter: settings, MotivesGetter: motives,
+				OfficeHoursGetter: &mockOfficeHoursGetter{
+					hours: []deiz.OfficeHours{mondayMorningFirstConsultation},
+				}},
+		},
+		{
+			description: "should go on with registration when motive is allowed",
+
+			bookingInput: mondayBooking(10),
+			errorOutput:  deiz.GenericError,
+
+			usecase: RegisterUsecase{
+				TimezoneGetter: utc,
+				SettingsGetter: settings,
+				MotivesGetter:  motives,
+				OfficeHoursGetter: &mockOfficeHoursGetter{
+					hours: []deiz.OfficeHours{mondayMorningFirstConsultation},
+				},
+				PatientGetter: &mockPatientGetter{err: deiz.GenericError},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			_, err := test.usecase.RegisterBookingFromPatient(context.Background(), test.bookingInput)
+			assert.Equal(t, test.errorOutput, err)
+		})
+	}
+}
+
+func TestRegisterBookingFromPatientTimezone(t *testing.T) {
+	utc := &mockTimezoneGetter{tz: deiz.Timezone{ID: 1, Name: "UTC"}}
+	knownPatient := deiz.Patient{ID: 2, Name: "DUPONT", Surname: "Jean", Phone: "0600000000", Timezone: "Europe/Paris"}
+	remoteBooking := func(timezone string) *deiz.Booking {
+		return &deiz.Booking{
+			Start:       time.Date(2021, 1, 4, 10, 0, 0, 0, time.UTC),
+			End:         time.Date(2021, 1, 4, 11, 0, 0, 0, time.UTC),
+			Clinician:   deiz.Clinician{ID: 1},
+			MeetingMode: deiz.RemoteMode,
+			Patient:     deiz.Patient{Email: "jean@dupont.fr", Timezone: timezone},
+		}
+	}
+
+	var tests = []struct {
+		description string
+
+		bookingInput *deiz.Booking
+		errorOutput  error
+
+		usecase RegisterUsecase
+	}{
+		{
+			description: "should refuse an unknown patient timezone",
+
+			bookingInput: remoteBooking("Mars/Olympus_Mons"),
+			errorOutput:  deiz.ErrorStructValidation,
+
+			usecase: RegisterUsecase{
+				TimezoneGetter:    utc,
+				SettingsGetter:    &mockSettingsGetter{},
+				MotivesGetter:     &mockMotivesGetter{},
+				OfficeHoursGetter: &mockOfficeHoursGetter{},
+				PatientGetter:     &mockPatientGetter{patient: knownPatient},
+			},
+		},
+		{
+			description: "should store new timezone of a known patient",
+
+			bookingInput: remoteBooking("America/Martinique"),
+			errorOutput:  deiz.GenericError,
+
+			usecase: RegisterUsecase{
+				TimezoneGetter:         utc,
+				SettingsGetter:         &mockSettingsGetter{},
+				MotivesGetter:          &mockMotivesGetter{},
+				OfficeHoursGetter:      &mockOfficeHoursGetter{},
+				PatientGetter:          &mockPatientGetter{patient: knownPatient},
+				PatientTimezoneUpdater: &mockPatientTimezoneUpdater{err: deiz.GenericError},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			_, err := test.usecase.RegisterBookingFromPatient(context.Background(), test.bookingInput)
+			assert.Equal(t, test.errorOutput, err)
+		})
+	}
+}
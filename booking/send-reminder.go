@@ -4,23 +4,43 @@ import (
 	"context"
 	"fmt"
 	"github.com/audrenbdb/deiz"
+	"github.com/audrenbdb/deiz/interval"
 	"time"
 )
 
+//SendReminders mails patients their bookings in 48h,
+//along with a link to pay online bookings not paid yet.
+//A link that cannot be issued does not hold back reminders, its error being returned once all are sent.
 func (r *SendReminderUsecase) SendReminders(ctx context.Context) error {
 	bookings, err := getBookingsAwaitingRecall(ctx, r.Getter)
 	if err != nil {
 		return err
 	}
+	var linkErr error
 	for _, b := range bookings {
 		if b.Patient.IsEmailSet() && b.BookingType == deiz.AppointmentBooking {
-			err := r.Mailer.MailBookingReminder(&b)
+			paymentToken, err := r.issuePaymentLink(ctx, &b)
+			if err != nil && linkErr == nil {
+				linkErr = fmt.Errorf("unable to issue payment link of booking %d: %s", b.ID, err)
+			}
+			err = r.Mailer.MailBookingReminder(&b, paymentToken)
 			if err != nil {
 				return err
 			}
 		}
 	}
-	return nil
+	return linkErr
+}
+
+func (r *SendReminderUsecase) issuePaymentLink(ctx context.Context, b *deiz.Booking) (string, error) {
+	if !b.HasBalance() {
+		return "", nil
+	}
+	token, err := r.LinkIssuer.IssuePaymentLink(ctx, b)
+	if err == deiz.ErrorNothingToPay {
+		return "", nil
+	}
+	return token, err
 }
 
 func getBookingsAwaitingRecall(ctx context.Context, getter bookingsInTimeRangeGetter) ([]deiz.Booking, error) {
@@ -51,16 +71,28 @@ func (t *timeRange) isNull() bool {
 	return t.start.IsZero() && t.end.IsZero()
 }
 
+func (t timeRange) toRange() interval.Range {
+	return interval.Range{Start: t.start, End: t.end}
+}
+
+func timeRangeFromRange(r interval.Range) timeRange {
+	return timeRange{start: r.Start, end: r.End}
+}
+
 type (
 	bookingsInTimeRangeGetter interface {
 		GetBookingsInTimeRange(ctx context.Context, start, end time.Time) ([]deiz.Booking, error)
 	}
 	reminderMailer interface {
-		MailBookingReminder(b *deiz.Booking) error
+		MailBookingReminder(b *deiz.Booking, paymentToken string) error
+	}
+	paymentLinkIssuer interface {
+		IssuePaymentLink(ctx context.Context, b *deiz.Booking) (string, error)
 	}
 )
 
 type SendReminderUsecase struct {
-	Getter bookingsInTimeRangeGetter
-	Mailer reminderMailer
+	Getter     bookingsInTimeRangeGetter
+	Mailer     reminderMailer
+	LinkIssuer paymentLinkIssuer
 }
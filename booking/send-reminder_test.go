@@ -0,0 +1,93 @@
+package booking
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/audrenbdb/deiz"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockBookingsInTimeRangeGetter struct {
+	bookings []deiz.Booking
+	err      error
+}
+
+func (m *mockBookingsInTimeRangeGetter) GetBookingsInTimeRange(ctx context.Context, start, end time.Time) ([]deiz.Booking, error) {
+	return m.bookings, m.err
+}
+
+type mockReminderMailer struct {
+	tokens map[int]string
+	err    error
+}
+
+func (m *mockReminderMailer) MailBookingReminder(b *deiz.Booking, paymentToken string) error {
+	if m.tokens == nil {
+		m.tokens = map[int]string{}
+	}
+	m.tokens[b.ID] = paymentToken
+	return m.err
+}
+
+type mockPaymentLinkIssuer struct {
+	failing map[int]error
+	issued  []int
+}
+
+func (m *mockPaymentLinkIssuer) IssuePaymentLink(ctx context.Context, b *deiz.Booking) (string, error) {
+	m.issued = append(m.issued, b.ID)
+	if err := m.failing[b.ID]; err != nil {
+		return "", err
+	}
+	return "token", nil
+}
+
+func TestSendReminders(t *testing.T) {
+	patient := deiz.Patient{Email: "patient@deiz.fr"}
+	bookings := []deiz.Booking{
+		{ID: 1, Patient: patient, Confirmed: true, Price: 5000, BookingType: deiz.AppointmentBooking},
+		{ID: 2, Patient: patient, Confirmed: true, Price: 5000, BookingType: deiz.AppointmentBooking},
+		{ID: 3, Confirmed: true, Price: 5000, BookingType: deiz.AppointmentBooking},
+		{ID: 4, Patient: patient, Confirmed: true, Price: 5000, Paid: true, BookingType: deiz.AppointmentBooking},
+		{ID: 5, Patient: patient, Confirmed: true, BookingType: deiz.AppointmentBooking},
+	}
+
+	t.Run("should fail to get bookings to remind", func(t *testing.T) {
+		u := SendReminderUsecase{Getter: &mockBookingsInTimeRangeGetter{err: deiz.GenericError}}
+		assert.Error(t, u.SendReminders(context.Background()))
+	})
+	t.Run("should send reminders without issuing links for bookings with nothing to pay", func(t *testing.T) {
+		mailer := &mockReminderMailer{}
+		issuer := &mockPaymentLinkIssuer{failing: map[int]error{2: deiz.ErrorNothingToPay}}
+		u := SendReminderUsecase{
+			Getter:     &mockBookingsInTimeRangeGetter{bookings: bookings},
+			Mailer:     mailer,
+			LinkIssuer: issuer,
+		}
+		err := u.SendReminders(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, []int{1, 2}, issuer.issued)
+		assert.Equal(t, map[int]string{1: "token", 2: "", 4: "", 5: ""}, mailer.tokens)
+	})
+	t.Run("should send every reminder and fail when a link cannot be issued", func(t *testing.T) {
+		mailer := &mockReminderMailer{}
+		u := SendReminderUsecase{
+			Getter:     &mockBookingsInTimeRangeGetter{bookings: bookings},
+			Mailer:     mailer,
+			LinkIssuer: &mockPaymentLinkIssuer{failing: map[int]error{1: deiz.GenericError}},
+		}
+		err := u.SendReminders(context.Background())
+		assert.Error(t, err)
+		assert.Equal(t, map[int]string{1: "", 2: "token", 4: "", 5: ""}, mailer.tokens)
+	})
+	t.Run("should fail to mail reminder", func(t *testing.T) {
+		u := SendReminderUsecase{
+			Getter:     &mockBookingsInTimeRangeGetter{bookings: bookings},
+			Mailer:     &mockReminderMailer{err: deiz.GenericError},
+			LinkIssuer: &mockPaymentLinkIssuer{},
+		}
+		assert.Equal(t, deiz.GenericError, u.SendReminders(context.Background()))
+	})
+}
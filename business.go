@@ -7,9 +7,25 @@ type Business struct {
 	Identifier   string       `json:"identifier"`
 	TaxExemption TaxExemption `json:"taxExemption"`
 	Address      Address      `json:"address"`
+	//InvoiceNumbering options, default numbering being DEIZ-<clinician id>-<number>
+	InvoiceNumbering InvoiceNumbering `json:"invoiceNumbering"`
 }
 
 type TaxExemption struct {
 	ID   int    `json:"id"`
 	Code string `json:"code"`
 }
+
+//SIREN is the company number, first 9 digits of business SIRET identifier
+func (b *Business) SIREN() string {
+	digits := []rune{}
+	for _, r := range b.Identifier {
+		if r >= '0' && r <= '9' {
+			digits = append(digits, r)
+		}
+	}
+	if len(digits) < 9 {
+		return ""
+	}
+	return string(digits[:9])
+}
@@ -1,20 +1,119 @@
 package deiz
 
+import "time"
+
 type CalendarSettings struct {
 	ID                int           `json:"id"`
 	DefaultMotive     BookingMotive `json:"defaultMotive"`
 	Timezone          Timezone      `json:"timezone"`
 	RemoteAllowed     bool          `json:"remoteAllowed"`
 	NewPatientAllowed bool          `json:"newPatientAllowed"`
+	//SlotGranularity in mn between two public slot starts, such as every 15 or 30 mn.
+	//0 means slots follow each other according to appointment duration.
+	SlotGranularity int `json:"slotGranularity"`
+	//Listed clinicians opted in to appear in the public directory
+	Listed bool `json:"listed"`
+	//Prepayment patients make online when booking from public calendar
+	Prepayment PrepaymentPolicy `json:"prepayment"`
+	//Cancellation policy refunding patients who prepaid online
+	Cancellation CancellationPolicy `json:"cancellation"`
+}
+
+//PrepaymentMode tells what patients booking online pay upfront
+type PrepaymentMode uint8
+
+const (
+	NoPrepayment PrepaymentMode = iota
+	FullPrepayment
+	DepositPrepayment
+)
+
+//PrepaymentHoldDuration is the time a patient has to pay a slot held from public calendar before it is released.
+//Stripe checkout sessions lasting at least 30 minutes, hold outlasts its checkout by PrepaymentCheckoutMargin.
+const PrepaymentHoldDuration = 35 * time.Minute
+
+//PrepaymentCheckoutMargin between expiry of the checkout paying a held slot and release of the slot,
+//so that no checkout is paid once its slot is released
+const PrepaymentCheckoutMargin = 4 * time.Minute
+
+//PrepaymentPolicy of a clinician, required from patients to fight no-shows
+type PrepaymentPolicy struct {
+	Mode PrepaymentMode `json:"mode"`
+	//DepositPercent of booking price charged in deposit mode
+	DepositPercent int `json:"depositPercent"`
+}
+
+func (p PrepaymentPolicy) IsRequired() bool {
+	return p.Mode == FullPrepayment || p.Mode == DepositPrepayment
+}
+
+func (p PrepaymentPolicy) IsValid() bool {
+	switch p.Mode {
+	case NoPrepayment, FullPrepayment:
+		return true
+	case DepositPrepayment:
+		return p.DepositPercent > 0 && p.DepositPercent < 100
+	default:
+		return false
+	}
+}
+
+//Amount charged upfront for a booking of given price, deposits being rounded to the cent
+func (p PrepaymentPolicy) Amount(price int64) int64 {
+	switch p.Mode {
+	case FullPrepayment:
+		return price
+	case DepositPrepayment:
+		return (price*int64(p.DepositPercent) + 50) / 100
+	default:
+		return 0
+	}
+}
+
+//CancellationPolicy tells what a patient who prepaid online gets back when cancelling.
+//A clinician cancelling always refunds in full.
+type CancellationPolicy struct {
+	//NoticeHours before booking start a patient may cancel with a full refund
+	NoticeHours int `json:"noticeHours"`
+	//LateRefundPercent of amount paid refunded to a patient cancelling later
+	LateRefundPercent int `json:"lateRefundPercent"`
+}
+
+func (c CancellationPolicy) IsValid() bool {
+	return c.NoticeHours >= 0 && c.LateRefundPercent >= 0 && c.LateRefundPercent <= 100
+}
+
+//RefundAmount of a booking starting at start a patient cancels at canceledAt, rounded to the cent
+func (c CancellationPolicy) RefundAmount(paid int64, start time.Time, canceledAt time.Time) int64 {
+	if canceledAt.Before(start.Add(-time.Duration(c.NoticeHours) * time.Hour)) {
+		return paid
+	}
+	return (paid*int64(c.LateRefundPercent) + 50) / 100
 }
 
+//DefaultTimezoneName is used when a clinician timezone is unknown
+const DefaultTimezoneName = "Europe/Paris"
+
 type Timezone struct {
-	ID   int    `json:"id" validate:"required"`
+	ID int `json:"id" validate:"required"`
+	//Name is an IANA time zone name, such as Europe/Paris or America/Martinique
 	Name string `json:"name" validate:"required"`
 }
 
+//Location loads the timezone location, falling back to default timezone when name is not set
+func (t Timezone) Location() (*time.Location, error) {
+	if t.Name == "" {
+		return time.LoadLocation(DefaultTimezoneName)
+	}
+	return time.LoadLocation(t.Name)
+}
+
 func (s *CalendarSettings) IsValid() bool {
-	return s.ID != 0 && s.Timezone.ID != 0
+	return s.ID != 0 && s.Timezone.ID != 0 && slotGranularityValid(s.SlotGranularity) && s.Prepayment.IsValid() && s.Cancellation.IsValid()
+}
+
+func slotGranularityValid(granularity int) bool {
+	return granularity >= 0 && granularity <= 120 && granularity%5 == 0
 }
 
 func (s *CalendarSettings) IsInvalid() bool {
@@ -1,5 +1,7 @@
 package deiz
 
+import "time"
+
 type Clinician struct {
 	ID         int    `json:"id"`
 	Name       string `json:"name"`
@@ -8,6 +10,13 @@ type Clinician struct {
 	Email      string `json:"email"`
 	Profession string `json:"profession"`
 	Adeli      Adeli  `json:"adeli"`
+	//Timezone of clinician calendar, used to display dates
+	Timezone Timezone `json:"timezone"`
+}
+
+//Location returns clinician calendar location
+func (c *Clinician) Location() (*time.Location, error) {
+	return c.Timezone.Location()
 }
 
 type Adeli struct {
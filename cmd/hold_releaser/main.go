@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/audrenbdb/deiz/booking"
+	"github.com/audrenbdb/deiz/repo/psql"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"log"
+	"os"
+)
+
+//hold_releaser frees public slots held for a prepayment patient did not make in time.
+//Meant to run every few minutes.
+func main() {
+	ctx := context.Background()
+
+	psqlDB, err := pgxpool.Connect(ctx, os.Getenv("DATABASE_URL"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to start db pool: %v\n", err)
+		os.Exit(1)
+	}
+	repo := psql.NewRepo(psqlDB, nil)
+	uc := booking.ReleaseHoldsUsecase{
+		Deleter: repo,
+	}
+	if err := uc.ReleaseExpiredHolds(ctx); err != nil {
+		log.Println(err)
+	}
+}
@@ -12,18 +12,25 @@ import (
 	"github.com/audrenbdb/deiz/account/motive"
 	"github.com/audrenbdb/deiz/account/officehours"
 	"github.com/audrenbdb/deiz/account/settings"
-	"github.com/audrenbdb/deiz/account/stripekeys"
+	"github.com/audrenbdb/deiz/account/stripeconnect"
+	"github.com/audrenbdb/deiz/accounting"
+	"github.com/audrenbdb/deiz/admin"
+	"github.com/audrenbdb/deiz/analytics"
 	"github.com/audrenbdb/deiz/auth"
 	"github.com/audrenbdb/deiz/billing"
+	"github.com/audrenbdb/deiz/blob"
 	"github.com/audrenbdb/deiz/booking"
 	"github.com/audrenbdb/deiz/contact"
 	"github.com/audrenbdb/deiz/crypt"
+	"github.com/audrenbdb/deiz/directory"
 	"github.com/audrenbdb/deiz/http/echo"
 	"github.com/audrenbdb/deiz/intl"
 	"github.com/audrenbdb/deiz/mail"
 	"github.com/audrenbdb/deiz/mail/mailtmpl"
 	"github.com/audrenbdb/deiz/patient"
+	"github.com/audrenbdb/deiz/paylink"
 	"github.com/audrenbdb/deiz/pdf"
+	"github.com/audrenbdb/deiz/practice"
 	"github.com/audrenbdb/deiz/repo/psql"
 	"github.com/audrenbdb/deiz/stripe"
 	"github.com/audrenbdb/deiz/usecase"
@@ -68,14 +75,20 @@ func main() {
 	flag.Parse()
 
 	repo := psql.NewRepo(psqlDB, fbClient)
-	paris, _ := time.LoadLocation("Europe/Paris")
-	intl := intl.NewIntlParser("Fr", paris)
+	//default location, dates are displayed in clinician own timezone whenever it is known
+	defaultLoc, err := time.LoadLocation(deiz.DefaultTimezoneName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to load default location: %v\n", err)
+		os.Exit(1)
+	}
+	intl := intl.NewIntlParser("Fr", defaultLoc)
 	pdf := pdf.NewService(pdf.ServiceDeps{
 		FontFamily: "oxygen",
 		FontFile:   "oxygen.ttf",
 		FontDir:    filepath.Join(path, "../../assets", "fonts"),
 		Intl:       intl,
 	})
+	blobs := newBlobStore(path)
 
 	if isProd {
 		mail := mail.NewService(mail.Deps{
@@ -89,8 +102,10 @@ func main() {
 			CredentialsGetter: auth.FirebaseHTTP(fbClient),
 			AccountUsecases:   newAccountUsecases(repo),
 			PatientUsecases:   newPatientUsecases(repo),
-			BookingUsecases:   newBookingUsecases(paris, repo, mail),
-			BillingUsecases:   newBillingUsecases(repo, mail, pdf),
+			BookingUsecases:   newBookingUsecases(repo, mail, newRefundUsecase(repo, pdf, blobs)),
+			BillingUsecases:   newBillingUsecases(repo, mail, pdf, blobs),
+			PracticeUsecases:  newPracticeUsecases(repo),
+			AdminUsecases:     newAdminUsecases(repo),
 		})
 	} else {
 		mail := mail.NewService(mail.Deps{
@@ -103,10 +118,12 @@ func main() {
 			CredentialsGetter: auth.MockHTTP(deiz.Credentials{
 				UserID: 7, Role: deiz.ClinicianRole,
 			}),
-			AccountUsecases: newAccountUsecases(repo),
-			PatientUsecases: newPatientUsecases(repo),
-			BookingUsecases: newBookingUsecases(paris, repo, mail),
-			BillingUsecases: newBillingUsecases(repo, mail, pdf),
+			AccountUsecases:  newAccountUsecases(repo),
+			PatientUsecases:  newPatientUsecases(repo),
+			BookingUsecases:  newBookingUsecases(repo, mail, newRefundUsecase(repo, pdf, blobs)),
+			BillingUsecases:  newBillingUsecases(repo, mail, pdf, blobs),
+			PracticeUsecases: newPracticeUsecases(repo),
+			AdminUsecases:    newAdminUsecases(repo),
 		})
 	}
 
@@ -125,6 +142,29 @@ func newFireBaseClient(ctx context.Context, path string) (*firebaseAuth.Client,
 	return fbApp.Auth(ctx)
 }
 
+//newBlobStore archives invoices in an S3 compatible bucket when configured, on local disk otherwise
+func newBlobStore(path string) billingBlobStore {
+	if endpoint := os.Getenv("S3_ENDPOINT"); endpoint != "" {
+		return blob.NewS3(blob.S3Deps{
+			Endpoint:  endpoint,
+			Bucket:    os.Getenv("S3_BUCKET"),
+			Region:    os.Getenv("S3_REGION"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+		})
+	}
+	dir := os.Getenv("ARCHIVE_DIR")
+	if dir == "" {
+		dir = filepath.Join(path, "../../archives")
+	}
+	return blob.NewFileSystem(dir)
+}
+
+type billingBlobStore interface {
+	PutBlob(ctx context.Context, key string, data []byte) error
+	GetBlob(ctx context.Context, key string) ([]byte, error)
+}
+
 func getPath() (string, error) {
 	ex, err := os.Executable()
 	if err != nil {
@@ -134,15 +174,21 @@ func getPath() (string, error) {
 }
 
 func newAccountUsecases(repo *psql.Repo) usecase.AccountUsecases {
-	crypt := crypt.NewService()
 	motiveUc := &motive.BookingMotiveUsecase{
 		MotiveUpdater: repo,
 		MotiveDeleter: repo,
 		MotiveCreater: repo,
 	}
 	officeHoursUc := &officehours.Usecase{
-		Deleter: repo,
-		Creater: repo,
+		TimezoneGetter:            repo,
+		Deleter:                   repo,
+		Creater:                   repo,
+		Updater:                   repo,
+		Getter:                    repo,
+		Replacer:                  repo,
+		ExtraAvailabilityCreater:  repo,
+		ExtraAvailabilityDeleter:  repo,
+		ExtraAvailabilitiesGetter: repo,
 	}
 	clinicianUc := &clinician.EditUsecase{
 		PhoneUpdater:      repo,
@@ -150,6 +196,12 @@ func newAccountUsecases(repo *psql.Repo) usecase.AccountUsecases {
 		AdeliUpdater:      repo,
 		ProfessionUpdater: repo,
 	}
+	roomUc := &address.RoomUsecase{
+		AccountGetter: repo,
+		RoomCreater:   repo,
+		RoomDeleter:   repo,
+		RoomsGetter:   repo,
+	}
 	businessUc := &business.Usecase{
 		BusinessUpdater: repo,
 		AddressUpdater:  repo,
@@ -179,6 +231,9 @@ func newAccountUsecases(repo *psql.Repo) usecase.AccountUsecases {
 			OfficeAddressAdder: &address.AddAddressUsecase{AddressCreater: repo},
 			AddressDeleter:     &address.DeleteAddressUsecase{AccountGetter: repo, AddressDeleter: repo},
 			AddressEditer:      &address.EditAddressUsecase{AddressUpdater: repo, AccountGetter: repo},
+			RoomAdder:          roomUc,
+			RoomRemover:        roomUc,
+			RoomsGetter:        roomUc,
 		},
 		MotiveUsecases: usecase.MotiveUsecases{
 			MotiveAdder:   motiveUc,
@@ -186,15 +241,21 @@ func newAccountUsecases(repo *psql.Repo) usecase.AccountUsecases {
 			MotiveEditer:  motiveUc,
 		},
 		OfficeHoursUsecases: usecase.OfficeHoursUsecases{
-			OfficeHoursAdder:   officeHoursUc,
-			OfficeHoursRemover: officeHoursUc,
+			OfficeHoursAdder:         officeHoursUc,
+			OfficeHoursEditer:        officeHoursUc,
+			OfficeHoursRemover:       officeHoursUc,
+			WeeklyScheduleReplacer:   officeHoursUc,
+			ExtraAvailabilityAdder:   officeHoursUc,
+			ExtraAvailabilityRemover: officeHoursUc,
 		},
 		CalendarSettingsUsecases: &settings.CalendarSettingsUsecase{
 			SettingsUpdater: repo,
 		},
-		StripeKeysUsecases: &stripekeys.Usecase{
-			Crypter:           crypt,
-			StripeKeysUpdater: repo,
+		StripeConnectUsecases: &stripeconnect.Usecase{
+			ClinicianGetter: repo,
+			AccountGetter:   repo,
+			AccountUpdater:  repo,
+			Connecter:       stripe.NewService(),
 		},
 	}
 }
@@ -220,61 +281,226 @@ func newPatientUsecases(repo *psql.Repo) usecase.PatientUsecases {
 	}
 }
 
-func newBillingUsecases(repo *psql.Repo, mailer *mail.Mailer, pdf *pdf.Pdf) usecase.BillingUsecases {
+func newPracticeUsecases(repo *psql.Repo) usecase.PracticeUsecases {
+	uc := &practice.Usecase{
+		Creater:            repo,
+		Getter:             repo,
+		MemberAdder:        repo,
+		MemberRemover:      repo,
+		InvitationAccepter: repo,
+		ClinicianGetter:    repo,
+		MembershipChecker:  repo,
+	}
+	return usecase.PracticeUsecases{
+		Creater:                 uc,
+		Getter:                  uc,
+		MemberAdder:             uc,
+		MemberRemover:           uc,
+		InvitationAccepter:      uc,
+		ActingClinicianResolver: uc,
+	}
+}
+
+func newAdminUsecases(repo *psql.Repo) usecase.AdminUsecases {
+	uc := &admin.Usecase{
+		AccountsLister:  repo,
+		AccountDisabler: repo,
+		AccountGetter:   repo,
+		CalendarReader: &booking.ReadCalendarUsecase{
+			TimezoneGetter:            repo,
+			OfficeHoursGetter:         repo,
+			ExtraAvailabilitiesGetter: repo,
+			SettingsGetter:            repo,
+			BookingsGetter:            repo,
+			RoomBookingsGetter:        repo,
+		},
+		UsageGetter:     repo,
+		AuditLogger:     repo,
+		AuditLogsGetter: repo,
+	}
+	return usecase.AdminUsecases{
+		AccountsLister:      uc,
+		AccountEnabler:      uc,
+		AccountImpersonator: uc,
+		UsageStatsGetter:    uc,
+		AuditLogsGetter:     uc,
+	}
+}
+
+func newBillingUsecases(repo *psql.Repo, mailer *mail.Mailer, pdf *pdf.Pdf, blobs billingBlobStore) usecase.BillingUsecases {
 	stripe := stripe.NewService()
 	crypt := crypt.NewService()
+	exporter := accounting.NewService()
+	invoiceCreater := &billing.CreateInvoiceUsecase{
+		Saver:          repo,
+		PdfCreater:     pdf,
+		Blobs:          blobs,
+		Mailer:         mailer,
+		BookingGetter:  repo,
+		BusinessGetter: repo,
+	}
 	return usecase.BillingUsecases{
-		InvoiceCreater: &billing.CreateInvoiceUsecase{
-			Counter:    repo,
-			Saver:      repo,
-			PdfCreater: pdf,
-			Mailer:     mailer,
-		},
-		InvoiceCanceler: &billing.CancelInvoiceUsecase{
-			Counter: repo,
-			Saver:   repo,
-		},
+		InvoiceCreater:  invoiceCreater,
+		InvoiceCanceler: newInvoiceCanceler(repo, pdf, blobs),
 		InvoiceMailer: &billing.MailInvoiceUsecase{
+			InvoiceGetter:             repo,
 			InvoiceMailer:             mailer,
 			InvoicesSummaryMailer:     mailer,
 			PdfInvoicesSummaryCreater: pdf,
 			PdfInvoiceCreater:         pdf,
+			Blobs:                     blobs,
+			ArchiveSaver:              repo,
 			InvoicesGetter:            repo,
+			TimezoneGetter:            repo,
+			Exporter:                  exporter,
+			BusinessGetter:            repo,
+		},
+		InvoicePDFGetter: &billing.DownloadInvoiceUsecase{
+			Getter:       repo,
+			PdfCreater:   pdf,
+			Blobs:        blobs,
+			ArchiveSaver: repo,
 		},
 		InvoicesGetter: &billing.GetPeriodInvoicesUsecase{Getter: repo},
-		StripeSessionCreater: &billing.CreateStripeSessionUsecase{
+		InvoicesExporter: &billing.ExportInvoicesUsecase{
+			InvoicesGetter: repo,
+			TimezoneGetter: repo,
+			BusinessGetter: repo,
+			Exporter:       exporter,
+		},
+		StripeSessionCreater: newStripeSessionUsecase(repo),
+		StripeWebhookHandler: &billing.StripeWebhookUsecase{
 			Crypter:              crypt,
-			StripeSessionCreater: stripe,
+			WebhookSecretGetter:  repo,
+			EventParser:          stripe,
+			AccountGetter:        repo,
+			BookingGetter:        repo,
+			PaymentMethodsGetter: repo,
+			BusinessGetter:       repo,
+			InvoiceCreater:       invoiceCreater,
+			PaymentsGetter:       repo,
+			PaymentSaver:         repo,
+			BookingConfirmer:     newBookingRegister(repo, mailer),
 			SecretKeyGetter:      repo,
+			Refunder:             stripe,
+		},
+		RevenueStatsGetter: &analytics.DashboardUsecase{
+			TimezoneGetter:            repo,
+			InvoicesGetter:            repo,
+			BookingsGetter:            repo,
+			UnpaidBookingsGetter:      repo,
+			CancellationsGetter:       repo,
+			OfficeHoursGetter:         repo,
+			ExtraAvailabilitiesGetter: repo,
 		},
-		UnpaidBookingsGetter: &billing.GetUnpaidBookingsUsecase{Getter: repo},
+		UnpaidBookingsGetter:  &billing.GetUnpaidBookingsUsecase{Getter: repo},
+		PaymentReminderSender: newPaymentLinkUsecase(repo, mailer),
+		PaymentUsecases:       newPaymentUsecases(repo),
 	}
 }
 
-func newBookingUsecases(paris *time.Location, repo *psql.Repo, mailer *mail.Mailer) usecase.BookingUsecases {
-	bookingRegister := &booking.RegisterUsecase{
-		Loc:            paris,
-		PatientGetter:  repo,
-		PatientCreater: repo,
-		BookingCreater: repo,
-		BookingUpdater: repo,
-		BookingGetter:  repo,
-		BookingMailer:  mailer,
+func newPaymentUsecases(repo *psql.Repo) usecase.PaymentUsecases {
+	uc := &billing.PaymentUsecase{
+		Saver:   repo,
+		Deleter: repo,
+		Getter:  repo,
+	}
+	return usecase.PaymentUsecases{
+		Recorder:       uc,
+		Remover:        uc,
+		BookingsGetter: uc,
+	}
+}
+
+func newInvoiceCanceler(repo *psql.Repo, pdf *pdf.Pdf, blobs billingBlobStore) *billing.CancelInvoiceUsecase {
+	return &billing.CancelInvoiceUsecase{
+		Getter:     repo,
+		Saver:      repo,
+		PdfCreater: pdf,
+		Blobs:      blobs,
+	}
+}
+
+//newRefundUsecase refunds online payments of cancelled bookings on clinician Stripe account
+func newRefundUsecase(repo *psql.Repo, pdf *pdf.Pdf, blobs billingBlobStore) *billing.RefundUsecase {
+	return &billing.RefundUsecase{
+		Crypter:         crypt.NewService(),
+		SecretKeyGetter: repo,
+		SettingsGetter:  repo,
+		PaymentsGetter:  repo,
+		PaymentSaver:    repo,
+		Refunder:        stripe.NewService(),
+		InvoiceCrediter: newInvoiceCanceler(repo, pdf, blobs),
 	}
+}
+
+//newStripeSessionUsecase opens checkout sessions on clinician Stripe account
+func newStripeSessionUsecase(repo *psql.Repo) *billing.CreateStripeSessionUsecase {
+	return &billing.CreateStripeSessionUsecase{
+		Crypter:              crypt.NewService(),
+		StripeSessionCreater: stripe.NewService(),
+		SecretKeyGetter:      repo,
+		AccountGetter:        repo,
+		BookingGetter:        repo,
+		PaymentsGetter:       repo,
+		LinkVerifier:         paylink.NewService(),
+	}
+}
+
+//newPaymentLinkUsecase signs links patients pay unpaid bookings with, and mails payment reminders
+func newPaymentLinkUsecase(repo *psql.Repo, mailer *mail.Mailer) *billing.PaymentLinkUsecase {
+	return &billing.PaymentLinkUsecase{
+		Crypter:         crypt.NewService(),
+		SecretKeyGetter: repo,
+		AccountGetter:   repo,
+		UnpaidGetter:    repo,
+		Signer:          paylink.NewService(),
+		Mailer:          mailer,
+	}
+}
+
+func newBookingRegister(repo *psql.Repo, mailer *mail.Mailer) *booking.RegisterUsecase {
+	return &booking.RegisterUsecase{
+		TimezoneGetter:           repo,
+		OfficeHoursGetter:        repo,
+		PatientGetter:            repo,
+		PatientCreater:           repo,
+		PatientTimezoneUpdater:   repo,
+		BookingCreater:           repo,
+		BookingUpdater:           repo,
+		BookingGetter:            repo,
+		RoomBookingsGetter:       repo,
+		BookingMailer:            mailer,
+		SettingsGetter:           repo,
+		MotivesGetter:            repo,
+		PrepaymentSessionCreater: newStripeSessionUsecase(repo),
+		HoldConfirmer:            repo,
+		BookingDeleter:           repo,
+	}
+}
+
+func newBookingUsecases(repo *psql.Repo, mailer *mail.Mailer, refunder *billing.RefundUsecase) usecase.BookingUsecases {
+	bookingRegister := newBookingRegister(repo, mailer)
 	bookingPreRegister := &booking.PreRegisterUsecase{
-		BookingGetter:  repo,
-		BookingCreater: repo,
-		Loc:            paris,
+		BookingGetter:      repo,
+		BookingCreater:     repo,
+		TimezoneGetter:     repo,
+		RoomBookingsGetter: repo,
+		OfficeHoursGetter:  repo,
 	}
 	calendarReader := &booking.ReadCalendarUsecase{
-		Loc:               paris,
-		OfficeHoursGetter: repo,
-		BookingsGetter:    repo,
+		TimezoneGetter:            repo,
+		OfficeHoursGetter:         repo,
+		ExtraAvailabilitiesGetter: repo,
+		SettingsGetter:            repo,
+		BookingsGetter:            repo,
+		RoomBookingsGetter:        repo,
 	}
 	bookingSlotDeleter := &booking.DeleteSlotUsecase{
 		BookingGetter:  repo,
 		BookingDeleter: repo,
 		CancelMailer:   mailer,
+		Refunder:       refunder,
 	}
 	bookingSlotBlocker := &booking.BlockSlotUsecase{
 		Blocker: repo,
@@ -285,5 +511,10 @@ func newBookingUsecases(paris *time.Location, repo *psql.Repo, mailer *mail.Mail
 		CalendarReader: calendarReader,
 		SlotDeleter:    bookingSlotDeleter,
 		SlotBlocker:    bookingSlotBlocker,
+		NoShowMarker:   &booking.NoShowUsecase{Updater: repo},
+		Directory: &directory.Usecase{
+			Searcher:        repo,
+			FreeSlotsReader: calendarReader,
+		},
 	}
 }
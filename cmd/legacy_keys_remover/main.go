@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/audrenbdb/deiz/account/stripeconnect"
+	"github.com/audrenbdb/deiz/repo/psql"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"log"
+	"os"
+)
+
+//legacy_keys_remover erases Stripe keys clinicians pasted before Stripe Connect once no legacy payment needs them.
+//Meant to run daily.
+func main() {
+	ctx := context.Background()
+
+	psqlDB, err := pgxpool.Connect(ctx, os.Getenv("DATABASE_URL"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to start db pool: %v\n", err)
+		os.Exit(1)
+	}
+	repo := psql.NewRepo(psqlDB, nil)
+	uc := stripeconnect.LegacyKeysUsecase{
+		Remover: repo,
+	}
+	if err := uc.RemoveSettledLegacyKeys(ctx); err != nil {
+		log.Println(err)
+	}
+}
@@ -3,10 +3,14 @@ package main
 import (
 	"context"
 	"fmt"
+	"github.com/audrenbdb/deiz"
+	"github.com/audrenbdb/deiz/billing"
 	"github.com/audrenbdb/deiz/booking"
+	"github.com/audrenbdb/deiz/crypt"
 	"github.com/audrenbdb/deiz/intl"
 	"github.com/audrenbdb/deiz/mail"
 	"github.com/audrenbdb/deiz/mail/mailtmpl"
+	"github.com/audrenbdb/deiz/paylink"
 	"github.com/audrenbdb/deiz/repo/psql"
 	"github.com/jackc/pgx/v4/pgxpool"
 	"log"
@@ -23,7 +27,7 @@ func main() {
 		os.Exit(1)
 	}
 
-	paris, err := time.LoadLocation("Europe/Paris")
+	defaultLoc, err := time.LoadLocation(deiz.DefaultTimezoneName)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "unable to load location: %v\n", err)
 		os.Exit(1)
@@ -38,11 +42,17 @@ func main() {
 		Templates: mailTemplates,
 		//Client:    mail.NewGmailClient(),
 		Client: mail.NewPostFixClient(),
-		Intl:   intl.NewIntlParser("Fr", paris),
+		Intl:   intl.NewIntlParser("Fr", defaultLoc),
 	})
 	reminder := booking.SendReminderUsecase{
 		Getter: repo,
 		Mailer: mail,
+		LinkIssuer: &billing.PaymentLinkUsecase{
+			Crypter:         crypt.NewService(),
+			SecretKeyGetter: repo,
+			AccountGetter:   repo,
+			Signer:          paylink.NewService(),
+		},
 	}
 	if err := reminder.SendReminders(ctx); err != nil {
 		log.Println(err)
@@ -36,3 +36,8 @@ func (f *GetInTouchForm) Valid() bool {
 func (f *GetInTouchForm) Invalid() bool {
 	return !f.Valid()
 }
+
+//DirectoryFilter lists clinicians matching prospect job and city
+func (f *GetInTouchForm) DirectoryFilter() DirectoryFilter {
+	return DirectoryFilter{Profession: f.Job, City: f.City}
+}
@@ -0,0 +1,30 @@
+package deiz
+
+//DirectoryPageSize is how many clinicians a page of the public directory lists
+const DirectoryPageSize = 10
+
+//DirectoryFilter narrows the public clinician directory, zero values meaning no filter
+type DirectoryFilter struct {
+	Profession string
+	City       string
+	PostCode   int
+	//MeetingMode is only applied when FilterMeetingMode is set, remote mode being its zero value
+	MeetingMode       MeetingMode
+	FilterMeetingMode bool
+	//Page of results to list, starting at 0
+	Page int
+}
+
+//DirectoryEntry is a clinician listed in the public directory
+type DirectoryEntry struct {
+	Clinician       Clinician     `json:"clinician"`
+	OfficeAddresses []Address     `json:"officeAddresses"`
+	DefaultMotive   BookingMotive `json:"defaultMotive"`
+	//NextSlot is the first free slot found, nil if none is available soon
+	NextSlot *Booking `json:"nextSlot"`
+}
+
+//AllowsMeetingMode checks if a slot matches the meeting mode filter
+func (f *DirectoryFilter) AllowsMeetingMode(mode MeetingMode) bool {
+	return !f.FilterMeetingMode || f.MeetingMode == mode
+}
@@ -0,0 +1,139 @@
+//Package directory lists clinicians publicly, with their next available slot
+package directory
+
+import (
+	"context"
+	"github.com/audrenbdb/deiz"
+	"sync"
+	"time"
+)
+
+//nextSlotSearchWeeks limits how far ahead next available slot is looked for
+const nextSlotSearchWeeks = 4
+
+//nextSlotCacheDuration is how long the next slot of a clinician is kept before being looked for again,
+//directory being public and reading calendars costly
+const nextSlotCacheDuration = 5 * time.Minute
+
+type (
+	Searcher interface {
+		SearchDirectory(ctx context.Context, f deiz.DirectoryFilter) ([]deiz.DirectoryEntry, error)
+	}
+	FreeSlotsReader interface {
+		GetCalendarFreeSlots(ctx context.Context, start time.Time, defaultDuration int, motiveID int, clinicianID int) ([]deiz.Booking, error)
+	}
+)
+
+type Usecase struct {
+	Searcher        Searcher
+	FreeSlotsReader FreeSlotsReader
+
+	cache nextSlotCache
+}
+
+//SearchDirectory lists clinicians matching filter, each with its next available slot from given date
+func (u *Usecase) SearchDirectory(ctx context.Context, f deiz.DirectoryFilter, from time.Time) ([]deiz.DirectoryEntry, error) {
+	entries, err := u.Searcher.SearchDirectory(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		entries[i].NextSlot, err = u.getCachedNextSlot(ctx, entries[i], f, from)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+//getCachedNextSlot returns next slot found recently for the same clinician, motive and meeting mode,
+//unless it is already past
+func (u *Usecase) getCachedNextSlot(ctx context.Context, e deiz.DirectoryEntry, f deiz.DirectoryFilter, from time.Time) (*deiz.Booking, error) {
+	key := nextSlotKey{
+		clinicianID:       e.Clinician.ID,
+		motiveID:          e.DefaultMotive.ID,
+		meetingMode:       f.MeetingMode,
+		filterMeetingMode: f.FilterMeetingMode,
+	}
+	if slot, ok := u.cache.get(key, from); ok {
+		return slot, nil
+	}
+	slot, err := u.getNextSlot(ctx, e, f, from)
+	if err != nil {
+		return nil, err
+	}
+	u.cache.set(key, slot, from)
+	return slot, nil
+}
+
+//getNextSlot looks week after week for the earliest free slot matching filter meeting mode
+func (u *Usecase) getNextSlot(ctx context.Context, e deiz.DirectoryEntry, f deiz.DirectoryFilter, from time.Time) (*deiz.Booking, error) {
+	for week := 0; week < nextSlotSearchWeeks; week++ {
+		slots, err := u.FreeSlotsReader.GetCalendarFreeSlots(ctx, from.AddDate(0, 0, 7*week), e.DefaultMotive.Duration, e.DefaultMotive.ID, e.Clinician.ID)
+		if err != nil {
+			return nil, err
+		}
+		if next := earliestSlot(slots, f); next != nil {
+			return next, nil
+		}
+	}
+	return nil, nil
+}
+
+func earliestSlot(slots []deiz.Booking, f deiz.DirectoryFilter) *deiz.Booking {
+	var earliest *deiz.Booking
+	for i := range slots {
+		if !f.AllowsMeetingMode(slots[i].MeetingMode) {
+			continue
+		}
+		if earliest == nil || slots[i].Start.Before(earliest.Start) {
+			earliest = &slots[i]
+		}
+	}
+	return earliest
+}
+
+type nextSlotKey struct {
+	clinicianID       int
+	motiveID          int
+	meetingMode       deiz.MeetingMode
+	filterMeetingMode bool
+}
+
+type cachedNextSlot struct {
+	slot     *deiz.Booking
+	cachedAt time.Time
+}
+
+//nextSlotCache keeps next slots found per clinician, safe for concurrent requests
+type nextSlotCache struct {
+	mu    sync.Mutex
+	slots map[nextSlotKey]cachedNextSlot
+}
+
+func (c *nextSlotCache) get(key nextSlotKey, now time.Time) (*deiz.Booking, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cached, ok := c.slots[key]
+	if !ok || now.Sub(cached.cachedAt) > nextSlotCacheDuration || now.Before(cached.cachedAt) {
+		return nil, false
+	}
+	if cached.slot != nil && cached.slot.Start.Before(now) {
+		return nil, false
+	}
+	return cached.slot, true
+}
+
+func (c *nextSlotCache) set(key nextSlotKey, slot *deiz.Booking, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.slots == nil {
+		c.slots = map[nextSlotKey]cachedNextSlot{}
+	}
+	for k, cached := range c.slots {
+		if now.Sub(cached.cachedAt) > nextSlotCacheDuration {
+			delete(c.slots, k)
+		}
+	}
+	c.slots[key] = cachedNextSlot{slot: slot, cachedAt: now}
+}
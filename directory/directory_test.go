@@ -0,0 +1,137 @@
+package directory_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/audrenbdb/deiz"
+	"github.com/audrenbdb/deiz/directory"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockSearcher struct {
+	entries []deiz.DirectoryEntry
+	err     error
+}
+
+func (m *mockSearcher) SearchDirectory(ctx context.Context, f deiz.DirectoryFilter) ([]deiz.DirectoryEntry, error) {
+	return m.entries, m.err
+}
+
+//mockFreeSlotsReader returns free slots by week start
+type mockFreeSlotsReader struct {
+	slots map[time.Time][]deiz.Booking
+	err   error
+	reads int
+}
+
+func (m *mockFreeSlotsReader) GetCalendarFreeSlots(ctx context.Context, start time.Time, defaultDuration int, motiveID int, clinicianID int) ([]deiz.Booking, error) {
+	m.reads++
+	return m.slots[start], m.err
+}
+
+func TestSearchDirectory(t *testing.T) {
+	from := time.Date(2021, 1, 4, 8, 0, 0, 0, time.UTC)
+	slot := func(day int, mode deiz.MeetingMode) deiz.Booking {
+		return deiz.Booking{
+			Start:       time.Date(2021, 1, day, 10, 0, 0, 0, time.UTC),
+			End:         time.Date(2021, 1, day, 11, 0, 0, 0, time.UTC),
+			MeetingMode: mode,
+		}
+	}
+	entry := func() []deiz.DirectoryEntry {
+		return []deiz.DirectoryEntry{{Clinician: deiz.Clinician{ID: 1}}}
+	}
+	secondWeekRemote := slot(12, deiz.RemoteMode)
+
+	var tests = []struct {
+		description string
+
+		filterInput deiz.DirectoryFilter
+		searcher    *mockSearcher
+		reader      *mockFreeSlotsReader
+
+		nextSlotOutput *deiz.Booking
+		errorOutput    error
+	}{
+		{
+			description: "should fail to search directory",
+			searcher:    &mockSearcher{err: deiz.GenericError},
+			errorOutput: deiz.GenericError,
+		},
+		{
+			description: "should fail to read free slots",
+			searcher:    &mockSearcher{entries: entry()},
+			reader:      &mockFreeSlotsReader{err: deiz.GenericError},
+			errorOutput: deiz.GenericError,
+		},
+		{
+			description: "should have no next slot",
+			searcher:    &mockSearcher{entries: entry()},
+			reader:      &mockFreeSlotsReader{},
+		},
+		{
+			description: "should pick earliest slot of the week",
+			searcher:    &mockSearcher{entries: entry()},
+			reader: &mockFreeSlotsReader{slots: map[time.Time][]deiz.Booking{
+				from: {slot(6, deiz.InOfficeMode), slot(5, deiz.InOfficeMode)},
+			}},
+			nextSlotOutput: &deiz.Booking{
+				Start:       time.Date(2021, 1, 5, 10, 0, 0, 0, time.UTC),
+				End:         time.Date(2021, 1, 5, 11, 0, 0, 0, time.UTC),
+				MeetingMode: deiz.InOfficeMode,
+			},
+		},
+		{
+			description: "should look in following weeks for a slot matching meeting mode",
+			filterInput: deiz.DirectoryFilter{FilterMeetingMode: true, MeetingMode: deiz.RemoteMode},
+			searcher:    &mockSearcher{entries: entry()},
+			reader: &mockFreeSlotsReader{slots: map[time.Time][]deiz.Booking{
+				from:                  {slot(5, deiz.InOfficeMode)},
+				from.AddDate(0, 0, 7): {secondWeekRemote},
+			}},
+			nextSlotOutput: &secondWeekRemote,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			u := directory.Usecase{Searcher: test.searcher, FreeSlotsReader: test.reader}
+			entries, err := u.SearchDirectory(context.Background(), test.filterInput, from)
+			assert.Equal(t, test.errorOutput, err)
+			if err == nil {
+				assert.Equal(t, test.nextSlotOutput, entries[0].NextSlot)
+			}
+		})
+	}
+}
+
+func TestSearchDirectoryCachesNextSlot(t *testing.T) {
+	from := time.Date(2021, 1, 4, 8, 0, 0, 0, time.UTC)
+	next := deiz.Booking{
+		Start: time.Date(2021, 1, 5, 10, 0, 0, 0, time.UTC),
+		End:   time.Date(2021, 1, 5, 11, 0, 0, 0, time.UTC),
+	}
+	reader := &mockFreeSlotsReader{slots: map[time.Time][]deiz.Booking{from: {next}}}
+	u := directory.Usecase{
+		Searcher:        &mockSearcher{entries: []deiz.DirectoryEntry{{Clinician: deiz.Clinician{ID: 1}}}},
+		FreeSlotsReader: reader,
+	}
+
+	t.Run("should read calendar once within cache duration", func(t *testing.T) {
+		_, err := u.SearchDirectory(context.Background(), deiz.DirectoryFilter{}, from)
+		assert.NoError(t, err)
+		entries, err := u.SearchDirectory(context.Background(), deiz.DirectoryFilter{}, from.Add(time.Minute))
+		assert.NoError(t, err)
+		assert.Equal(t, &next, entries[0].NextSlot)
+		assert.Equal(t, 1, reader.reads)
+	})
+	t.Run("should read calendar again once cached slot is past", func(t *testing.T) {
+		reader.slots[next.End] = nil
+		entries, err := u.SearchDirectory(context.Background(), deiz.DirectoryFilter{}, next.End)
+		assert.NoError(t, err)
+		assert.Nil(t, entries[0].NextSlot)
+		assert.Equal(t, 1+4, reader.reads)
+	})
+}
@@ -5,6 +5,19 @@ const ErrorClinicianDoesNotExist = "ce clinicien n'existe pas"
 const ErrorUnauthorized Error = "unauthorized"
 const ErrorStructValidation Error = "unable to validate struct"
 const ErrorBookingSlotAlreadyFilled Error = "Opération incomplète, les créneaux n'étaient pas tous libres"
+const ErrorOfficeHoursOverlap Error = "Ces horaires chevauchent des horaires existants"
+const ErrorAlreadyInPractice Error = "Ce compte appartient déjà à un cabinet"
+const ErrorMotiveNotAllowed Error = "Ce motif de consultation n'est pas proposé sur ce créneau"
+const ErrorInvoiceArchiveAltered Error = "L'archive de cette facture a été altérée"
+const ErrorInvoiceAlreadyCanceled Error = "Cette facture a déjà été annulée par un avoir"
+const ErrorPaymentAlreadyRecorded Error = "Ce paiement a déjà été enregistré"
+const ErrorStripeAccountNotConnected Error = "Aucun compte Stripe n'est connecté"
+const ErrorBookingAlreadyPaid Error = "Cette réservation a déjà été réglée"
+const ErrorInvoiceAmountsMismatch Error = "Les montants de la facture ne correspondent pas au prix et à la T.V.A applicable"
+const ErrorPaymentLinkExpired Error = "Ce lien de paiement a expiré"
+const ErrorPatientEmailNotSet Error = "Ce patient n'a pas d'adresse email"
+const ErrorBookingNotFound Error = "Cette réservation n'existe pas"
+const ErrorNothingToPay Error = "Cette réservation n'a rien à régler"
 
 type Error string
 
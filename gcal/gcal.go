@@ -13,6 +13,8 @@ type Event struct {
 
 	Start time.Time
 	End   time.Time
+	//Timezone is the IANA name of the location start and end are expressed in
+	Timezone string
 }
 
 func NewLink(event Event) string {
@@ -26,7 +28,7 @@ func NewLink(event Event) string {
 	params.Add("text", event.Title)
 	params.Add("details", event.Details)
 	params.Add("location", event.Location)
-	params.Add("ctz", "Europe/Paris")
+	params.Add("ctz", event.Timezone)
 
 	baseURL.RawQuery = params.Encode()
 	return baseURL.String()
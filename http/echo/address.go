@@ -44,3 +44,55 @@ func getAddressFromRequest(c echo.Context) (deiz.Address, error) {
 	var a deiz.Address
 	return a, c.Bind(&a)
 }
+
+func handleGetAddressRooms(getter usecase.RoomsGetter) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		addressID, err := getURLIntegerParam(c, "aid")
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+		rooms, err := getter.GetAddressRooms(ctx, addressID, getCredFromEchoCtx(c))
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+		return c.JSON(http.StatusOK, rooms)
+	}
+}
+
+func handlePostAddressRoom(adder usecase.RoomAdder) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		addressID, err := getURLIntegerParam(c, "aid")
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+		var room deiz.Room
+		if err := c.Bind(&room); err != nil {
+			return c.JSON(http.StatusBadRequest, errBind.Error())
+		}
+		room.AddressID = addressID
+		if err := adder.AddRoom(ctx, &room, getCredFromEchoCtx(c)); err != nil {
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+		return c.JSON(http.StatusOK, room)
+	}
+}
+
+func handleDeleteAddressRoom(remover usecase.RoomRemover) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		addressID, err := getURLIntegerParam(c, "aid")
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+		roomID, err := getURLIntegerParam(c, "id")
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+		if err := remover.RemoveRoom(ctx, roomID, addressID, getCredFromEchoCtx(c)); err != nil {
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+		return nil
+	}
+}
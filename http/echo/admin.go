@@ -0,0 +1,117 @@
+package echo
+
+import (
+	"github.com/audrenbdb/deiz/usecase"
+	"github.com/labstack/echo/v4"
+	"net/http"
+)
+
+func handleGetAdminAccounts(lister usecase.AdminAccountsLister) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		adminID := getCredFromEchoCtx(c).UserID
+		accounts, err := lister.ListAccounts(ctx, adminID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+		return c.JSON(http.StatusOK, accounts)
+	}
+}
+
+func handlePatchAdminAccountStatus(enabler usecase.AdminAccountEnabler) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		adminID := getCredFromEchoCtx(c).UserID
+		clinicianID, err := getURLIntegerParam(c, "id")
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+		var status struct {
+			Disabled bool `json:"disabled"`
+		}
+		if err := c.Bind(&status); err != nil {
+			return c.JSON(http.StatusBadRequest, errBind.Error())
+		}
+		if status.Disabled {
+			err = enabler.DisableAccount(ctx, clinicianID, adminID)
+		} else {
+			err = enabler.EnableAccount(ctx, clinicianID, adminID)
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+		return nil
+	}
+}
+
+func handleGetAdminImpersonatedAccount(impersonator usecase.AdminAccountImpersonator) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		adminID := getCredFromEchoCtx(c).UserID
+		clinicianID, err := getURLIntegerParam(c, "id")
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+		acc, err := impersonator.ImpersonateAccount(ctx, clinicianID, adminID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+		return c.JSON(http.StatusOK, acc)
+	}
+}
+
+func handleGetAdminImpersonatedBookings(impersonator usecase.AdminAccountImpersonator) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		adminID := getCredFromEchoCtx(c).UserID
+		clinicianID, err := getURLIntegerParam(c, "id")
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+		from, err := getTimeFromParam(c, "from")
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+		duration, err := getBookingDurationFromParam(c)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+		bookings, err := impersonator.ImpersonateCalendar(ctx, from, duration, clinicianID, adminID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+		return c.JSON(http.StatusOK, bookings)
+	}
+}
+
+func handleGetAdminUsageStats(getter usecase.AdminUsageStatsGetter) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		adminID := getCredFromEchoCtx(c).UserID
+		from, err := getTimeFromParam(c, "from")
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+		to, err := getTimeFromParam(c, "to")
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+		stats, err := getter.GetUsageStats(ctx, from, to, adminID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+		return c.JSON(http.StatusOK, stats)
+	}
+}
+
+func handleGetAdminAuditLogs(getter usecase.AdminAuditLogsGetter) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		adminID := getCredFromEchoCtx(c).UserID
+		logs, err := getter.GetAuditLogs(ctx, adminID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+		return c.JSON(http.StatusOK, logs)
+	}
+}
@@ -0,0 +1,28 @@
+package echo
+
+import (
+	"github.com/audrenbdb/deiz"
+	"github.com/audrenbdb/deiz/usecase"
+	"github.com/labstack/echo/v4"
+	"net/http"
+)
+
+func handleGetRevenueStats(getter usecase.RevenueStatsGetter) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		clinicianID := getCredFromEchoCtx(c).UserID
+		start, err := getTimeFromParam(c, "start")
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+		end, err := getTimeFromParam(c, "end")
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+		stats, err := getter.GetRevenueStats(ctx, start, end, deiz.StatsBucket(c.QueryParam("bucket")), clinicianID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+		return c.JSON(http.StatusOK, stats)
+	}
+}
@@ -23,11 +23,30 @@ func getCredFromEchoCtx(c echo.Context) deiz.Credentials {
 	return credCtx.credentials
 }
 
+//roleMW guards routes requiring given role.
+//Staff reaching clinician routes only on behalf of a practice clinician, they are refused clinician routes acting as themselves.
 func roleMW(auth auth.CredentialsFromHttpRequest, minRole deiz.Role) func(next echo.HandlerFunc) echo.HandlerFunc {
+	return credentialsMW(auth, func(cred deiz.Credentials) bool {
+		if minRole == deiz.ClinicianRole && cred.ActsOnBehalfOnly() {
+			return false
+		}
+		return cred.HasRole(minRole)
+	})
+}
+
+//staffAllowedMW guards clinician routes staff may reach too: either on behalf of a practice clinician,
+//resolved with getActingClinicianID, or on their own practice membership
+func staffAllowedMW(auth auth.CredentialsFromHttpRequest) func(next echo.HandlerFunc) echo.HandlerFunc {
+	return credentialsMW(auth, func(cred deiz.Credentials) bool {
+		return cred.HasRole(deiz.ClinicianRole)
+	})
+}
+
+func credentialsMW(auth auth.CredentialsFromHttpRequest, allowed func(cred deiz.Credentials) bool) func(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			cred := auth(c.Request())
-			if cred.Role < deiz.Role(minRole) {
+			if !allowed(cred) {
 				return c.JSON(http.StatusUnauthorized, errUnauthorizedRole.Error())
 			}
 			return next(&echoCtxCredentials{c, cred})
@@ -1,9 +1,12 @@
 package echo
 
 import (
+	"errors"
+	"fmt"
 	"github.com/audrenbdb/deiz"
 	"github.com/audrenbdb/deiz/usecase"
 	"github.com/labstack/echo/v4"
+	"io"
 	"net/http"
 	"strconv"
 )
@@ -27,11 +30,11 @@ func handleGetSessionCheckout(creater usecase.StripeSessionCreater) echo.Handler
 		if err != nil {
 			return c.JSON(http.StatusBadRequest, err.Error())
 		}
-		amount, err := getURLIntegerQueryParam(c, "amount")
+		bookingID, err := getURLIntegerQueryParam(c, "bookingId")
 		if err != nil {
 			return c.JSON(http.StatusBadRequest, err.Error())
 		}
-		session, err := creater.CreateStripePaymentSession(ctx, int64(amount), clinicianID)
+		session, err := creater.CreateStripePaymentSession(ctx, bookingID, clinicianID)
 		if err != nil {
 			return c.JSON(http.StatusInternalServerError, err.Error())
 		}
@@ -39,12 +42,103 @@ func handleGetSessionCheckout(creater usecase.StripeSessionCreater) echo.Handler
 	}
 }
 
+//handleGetPaymentLinkSessionCheckout opens a checkout for a patient following a payment link mailed to them
+func handleGetPaymentLinkSessionCheckout(creater usecase.StripeSessionCreater) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		session, err := creater.CreateStripePaymentLinkSession(ctx, c.QueryParam("token"))
+		if errors.Is(err, deiz.ErrorUnauthorized) {
+			return c.JSON(http.StatusUnauthorized, err.Error())
+		}
+		if errors.Is(err, deiz.ErrorPaymentLinkExpired) {
+			return c.JSON(http.StatusGone, err.Error())
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+		return c.JSON(http.StatusOK, session)
+	}
+}
+
+func handlePostPaymentReminder(sender usecase.PaymentReminderSender) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		clinicianID := getCredFromEchoCtx(c).UserID
+		bookingID, err := getURLIntegerParam(c, "id")
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+		if err := sender.SendPaymentReminder(ctx, bookingID, clinicianID); err != nil {
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+		return c.NoContent(http.StatusOK)
+	}
+}
+
+//handlePostPaymentReminders mails a payment reminder to every patient with an unpaid booking, returning how many were sent
+func handlePostPaymentReminders(sender usecase.PaymentReminderSender) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		clinicianID := getCredFromEchoCtx(c).UserID
+		sent, err := sender.SendPaymentReminders(ctx, clinicianID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+		return c.JSON(http.StatusOK, sent)
+	}
+}
+
+//handlePostStripeWebhook receives events of Stripe webhook endpoint of a clinician not connected yet.
+//Raw body is read as is, signature being computed over it.
+func handlePostStripeWebhook(handler usecase.StripeWebhookHandler) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		clinicianID, err := getURLIntegerParam(c, "clinicianId")
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+		payload, err := io.ReadAll(c.Request().Body)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+		err = handler.HandleStripeWebhook(ctx, payload, c.Request().Header.Get("Stripe-Signature"), clinicianID)
+		if errors.Is(err, deiz.ErrorUnauthorized) {
+			return c.JSON(http.StatusUnauthorized, err.Error())
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+		return c.NoContent(http.StatusOK)
+	}
+}
+
+//handlePostStripeConnectWebhook receives events of clinicians connected accounts on platform endpoint
+func handlePostStripeConnectWebhook(handler usecase.StripeWebhookHandler) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		payload, err := io.ReadAll(c.Request().Body)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+		err = handler.HandleStripeConnectWebhook(ctx, payload, c.Request().Header.Get("Stripe-Signature"))
+		if errors.Is(err, deiz.ErrorUnauthorized) {
+			return c.JSON(http.StatusUnauthorized, err.Error())
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+		return c.NoContent(http.StatusOK)
+	}
+}
+
 func handlePostPDFBookingInvoicesPeriodSummary(mailer usecase.InvoiceMailer) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		ctx := c.Request().Context()
 		clinicianID := getCredFromEchoCtx(c).UserID
 		type post struct {
 			SendTo string `json:"sendTo"`
+			//Export format of accounting journal attached, none if empty
+			Export deiz.ExportFormat `json:"export"`
 		}
 		var p post
 		if err := c.Bind(&p); err != nil {
@@ -58,21 +152,47 @@ func handlePostPDFBookingInvoicesPeriodSummary(mailer usecase.InvoiceMailer) ech
 		if err != nil {
 			return c.JSON(http.StatusBadRequest, err.Error())
 		}
-		return mailer.MailInvoicesSummary(ctx, start, end, p.SendTo, clinicianID)
+		return mailer.MailInvoicesSummary(ctx, start, end, p.SendTo, clinicianID, p.Export)
+	}
+}
+
+func handleGetInvoicesExport(exporter usecase.InvoicesExporter) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		clinicianID := getCredFromEchoCtx(c).UserID
+		start, err := getTimeFromParam(c, "start")
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+		end, err := getTimeFromParam(c, "end")
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+		export, err := exporter.ExportInvoices(ctx, start, end, clinicianID, deiz.ExportFormat(c.QueryParam("format")))
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+		c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, export.Filename))
+		return c.Blob(http.StatusOK, export.ContentType, export.Data.Bytes())
 	}
 }
 
 func handlePostPDFBookingInvoice(mailer usecase.InvoiceMailer) echo.HandlerFunc {
 	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		clinicianID := getCredFromEchoCtx(c).UserID
+		invoiceID, err := getURLIntegerParam(c, "id")
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
 		type post struct {
-			SendTo  string              `json:"sendTo"`
-			Invoice deiz.BookingInvoice `json:"invoice"`
+			SendTo string `json:"sendTo"`
 		}
 		var p post
 		if err := c.Bind(&p); err != nil {
 			return c.JSON(http.StatusBadRequest, errBind.Error())
 		}
-		err := mailer.MailInvoice(&p.Invoice, p.SendTo)
+		err = mailer.MailInvoice(ctx, invoiceID, clinicianID, p.SendTo)
 		if err != nil {
 			return c.JSON(http.StatusInternalServerError, err.Error())
 		}
@@ -80,6 +200,23 @@ func handlePostPDFBookingInvoice(mailer usecase.InvoiceMailer) echo.HandlerFunc
 	}
 }
 
+func handleGetInvoicePDF(getter usecase.InvoicePDFGetter) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		clinicianID := getCredFromEchoCtx(c).UserID
+		invoiceID, err := getURLIntegerParam(c, "id")
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+		pdf, err := getter.GetInvoicePDF(ctx, invoiceID, clinicianID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+		c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="facture-%d.pdf"`, invoiceID))
+		return c.Blob(http.StatusOK, "application/pdf", pdf.Bytes())
+	}
+}
+
 func handlePostBookingInvoice(creater usecase.InvoiceCreater) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		ctx := c.Request().Context()
@@ -100,19 +237,19 @@ func handlePostBookingInvoice(creater usecase.InvoiceCreater) echo.HandlerFunc {
 	}
 }
 
-func handlePostCancelInvoice(canceler usecase.InvoiceCanceler) echo.HandlerFunc {
+func handlePostCreditNote(canceler usecase.InvoiceCanceler) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		ctx := c.Request().Context()
-		invoice, err := getInvoiceFromRequest(c)
-		invoice.ClinicianID = getCredFromEchoCtx(c).UserID
+		clinicianID := getCredFromEchoCtx(c).UserID
+		invoiceID, err := getURLIntegerParam(c, "id")
 		if err != nil {
 			return c.JSON(http.StatusBadRequest, err.Error())
 		}
-		if err := canceler.CancelInvoice(ctx, invoice); err != nil {
+		creditNote, err := canceler.CancelInvoice(ctx, invoiceID, clinicianID)
+		if err != nil {
 			return c.JSON(http.StatusInternalServerError, err.Error())
-
 		}
-		return c.JSON(http.StatusOK, invoice)
+		return c.JSON(http.StatusOK, creditNote)
 	}
 }
 
@@ -6,6 +6,7 @@ import (
 	"github.com/labstack/echo/v4"
 	"net/http"
 	"strconv"
+	"time"
 )
 
 func handleGetPatientBookings(getter usecase.PatientBookingsGetter) echo.HandlerFunc {
@@ -60,10 +61,13 @@ func handlePostPreRegisteredBookings(register usecase.BookingPreRegister) echo.H
 	}
 }
 
-func handlePostBookings(register usecase.BookingRegister) echo.HandlerFunc {
+func handlePostBookings(register usecase.BookingRegister, resolver usecase.ActingClinicianResolver) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		ctx := c.Request().Context()
-		clinicianID := getCredFromEchoCtx(c).UserID
+		clinicianID, err := getActingClinicianID(c, resolver)
+		if err != nil {
+			return c.JSON(http.StatusUnauthorized, err.Error())
+		}
 
 		notifyPatient, err := strconv.ParseBool(c.QueryParam("notifyPatient"))
 		if err != nil {
@@ -88,17 +92,21 @@ func handlePublicPostBooking(register usecase.BookingRegister) echo.HandlerFunc
 		if err := c.Bind(&b); err != nil {
 			return c.JSON(http.StatusBadRequest, err.Error())
 		}
-		if err := register.RegisterBookingFromPatient(ctx, &b); err != nil {
+		session, err := register.RegisterBookingFromPatient(ctx, &b)
+		if err != nil {
 			return c.JSON(http.StatusInternalServerError, err.Error())
 		}
-		return nil
+		return c.JSON(http.StatusOK, session)
 	}
 }
 
-func handleDeleteBooking(deleter usecase.BookingSlotDeleter) echo.HandlerFunc {
+func handleDeleteBooking(deleter usecase.BookingSlotDeleter, resolver usecase.ActingClinicianResolver) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		ctx := c.Request().Context()
-		clinicianID := getCredFromEchoCtx(c).UserID
+		clinicianID, err := getActingClinicianID(c, resolver)
+		if err != nil {
+			return c.JSON(http.StatusUnauthorized, err.Error())
+		}
 		bookingID, err := getURLIntegerParam(c, "id")
 		if err != nil {
 			return c.JSON(http.StatusBadRequest, err.Error())
@@ -114,6 +122,31 @@ func handleDeleteBooking(deleter usecase.BookingSlotDeleter) echo.HandlerFunc {
 	}
 }
 
+func handlePatchBookingNoShow(marker usecase.BookingNoShowMarker, resolver usecase.ActingClinicianResolver) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		clinicianID, err := getActingClinicianID(c, resolver)
+		if err != nil {
+			return c.JSON(http.StatusUnauthorized, err.Error())
+		}
+		bookingID, err := getURLIntegerParam(c, "id")
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+		type patch struct {
+			NoShow bool `json:"noShow"`
+		}
+		var p patch
+		if err := c.Bind(&p); err != nil {
+			return c.JSON(http.StatusBadRequest, errBind.Error())
+		}
+		if err := marker.MarkNoShow(ctx, bookingID, p.NoShow, clinicianID); err != nil {
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+		return nil
+	}
+}
+
 func handleGetFreeBookingSlots(getter usecase.CalendarReader) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		ctx := c.Request().Context()
@@ -130,7 +163,11 @@ func handleGetFreeBookingSlots(getter usecase.CalendarReader) echo.HandlerFunc {
 		if err != nil {
 			return c.JSON(http.StatusBadRequest, err.Error())
 		}
-		bookings, err := getter.GetCalendarFreeSlots(ctx, from, duration, clinicianID)
+		motiveID, err := getMotiveFromParam(c)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+		bookings, err := getter.GetCalendarFreeSlots(ctx, from, duration, motiveID, clinicianID)
 		if err != nil {
 			return c.JSON(http.StatusInternalServerError, err.Error())
 		}
@@ -138,10 +175,13 @@ func handleGetFreeBookingSlots(getter usecase.CalendarReader) echo.HandlerFunc {
 	}
 }
 
-func handleGetBookingSlots(getter usecase.CalendarReader) echo.HandlerFunc {
+func handleGetBookingSlots(getter usecase.CalendarReader, resolver usecase.ActingClinicianResolver) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		ctx := c.Request().Context()
-		clinicianID := getCredFromEchoCtx(c).UserID
+		clinicianID, err := getActingClinicianID(c, resolver)
+		if err != nil {
+			return c.JSON(http.StatusUnauthorized, err.Error())
+		}
 		from, err := getTimeFromParam(c, "from")
 		if err != nil {
 			return c.JSON(http.StatusBadRequest, err.Error())
@@ -162,6 +202,14 @@ func getBookingDurationFromParam(c echo.Context) (int, error) {
 	return getURLIntegerQueryParam(c, "bookingDuration")
 }
 
+//getMotiveFromParam reads optional booking motive, returning 0 if not provided
+func getMotiveFromParam(c echo.Context) (int, error) {
+	if c.QueryParam("motive") == "" {
+		return 0, nil
+	}
+	return getURLIntegerQueryParam(c, "motive")
+}
+
 func handlePostBlockedBookingSlots(blocker usecase.BookingSlotBlocker) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		ctx := c.Request().Context()
@@ -207,3 +255,49 @@ func handleDeletePublicBooking(deleter usecase.BookingSlotDeleter) echo.HandlerF
 		return nil
 	}
 }
+
+func handleGetDirectory(searcher usecase.DirectorySearcher) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		f, err := getDirectoryFilterFromParams(c)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+		entries, err := searcher.SearchDirectory(ctx, f, time.Now().UTC())
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+		return c.JSON(http.StatusOK, entries)
+	}
+}
+
+//getDirectoryFilterFromParams reads optional profession, city, postCode, meetingMode and page params
+func getDirectoryFilterFromParams(c echo.Context) (deiz.DirectoryFilter, error) {
+	f := deiz.DirectoryFilter{
+		Profession: c.QueryParam("profession"),
+		City:       c.QueryParam("city"),
+	}
+	if c.QueryParam("postCode") != "" {
+		postCode, err := getURLIntegerQueryParam(c, "postCode")
+		if err != nil {
+			return deiz.DirectoryFilter{}, err
+		}
+		f.PostCode = postCode
+	}
+	if c.QueryParam("meetingMode") != "" {
+		mode, err := getURLIntegerQueryParam(c, "meetingMode")
+		if err != nil {
+			return deiz.DirectoryFilter{}, err
+		}
+		f.MeetingMode = deiz.MeetingMode(mode)
+		f.FilterMeetingMode = true
+	}
+	if c.QueryParam("page") != "" {
+		page, err := getURLIntegerQueryParam(c, "page")
+		if err != nil || page < 0 {
+			return deiz.DirectoryFilter{}, deiz.ErrorStructValidation
+		}
+		f.Page = page
+	}
+	return f, nil
+}
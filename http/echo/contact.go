@@ -3,8 +3,10 @@ package echo
 import (
 	"context"
 	"github.com/audrenbdb/deiz"
+	"github.com/audrenbdb/deiz/usecase"
 	"github.com/labstack/echo/v4"
 	"net/http"
+	"time"
 )
 
 type (
@@ -32,7 +34,8 @@ func handlePostContactFormToClinician(sender ContactFormToClinicianSender) echo.
 	}
 }
 
-func handlePostGetInTouchForm(sender GetInTouchSender) echo.HandlerFunc {
+//handlePostGetInTouchForm sends the form and responds with directory listings matching prospect job and city
+func handlePostGetInTouchForm(sender GetInTouchSender, searcher usecase.DirectorySearcher) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		ctx := c.Request().Context()
 
@@ -44,6 +47,10 @@ func handlePostGetInTouchForm(sender GetInTouchSender) echo.HandlerFunc {
 		if err != nil {
 			return c.JSON(http.StatusInternalServerError, err.Error())
 		}
-		return nil
+		entries, err := searcher.SearchDirectory(ctx, f.DirectoryFilter(), time.Now().UTC())
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+		return c.JSON(http.StatusOK, entries)
 	}
 }
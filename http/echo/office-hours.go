@@ -23,6 +23,43 @@ func handlePostOfficeHours(adder usecase.OfficeHoursAdder) echo.HandlerFunc {
 	}
 }
 
+func handlePatchOfficeHours(editer usecase.OfficeHoursEditer) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		clinicianID := getCredFromEchoCtx(c).UserID
+		hoursID, err := getURLIntegerParam(c, "id")
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+		var h deiz.OfficeHours
+		if err := c.Bind(&h); err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+		h.ID = hoursID
+		err = editer.EditOfficeHours(ctx, &h, clinicianID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+		return c.JSON(http.StatusOK, h)
+	}
+}
+
+func handlePutWeeklySchedule(replacer usecase.WeeklyScheduleReplacer) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		clinicianID := getCredFromEchoCtx(c).UserID
+		var hours []*deiz.OfficeHours
+		if err := c.Bind(&hours); err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+		err := replacer.ReplaceWeeklySchedule(ctx, hours, clinicianID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+		return c.JSON(http.StatusOK, hours)
+	}
+}
+
 func handleDeleteOfficeHours(remover usecase.OfficeHoursRemover) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		ctx := c.Request().Context()
@@ -38,3 +75,35 @@ func handleDeleteOfficeHours(remover usecase.OfficeHoursRemover) echo.HandlerFun
 		return nil
 	}
 }
+
+func handlePostExtraAvailability(adder usecase.ExtraAvailabilityAdder) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		clinicianID := getCredFromEchoCtx(c).UserID
+		var a deiz.ExtraAvailability
+		if err := c.Bind(&a); err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+		err := adder.AddExtraAvailability(ctx, &a, clinicianID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+		return c.JSON(http.StatusOK, a)
+	}
+}
+
+func handleDeleteExtraAvailability(remover usecase.ExtraAvailabilityRemover) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		clinicianID := getCredFromEchoCtx(c).UserID
+		availabilityID, err := getURLIntegerParam(c, "id")
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+		err = remover.RemoveExtraAvailability(ctx, availabilityID, clinicianID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+		return nil
+	}
+}
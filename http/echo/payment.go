@@ -0,0 +1,54 @@
+package echo
+
+import (
+	"github.com/audrenbdb/deiz"
+	"github.com/audrenbdb/deiz/usecase"
+	"github.com/labstack/echo/v4"
+	"net/http"
+)
+
+func handlePostPayment(recorder usecase.PaymentRecorder) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		clinicianID := getCredFromEchoCtx(c).UserID
+		var p deiz.Payment
+		if err := c.Bind(&p); err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+		if err := recorder.RecordPayment(ctx, &p, clinicianID); err != nil {
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+		return c.JSON(http.StatusOK, p)
+	}
+}
+
+func handleDeletePayment(remover usecase.PaymentRemover) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		clinicianID := getCredFromEchoCtx(c).UserID
+		paymentID, err := getURLIntegerParam(c, "id")
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+		if err := remover.RemovePayment(ctx, paymentID, clinicianID); err != nil {
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+		return nil
+	}
+}
+
+func handleGetBookingPayments(getter usecase.BookingPaymentsGetter) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		clinicianID := getCredFromEchoCtx(c).UserID
+		bookingID, err := getURLIntegerParam(c, "id")
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+		payments, err := getter.GetBookingPayments(ctx, bookingID, clinicianID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+		return c.JSON(http.StatusOK, payments)
+	}
+}
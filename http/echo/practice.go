@@ -0,0 +1,95 @@
+package echo
+
+import (
+	"github.com/audrenbdb/deiz"
+	"github.com/audrenbdb/deiz/usecase"
+	"github.com/labstack/echo/v4"
+	"net/http"
+)
+
+func handlePostPractice(creater usecase.PracticeCreater) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		clinicianID := getCredFromEchoCtx(c).UserID
+		var p deiz.Practice
+		if err := c.Bind(&p); err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+		if err := creater.CreatePractice(ctx, &p, clinicianID); err != nil {
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+		return c.JSON(http.StatusOK, p)
+	}
+}
+
+func handleGetPractice(getter usecase.PracticeGetter) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		personID := getCredFromEchoCtx(c).UserID
+		p, err := getter.GetPractice(ctx, personID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+		return c.JSON(http.StatusOK, p)
+	}
+}
+
+func handlePostPracticeMember(adder usecase.PracticeMemberAdder) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		personID := getCredFromEchoCtx(c).UserID
+		var member struct {
+			Email string `json:"email"`
+		}
+		if err := c.Bind(&member); err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+		if err := adder.AddPracticeMember(ctx, member.Email, personID); err != nil {
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+		return nil
+	}
+}
+
+func handlePostPracticeInvitationAcceptance(accepter usecase.PracticeInvitationAccepter) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		personID := getCredFromEchoCtx(c).UserID
+		if err := accepter.AcceptPracticeInvitation(ctx, personID); err != nil {
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+		return nil
+	}
+}
+
+func handleDeletePracticeMember(remover usecase.PracticeMemberRemover) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		personID := getCredFromEchoCtx(c).UserID
+		memberID, err := getURLIntegerParam(c, "id")
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+		if err := remover.RemovePracticeMember(ctx, memberID, personID); err != nil {
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+		return nil
+	}
+}
+
+//getActingClinicianID returns the clinician the request acts for.
+//Staff set the clinician query param to act on behalf of a practice member, never acting as themselves.
+func getActingClinicianID(c echo.Context, resolver usecase.ActingClinicianResolver) (int, error) {
+	cred := getCredFromEchoCtx(c)
+	if c.QueryParam("clinician") == "" {
+		if cred.ActsOnBehalfOnly() {
+			return 0, deiz.ErrorUnauthorized
+		}
+		return cred.UserID, nil
+	}
+	onBehalfOfID, err := getURLIntegerQueryParam(c, "clinician")
+	if err != nil {
+		return 0, err
+	}
+	return resolver.ResolveActingClinician(c.Request().Context(), cred, onBehalfOfID)
+}
@@ -22,14 +22,17 @@ type EchoServerDeps struct {
 	PatientUsecases   usecase.PatientUsecases
 	BookingUsecases   usecase.BookingUsecases
 	BillingUsecases   usecase.BillingUsecases
+	PracticeUsecases  usecase.PracticeUsecases
+	AdminUsecases     usecase.AdminUsecases
 	ContactService    ContactService
 	CredentialsGetter auth.CredentialsFromHttpRequest
 }
 
 func StartEchoServer(deps EchoServerDeps) error {
 	clinicianMW := roleMW(deps.CredentialsGetter, deiz.ClinicianRole)
+	staffMW := staffAllowedMW(deps.CredentialsGetter)
 	publicMW := roleMW(deps.CredentialsGetter, deiz.PublicRole)
-	//adminMW := roleMW(credentialsGetter, 3)
+	adminMW := roleMW(deps.CredentialsGetter, deiz.AdminRole)
 
 	e := echo.New()
 
@@ -42,15 +45,21 @@ func StartEchoServer(deps EchoServerDeps) error {
 	e.POST("/api/businesses/:bid/address", handlePostBusinessAddress(deps.AccountUsecases.BusinessUsecases.BusinessAddressSetter), clinicianMW)
 	e.PATCH("/api/businesses/:bid/addresses/:aid", handlePatchBusinessAddress(deps.AccountUsecases.BusinessUsecases.BusinessAddressEditer), clinicianMW)
 
-	e.GET("/api/bookings", handleGetBookingSlots(deps.BookingUsecases.CalendarReader), clinicianMW)
+	e.GET("/api/bookings", handleGetBookingSlots(deps.BookingUsecases.CalendarReader, deps.PracticeUsecases.ActingClinicianResolver), staffMW)
 	e.POST("/api/bookings/blocked", handlePostBlockedBookingSlots(deps.BookingUsecases.SlotBlocker), clinicianMW)
-	e.POST("/api/bookings", handlePostBookings(deps.BookingUsecases.Register), clinicianMW)
+	e.POST("/api/bookings", handlePostBookings(deps.BookingUsecases.Register, deps.PracticeUsecases.ActingClinicianResolver), staffMW)
 	e.POST("/api/bookings/pre-registered", handlePostPreRegisteredBookings(deps.BookingUsecases.PreRegister), clinicianMW)
 	e.PATCH("/api/bookings/pre-registered", handlePatchPreRegisteredBooking(deps.BookingUsecases.Register), clinicianMW)
 	e.DELETE("/api/bookings/:id/blocked", handleDeleteBookingSlotBlocked(deps.BookingUsecases.SlotDeleter), clinicianMW)
-	e.DELETE("/api/bookings/:id", handleDeleteBooking(deps.BookingUsecases.SlotDeleter), clinicianMW)
+	e.DELETE("/api/bookings/:id", handleDeleteBooking(deps.BookingUsecases.SlotDeleter, deps.PracticeUsecases.ActingClinicianResolver), staffMW)
+	e.PATCH("/api/bookings/:id/no-show", handlePatchBookingNoShow(deps.BookingUsecases.NoShowMarker, deps.PracticeUsecases.ActingClinicianResolver), staffMW)
 
 	e.GET("/api/bookings/unpaid", handleGetUnpaidBookings(deps.BillingUsecases.UnpaidBookingsGetter), clinicianMW)
+	e.POST("/api/bookings/unpaid/payment-reminders", handlePostPaymentReminders(deps.BillingUsecases.PaymentReminderSender), clinicianMW)
+	e.POST("/api/bookings/:id/payment-reminders", handlePostPaymentReminder(deps.BillingUsecases.PaymentReminderSender), clinicianMW)
+	e.GET("/api/bookings/:id/payments", handleGetBookingPayments(deps.BillingUsecases.PaymentUsecases.BookingsGetter), clinicianMW)
+	e.POST("/api/payments", handlePostPayment(deps.BillingUsecases.PaymentUsecases.Recorder), clinicianMW)
+	e.DELETE("/api/payments/:id", handleDeletePayment(deps.BillingUsecases.PaymentUsecases.Remover), clinicianMW)
 
 	e.PATCH("/api/clinicians/:id/phone", handlePatchClinicianPhone(deps.AccountUsecases.ClinicianUsecases.PhoneEditer), clinicianMW)
 	e.PATCH("/api/clinicians/:id/email", handlePatchClinicianEmail(deps.AccountUsecases.ClinicianUsecases.EmailEditer), clinicianMW)
@@ -59,6 +68,9 @@ func StartEchoServer(deps EchoServerDeps) error {
 	e.DELETE("/api/clinicians/:id/addresses/:aid", handleDeleteClinicianAddress(deps.AccountUsecases.AccountAddressUsecases.AddressDeleter), clinicianMW)
 
 	e.POST("/api/office-addresses", handlePostClinicianAddress(deps.AccountUsecases.AccountAddressUsecases.OfficeAddressAdder), clinicianMW)
+	e.GET("/api/office-addresses/:aid/rooms", handleGetAddressRooms(deps.AccountUsecases.AccountAddressUsecases.RoomsGetter), clinicianMW)
+	e.POST("/api/office-addresses/:aid/rooms", handlePostAddressRoom(deps.AccountUsecases.AccountAddressUsecases.RoomAdder), clinicianMW)
+	e.DELETE("/api/office-addresses/:aid/rooms/:id", handleDeleteAddressRoom(deps.AccountUsecases.AccountAddressUsecases.RoomRemover), clinicianMW)
 
 	e.GET("/api/patients", handleGetPatients(deps.PatientUsecases.Searcher), clinicianMW)
 	e.POST("/api/patients", handlePostPatient(deps.PatientUsecases.Adder), clinicianMW)
@@ -70,28 +82,54 @@ func StartEchoServer(deps EchoServerDeps) error {
 	e.POST("/api/pdf-booking-invoices/:id", handlePostPDFBookingInvoice(deps.BillingUsecases.InvoiceMailer), clinicianMW)
 	e.POST("/api/pdf-booking-invoices", handlePostPDFBookingInvoicesPeriodSummary(deps.BillingUsecases.InvoiceMailer), clinicianMW)
 	e.POST("/api/booking-invoices", handlePostBookingInvoice(deps.BillingUsecases.InvoiceCreater), clinicianMW)
-	e.POST("/api/booking-invoices/canceled", handlePostCancelInvoice(deps.BillingUsecases.InvoiceCanceler), clinicianMW)
+	e.POST("/api/booking-invoices/:id/credit-notes", handlePostCreditNote(deps.BillingUsecases.InvoiceCanceler), clinicianMW)
 	e.GET("/api/booking-invoices", handleGetPeriodInvoices(deps.BillingUsecases.InvoicesGetter), clinicianMW)
+	e.GET("/api/booking-invoices/export", handleGetInvoicesExport(deps.BillingUsecases.InvoicesExporter), clinicianMW)
+	e.GET("/api/booking-invoices/:id/pdf", handleGetInvoicePDF(deps.BillingUsecases.InvoicePDFGetter), clinicianMW)
+	e.GET("/api/revenue-stats", handleGetRevenueStats(deps.BillingUsecases.RevenueStatsGetter), clinicianMW)
 
 	e.PATCH("/api/clinician-accounts/calendar-settings", handlePatchCalendarSettings(deps.AccountUsecases.CalendarSettingsUsecases), clinicianMW)
 
 	e.POST("/api/office-hours", handlePostOfficeHours(deps.AccountUsecases.OfficeHoursUsecases.OfficeHoursAdder), clinicianMW)
+	e.PUT("/api/office-hours", handlePutWeeklySchedule(deps.AccountUsecases.OfficeHoursUsecases.WeeklyScheduleReplacer), clinicianMW)
+	e.PATCH("/api/office-hours/:id", handlePatchOfficeHours(deps.AccountUsecases.OfficeHoursUsecases.OfficeHoursEditer), clinicianMW)
 	e.DELETE("/api/office-hours/:id", handleDeleteOfficeHours(deps.AccountUsecases.OfficeHoursUsecases.OfficeHoursRemover), clinicianMW)
+	e.POST("/api/extra-availabilities", handlePostExtraAvailability(deps.AccountUsecases.OfficeHoursUsecases.ExtraAvailabilityAdder), clinicianMW)
+	e.DELETE("/api/extra-availabilities/:id", handleDeleteExtraAvailability(deps.AccountUsecases.OfficeHoursUsecases.ExtraAvailabilityRemover), clinicianMW)
 
 	e.POST("/api/booking-motives", handlePostBookingMotive(deps.AccountUsecases.MotiveUsecases.MotiveAdder), clinicianMW)
 	e.PATCH("/api/booking-motives/:id", handlePatchBookingMotive(deps.AccountUsecases.MotiveUsecases.MotiveEditer), clinicianMW)
 	e.DELETE("/api/booking-motives/:id", handleDeleteBookingMotive(deps.AccountUsecases.MotiveUsecases.MotiveRemover), clinicianMW)
 
-	e.PATCH("/api/clinician-accounts/stripe-keys", handlePatchStripeKeys(deps.AccountUsecases.StripeKeysUsecases), clinicianMW)
+	e.POST("/api/practices", handlePostPractice(deps.PracticeUsecases.Creater), clinicianMW)
+	e.GET("/api/practices", handleGetPractice(deps.PracticeUsecases.Getter), staffMW)
+	e.POST("/api/practices/members", handlePostPracticeMember(deps.PracticeUsecases.MemberAdder), clinicianMW)
+	e.POST("/api/practices/invitation/acceptance", handlePostPracticeInvitationAcceptance(deps.PracticeUsecases.InvitationAccepter), staffMW)
+	e.DELETE("/api/practices/members/:id", handleDeletePracticeMember(deps.PracticeUsecases.MemberRemover), staffMW)
+
+	e.POST("/api/clinician-accounts/stripe-account/onboarding-links", handlePostStripeOnboardingLink(deps.AccountUsecases.StripeConnectUsecases), clinicianMW)
+	e.PATCH("/api/clinician-accounts/stripe-account", handlePatchStripeAccount(deps.AccountUsecases.StripeConnectUsecases), clinicianMW)
+
+	/* AdminRole API */
+	e.GET("/api/admin/clinician-accounts", handleGetAdminAccounts(deps.AdminUsecases.AccountsLister), adminMW)
+	e.PATCH("/api/admin/clinician-accounts/:id/status", handlePatchAdminAccountStatus(deps.AdminUsecases.AccountEnabler), adminMW)
+	e.GET("/api/admin/clinician-accounts/:id", handleGetAdminImpersonatedAccount(deps.AdminUsecases.AccountImpersonator), adminMW)
+	e.GET("/api/admin/clinician-accounts/:id/bookings", handleGetAdminImpersonatedBookings(deps.AdminUsecases.AccountImpersonator), adminMW)
+	e.GET("/api/admin/usage-stats", handleGetAdminUsageStats(deps.AdminUsecases.UsageStatsGetter), adminMW)
+	e.GET("/api/admin/audit-logs", handleGetAdminAuditLogs(deps.AdminUsecases.AuditLogsGetter), adminMW)
 
 	/* PublicRole API */
 	e.GET("/api/public/clinician-accounts", handleGetClinicianAccount(deps.AccountUsecases.AccountDataGetter), publicMW)
+	e.GET("/api/public/directory", handleGetDirectory(deps.BookingUsecases.Directory))
 	e.GET("/api/public/booking-slots", handleGetFreeBookingSlots(deps.BookingUsecases.CalendarReader))
 	e.POST("/api/public/bookings", handlePublicPostBooking(deps.BookingUsecases.Register))
 	e.GET("/api/public/session-checkout", handleGetSessionCheckout(deps.BillingUsecases.StripeSessionCreater))
+	e.GET("/api/public/payment-links/session-checkout", handleGetPaymentLinkSessionCheckout(deps.BillingUsecases.StripeSessionCreater))
+	e.POST("/api/public/stripe-webhooks", handlePostStripeConnectWebhook(deps.BillingUsecases.StripeWebhookHandler))
+	e.POST("/api/public/stripe-webhooks/:clinicianId", handlePostStripeWebhook(deps.BillingUsecases.StripeWebhookHandler))
 	e.DELETE("/api/public/bookings/:id", handleDeletePublicBooking(deps.BookingUsecases.SlotDeleter))
 	e.POST("/api/public/contact-form", handlePostContactFormToClinician(deps.ContactService))
-	e.POST("/api/public/get-in-touch-form", handlePostGetInTouchForm(deps.ContactService))
+	e.POST("/api/public/get-in-touch-form", handlePostGetInTouchForm(deps.ContactService, deps.BookingUsecases.Directory))
 
 	e.Use(
 		middleware.CORS(),
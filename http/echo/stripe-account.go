@@ -0,0 +1,33 @@
+package echo
+
+import (
+	"github.com/audrenbdb/deiz/usecase"
+	"github.com/labstack/echo/v4"
+	"net/http"
+)
+
+//handlePostStripeOnboardingLink returns the Stripe url clinician is redirected to for connecting its account
+func handlePostStripeOnboardingLink(connecter usecase.StripeAccountConnecter) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		clinicianID := getCredFromEchoCtx(c).UserID
+		url, err := connecter.StartStripeOnboarding(ctx, clinicianID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+		return c.JSON(http.StatusOK, url)
+	}
+}
+
+//handlePatchStripeAccount refreshes clinician connected account once back from Stripe onboarding
+func handlePatchStripeAccount(connecter usecase.StripeAccountConnecter) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		clinicianID := getCredFromEchoCtx(c).UserID
+		account, err := connecter.RefreshStripeAccount(ctx, clinicianID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+		return c.JSON(http.StatusOK, account)
+	}
+}
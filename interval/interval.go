@@ -0,0 +1,161 @@
+//Package interval implements time interval arithmetic used to compute calendar availabilities.
+//Every operation is iterative and works on ranges sorted by start date.
+package interval
+
+import (
+	"sort"
+	"time"
+)
+
+//Range is a half-open time interval [Start, End)
+type Range struct {
+	Start time.Time
+	End   time.Time
+}
+
+func (r Range) IsNull() bool {
+	return !r.Start.Before(r.End)
+}
+
+func (r Range) Overlaps(o Range) bool {
+	return r.Start.Before(o.End) && o.Start.Before(r.End)
+}
+
+//Contains checks if o is fully included in r
+func (r Range) Contains(o Range) bool {
+	return !o.Start.Before(r.Start) && !o.End.After(r.End)
+}
+
+//Intersect returns the common part of two ranges, a zero range if they do not overlap
+func (r Range) Intersect(o Range) Range {
+	if !r.Overlaps(o) {
+		return Range{}
+	}
+	if o.Start.After(r.Start) {
+		r.Start = o.Start
+	}
+	if o.End.Before(r.End) {
+		r.End = o.End
+	}
+	return r
+}
+
+//Sort orders ranges by start date, then by end date
+func Sort(ranges []Range) {
+	sort.Slice(ranges, func(i, j int) bool {
+		if ranges[i].Start.Equal(ranges[j].Start) {
+			return ranges[i].End.Before(ranges[j].End)
+		}
+		return ranges[i].Start.Before(ranges[j].Start)
+	})
+}
+
+//Union merges overlapping or adjacent ranges.
+//Result is sorted and free of null ranges.
+func Union(ranges []Range) []Range {
+	sorted := make([]Range, 0, len(ranges))
+	for _, r := range ranges {
+		if !r.IsNull() {
+			sorted = append(sorted, r)
+		}
+	}
+	Sort(sorted)
+	merged := []Range{}
+	for _, r := range sorted {
+		last := len(merged) - 1
+		if last >= 0 && !r.Start.After(merged[last].End) {
+			if r.End.After(merged[last].End) {
+				merged[last].End = r.End
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+//Subtract removes every part of ranges covered by removed ones.
+//Result is sorted and free of null ranges.
+func Subtract(ranges []Range, removed []Range) []Range {
+	from := Union(ranges)
+	cut := Union(removed)
+	result := []Range{}
+	j := 0
+	for _, r := range from {
+		//skip removed ranges ending before current range starts
+		for j < len(cut) && !cut[j].End.After(r.Start) {
+			j++
+		}
+		start := r.Start
+		for k := j; k < len(cut) && cut[k].Start.Before(r.End); k++ {
+			if cut[k].Start.After(start) {
+				result = append(result, Range{Start: start, End: cut[k].Start})
+			}
+			if cut[k].End.After(start) {
+				start = cut[k].End
+			}
+		}
+		if start.Before(r.End) {
+			result = append(result, Range{Start: start, End: r.End})
+		}
+	}
+	return result
+}
+
+//Split cuts a range in slots of given duration, a new slot starting every step.
+//Slots overlap each other when step is shorter than duration.
+func Split(r Range, duration, step time.Duration) []Range {
+	return SplitAligned(r, r.Start, duration, step)
+}
+
+//SplitAligned cuts a range in slots of given duration, starting every step from anchor.
+//It allows slots of several ranges to share the same grid.
+func SplitAligned(r Range, anchor time.Time, duration, step time.Duration) []Range {
+	slots := []Range{}
+	if duration <= 0 || step <= 0 {
+		return slots
+	}
+	start := anchor
+	if start.Before(r.Start) {
+		steps := (r.Start.Sub(anchor) + step - 1) / step
+		start = anchor.Add(steps * step)
+	}
+	for end := start.Add(duration); !end.After(r.End); end = start.Add(duration) {
+		slots = append(slots, Range{Start: start, End: end})
+		start = start.Add(step)
+	}
+	return slots
+}
+
+//Weekly projects a weekly event, defined by weekday and minutes since midnight in given location,
+//on every matching day of limit. Occurrences are not cut to limit.
+//Days are walked by calendar date so that DST transitions keep the local wall clock.
+//An occurrence emptied by the hour skipped on a spring DST transition is dropped.
+func Weekly(limit Range, weekday, startMn, endMn int, loc *time.Location) []Range {
+	occurrences := []Range{}
+	y, m, d := limit.Start.In(loc).Date()
+	for day := time.Date(y, m, d, 0, 0, 0, 0, loc); day.Before(limit.End); day = nextDay(day, loc) {
+		if int(day.Weekday()) != weekday {
+			continue
+		}
+		occurrence := Range{
+			Start: atMinute(day, startMn, loc),
+			End:   atMinute(day, endMn, loc),
+		}
+		if occurrence.IsNull() {
+			continue
+		}
+		occurrences = append(occurrences, occurrence)
+	}
+	return occurrences
+}
+
+func nextDay(day time.Time, loc *time.Location) time.Time {
+	y, m, d := day.Date()
+	return time.Date(y, m, d+1, 0, 0, 0, 0, loc)
+}
+
+func atMinute(day time.Time, mn int, loc *time.Location) time.Time {
+	y, m, d := day.Date()
+	return time.Date(y, m, d, mn/60, mn%60, 0, 0, loc).UTC()
+}
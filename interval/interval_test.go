@@ -0,0 +1,359 @@
+package interval
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func at(h, m int) time.Time {
+	return time.Date(2021, 7, 5, h, m, 0, 0, time.UTC)
+}
+
+func TestUnion(t *testing.T) {
+	var tests = []struct {
+		description string
+
+		ranges []Range
+
+		outputRanges []Range
+	}{
+		{
+			description: "should merge overlapping and adjacent ranges",
+
+			ranges: []Range{{at(10, 0), at(11, 0)}, {at(9, 0), at(10, 0)}, {at(10, 30), at(12, 0)}},
+
+			outputRanges: []Range{{at(9, 0), at(12, 0)}},
+		},
+		{
+			description: "should keep disjoint ranges sorted and drop null ones",
+
+			ranges: []Range{{at(14, 0), at(15, 0)}, {at(9, 0), at(10, 0)}, {at(11, 0), at(11, 0)}},
+
+			outputRanges: []Range{{at(9, 0), at(10, 0)}, {at(14, 0), at(15, 0)}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			assert.Equal(t, test.outputRanges, Union(test.ranges))
+		})
+	}
+}
+
+func TestSubtract(t *testing.T) {
+	var tests = []struct {
+		description string
+
+		ranges  []Range
+		removed []Range
+
+		outputRanges []Range
+	}{
+		{
+			description: "should cut removed ranges out of a range",
+
+			ranges:  []Range{{at(9, 0), at(12, 0)}},
+			removed: []Range{{at(10, 0), at(10, 30)}, {at(8, 0), at(9, 15)}, {at(11, 30), at(13, 0)}},
+
+			outputRanges: []Range{{at(9, 15), at(10, 0)}, {at(10, 30), at(11, 30)}},
+		},
+		{
+			description: "should remove a fully covered range",
+
+			ranges:  []Range{{at(9, 0), at(10, 0)}, {at(14, 0), at(15, 0)}},
+			removed: []Range{{at(8, 0), at(10, 0)}},
+
+			outputRanges: []Range{{at(14, 0), at(15, 0)}},
+		},
+		{
+			description: "should cut a removed range spanning several ranges",
+
+			ranges:  []Range{{at(9, 0), at(10, 0)}, {at(11, 0), at(12, 0)}},
+			removed: []Range{{at(9, 30), at(11, 30)}},
+
+			outputRanges: []Range{{at(9, 0), at(9, 30)}, {at(11, 30), at(12, 0)}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			assert.Equal(t, test.outputRanges, Subtract(test.ranges, test.removed))
+		})
+	}
+}
+
+func TestSplitAligned(t *testing.T) {
+	var tests = []struct {
+		description string
+
+		r        Range
+		anchor   time.Time
+		duration time.Duration
+		step     time.Duration
+
+		outputRanges []Range
+	}{
+		{
+			description: "should cut consecutive slots",
+
+			r:        Range{at(9, 0), at(10, 40)},
+			anchor:   at(9, 0),
+			duration: 30 * time.Minute,
+			step:     30 * time.Minute,
+
+			outputRanges: []Range{{at(9, 0), at(9, 30)}, {at(9, 30), at(10, 0)}, {at(10, 0), at(10, 30)}},
+		},
+		{
+			description: "should start slots on the anchor grid",
+
+			r:        Range{at(9, 10), at(10, 0)},
+			anchor:   at(9, 0),
+			duration: 30 * time.Minute,
+			step:     15 * time.Minute,
+
+			outputRanges: []Range{{at(9, 15), at(9, 45)}, {at(9, 30), at(10, 0)}},
+		},
+		{
+			description: "should return no slot for a null step",
+
+			r:        Range{at(9, 0), at(10, 0)},
+			anchor:   at(9, 0),
+			duration: 30 * time.Minute,
+
+			outputRanges: []Range{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			assert.Equal(t, test.outputRanges, SplitAligned(test.r, test.anchor, test.duration, test.step))
+		})
+	}
+}
+
+func TestWeekly(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip("Europe/Paris location unavailable")
+	}
+
+	var tests = []struct {
+		description string
+
+		limit   Range
+		weekday int
+		startMn int
+		endMn   int
+
+		outputRanges []Range
+	}{
+		{
+			description: "should project sunday hours on spring DST day",
+
+			limit:   Range{time.Date(2021, 3, 22, 0, 0, 0, 0, paris), time.Date(2021, 3, 29, 0, 0, 0, 0, paris)},
+			weekday: 0,
+			startMn: 540,
+			endMn:   720,
+
+			outputRanges: []Range{{
+				time.Date(2021, 3, 28, 7, 0, 0, 0, time.UTC),
+				time.Date(2021, 3, 28, 10, 0, 0, 0, time.UTC),
+			}},
+		},
+		{
+			description: "should project sunday hours on autumn DST day",
+
+			limit:   Range{time.Date(2021, 10, 25, 0, 0, 0, 0, paris), time.Date(2021, 11, 1, 0, 0, 0, 0, paris)},
+			weekday: 0,
+			startMn: 180,
+			endMn:   240,
+
+			outputRanges: []Range{{
+				time.Date(2021, 10, 31, 2, 0, 0, 0, time.UTC),
+				time.Date(2021, 10, 31, 3, 0, 0, 0, time.UTC),
+			}},
+		},
+		{
+			description: "should project every matching day of a long limit",
+
+			limit:   Range{time.Date(2021, 3, 20, 0, 0, 0, 0, paris), time.Date(2021, 4, 3, 0, 0, 0, 0, paris)},
+			weekday: 1,
+			startMn: 540,
+			endMn:   600,
+
+			outputRanges: []Range{{
+				time.Date(2021, 3, 22, 8, 0, 0, 0, time.UTC),
+				time.Date(2021, 3, 22, 9, 0, 0, 0, time.UTC),
+			}, {
+				time.Date(2021, 3, 29, 7, 0, 0, 0, time.UTC),
+				time.Date(2021, 3, 29, 8, 0, 0, 0, time.UTC),
+			}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			assert.Equal(t, test.outputRanges, Weekly(test.limit, test.weekday, test.startMn, test.endMn, paris))
+		})
+	}
+}
+
+//randomRanges generates ranges on a 15 mn grid around Europe/Paris DST transitions
+func randomRanges(rnd *rand.Rand, anchor time.Time, n int) []Range {
+	ranges := make([]Range, 0, n)
+	for i := 0; i < n; i++ {
+		start := anchor.Add(time.Duration(rnd.Intn(7*24*4)) * 15 * time.Minute)
+		ranges = append(ranges, Range{start, start.Add(time.Duration(rnd.Intn(16)) * 15 * time.Minute)})
+	}
+	return ranges
+}
+
+//covered checks if t is covered by any of given ranges
+func covered(t time.Time, ranges []Range) bool {
+	for _, r := range ranges {
+		if !t.Before(r.Start) && t.Before(r.End) {
+			return true
+		}
+	}
+	return false
+}
+
+func dstAnchors(t *testing.T) []time.Time {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip("Europe/Paris location unavailable")
+	}
+	return []time.Time{
+		time.Date(2021, 3, 24, 0, 0, 0, 0, paris),
+		time.Date(2021, 10, 27, 0, 0, 0, 0, paris),
+		time.Date(2022, 3, 23, 0, 0, 0, 0, paris),
+		time.Date(2022, 10, 26, 0, 0, 0, 0, paris),
+	}
+}
+
+func TestUnionSubtractProperties(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, anchor := range dstAnchors(t) {
+		for i := 0; i < 200; i++ {
+			ranges := randomRanges(rnd, anchor, 1+rnd.Intn(10))
+			removed := randomRanges(rnd, anchor, rnd.Intn(10))
+			union := Union(ranges)
+			diff := Subtract(ranges, removed)
+
+			for j := range union {
+				assert.False(t, union[j].IsNull())
+				if j > 0 {
+					assert.True(t, union[j-1].End.Before(union[j].Start), "union ranges should be disjoint and sorted")
+				}
+			}
+			for j := range diff {
+				assert.False(t, diff[j].IsNull())
+				if j > 0 {
+					assert.False(t, diff[j].Start.Before(diff[j-1].End), "difference ranges should be disjoint and sorted")
+				}
+			}
+			//every 15 mn point is covered by the union as by the ranges,
+			//and by the difference only when it is not removed
+			for p := anchor.Add(-time.Hour); p.Before(anchor.AddDate(0, 0, 9)); p = p.Add(15 * time.Minute) {
+				assert.Equal(t, covered(p, ranges), covered(p, union))
+				assert.Equal(t, covered(p, ranges) && !covered(p, removed), covered(p, diff))
+			}
+		}
+	}
+}
+
+func TestSplitProperties(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, anchor := range dstAnchors(t) {
+		for i := 0; i < 200; i++ {
+			r := randomRanges(rnd, anchor, 1)[0]
+			duration := time.Duration(1+rnd.Intn(8)) * 15 * time.Minute
+			step := time.Duration(1+rnd.Intn(8)) * 5 * time.Minute
+			slots := Split(r, duration, step)
+			for j, s := range slots {
+				assert.Equal(t, duration, s.End.Sub(s.Start))
+				assert.True(t, r.Contains(s))
+				assert.Equal(t, time.Duration(j)*step, s.Start.Sub(r.Start))
+			}
+			//no slot fits after the last one
+			next := r.Start.Add(time.Duration(len(slots)) * step)
+			assert.True(t, next.Add(duration).After(r.End))
+		}
+	}
+}
+
+func TestWeeklyProperties(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip("Europe/Paris location unavailable")
+	}
+	for _, anchor := range dstAnchors(t) {
+		for i := 0; i < 200; i++ {
+			weekday := rnd.Intn(7)
+			startMn := rnd.Intn(96) * 15
+			endMn := startMn + (1+rnd.Intn(96-startMn/15))*15
+			if endMn > 1440 {
+				endMn = 1440
+			}
+			limit := Range{anchor, anchor.AddDate(0, 0, 7)}
+			occurrences := Weekly(limit, weekday, startMn, endMn, paris)
+			//only an event lying within the skipped spring hour may vanish
+			skipped := startMn >= 120 && startMn < 180 && endMn <= startMn+60
+			assert.True(t, len(occurrences) == 1 || skipped && len(occurrences) == 0,
+				"a week should contain exactly one occurrence")
+			for _, o := range occurrences {
+				start := o.Start.In(paris)
+				assert.Equal(t, weekday, int(start.Weekday()))
+				//wall clock is kept unless start falls in the skipped spring hour
+				if startMn < 120 || startMn >= 180 {
+					assert.Equal(t, startMn, start.Hour()*60+start.Minute())
+				}
+				assert.True(t, o.Start.Before(o.End))
+			}
+		}
+	}
+}
+
+func benchmarkRanges(n int) ([]Range, []Range) {
+	rnd := rand.New(rand.NewSource(1))
+	anchor := time.Date(2021, 3, 24, 0, 0, 0, 0, time.UTC)
+	return randomRanges(rnd, anchor, n), randomRanges(rnd, anchor, n)
+}
+
+func BenchmarkUnion(b *testing.B) {
+	ranges, _ := benchmarkRanges(1000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Union(ranges)
+	}
+}
+
+func BenchmarkSubtract(b *testing.B) {
+	ranges, removed := benchmarkRanges(1000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Subtract(ranges, removed)
+	}
+}
+
+func BenchmarkSplit(b *testing.B) {
+	r := Range{time.Date(2021, 3, 22, 0, 0, 0, 0, time.UTC), time.Date(2021, 3, 29, 0, 0, 0, 0, time.UTC)}
+	for i := 0; i < b.N; i++ {
+		Split(r, 30*time.Minute, 15*time.Minute)
+	}
+}
+
+func BenchmarkWeekly(b *testing.B) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		b.Skip("Europe/Paris location unavailable")
+	}
+	limit := Range{time.Date(2021, 1, 1, 0, 0, 0, 0, paris), time.Date(2022, 1, 1, 0, 0, 0, 0, paris)}
+	for i := 0; i < b.N; i++ {
+		Weekly(limit, 1, 540, 720, paris)
+	}
+}
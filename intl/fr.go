@@ -42,6 +42,11 @@ type Fr struct {
 	loc *time.Location
 }
 
+//In returns a french formatter displaying dates in given location
+func (fr *Fr) In(loc *time.Location) *Fr {
+	return &Fr{loc: loc}
+}
+
 type Parser struct {
 	Fr Fr
 }
@@ -1,11 +1,17 @@
 package deiz
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"time"
 )
 
-const invoiceIDFormat = "DEIZ-%d-%08d"
+const (
+	invoicePrefixFormat   = "DEIZ-%d"
+	invoiceIDFormat       = "%s-%08d"
+	yearlyInvoiceIDFormat = "%s-%d-%06d"
+)
 
 type BookingInvoice struct {
 	ID              int           `json:"id"`
@@ -25,11 +31,86 @@ type BookingInvoice struct {
 	Exemption       string        `json:"exemption"`
 	PaymentMethod   PaymentMethod `json:"paymentMethod"`
 	Canceled        bool          `json:"canceled"`
+	Kind            InvoiceKind   `json:"kind"`
+	//CanceledInvoice is the invoice a credit note cancels
+	CanceledInvoice InvoiceReference `json:"canceledInvoice"`
+	//Lines billed, invoice booking, delivery date and label summarizing them
+	Lines   []InvoiceLine  `json:"lines"`
+	Archive InvoiceArchive `json:"archive"`
+	//PaymentReference at its provider of the payment recorded with invoice,
+	//such as the Stripe payment intent of a booking paid online
+	PaymentReference string `json:"paymentReference"`
+	//PaymentAccountID of the Stripe connected account the payment recorded with invoice was made on
+	PaymentAccountID string `json:"paymentAccountId"`
+}
+
+//InvoiceKind tells invoices from credit notes cancelling them
+type InvoiceKind uint8
+
+const (
+	InvoiceDocument InvoiceKind = iota
+	CreditNoteDocument
+)
+
+type InvoiceReference struct {
+	ID         int    `json:"id"`
+	Identifier string `json:"identifier"`
+}
+
+//InvoiceArchive locates the exact PDF issued for an invoice.
+//French bookkeeping rules require an issued invoice never to change.
+type InvoiceArchive struct {
+	Key    string `json:"key"`
+	SHA256 string `json:"sha256"`
+}
+
+func (a *InvoiceArchive) IsSet() bool {
+	return a.Key != ""
+}
+
+//Matches checks archived data integrity against its hash
+func (a *InvoiceArchive) Matches(data []byte) bool {
+	return ArchiveHash(data) == a.SHA256
+}
+
+//ArchiveHash is the hex encoded SHA-256 of archived data
+func ArchiveHash(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+//InvoiceLine is a service billed, related to a booking if any.
+//A single invoice may cover several sessions, such as a monthly invoice to a family.
+type InvoiceLine struct {
+	ID              int       `json:"id"`
+	Booking         Booking   `json:"booking"`
+	DeliveryDate    time.Time `json:"deliveryDate"`
+	DeliveryDateStr string    `json:"deliveryDateStr"`
+	Label           string    `json:"label"`
+	PriceBeforeTax  int64     `json:"priceBeforeTax"`
+	PriceAfterTax   int64     `json:"priceAfterTax"`
+}
+
+//ApplyTax computes line amounts from price paid, tax included.
+//Amounts already set must match computed ones.
+func (l *InvoiceLine) ApplyTax(priceAfterTax int64, rate TaxRate) error {
+	beforeTax := rate.BeforeTax(priceAfterTax)
+	if l.PriceAfterTax != 0 && l.PriceAfterTax != priceAfterTax {
+		return ErrorInvoiceAmountsMismatch
+	}
+	if l.PriceBeforeTax != 0 && l.PriceBeforeTax != beforeTax {
+		return ErrorInvoiceAmountsMismatch
+	}
+	l.PriceAfterTax = priceAfterTax
+	l.PriceBeforeTax = beforeTax
+	return nil
 }
 
 type PaymentMethod struct {
 	ID   int    `json:"id"`
 	Name string `json:"name"`
+	//Account number payments are booked to in accounting exports
+	Account string `json:"account"`
 }
 
 func (m *PaymentMethod) IsValid() bool {
@@ -42,12 +123,232 @@ func (i *BookingInvoice) IsInvalid() bool {
 
 func (i *BookingInvoice) RemoveBooking() {
 	i.Booking = Booking{}
+	for n := range i.Lines {
+		i.Lines[n].Booking = Booking{}
+	}
+}
+
+func (i *BookingInvoice) IsCreditNote() bool {
+	return i.Kind == CreditNoteDocument
+}
+
+//IsCancelable tells if a credit note may still be issued for invoice
+func (i *BookingInvoice) IsCancelable() bool {
+	return !i.Canceled && !i.IsCreditNote()
+}
+
+//CreditNote builds the credit note cancelling invoice.
+//French invoicing rules forbid deleting an invoice, a credit note with negative amounts
+//and the cancelled invoice identifier offsetting it instead.
+//Lines keep their booking, credit note itself being related to none.
+func (i *BookingInvoice) CreditNote() BookingInvoice {
+	lines := make([]InvoiceLine, len(i.Lines))
+	for n, l := range i.Lines {
+		l.ID = 0
+		l.PriceBeforeTax = -l.PriceBeforeTax
+		l.PriceAfterTax = -l.PriceAfterTax
+		lines[n] = l
+	}
+	return BookingInvoice{
+		ClinicianID:     i.ClinicianID,
+		Sender:          i.Sender,
+		Recipient:       i.Recipient,
+		CityAndDate:     i.CityAndDate,
+		DeliveryDate:    i.DeliveryDate,
+		DeliveryDateStr: i.DeliveryDateStr,
+		Label:           i.Label,
+		PriceBeforeTax:  -i.PriceBeforeTax,
+		PriceAfterTax:   -i.PriceAfterTax,
+		TaxFee:          i.TaxFee,
+		Exemption:       i.Exemption,
+		PaymentMethod:   i.PaymentMethod,
+		Kind:            CreditNoteDocument,
+		CanceledInvoice: InvoiceReference{ID: i.ID, Identifier: i.Identifier},
+		Lines:           lines,
+	}
+}
+
+//PartialCreditNote builds the credit note paying back amount, tax included, on the line of a booking.
+//Other lines stand, as does the part of the booking line not credited, such as a cancellation fee kept.
+//Credit note has no line when invoice does not bill booking.
+func (i *BookingInvoice) PartialCreditNote(bookingID int, amount int64) BookingInvoice {
+	c := i.CreditNote()
+	c.Lines = []InvoiceLine{}
+	c.PriceBeforeTax, c.PriceAfterTax = 0, 0
+	for _, l := range i.Lines {
+		if l.Booking.ID != bookingID || bookingID == 0 {
+			continue
+		}
+		l.ID = 0
+		if amount < l.PriceAfterTax {
+			l.PriceBeforeTax = i.TaxRate().BeforeTax(amount)
+			l.PriceAfterTax = amount
+		}
+		l.PriceBeforeTax = -l.PriceBeforeTax
+		l.PriceAfterTax = -l.PriceAfterTax
+		c.Lines = append(c.Lines, l)
+		c.PriceBeforeTax += l.PriceBeforeTax
+		c.PriceAfterTax += l.PriceAfterTax
+		c.Label = l.Label
+		c.DeliveryDate = l.DeliveryDate
+		c.DeliveryDateStr = l.DeliveryDateStr
+	}
+	return c
 }
 
 func (i *BookingInvoice) IsValid() bool {
-	return i.TaxFee >= 0 && i.PaymentMethod.IsValid() && i.CityAndDate != ""
+	return i.TaxFee >= 0 && i.PaymentMethod.IsValid() && i.CityAndDate != "" && i.linesBookedOnce()
+}
+
+func (i *BookingInvoice) linesBookedOnce() bool {
+	booked := map[int]bool{}
+	for _, l := range i.Lines {
+		if l.Booking.ID == 0 {
+			continue
+		}
+		if booked[l.Booking.ID] {
+			return false
+		}
+		booked[l.Booking.ID] = true
+	}
+	return true
+}
+
+//SetLines builds a single line out of invoice fields when it has none,
+//as sent by clients invoicing one booking.
+//Invoice fields left empty are then filled from its lines.
+func (i *BookingInvoice) SetLines() {
+	if len(i.Lines) == 0 {
+		i.Lines = []InvoiceLine{{
+			Booking:         i.Booking,
+			DeliveryDate:    i.DeliveryDate,
+			DeliveryDateStr: i.DeliveryDateStr,
+			Label:           i.Label,
+			PriceBeforeTax:  i.PriceBeforeTax,
+			PriceAfterTax:   i.PriceAfterTax,
+		}}
+	}
+	first, last := i.Lines[0], i.Lines[len(i.Lines)-1]
+	if i.Label == "" {
+		i.Label = first.Label
+	}
+	if i.DeliveryDate.IsZero() {
+		i.DeliveryDate = last.DeliveryDate
+	}
+	if i.DeliveryDateStr == "" {
+		i.DeliveryDateStr = last.DeliveryDateStr
+	}
+	i.Booking = Booking{}
+	if len(i.Lines) == 1 {
+		i.Booking = first.Booking
+	}
+}
+
+//BookingIDs lists bookings billed by invoice lines
+func (i *BookingInvoice) BookingIDs() []int {
+	ids := []int{}
+	for _, l := range i.Lines {
+		if l.Booking.ID != 0 {
+			ids = append(ids, l.Booking.ID)
+		}
+	}
+	return ids
+}
+
+//Patient billed, the one of first booking found
+func (i *BookingInvoice) Patient() Patient {
+	if i.Booking.Patient.ID != 0 {
+		return i.Booking.Patient
+	}
+	for _, l := range i.Lines {
+		if l.Booking.Patient.ID != 0 {
+			return l.Booking.Patient
+		}
+	}
+	return Patient{}
+}
+
+//TaxRate applied on invoice
+func (i *BookingInvoice) TaxRate() TaxRate {
+	return TaxRateFromPercent(i.TaxFee)
+}
+
+//ApplyTax computes invoice amounts from lines price paid, tax included, and clinician tax regime.
+//linePrices holds the price of each line, in lines order.
+//Amounts already set must match computed ones, inconsistent ones being rejected.
+func (i *BookingInvoice) ApplyTax(linePrices []int64, b Business) error {
+	if len(linePrices) != len(i.Lines) {
+		return ErrorStructValidation
+	}
+	rate := b.TaxRate()
+	if i.TaxFee != 0 && i.TaxRate() != rate {
+		return ErrorInvoiceAmountsMismatch
+	}
+	var beforeTax, afterTax int64
+	for n := range i.Lines {
+		if err := i.Lines[n].ApplyTax(linePrices[n], rate); err != nil {
+			return err
+		}
+		beforeTax += i.Lines[n].PriceBeforeTax
+		afterTax += i.Lines[n].PriceAfterTax
+	}
+	if i.PriceAfterTax != 0 && i.PriceAfterTax != afterTax {
+		return ErrorInvoiceAmountsMismatch
+	}
+	if i.PriceBeforeTax != 0 && i.PriceBeforeTax != beforeTax {
+		return ErrorInvoiceAmountsMismatch
+	}
+	i.PriceAfterTax = afterTax
+	i.PriceBeforeTax = beforeTax
+	i.TaxFee = rate.Percent()
+	i.Exemption = ""
+	if rate == NoTaxRate {
+		i.Exemption = b.TaxExemption.Code
+	}
+	return nil
+}
+
+//InvoiceNumbering describes how clinician invoices are numbered.
+//Numbers follow each other without gap, as required by french invoicing rules.
+type InvoiceNumbering struct {
+	//Prefix replaces default DEIZ-<clinician id> prefix
+	Prefix string `json:"prefix"`
+	//YearlyReset restarts numbering every civil year, the year being part of identifier
+	YearlyReset bool `json:"yearlyReset"`
+}
+
+const invoicePrefixMaxLength = 20
+
+func (n *InvoiceNumbering) IsValid() bool {
+	if len(n.Prefix) > invoicePrefixMaxLength {
+		return false
+	}
+	for _, r := range n.Prefix {
+		if !(r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+//Sequence returns prefix and year of the sequence invoices are numbered from.
+//Year is 0 when numbering is not reset yearly, so that changing numbering never reuses a number.
+func (n *InvoiceNumbering) Sequence(clinicianID, year int) (string, int) {
+	prefix := n.Prefix
+	if prefix == "" {
+		prefix = fmt.Sprintf(invoicePrefixFormat, clinicianID)
+	}
+	if !n.YearlyReset {
+		year = 0
+	}
+	return prefix, year
 }
 
-func (i *BookingInvoice) SetIdentifier(clinicianID, currentInvoiceCount int) {
-	i.Identifier = fmt.Sprintf(invoiceIDFormat, clinicianID, currentInvoiceCount+1)
+//Identifier formats the invoice identifier of given sequence number
+func (n *InvoiceNumbering) Identifier(clinicianID, year, number int) string {
+	prefix, year := n.Sequence(clinicianID, year)
+	if n.YearlyReset {
+		return fmt.Sprintf(yearlyInvoiceIDFormat, prefix, year, number)
+	}
+	return fmt.Sprintf(invoiceIDFormat, prefix, number)
 }
@@ -35,3 +35,227 @@ func TestInvoiceValid(t *testing.T) {
 		assert.Equal(t, test.valid, test.invoice.IsValid())
 	}
 }
+
+func TestInvoiceNumberingIdentifier(t *testing.T) {
+	var tests = []struct {
+		description string
+
+		numbering deiz.InvoiceNumbering
+
+		identifier     string
+		sequencePrefix string
+		sequenceYear   int
+	}{
+		{
+			description:    "should keep default identifier",
+			identifier:     "DEIZ-7-00000012",
+			sequencePrefix: "DEIZ-7",
+		},
+		{
+			description:    "should use clinician prefix",
+			numbering:      deiz.InvoiceNumbering{Prefix: "CAB"},
+			identifier:     "CAB-00000012",
+			sequencePrefix: "CAB",
+		},
+		{
+			description:    "should add year when numbering is reset every year",
+			numbering:      deiz.InvoiceNumbering{YearlyReset: true},
+			identifier:     "DEIZ-7-2021-000012",
+			sequencePrefix: "DEIZ-7",
+			sequenceYear:   2021,
+		},
+		{
+			description:    "should add year to clinician prefix",
+			numbering:      deiz.InvoiceNumbering{Prefix: "CAB", YearlyReset: true},
+			identifier:     "CAB-2021-000012",
+			sequencePrefix: "CAB",
+			sequenceYear:   2021,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			assert.Equal(t, test.identifier, test.numbering.Identifier(7, 2021, 12))
+			prefix, year := test.numbering.Sequence(7, 2021)
+			assert.Equal(t, test.sequencePrefix, prefix)
+			assert.Equal(t, test.sequenceYear, year)
+		})
+	}
+}
+
+func TestInvoiceNumberingValid(t *testing.T) {
+	assert.True(t, (&deiz.InvoiceNumbering{Prefix: "FAC_2021-A"}).IsValid())
+	assert.False(t, (&deiz.InvoiceNumbering{Prefix: "FAC 2021"}).IsValid())
+	assert.False(t, (&deiz.InvoiceNumbering{Prefix: "ABCDEFGHIJKLMNOPQRSTU"}).IsValid())
+}
+
+func TestInvoiceApplyTax(t *testing.T) {
+	exempted := deiz.Business{TaxExemption: deiz.TaxExemption{ID: 1, Code: "261-4-1°"}}
+	oneLine := func(l deiz.InvoiceLine) []deiz.InvoiceLine { return []deiz.InvoiceLine{l} }
+	var tests = []struct {
+		description string
+
+		invoice  deiz.BookingInvoice
+		prices   []int64
+		business deiz.Business
+
+		errorOutput   error
+		invoiceOutput deiz.BookingInvoice
+	}{
+		{
+			description: "should split price with standard rate",
+			invoice:     deiz.BookingInvoice{Lines: oneLine(deiz.InvoiceLine{})},
+			prices:      []int64{6000},
+			invoiceOutput: deiz.BookingInvoice{PriceBeforeTax: 5000, PriceAfterTax: 6000, TaxFee: 20,
+				Lines: oneLine(deiz.InvoiceLine{PriceBeforeTax: 5000, PriceAfterTax: 6000})},
+		},
+		{
+			description: "should round amount before tax to the nearest cent",
+			invoice:     deiz.BookingInvoice{Lines: oneLine(deiz.InvoiceLine{})},
+			prices:      []int64{1003},
+			invoiceOutput: deiz.BookingInvoice{PriceBeforeTax: 836, PriceAfterTax: 1003, TaxFee: 20,
+				Lines: oneLine(deiz.InvoiceLine{PriceBeforeTax: 836, PriceAfterTax: 1003})},
+		},
+		{
+			description: "should not charge tax to an exempted clinician",
+			invoice:     deiz.BookingInvoice{Lines: oneLine(deiz.InvoiceLine{})},
+			prices:      []int64{6000},
+			business:    exempted,
+			invoiceOutput: deiz.BookingInvoice{PriceBeforeTax: 6000, PriceAfterTax: 6000, Exemption: "261-4-1°",
+				Lines: oneLine(deiz.InvoiceLine{PriceBeforeTax: 6000, PriceAfterTax: 6000})},
+		},
+		{
+			description: "should sum lines rounded separately",
+			invoice:     deiz.BookingInvoice{Lines: []deiz.InvoiceLine{{}, {}}},
+			prices:      []int64{1003, 1003},
+			invoiceOutput: deiz.BookingInvoice{PriceBeforeTax: 1672, PriceAfterTax: 2006, TaxFee: 20,
+				Lines: []deiz.InvoiceLine{
+					{PriceBeforeTax: 836, PriceAfterTax: 1003},
+					{PriceBeforeTax: 836, PriceAfterTax: 1003},
+				}},
+		},
+		{
+			description: "should accept consistent amounts",
+			invoice: deiz.BookingInvoice{PriceBeforeTax: 5000, PriceAfterTax: 6000, TaxFee: 20,
+				Lines: oneLine(deiz.InvoiceLine{PriceBeforeTax: 5000, PriceAfterTax: 6000})},
+			prices: []int64{6000},
+			invoiceOutput: deiz.BookingInvoice{PriceBeforeTax: 5000, PriceAfterTax: 6000, TaxFee: 20,
+				Lines: oneLine(deiz.InvoiceLine{PriceBeforeTax: 5000, PriceAfterTax: 6000})},
+		},
+		{
+			description:   "should reject a line price differing from booking one",
+			invoice:       deiz.BookingInvoice{Lines: oneLine(deiz.InvoiceLine{PriceAfterTax: 5000})},
+			prices:        []int64{6000},
+			errorOutput:   deiz.ErrorInvoiceAmountsMismatch,
+			invoiceOutput: deiz.BookingInvoice{Lines: oneLine(deiz.InvoiceLine{PriceAfterTax: 5000})},
+		},
+		{
+			description: "should reject a total differing from lines sum",
+			invoice:     deiz.BookingInvoice{PriceAfterTax: 5000, Lines: oneLine(deiz.InvoiceLine{})},
+			prices:      []int64{6000},
+			errorOutput: deiz.ErrorInvoiceAmountsMismatch,
+			invoiceOutput: deiz.BookingInvoice{PriceAfterTax: 5000,
+				Lines: oneLine(deiz.InvoiceLine{PriceBeforeTax: 5000, PriceAfterTax: 6000})},
+		},
+		{
+			description:   "should reject a rate not matching clinician regime",
+			invoice:       deiz.BookingInvoice{TaxFee: 20, Lines: oneLine(deiz.InvoiceLine{})},
+			prices:        []int64{6000},
+			business:      exempted,
+			errorOutput:   deiz.ErrorInvoiceAmountsMismatch,
+			invoiceOutput: deiz.BookingInvoice{TaxFee: 20, Lines: oneLine(deiz.InvoiceLine{})},
+		},
+		{
+			description:   "should fail if a line price is missing",
+			invoice:       deiz.BookingInvoice{Lines: []deiz.InvoiceLine{{}, {}}},
+			prices:        []int64{6000},
+			errorOutput:   deiz.ErrorStructValidation,
+			invoiceOutput: deiz.BookingInvoice{Lines: []deiz.InvoiceLine{{}, {}}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			err := test.invoice.ApplyTax(test.prices, test.business)
+			assert.Equal(t, test.errorOutput, err)
+			assert.Equal(t, test.invoiceOutput, test.invoice)
+		})
+	}
+}
+
+func TestInvoiceSetLines(t *testing.T) {
+	t.Run("should build a line out of a single booking invoice", func(t *testing.T) {
+		i := deiz.BookingInvoice{Booking: deiz.Booking{ID: 1}, Label: "A", DeliveryDateStr: "B", PriceAfterTax: 6000}
+		i.SetLines()
+		assert.Equal(t, []deiz.InvoiceLine{{Booking: deiz.Booking{ID: 1}, Label: "A", DeliveryDateStr: "B", PriceAfterTax: 6000}}, i.Lines)
+		assert.Equal(t, []int{1}, i.BookingIDs())
+	})
+	t.Run("should summarize several lines", func(t *testing.T) {
+		i := deiz.BookingInvoice{Lines: []deiz.InvoiceLine{
+			{Booking: deiz.Booking{ID: 1, Patient: deiz.Patient{ID: 3}}, Label: "A", DeliveryDateStr: "B"},
+			{Label: "C", DeliveryDateStr: "D"},
+		}}
+		i.SetLines()
+		assert.Equal(t, "A", i.Label)
+		assert.Equal(t, "D", i.DeliveryDateStr)
+		assert.Equal(t, deiz.Booking{}, i.Booking)
+		assert.Equal(t, 3, i.Patient().ID)
+		assert.Equal(t, []int{1}, i.BookingIDs())
+	})
+}
+
+func TestInvoiceValidLines(t *testing.T) {
+	i := validInvoice
+	i.Lines = []deiz.InvoiceLine{{Booking: deiz.Booking{ID: 1}}, {Booking: deiz.Booking{ID: 1}}}
+	assert.False(t, i.IsValid())
+}
+
+func TestInvoiceCreditNote(t *testing.T) {
+	i := deiz.BookingInvoice{
+		ID: 4, ClinicianID: 2, Identifier: "DEIZ-2-00000004", Booking: deiz.Booking{ID: 1},
+		PriceBeforeTax: 5000, PriceAfterTax: 6000, TaxFee: 20,
+		Lines: []deiz.InvoiceLine{{ID: 7, Booking: deiz.Booking{ID: 1, Patient: deiz.Patient{ID: 3}}, PriceBeforeTax: 5000, PriceAfterTax: 6000}},
+	}
+	c := i.CreditNote()
+	assert.True(t, c.IsCreditNote())
+	assert.False(t, c.IsCancelable())
+	assert.Equal(t, deiz.InvoiceReference{ID: 4, Identifier: "DEIZ-2-00000004"}, c.CanceledInvoice)
+	assert.Equal(t, int64(-5000), c.PriceBeforeTax)
+	assert.Equal(t, int64(-6000), c.PriceAfterTax)
+	assert.Equal(t, float32(20), c.TaxFee)
+	assert.Equal(t, []deiz.InvoiceLine{{Booking: deiz.Booking{ID: 1, Patient: deiz.Patient{ID: 3}}, PriceBeforeTax: -5000, PriceAfterTax: -6000}}, c.Lines)
+	assert.Equal(t, deiz.Booking{}, c.Booking)
+	assert.Equal(t, 3, c.Patient().ID)
+	assert.Equal(t, int64(5000), i.Lines[0].PriceBeforeTax)
+}
+
+func TestInvoicePartialCreditNote(t *testing.T) {
+	i := deiz.BookingInvoice{
+		ID: 4, ClinicianID: 2, Identifier: "DEIZ-2-00000004", TaxFee: 20,
+		PriceBeforeTax: 8333, PriceAfterTax: 10000,
+		Lines: []deiz.InvoiceLine{
+			{ID: 7, Booking: deiz.Booking{ID: 1}, Label: "A", PriceBeforeTax: 4167, PriceAfterTax: 5000},
+			{ID: 8, Booking: deiz.Booking{ID: 2}, Label: "B", PriceBeforeTax: 4167, PriceAfterTax: 5000},
+		},
+	}
+	t.Run("should credit part of a booking line", func(t *testing.T) {
+		c := i.PartialCreditNote(2, 2500)
+		assert.True(t, c.IsCreditNote())
+		assert.Equal(t, deiz.InvoiceReference{ID: 4, Identifier: "DEIZ-2-00000004"}, c.CanceledInvoice)
+		assert.Equal(t, []deiz.InvoiceLine{{Booking: deiz.Booking{ID: 2}, Label: "B", PriceBeforeTax: -2083, PriceAfterTax: -2500}}, c.Lines)
+		assert.Equal(t, int64(-2083), c.PriceBeforeTax)
+		assert.Equal(t, int64(-2500), c.PriceAfterTax)
+		assert.Equal(t, "B", c.Label)
+	})
+	t.Run("should credit at most the booking line", func(t *testing.T) {
+		c := i.PartialCreditNote(1, 7000)
+		assert.Equal(t, int64(-4167), c.PriceBeforeTax)
+		assert.Equal(t, int64(-5000), c.PriceAfterTax)
+		assert.Equal(t, []int{1}, c.BookingIDs())
+	})
+	t.Run("should credit nothing of a booking not billed", func(t *testing.T) {
+		c := i.PartialCreditNote(3, 2500)
+		assert.Empty(t, c.Lines)
+		assert.Equal(t, int64(0), c.PriceAfterTax)
+	})
+}
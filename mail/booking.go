@@ -6,11 +6,20 @@ import (
 	"github.com/audrenbdb/deiz/gcal"
 	"github.com/audrenbdb/deiz/gmaps"
 	"net/url"
+	"strings"
 	"time"
 )
 
-func (m *Mailer) MailBookingReminder(b *deiz.Booking) error {
+//MailBookingReminder reminds patient of an upcoming booking,
+//with a link to pay it online if a payment token is given
+func (m *Mailer) MailBookingReminder(b *deiz.Booking, paymentToken string) error {
 	details := m.getBookingEmailDetails(b, b.Clinician.FullName())
+	plainBody := details.plainBodyToPatient()
+	if paymentToken != "" {
+		details.PaymentLink = buildPaymentURL(paymentToken).String()
+		plainBody += fmt.Sprintf(`\n
+	Régler en ligne : %s`, details.PaymentLink)
+	}
 	template, err := m.htmlTemplate("booking-reminder.html", details)
 	if err != nil {
 		return err
@@ -20,7 +29,7 @@ func (m *Mailer) MailBookingReminder(b *deiz.Booking) error {
 		from:      noReplyAddress,
 		subject:   "Rappel de rdv: " + details.BookingDate,
 		template:  template,
-		plainBody: details.plainBodyToPatient(),
+		plainBody: plainBody,
 	}))
 }
 
@@ -60,6 +69,35 @@ type gCalendarEvent struct {
 	details  string
 }
 
+//bookingDate formats booking start in clinician location.
+//For remote consultations, patient local time is added when it differs.
+func (m *Mailer) bookingDate(b *deiz.Booking) string {
+	clinicianLoc := m.clinicianLocation(&b.Clinician)
+	clinicianDate := m.intl.Fr.In(clinicianLoc).FmtMMMEEEEd(b.Start)
+	if b.MeetingMode != deiz.RemoteMode {
+		return clinicianDate
+	}
+	patientLoc, err := b.Patient.Location()
+	if err != nil {
+		return clinicianDate
+	}
+	patientDate := m.intl.Fr.In(patientLoc).FmtMMMEEEEd(b.Start)
+	if patientDate == clinicianDate {
+		return clinicianDate
+	}
+	return fmt.Sprintf("%s (heure de %s), soit %s (heure de %s)",
+		clinicianDate, locationCity(clinicianLoc), patientDate, locationCity(patientLoc))
+}
+
+//locationCity returns the city part of an IANA location name, such as Martinique for America/Martinique
+func locationCity(loc *time.Location) string {
+	name := loc.String()
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	return strings.ReplaceAll(name, "_", " ")
+}
+
 func buildCancelURL(deleteID string) *url.URL {
 	cancelURL, _ := url.Parse("https://deiz.fr")
 	cancelURL.Path += "bookings/delete"
@@ -77,6 +115,7 @@ type bookingEmailDetails struct {
 	GCalendarLink    string
 	GMapsLink        string
 	CancelLink       string
+	PaymentLink      string
 	Address          string
 	AvailabilityType int
 	Motive           string
@@ -121,14 +160,16 @@ func (details *bookingEmailDetails) plainBodyToClinician() string {
 }
 
 func (m *Mailer) getBookingEmailDetails(b *deiz.Booking, with string) bookingEmailDetails {
+	loc := m.clinicianLocation(&b.Clinician)
 	return bookingEmailDetails{
 		Clinician:   b.Clinician.FullName(),
 		Patient:     b.Patient.FullName(),
 		Phone:       b.Clinician.Phone,
-		BookingDate: m.intl.Fr.FmtMMMEEEEd(b.Start),
+		BookingDate: m.bookingDate(b),
 		GCalendarLink: gcal.NewLink(gcal.Event{
-			Start:    b.Start.In(m.tz),
-			End:      b.End.In(m.tz),
+			Start:    b.Start.In(loc),
+			End:      b.End.In(loc),
+			Timezone: loc.String(),
 			Title:    fmt.Sprintf("Consultation avec %s", with),
 			Location: b.Address,
 		}),
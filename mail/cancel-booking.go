@@ -36,7 +36,7 @@ func (m *Mailer) MailCancelBookingToClinician(b *deiz.Booking) error {
 
 func (m *Mailer) getCancelEmailDetails(b *deiz.Booking) cancelEmailDetails {
 	return cancelEmailDetails{
-		BookingDate: m.intl.Fr.FmtMMMEEEEd(b.Start),
+		BookingDate: m.bookingDate(b),
 		Name:        b.Patient.Surname + " " + b.Patient.Name,
 		Phone:       b.Patient.Phone,
 		Email:       b.Patient.Email,
@@ -22,19 +22,25 @@ func (m *Mailer) MailBookingInvoice(invoice *deiz.BookingInvoice, invoicePDF *by
 		attachment: invoicePDF}))
 }
 
-func (m *Mailer) MailInvoicesSummary(summaryPDF *bytes.Buffer, start, end time.Time, sendTo string) error {
-	details := m.getInvoicesEmailDetails(start, end)
+//MailInvoicesSummary sends invoices summary, with an accounting export attached if any
+func (m *Mailer) MailInvoicesSummary(summaryPDF *bytes.Buffer, export *deiz.AccountingExport, start, end time.Time, loc *time.Location, sendTo string) error {
+	details := m.getInvoicesEmailDetails(start, end, loc)
 	template, err := m.htmlTemplate("invoices-summary.html", details)
 	if err != nil {
 		return err
 	}
 	plainBody := details.plainBody()
+	var attachments []attachment
+	if export != nil {
+		attachments = append(attachments, attachment{name: export.Filename, data: export.Data})
+	}
 	return m.client.Send(createMail(mail{
 		to:       sendTo,
 		from:     noReplyAddress,
 		subject:  "Résumé de factures",
 		template: template, plainBody: plainBody,
-		attachment: summaryPDF,
+		attachment:  summaryPDF,
+		attachments: attachments,
 	}))
 }
 
@@ -70,10 +76,11 @@ type invoiceEmailDetails struct {
 	Amount string
 }
 
-func (m *Mailer) getInvoicesEmailDetails(start, end time.Time) invoicesEmailDetail {
+func (m *Mailer) getInvoicesEmailDetails(start, end time.Time, loc *time.Location) invoicesEmailDetail {
+	fr := m.intl.Fr.In(loc)
 	return invoicesEmailDetail{
-		Start: m.intl.Fr.FmtyMd(start),
-		End:   m.intl.Fr.FmtyMd(end),
+		Start: fr.FmtyMd(start),
+		End:   fr.FmtyMd(end),
 	}
 }
 
@@ -2,6 +2,7 @@ package mail
 
 import (
 	"bytes"
+	"github.com/audrenbdb/deiz"
 	"github.com/audrenbdb/deiz/intl"
 	"gopkg.in/gomail.v2"
 	"io"
@@ -16,7 +17,7 @@ const noReplyAddress = "noreply@deiz.fr"
 type Mailer struct {
 	tmpl *template.Template
 	intl *intl.Parser
-	//location to use accross email sent
+	//default location, used when clinician timezone cannot be loaded
 	tz     *time.Location
 	client client
 }
@@ -44,7 +45,7 @@ type Deps struct {
 }
 
 func NewService(deps Deps) *Mailer {
-	tz, _ := time.LoadLocation("Europe/Paris")
+	tz, _ := time.LoadLocation(deiz.DefaultTimezoneName)
 	return &Mailer{
 		tmpl: deps.Templates,
 		//tmpl:   deps.Templates,
@@ -54,6 +55,15 @@ func NewService(deps Deps) *Mailer {
 	}
 }
 
+//clinicianLocation returns location of clinician calendar, dates in emails being displayed in it
+func (m *Mailer) clinicianLocation(c *deiz.Clinician) *time.Location {
+	loc, err := c.Location()
+	if err != nil {
+		return m.tz
+	}
+	return loc
+}
+
 func NewPostFixClient() *PostFix {
 	return &PostFix{
 		BinPath: "/usr/sbin/sendmail",
@@ -109,6 +119,13 @@ type mail struct {
 	template   *bytes.Buffer
 	plainBody  string
 	attachment *bytes.Buffer
+	//attachments sent along the PDF one, with their file name
+	attachments []attachment
+}
+
+type attachment struct {
+	name string
+	data *bytes.Buffer
 }
 
 func createMail(mail mail) *gomail.Message {
@@ -126,6 +143,13 @@ func createMail(mail mail) *gomail.Message {
 			return err
 		}))
 	}
+	for _, a := range mail.attachments {
+		data := a.data
+		m.Attach(a.name, gomail.SetCopyFunc(func(w io.Writer) error {
+			_, err := io.Copy(w, data)
+			return err
+		}))
+	}
 	return m
 }
 
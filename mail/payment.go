@@ -0,0 +1,67 @@
+package mail
+
+import (
+	"fmt"
+	"github.com/audrenbdb/deiz"
+	"net/url"
+)
+
+//MailPaymentReminder asks patient of an unpaid booking to pay its balance online
+func (m *Mailer) MailPaymentReminder(balance *deiz.BookingBalance, paymentToken string) error {
+	details := m.getPaymentReminderEmailDetails(balance, paymentToken)
+	template, err := m.htmlTemplate("payment-reminder.html", details)
+	if err != nil {
+		return err
+	}
+	return m.client.Send(createMail(mail{
+		to:        balance.Booking.Patient.Email,
+		from:      noReplyAddress,
+		subject:   "Relance de paiement",
+		template:  template,
+		plainBody: details.plainBody(),
+	}))
+}
+
+type paymentReminderEmailDetails struct {
+	Clinician   string
+	Phone       string
+	BookingDate string
+	Amount      string
+	PaymentLink string
+}
+
+func (details *paymentReminderEmailDetails) plainBody() string {
+	return fmt.Sprintf(`Deiz\n
+	Relance de paiement\n
+	\n
+	Consultation avec %s\n
+	%s\n
+	Reste à régler : %s\n
+	\n
+	Régler en ligne : %s\n
+	Pour toute question : %s\n
+	\n
+	Deiz\n
+	Agenda pour thérapeutes\n
+	https://deiz.fr
+	`, details.Clinician, details.BookingDate, details.Amount, details.PaymentLink, details.Phone)
+}
+
+func (m *Mailer) getPaymentReminderEmailDetails(balance *deiz.BookingBalance, paymentToken string) paymentReminderEmailDetails {
+	return paymentReminderEmailDetails{
+		Clinician:   balance.Booking.Clinician.FullName(),
+		Phone:       balance.Booking.Clinician.Phone,
+		BookingDate: m.bookingDate(&balance.Booking),
+		Amount:      fmt.Sprintf("%.2f€", float64(balance.Balance)/100),
+		PaymentLink: buildPaymentURL(paymentToken).String(),
+	}
+}
+
+func buildPaymentURL(token string) *url.URL {
+	paymentURL, _ := url.Parse("https://deiz.fr")
+	paymentURL.Path += "bookings/payment"
+	params := url.Values{}
+	params.Add("token", token)
+	paymentURL.RawQuery = params.Encode()
+	return paymentURL
+}
@@ -1,6 +1,9 @@
 package deiz
 
-import "time"
+import (
+	"github.com/audrenbdb/deiz/interval"
+	"time"
+)
 
 type OfficeHours struct {
 	ID          int         `json:"id"`
@@ -8,6 +11,24 @@ type OfficeHours struct {
 	EndMn       int         `json:"endMn"`
 	WeekDay     int         `json:"weekDay"`
 	Address     Address     `json:"address"`
+	Room        Room        `json:"room"`
+	MeetingMode MeetingMode `json:"meetingMode"`
+	//ValidFrom and ValidUntil optionally restrict office hours to a period of the year, such as a summer schedule.
+	//Both dates are inclusive, a zero value means no limit.
+	ValidFrom  time.Time `json:"validFrom"`
+	ValidUntil time.Time `json:"validUntil"`
+	//MotiveIDs restricts booking motives that can be booked in these office hours.
+	//No motive means every motive is allowed.
+	MotiveIDs []int `json:"motiveIds"`
+}
+
+//ExtraAvailability is a one-off opening outside of weekly office hours,
+//such as an exceptional saturday morning.
+type ExtraAvailability struct {
+	ID          int         `json:"id"`
+	Start       time.Time   `json:"start"`
+	End         time.Time   `json:"end"`
+	Address     Address     `json:"address"`
 	MeetingMode MeetingMode `json:"meetingMode"`
 }
 
@@ -20,7 +41,8 @@ const (
 )
 
 func (h *OfficeHours) IsValid() bool {
-	return h.StartMn < h.EndMn && h.WeekDay >= 0 && h.WeekDay <= 6
+	return h.StartMn >= 0 && h.EndMn <= 1440 && h.StartMn < h.EndMn &&
+		h.WeekDay >= 0 && h.WeekDay <= 6 && h.validityPeriodValid()
 }
 
 func (h *OfficeHours) IsInvalid() bool {
@@ -30,3 +52,81 @@ func (h *OfficeHours) IsInvalid() bool {
 func (h *OfficeHours) IsWithinDate(d time.Time) bool {
 	return int(d.Weekday()) == h.WeekDay
 }
+
+//IsActiveOn checks if given date is within office hours validity period
+func (h *OfficeHours) IsActiveOn(d time.Time) bool {
+	day := truncateToDay(d)
+	if !h.ValidFrom.IsZero() && day.Before(truncateToDay(h.ValidFrom)) {
+		return false
+	}
+	if !h.ValidUntil.IsZero() && day.After(truncateToDay(h.ValidUntil)) {
+		return false
+	}
+	return true
+}
+
+//AllowsMotive checks if given booking motive can be booked in office hours
+func (h *OfficeHours) AllowsMotive(motiveID int) bool {
+	if len(h.MotiveIDs) == 0 {
+		return true
+	}
+	for _, id := range h.MotiveIDs {
+		if id == motiveID {
+			return true
+		}
+	}
+	return false
+}
+
+//Overlaps checks if two office hours share the same weekday window during a common validity period
+func (h *OfficeHours) Overlaps(o OfficeHours) bool {
+	return h.WeekDay == o.WeekDay && h.StartMn < o.EndMn && o.StartMn < h.EndMn && h.validityPeriodOverlaps(o)
+}
+
+func (h *OfficeHours) validityPeriodValid() bool {
+	if h.ValidFrom.IsZero() || h.ValidUntil.IsZero() {
+		return true
+	}
+	return !truncateToDay(h.ValidUntil).Before(truncateToDay(h.ValidFrom))
+}
+
+func (h *OfficeHours) validityPeriodOverlaps(o OfficeHours) bool {
+	startsBeforeOtherEnds := h.ValidFrom.IsZero() || o.ValidUntil.IsZero() ||
+		!truncateToDay(h.ValidFrom).After(truncateToDay(o.ValidUntil))
+	otherStartsBeforeEnd := o.ValidFrom.IsZero() || h.ValidUntil.IsZero() ||
+		!truncateToDay(o.ValidFrom).After(truncateToDay(h.ValidUntil))
+	return startsBeforeOtherEnds && otherStartsBeforeEnd
+}
+
+func (a *ExtraAvailability) IsValid() bool {
+	return a.Start.Before(a.End)
+}
+
+func (a *ExtraAvailability) IsInvalid() bool {
+	return !a.IsValid()
+}
+
+func (a *ExtraAvailability) Overlaps(o ExtraAvailability) bool {
+	return a.timeRange().Overlaps(o.timeRange())
+}
+
+//OverlapsOfficeHours checks if extra availability falls within given weekly office hours,
+//office hours being projected in given location
+func (a *ExtraAvailability) OverlapsOfficeHours(h OfficeHours, loc *time.Location) bool {
+	for _, occurrence := range interval.Weekly(a.timeRange(), h.WeekDay, h.StartMn, h.EndMn, loc) {
+		if h.IsActiveOn(occurrence.Start.In(loc)) && a.timeRange().Overlaps(occurrence) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *ExtraAvailability) timeRange() interval.Range {
+	return interval.Range{Start: a.Start, End: a.End}
+}
+
+//truncateToDay returns the given date at midnight UTC, dropping time and location
+func truncateToDay(d time.Time) time.Time {
+	y, m, day := d.Date()
+	return time.Date(y, m, day, 0, 0, 0, 0, time.UTC)
+}
@@ -3,6 +3,7 @@ package deiz
 import (
 	"github.com/audrenbdb/deiz/valid"
 	"strings"
+	"time"
 )
 
 //Patient uses the application to book clinician appointment
@@ -14,6 +15,9 @@ type Patient struct {
 	Email   string  `json:"email"`
 	Note    string  `json:"note"`
 	Address Address `json:"address"`
+	//Timezone is the IANA time zone name of the patient, captured at public booking time.
+	//Empty when unknown.
+	Timezone string `json:"timezone"`
 }
 
 func (p *Patient) FullName() string {
@@ -33,7 +37,29 @@ func (p *Patient) IsEmailSet() bool {
 }
 
 func (p *Patient) IsValid() bool {
-	return len(p.Name) >= 2 && len(p.Surname) >= 2 && valid.Phone(p.Phone) && (valid.Email(p.Email) || p.Email == "")
+	return len(p.Name) >= 2 && len(p.Surname) >= 2 && valid.Phone(p.Phone) && (valid.Email(p.Email) || p.Email == "") &&
+		p.IsTimezoneValid()
+}
+
+//IsTimezoneValid checks that patient timezone is either unknown or a loadable location
+func (p *Patient) IsTimezoneValid() bool {
+	if p.Timezone == "" {
+		return true
+	}
+	_, err := time.LoadLocation(p.Timezone)
+	return err == nil
+}
+
+func (p *Patient) IsTimezoneSet() bool {
+	return p.Timezone != ""
+}
+
+//Location returns patient location, an error being returned when it is unknown
+func (p *Patient) Location() (*time.Location, error) {
+	if !p.IsTimezoneSet() {
+		return nil, ErrorStructValidation
+	}
+	return time.LoadLocation(p.Timezone)
 }
 
 func (p *Patient) IsInvalid() bool {
@@ -49,4 +75,5 @@ func (p *Patient) Sanitize() {
 	p.Email = strings.ToLower(p.Email)
 	p.Phone = strings.TrimSpace(p.Phone)
 	p.Phone = strings.Title(p.Phone)
+	p.Timezone = strings.TrimSpace(p.Timezone)
 }
@@ -0,0 +1,70 @@
+/*
+Package paylink signs payment links sent to patients, so that a booking can be paid without being logged in
+*/
+package paylink
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"github.com/audrenbdb/deiz"
+	"os"
+	"strings"
+	"time"
+)
+
+const errSecretNotProvided Error = "payment link secret not found"
+
+type Error string
+
+func (e Error) Error() string {
+	return string(e)
+}
+
+type service struct {
+	secret []byte
+}
+
+//Sign creates a token for the payment of a booking, valid until expiresAt
+func (s *service) Sign(bookingID int, expiresAt time.Time) (string, error) {
+	if len(s.secret) == 0 {
+		return "", errSecretNotProvided
+	}
+	claims := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d.%d", bookingID, expiresAt.Unix())))
+	return claims + "." + s.signature(claims), nil
+}
+
+//Verify checks token signature and expiry, returning the booking it pays
+func (s *service) Verify(token string, now time.Time) (int, error) {
+	if len(s.secret) == 0 {
+		return 0, errSecretNotProvided
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 || !hmac.Equal([]byte(parts[1]), []byte(s.signature(parts[0]))) {
+		return 0, deiz.ErrorUnauthorized
+	}
+	claims, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return 0, deiz.ErrorUnauthorized
+	}
+	var bookingID int
+	var expiresAt int64
+	if _, err := fmt.Sscanf(string(claims), "%d.%d", &bookingID, &expiresAt); err != nil {
+		return 0, deiz.ErrorUnauthorized
+	}
+	if now.Unix() > expiresAt {
+		return 0, deiz.ErrorPaymentLinkExpired
+	}
+	return bookingID, nil
+}
+
+func (s *service) signature(claims string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(claims))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func NewService() *service {
+	return &service{secret: []byte(os.Getenv("PAYMENT_LINK_SECRET"))}
+}
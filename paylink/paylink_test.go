@@ -0,0 +1,76 @@
+package paylink
+
+import (
+	"github.com/audrenbdb/deiz"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	s := &service{secret: []byte("secret")}
+	now := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+	token, err := s.Sign(12, now.Add(time.Hour))
+	assert.NoError(t, err)
+
+	var tests = []struct {
+		description string
+
+		service *service
+		token   string
+		now     time.Time
+
+		expectedBookingID int
+		expectedError     error
+	}{
+		{
+			description:       "should return booking of a valid token",
+			service:           s,
+			token:             token,
+			now:               now,
+			expectedBookingID: 12,
+		},
+		{
+			description:   "should reject an expired token",
+			service:       s,
+			token:         token,
+			now:           now.Add(2 * time.Hour),
+			expectedError: deiz.ErrorPaymentLinkExpired,
+		},
+		{
+			description:   "should reject a token signed with another secret",
+			service:       &service{secret: []byte("other")},
+			token:         token,
+			now:           now,
+			expectedError: deiz.ErrorUnauthorized,
+		},
+		{
+			description:   "should reject a token altered after signature",
+			service:       s,
+			token:         "MTMuMTYxNDU5NjQwMA." + token[len(token)-43:],
+			now:           now,
+			expectedError: deiz.ErrorUnauthorized,
+		},
+		{
+			description:   "should reject a malformed token",
+			service:       s,
+			token:         "token",
+			now:           now,
+			expectedError: deiz.ErrorUnauthorized,
+		},
+		{
+			description:   "should fail without secret",
+			service:       &service{},
+			token:         token,
+			now:           now,
+			expectedError: errSecretNotProvided,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			bookingID, err := test.service.Verify(test.token, test.now)
+			assert.Equal(t, test.expectedError, err)
+			assert.Equal(t, test.expectedBookingID, bookingID)
+		})
+	}
+}
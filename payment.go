@@ -0,0 +1,100 @@
+package deiz
+
+import "time"
+
+//Payment is an entry of clinician payment ledger,
+//an amount received against a booking or an invoice.
+//Patients may pay a booking in several instalments, with different methods.
+type Payment struct {
+	ID        int           `json:"id"`
+	BookingID int           `json:"bookingId"`
+	InvoiceID int           `json:"invoiceId"`
+	Amount    int64         `json:"amount"`
+	Method    PaymentMethod `json:"method"`
+	PaidAt    time.Time     `json:"paidAt"`
+	//Reference of the payment at its provider, such as a Stripe payment intent, empty if none
+	Reference string `json:"reference"`
+	//AccountID of the Stripe connected account payment was made on,
+	//empty for payments made with clinician legacy keys or not made through Stripe
+	AccountID string `json:"accountId"`
+}
+
+func (p *Payment) IsValid() bool {
+	return p.Amount > 0 && p.Method.IsValid() && (p.BookingID != 0 || p.InvoiceID != 0)
+}
+
+type PaymentStatus uint8
+
+const (
+	Unpaid PaymentStatus = iota
+	PartiallyPaid
+	FullyPaid
+)
+
+//BookingBalance is the outstanding amount of a booking, derived from payments recorded
+type BookingBalance struct {
+	Booking    Booking       `json:"booking"`
+	AmountPaid int64         `json:"amountPaid"`
+	Balance    int64         `json:"balance"`
+	Status     PaymentStatus `json:"status"`
+}
+
+//SetBalance derives booking balance and payment status from amount paid
+func (b *BookingBalance) SetBalance(amountPaid int64) {
+	b.AmountPaid = amountPaid
+	b.Balance = b.Booking.Price - amountPaid
+	switch {
+	case amountPaid <= 0:
+		b.Status = Unpaid
+	case b.Balance > 0:
+		b.Status = PartiallyPaid
+	default:
+		b.Status = FullyPaid
+	}
+}
+
+//PaymentLinkValidity is how long a payment link sent to a patient can be used to pay a booking
+const PaymentLinkValidity = 30 * 24 * time.Hour
+
+//OnlinePaymentMethodName is the payment method of bookings paid through a Stripe checkout
+const OnlinePaymentMethodName = "Carte bancaire (en ligne)"
+
+//StripeAccount a clinician connected to the platform through Stripe Connect, to be paid online
+type StripeAccount struct {
+	ID string `json:"id"`
+	//ChargesEnabled once Stripe completed clinician onboarding
+	ChargesEnabled bool `json:"chargesEnabled"`
+}
+
+func (a StripeAccount) IsConnected() bool {
+	return a.ID != "" && a.ChargesEnabled
+}
+
+//LegacyStripeKeysRetention is how long keys a clinician pasted before Stripe Connect are kept once connected,
+//checkouts opened with them being paid and notified meanwhile
+const LegacyStripeKeysRetention = 3 * 24 * time.Hour
+
+//StripePayee tells on behalf of which Stripe account clinician payments are made.
+//Clinicians who pasted their secret key before Stripe Connect keep being charged with it until they connect.
+type StripePayee struct {
+	AccountID string
+	//LegacySecretKey of a clinician not connected yet
+	LegacySecretKey string
+}
+
+//CheckoutPayment is a Stripe checkout of a booking, metadata linking the session back to it
+type CheckoutPayment struct {
+	SessionID string
+	//AccountID of the connected account checkout was made on, empty for legacy clinician keys
+	AccountID string
+	//PaymentIntentID of the checkout, set once paid
+	PaymentIntentID string
+	BookingID       int
+	ClinicianID     int
+	//Amount in cents
+	Amount int64
+	//Paid is set when the checkout completed with payment captured
+	Paid bool
+	//ExpiresAt closes checkout session, zero leaving Stripe default expiry
+	ExpiresAt time.Time
+}
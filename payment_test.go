@@ -0,0 +1,33 @@
+package deiz_test
+
+import (
+	"github.com/audrenbdb/deiz"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestBookingBalance(t *testing.T) {
+	var tests = []struct {
+		description string
+
+		price      int64
+		amountPaid int64
+
+		balance int64
+		status  deiz.PaymentStatus
+	}{
+		{description: "should be unpaid", price: 6000, balance: 6000, status: deiz.Unpaid},
+		{description: "should be partially paid", price: 6000, amountPaid: 2000, balance: 4000, status: deiz.PartiallyPaid},
+		{description: "should be fully paid", price: 6000, amountPaid: 6000, status: deiz.FullyPaid},
+		{description: "should be overpaid", price: 6000, amountPaid: 7000, balance: -1000, status: deiz.FullyPaid},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			b := deiz.BookingBalance{Booking: deiz.Booking{Price: test.price}}
+			b.SetBalance(test.amountPaid)
+			assert.Equal(t, test.amountPaid, b.AmountPaid)
+			assert.Equal(t, test.balance, b.Balance)
+			assert.Equal(t, test.status, b.Status)
+		})
+	}
+}
@@ -4,12 +4,17 @@ import (
 	"bytes"
 	"fmt"
 	"github.com/audrenbdb/deiz"
+	"github.com/jung-kurt/gofpdf"
 	"golang.org/x/text/language"
 	"golang.org/x/text/message"
 	"time"
 )
 
+//CreateBookingInvoicePDF renders an invoice, or a credit note cancelling one
 func (pdf *Pdf) CreateBookingInvoicePDF(i *deiz.BookingInvoice) (*bytes.Buffer, error) {
+	if i.IsCreditNote() {
+		return pdf.createCreditNotePDF(i)
+	}
 	doc := pdf.createPDF(portrait, mm, A4)
 	p := message.NewPrinter(language.French)
 	initPDF(doc,
@@ -29,26 +34,78 @@ func (pdf *Pdf) CreateBookingInvoicePDF(i *deiz.BookingInvoice) (*bytes.Buffer,
 	doc.Ln(6)
 	doc.CellFormat(0, 0, fmt.Sprintf("Identifiant de facture : %s", i.Identifier), "", 0, "R", false, 0, "")
 	doc.Ln(20)
-	doc.SetFillColor(pdf.blueTheme.red, pdf.blueTheme.green, pdf.blueTheme.blue)
-	doc.SetTextColor(255, 255, 255)
-	doc.Cell(1, 0, "")
-	doc.CellFormat(56, 10, "Date", "", 0, "C", true, 0, "")
-	doc.Cell(1, 0, "")
-	doc.CellFormat(56, 10, "Prestation", "", 0, "C", true, 0, "")
-	doc.Cell(1, 0, "")
-	doc.CellFormat(56, 10, "Prix unitaire H.T.", "", 0, "C", true, 0, "")
+
+	invoiceLines(doc, p, i.Lines, pdf.blueTheme)
+	invoiceTotals(doc, p, i)
+	doc.CellFormat(171, 10, fmt.Sprintf("Acquitté ce jour via %s", i.PaymentMethod.Name), "", 1, "R", false, 0, "")
+	doc.Ln(20)
+
+	invoiceExemption(doc, i)
+	var buffer bytes.Buffer
+	err := doc.Output(&buffer)
+	if err != nil {
+		return &bytes.Buffer{}, err
+	}
+	return &buffer, nil
+}
+
+//createCreditNotePDF renders a credit note, referencing the invoice it cancels
+func (pdf *Pdf) createCreditNotePDF(i *deiz.BookingInvoice) (*bytes.Buffer, error) {
+	doc := pdf.createPDF(portrait, mm, A4)
+	p := message.NewPrinter(language.French)
+	initPDF(doc,
+		headerAsLetterFunc(
+			doc,
+			i.CityAndDate,
+			i.Sender,
+			i.Recipient,
+			pdf.blueTheme,
+			20),
+		footerFunc(doc, pdf.blueTheme),
+	)
+
+	//title
+	doc.Ln(1)
 	doc.SetTextColor(pdf.blueTheme.red, pdf.blueTheme.green, pdf.blueTheme.blue)
+	doc.Ln(6)
+	doc.CellFormat(0, 0, fmt.Sprintf("Avoir n° %s", i.Identifier), "", 0, "R", false, 0, "")
+	doc.Ln(6)
+	doc.CellFormat(0, 0, fmt.Sprintf("Annule la facture n° %s", i.CanceledInvoice.Identifier), "", 0, "R", false, 0, "")
+	doc.Ln(14)
 
-	//product details
-	doc.Ln(12)
-	doc.Cell(1, 0, "")
-	doc.CellFormat(56, 20, i.DeliveryDateStr, "", 0, "C", false, 0, "")
-	doc.Cell(1, 0, "")
-	doc.CellFormat(56, 20, i.Label, "", 0, "C", false, 0, "")
-	doc.Cell(1, 0, "")
-	doc.CellFormat(56, 20, p.Sprintf("%.2f €", float64(i.PriceBeforeTax)/100), "", 1, "C", false, 0, "")
+	invoiceLines(doc, p, i.Lines, pdf.blueTheme)
+	invoiceTotals(doc, p, i)
+	doc.CellFormat(171, 10, fmt.Sprintf("Avoir émis en annulation de la facture n° %s", i.CanceledInvoice.Identifier), "", 1, "R", false, 0, "")
+	doc.Ln(20)
 
-	//total
+	invoiceExemption(doc, i)
+	var buffer bytes.Buffer
+	err := doc.Output(&buffer)
+	if err != nil {
+		return &bytes.Buffer{}, err
+	}
+	return &buffer, nil
+}
+
+//invoiceLines renders lines table, continuing on next pages if needed
+func invoiceLines(doc *gofpdf.Fpdf, p *message.Printer, lines []deiz.InvoiceLine, colour rgb) {
+	invoiceLinesHeader(doc, colour)
+	for _, l := range lines {
+		if doc.GetY() > invoiceLinesMaxY {
+			doc.AddPage()
+			invoiceLinesHeader(doc, colour)
+		}
+		doc.Cell(1, 0, "")
+		doc.CellFormat(56, 10, l.DeliveryDateStr, "", 0, "C", false, 0, "")
+		doc.Cell(1, 0, "")
+		doc.CellFormat(56, 10, l.Label, "", 0, "C", false, 0, "")
+		doc.Cell(1, 0, "")
+		doc.CellFormat(56, 10, p.Sprintf("%.2f €", float64(l.PriceBeforeTax)/100), "", 1, "C", false, 0, "")
+	}
+	doc.Ln(10)
+}
+
+func invoiceTotals(doc *gofpdf.Fpdf, p *message.Printer, i *deiz.BookingInvoice) {
 	if doc.GetY() > 180 {
 		doc.AddPage()
 		doc.Ln(10)
@@ -63,8 +120,8 @@ func (pdf *Pdf) CreateBookingInvoicePDF(i *deiz.BookingInvoice) (*bytes.Buffer,
 	doc.CellFormat(5, 10, "€", "", 1, "R", false, 0, "")
 	doc.Ln(1)
 	doc.Cell(102, 0, "")
-	doc.CellFormat(50, 10, "T.V.A :", "", 0, "L", false, 0, "")
-	doc.CellFormat(15, 10, p.Sprintf("%.2f", float32(i.PriceAfterTax-i.PriceBeforeTax)/100), "", 0, "R", false, 0, "")
+	doc.CellFormat(50, 10, p.Sprintf("T.V.A (%v %%) :", i.TaxRate().Percent()), "", 0, "L", false, 0, "")
+	doc.CellFormat(15, 10, p.Sprintf("%.2f", float64(i.PriceAfterTax-i.PriceBeforeTax)/100), "", 0, "R", false, 0, "")
 	doc.CellFormat(5, 10, "€", "", 1, "R", false, 0, "")
 	doc.Ln(1)
 	doc.Cell(102, 0, "")
@@ -75,30 +132,41 @@ func (pdf *Pdf) CreateBookingInvoicePDF(i *deiz.BookingInvoice) (*bytes.Buffer,
 	doc.Cell(87, 0, "")
 	doc.CellFormat(84, 1, "", "B", 0, "L", false, 0, "")
 	doc.Ln(10)
-	doc.CellFormat(171, 10, fmt.Sprintf("Acquitté ce jour via %s", i.PaymentMethod.Name), "", 1, "R", false, 0, "")
-	doc.Ln(20)
+}
 
-	//tax exemption
+func invoiceExemption(doc *gofpdf.Fpdf, i *deiz.BookingInvoice) {
 	if i.Exemption != "" {
 		doc.CellFormat(171, 10, fmt.Sprintf("TVA non applicable - article %s du CGI", i.Exemption), "", 1, "C", false, 0, "")
 	}
-	var buffer bytes.Buffer
-	err := doc.Output(&buffer)
-	if err != nil {
-		return &bytes.Buffer{}, err
-	}
-	return &buffer, nil
 }
 
-func (pdf *Pdf) CreateInvoicesSummaryPDF(invoices []deiz.BookingInvoice, start, end time.Time) (*bytes.Buffer, error) {
+//invoiceLinesMaxY is the position past which lines continue on next page, table header being repeated
+const invoiceLinesMaxY = 250
+
+func invoiceLinesHeader(doc *gofpdf.Fpdf, colour rgb) {
+	doc.SetFillColor(colour.red, colour.green, colour.blue)
+	doc.SetTextColor(255, 255, 255)
+	doc.Cell(1, 0, "")
+	doc.CellFormat(56, 10, "Date", "", 0, "C", true, 0, "")
+	doc.Cell(1, 0, "")
+	doc.CellFormat(56, 10, "Prestation", "", 0, "C", true, 0, "")
+	doc.Cell(1, 0, "")
+	doc.CellFormat(56, 10, "Prix unitaire H.T.", "", 0, "C", true, 0, "")
+	doc.SetTextColor(colour.red, colour.green, colour.blue)
+	doc.Ln(12)
+}
+
+//CreateInvoicesSummaryPDF lists invoices of a period, dates being displayed in given location
+func (pdf *Pdf) CreateInvoicesSummaryPDF(invoices []deiz.BookingInvoice, start, end time.Time, loc *time.Location) (*bytes.Buffer, error) {
+	fr := pdf.intl.Fr.In(loc)
 	doc := pdf.createPDF(landscape, mm, A4)
 	p := message.NewPrinter(language.French)
 	totalBeforeTax, totalAfterTax := getInvoicesAmountSummary(invoices)
 
 	initPDF(doc,
 		headerAsPeriodEarningsSummary(doc,
-			pdf.intl.Fr.FmtyMd(start),
-			pdf.intl.Fr.FmtyMd(end), totalBeforeTax, totalAfterTax, pdf.blueTheme, 20),
+			fr.FmtyMd(start),
+			fr.FmtyMd(end), totalBeforeTax, totalAfterTax, pdf.blueTheme, 20),
 		footerFunc(doc, pdf.blueTheme),
 	)
 
@@ -107,9 +175,9 @@ func (pdf *Pdf) CreateInvoicesSummaryPDF(invoices []deiz.BookingInvoice, start,
 		doc.SetTextColor(pdf.blueTheme.red, pdf.blueTheme.green, pdf.blueTheme.blue)
 		doc.SetDrawColor(pdf.blueTheme.red, pdf.blueTheme.green, pdf.blueTheme.blue)
 		doc.Cell(10, 0, "")
-		doc.CellFormat(33, 4, pdf.intl.Fr.FmtyMd(i.CreatedAt), "", 0, "C", false, 0, "")
+		doc.CellFormat(33, 4, fr.FmtyMd(i.CreatedAt), "", 0, "C", false, 0, "")
 		doc.Cell(1, 0, "")
-		doc.CellFormat(33, 4, pdf.intl.Fr.FmtyMd(i.DeliveryDate), "", 0, "C", false, 0, "")
+		doc.CellFormat(33, 4, fr.FmtyMd(i.DeliveryDate), "", 0, "C", false, 0, "")
 		doc.Cell(1, 0, "")
 		doc.CellFormat(33, 4, i.Identifier, "", 0, "C", false, 0, "")
 		doc.Cell(1, 0, "")
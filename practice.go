@@ -0,0 +1,49 @@
+package deiz
+
+//Practice gathers clinicians and their staff working in the same cabinet.
+//Its owner invites members, who join once they accept. Members who joined share their patient directory.
+type Practice struct {
+	ID      int              `json:"id"`
+	Name    string           `json:"name"`
+	Members []PracticeMember `json:"members"`
+}
+
+type PracticeMember struct {
+	Clinician Clinician `json:"clinician"`
+	Role      Role      `json:"role"`
+	//Owner created the practice, alone managing its members
+	Owner bool `json:"owner"`
+	//Accepted is false while member has not accepted the invitation
+	Accepted bool `json:"accepted"`
+}
+
+func (p *Practice) IsValid() bool {
+	return len(p.Name) > 1
+}
+
+//HasMember checks if given person belongs to the practice, invitation pending or accepted
+func (p *Practice) HasMember(personID int) bool {
+	_, ok := p.member(personID)
+	return ok
+}
+
+//IsOwnedBy checks if given person owns the practice
+func (p *Practice) IsOwnedBy(personID int) bool {
+	m, ok := p.member(personID)
+	return ok && m.Owner
+}
+
+//InvitationPending checks if given person is invited to the practice without having accepted yet
+func (p *Practice) InvitationPending(personID int) bool {
+	m, ok := p.member(personID)
+	return ok && !m.Accepted
+}
+
+func (p *Practice) member(personID int) (PracticeMember, bool) {
+	for _, m := range p.Members {
+		if m.Clinician.ID == personID {
+			return m, true
+		}
+	}
+	return PracticeMember{}, false
+}
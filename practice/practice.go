@@ -0,0 +1,123 @@
+package practice
+
+import (
+	"context"
+	"github.com/audrenbdb/deiz"
+)
+
+type (
+	Creater interface {
+		CreatePractice(ctx context.Context, p *deiz.Practice, clinicianID int) error
+	}
+	Getter interface {
+		GetPersonPractice(ctx context.Context, personID int) (deiz.Practice, error)
+	}
+	MemberAdder interface {
+		AddPracticeMember(ctx context.Context, practiceID, personID int) error
+	}
+	MemberRemover interface {
+		RemovePracticeMember(ctx context.Context, practiceID, personID int) error
+	}
+	InvitationAccepter interface {
+		AcceptPracticeInvitation(ctx context.Context, practiceID, personID int) error
+	}
+	ClinicianGetter interface {
+		GetClinicianByEmail(ctx context.Context, email string) (deiz.Clinician, error)
+	}
+	MembershipChecker interface {
+		ArePracticeMembers(ctx context.Context, personID, otherPersonID int) (bool, error)
+	}
+)
+
+//CreatePractice creates a new practice owned by the clinician creating it, its first member
+func (u *Usecase) CreatePractice(ctx context.Context, p *deiz.Practice, clinicianID int) error {
+	if !p.IsValid() {
+		return deiz.ErrorStructValidation
+	}
+	existing, err := u.Getter.GetPersonPractice(ctx, clinicianID)
+	if err != nil {
+		return err
+	}
+	if existing.ID != 0 {
+		return deiz.ErrorAlreadyInPractice
+	}
+	return u.Creater.CreatePractice(ctx, p, clinicianID)
+}
+
+func (u *Usecase) GetPractice(ctx context.Context, personID int) (deiz.Practice, error) {
+	return u.Getter.GetPersonPractice(ctx, personID)
+}
+
+//AddPracticeMember invites the account registered with given email to the practice the requesting person owns.
+//Invitee joins the practice once accepting the invitation.
+func (u *Usecase) AddPracticeMember(ctx context.Context, email string, personID int) error {
+	p, err := u.Getter.GetPersonPractice(ctx, personID)
+	if err != nil {
+		return err
+	}
+	if !p.IsOwnedBy(personID) {
+		return deiz.ErrorUnauthorized
+	}
+	member, err := u.ClinicianGetter.GetClinicianByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	memberPractice, err := u.Getter.GetPersonPractice(ctx, member.ID)
+	if err != nil {
+		return err
+	}
+	if memberPractice.ID != 0 {
+		return deiz.ErrorAlreadyInPractice
+	}
+	return u.MemberAdder.AddPracticeMember(ctx, p.ID, member.ID)
+}
+
+//AcceptPracticeInvitation makes the requesting person join the practice they were invited to
+func (u *Usecase) AcceptPracticeInvitation(ctx context.Context, personID int) error {
+	p, err := u.Getter.GetPersonPractice(ctx, personID)
+	if err != nil {
+		return err
+	}
+	if !p.InvitationPending(personID) {
+		return deiz.ErrorUnauthorized
+	}
+	return u.InvitationAccepter.AcceptPracticeInvitation(ctx, p.ID, personID)
+}
+
+//RemovePracticeMember removes a member, or revokes an invitation, from the practice the requesting person owns.
+//A member may also leave the practice or decline an invitation, owner leaving last.
+func (u *Usecase) RemovePracticeMember(ctx context.Context, memberID, personID int) error {
+	p, err := u.Getter.GetPersonPractice(ctx, personID)
+	if err != nil {
+		return err
+	}
+	if !p.HasMember(memberID) {
+		return deiz.ErrorUnauthorized
+	}
+	if memberID == personID && p.IsOwnedBy(personID) && len(p.Members) > 1 {
+		return deiz.ErrorUnauthorized
+	}
+	if memberID != personID && !p.IsOwnedBy(personID) {
+		return deiz.ErrorUnauthorized
+	}
+	return u.MemberRemover.RemovePracticeMember(ctx, p.ID, memberID)
+}
+
+//ResolveActingClinician returns the clinician a request acts for.
+//Staff may act on behalf of any clinician who joined their practice, other users only for themselves.
+func (u *Usecase) ResolveActingClinician(ctx context.Context, cred deiz.Credentials, onBehalfOfID int) (int, error) {
+	if onBehalfOfID == 0 || onBehalfOfID == cred.UserID {
+		return cred.UserID, nil
+	}
+	if !cred.IsStaff() {
+		return 0, deiz.ErrorUnauthorized
+	}
+	same, err := u.MembershipChecker.ArePracticeMembers(ctx, cred.UserID, onBehalfOfID)
+	if err != nil {
+		return 0, err
+	}
+	if !same {
+		return 0, deiz.ErrorUnauthorized
+	}
+	return onBehalfOfID, nil
+}
@@ -0,0 +1,252 @@
+package practice_test
+
+import (
+	"context"
+	"errors"
+	"github.com/audrenbdb/deiz"
+	"github.com/audrenbdb/deiz/practice"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type (
+	mockGetter struct {
+		practices map[int]deiz.Practice
+		err       error
+	}
+	mockMemberAdder struct {
+		practiceID int
+		personID   int
+	}
+	mockClinicianGetter struct {
+		clinician deiz.Clinician
+		err       error
+	}
+	mockMembershipChecker struct {
+		same bool
+		err  error
+	}
+	mockMemberRemover struct {
+		removedID int
+	}
+	mockInvitationAccepter struct {
+		practiceID int
+		personID   int
+	}
+)
+
+func (m *mockMemberRemover) RemovePracticeMember(ctx context.Context, practiceID, personID int) error {
+	m.removedID = personID
+	return nil
+}
+
+func (m *mockInvitationAccepter) AcceptPracticeInvitation(ctx context.Context, practiceID, personID int) error {
+	m.practiceID = practiceID
+	m.personID = personID
+	return nil
+}
+
+//ownedPractice is owned by person 1, joined by person 2 and to be joined by person 3
+var ownedPractice = deiz.Practice{ID: 10, Members: []deiz.PracticeMember{
+	{Clinician: deiz.Clinician{ID: 1}, Owner: true, Accepted: true},
+	{Clinician: deiz.Clinician{ID: 2}, Accepted: true},
+	{Clinician: deiz.Clinician{ID: 3}},
+}}
+
+func (m *mockGetter) GetPersonPractice(ctx context.Context, personID int) (deiz.Practice, error) {
+	return m.practices[personID], m.err
+}
+
+func (m *mockMemberAdder) AddPracticeMember(ctx context.Context, practiceID, personID int) error {
+	m.practiceID = practiceID
+	m.personID = personID
+	return nil
+}
+
+func (m *mockClinicianGetter) GetClinicianByEmail(ctx context.Context, email string) (deiz.Clinician, error) {
+	return m.clinician, m.err
+}
+
+func (m *mockMembershipChecker) ArePracticeMembers(ctx context.Context, personID, otherPersonID int) (bool, error) {
+	return m.same, m.err
+}
+
+func TestResolveActingClinician(t *testing.T) {
+	var tests = []struct {
+		description string
+
+		checker mockMembershipChecker
+
+		inCred       deiz.Credentials
+		inOnBehalfOf int
+
+		outClinicianID int
+		outError       error
+	}{
+		{
+			description:    "should act for itself when nobody is given",
+			inCred:         deiz.Credentials{UserID: 1, Role: deiz.ClinicianRole},
+			outClinicianID: 1,
+		},
+		{
+			description:    "should act for itself when given itself",
+			inCred:         deiz.Credentials{UserID: 1, Role: deiz.ClinicianRole},
+			inOnBehalfOf:   1,
+			outClinicianID: 1,
+		},
+		{
+			description:  "should prevent a clinician from acting for another one",
+			checker:      mockMembershipChecker{same: true},
+			inCred:       deiz.Credentials{UserID: 1, Role: deiz.ClinicianRole},
+			inOnBehalfOf: 2,
+			outError:     deiz.ErrorUnauthorized,
+		},
+		{
+			description:  "should prevent staff from acting for a clinician outside practice",
+			inCred:       deiz.Credentials{UserID: 1, Role: deiz.StaffRole},
+			inOnBehalfOf: 2,
+			outError:     deiz.ErrorUnauthorized,
+		},
+		{
+			description:  "should fail to check membership",
+			checker:      mockMembershipChecker{err: errors.New("failed to check")},
+			inCred:       deiz.Credentials{UserID: 1, Role: deiz.StaffRole},
+			inOnBehalfOf: 2,
+			outError:     errors.New("failed to check"),
+		},
+		{
+			description:    "should let staff act for a practice member",
+			checker:        mockMembershipChecker{same: true},
+			inCred:         deiz.Credentials{UserID: 1, Role: deiz.StaffRole},
+			inOnBehalfOf:   2,
+			outClinicianID: 2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			u := practice.Usecase{MembershipChecker: &test.checker}
+			clinicianID, err := u.ResolveActingClinician(context.Background(), test.inCred, test.inOnBehalfOf)
+			assert.Equal(t, test.outError, err)
+			assert.Equal(t, test.outClinicianID, clinicianID)
+		})
+	}
+}
+
+func TestAddPracticeMember(t *testing.T) {
+	var tests = []struct {
+		description string
+
+		getter          mockGetter
+		clinicianGetter mockClinicianGetter
+
+		outPracticeID int
+		outPersonID   int
+		outError      error
+	}{
+		{
+			description: "should refuse a person without practice",
+			getter:      mockGetter{practices: map[int]deiz.Practice{}},
+			outError:    deiz.ErrorUnauthorized,
+		},
+		{
+			description:     "should refuse a member who does not own the practice",
+			getter:          mockGetter{practices: map[int]deiz.Practice{1: {ID: 10, Members: []deiz.PracticeMember{{Clinician: deiz.Clinician{ID: 1}, Accepted: true}}}}},
+			clinicianGetter: mockClinicianGetter{clinician: deiz.Clinician{ID: 4}},
+			outError:        deiz.ErrorUnauthorized,
+		},
+		{
+			description:     "should fail to find new member",
+			getter:          mockGetter{practices: map[int]deiz.Practice{1: ownedPractice}},
+			clinicianGetter: mockClinicianGetter{err: errors.New("not found")},
+			outError:        errors.New("not found"),
+		},
+		{
+			description: "should refuse a member of another practice",
+			getter: mockGetter{practices: map[int]deiz.Practice{
+				1: ownedPractice,
+				4: {ID: 11},
+			}},
+			clinicianGetter: mockClinicianGetter{clinician: deiz.Clinician{ID: 4}},
+			outError:        deiz.ErrorAlreadyInPractice,
+		},
+		{
+			description:     "should invite member",
+			getter:          mockGetter{practices: map[int]deiz.Practice{1: ownedPractice}},
+			clinicianGetter: mockClinicianGetter{clinician: deiz.Clinician{ID: 4}},
+			outPracticeID:   10,
+			outPersonID:     4,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			adder := &mockMemberAdder{}
+			u := practice.Usecase{
+				Getter:          &test.getter,
+				ClinicianGetter: &test.clinicianGetter,
+				MemberAdder:     adder,
+			}
+			err := u.AddPracticeMember(context.Background(), "member@deiz.fr", 1)
+			assert.Equal(t, test.outError, err)
+			assert.Equal(t, test.outPracticeID, adder.practiceID)
+			assert.Equal(t, test.outPersonID, adder.personID)
+		})
+	}
+}
+
+func TestAcceptPracticeInvitation(t *testing.T) {
+	getter := &mockGetter{practices: map[int]deiz.Practice{1: ownedPractice, 2: ownedPractice, 3: ownedPractice}}
+	var tests = []struct {
+		description string
+
+		inPersonID int
+
+		outPersonID int
+		outError    error
+	}{
+		{description: "should refuse a person without invitation", inPersonID: 4, outError: deiz.ErrorUnauthorized},
+		{description: "should refuse a member who already joined", inPersonID: 2, outError: deiz.ErrorUnauthorized},
+		{description: "should make invitee join the practice", inPersonID: 3, outPersonID: 3},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			accepter := &mockInvitationAccepter{}
+			u := practice.Usecase{Getter: getter, InvitationAccepter: accepter}
+			err := u.AcceptPracticeInvitation(context.Background(), test.inPersonID)
+			assert.Equal(t, test.outError, err)
+			assert.Equal(t, test.outPersonID, accepter.personID)
+		})
+	}
+}
+
+func TestRemovePracticeMember(t *testing.T) {
+	getter := &mockGetter{practices: map[int]deiz.Practice{1: ownedPractice, 2: ownedPractice, 3: ownedPractice}}
+	var tests = []struct {
+		description string
+
+		inMemberID int
+		inPersonID int
+
+		outRemovedID int
+		outError     error
+	}{
+		{description: "should refuse to remove a person outside practice", inMemberID: 4, inPersonID: 1, outError: deiz.ErrorUnauthorized},
+		{description: "should refuse a member removing another one", inMemberID: 3, inPersonID: 2, outError: deiz.ErrorUnauthorized},
+		{description: "should refuse an invitee removing a member", inMemberID: 2, inPersonID: 3, outError: deiz.ErrorUnauthorized},
+		{description: "should refuse owner leaving members behind", inMemberID: 1, inPersonID: 1, outError: deiz.ErrorUnauthorized},
+		{description: "should let owner remove a member", inMemberID: 2, inPersonID: 1, outRemovedID: 2},
+		{description: "should let owner revoke an invitation", inMemberID: 3, inPersonID: 1, outRemovedID: 3},
+		{description: "should let a member leave", inMemberID: 2, inPersonID: 2, outRemovedID: 2},
+		{description: "should let an invitee decline", inMemberID: 3, inPersonID: 3, outRemovedID: 3},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			remover := &mockMemberRemover{}
+			u := practice.Usecase{Getter: getter, MemberRemover: remover}
+			err := u.RemovePracticeMember(context.Background(), test.inMemberID, test.inPersonID)
+			assert.Equal(t, test.outError, err)
+			assert.Equal(t, test.outRemovedID, remover.removedID)
+		})
+	}
+}
@@ -0,0 +1,11 @@
+package practice
+
+type Usecase struct {
+	Creater            Creater
+	Getter             Getter
+	MemberAdder        MemberAdder
+	MemberRemover      MemberRemover
+	InvitationAccepter InvitationAccepter
+	ClinicianGetter    ClinicianGetter
+	MembershipChecker  MembershipChecker
+}
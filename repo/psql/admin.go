@@ -0,0 +1,126 @@
+package psql
+
+import (
+	"context"
+	"fmt"
+	"github.com/audrenbdb/deiz"
+	"time"
+)
+
+//ListClinicianAccounts lists every clinician and staff account, most recent first
+func (r *Repo) ListClinicianAccounts(ctx context.Context) ([]deiz.AccountSummary, error) {
+	const query = `SELECT p.id, p.name, p.surname, p.email, p.phone, COALESCE(p.profession, ''),
+	p.role, p.disabled, COALESCE(p.created_at, '0001-01-01')
+	FROM person p WHERE p.role >= $1 ORDER BY p.created_at DESC`
+	rows, err := r.conn.Query(ctx, query, deiz.ClinicianRole)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	accounts := []deiz.AccountSummary{}
+	for rows.Next() {
+		var a deiz.AccountSummary
+		err := rows.Scan(&a.Clinician.ID, &a.Clinician.Name, &a.Clinician.Surname, &a.Clinician.Email,
+			&a.Clinician.Phone, &a.Clinician.Profession, &a.Role, &a.Disabled, &a.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, a)
+	}
+	return accounts, rows.Err()
+}
+
+//UpdateAccountDisabled enables or disables an account and its firebase user if exists
+func (r *Repo) UpdateAccountDisabled(ctx context.Context, disabled bool, clinicianID int) error {
+	p, err := getPersonByID(ctx, r.conn, clinicianID)
+	if err != nil {
+		return err
+	}
+	tx, err := r.conn.Begin(ctx)
+	defer tx.Rollback(ctx)
+	if err != nil {
+		return err
+	}
+	tag, err := tx.Exec(ctx, `UPDATE person SET disabled = $1 WHERE id = $2`, disabled, clinicianID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return errNoRowsUpdated
+	}
+
+	//if fire base user is set, also disables the firebase account
+	u, err := r.firebaseAuth.GetUserByEmail(ctx, p.email)
+	if err != nil {
+		if err.Error() != fmt.Sprintf("cannot find user from email: \"%s\"", p.email) {
+			return err
+		}
+		return tx.Commit(ctx)
+	}
+	if err := updateFirebaseUserDisabled(ctx, r.firebaseAuth, disabled, u.UID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+//GetUsageStats counts appointments per week and invoices per month created in given period
+func (r *Repo) GetUsageStats(ctx context.Context, from, to time.Time) (deiz.UsageStats, error) {
+	const bookingsQuery = `SELECT date_trunc('week', lower(during)) AS period, COUNT(*)
+	FROM clinician_booking WHERE booking_type_id = 1 AND lower(during) >= $1 AND lower(during) < $2
+	GROUP BY period ORDER BY period`
+	const invoicesQuery = `SELECT date_trunc('month', created_at) AS period, COUNT(*)
+	FROM booking_invoice WHERE canceled = false AND created_at >= $1 AND created_at < $2
+	GROUP BY period ORDER BY period`
+	bookings, err := queryUsageCounts(ctx, r.conn, bookingsQuery, from, to)
+	if err != nil {
+		return deiz.UsageStats{}, err
+	}
+	invoices, err := queryUsageCounts(ctx, r.conn, invoicesQuery, from, to)
+	if err != nil {
+		return deiz.UsageStats{}, err
+	}
+	return deiz.UsageStats{BookingsPerWeek: bookings, InvoicesPerMonth: invoices}, nil
+}
+
+func queryUsageCounts(ctx context.Context, db db, query string, args ...interface{}) ([]deiz.UsageCount, error) {
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	counts := []deiz.UsageCount{}
+	for rows.Next() {
+		var c deiz.UsageCount
+		if err := rows.Scan(&c.Period, &c.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
+
+func (r *Repo) CreateAuditLog(ctx context.Context, l *deiz.AuditLog) error {
+	const query = `INSERT INTO audit_log(admin_person_id, action, target_person_id) VALUES($1, $2, NULLIF($3, 0))
+	RETURNING id, created_at`
+	return r.conn.QueryRow(ctx, query, l.AdminID, l.Action, l.TargetID).Scan(&l.ID, &l.CreatedAt)
+}
+
+//GetAuditLogs returns latest audit logs, most recent first
+func (r *Repo) GetAuditLogs(ctx context.Context, limit int) ([]deiz.AuditLog, error) {
+	const query = `SELECT id, COALESCE(admin_person_id, 0), action, COALESCE(target_person_id, 0), created_at
+	FROM audit_log ORDER BY created_at DESC, id DESC LIMIT $1`
+	rows, err := r.conn.Query(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	logs := []deiz.AuditLog{}
+	for rows.Next() {
+		var l deiz.AuditLog
+		if err := rows.Scan(&l.ID, &l.AdminID, &l.Action, &l.TargetID, &l.CreatedAt); err != nil {
+			return nil, err
+		}
+		logs = append(logs, l)
+	}
+	return logs, rows.Err()
+}
@@ -21,6 +21,12 @@ func updateFirebaseUserEmail(ctx context.Context, auth auth, email, firebaseUser
 	return err
 }
 
+func updateFirebaseUserDisabled(ctx context.Context, auth auth, disabled bool, firebaseUserID string) error {
+	userToUpdate := (&firebaseAuth.UserToUpdate{}).Disabled(disabled)
+	_, err := auth.UpdateUser(ctx, firebaseUserID, userToUpdate)
+	return err
+}
+
 func createFirebaseUser(ctx context.Context, auth auth, email, password string) (*firebaseAuth.UserRecord, error) {
 	userToCreate := (&firebaseAuth.UserToCreate{}).Email(email).EmailVerified(false).Password(password).Disabled(false)
 	return auth.CreateUser(ctx, userToCreate)
@@ -8,58 +8,153 @@ import (
 )
 
 const bookingSelect = `SELECT b.id, COALESCE(b.description, ''), b.delete_id, lower(b.during), upper(b.during), b.booking_type_id, COALESCE(b.meeting_mode_id, 0),
-	c.id, c.surname, c.name, c.phone, c.email,
-	COALESCE(p.id, 0), COALESCE(p.surname, ''), COALESCE(p.name, ''), COALESCE(p.phone, ''), COALESCE(p.email, ''),
-	COALESCE(b.address, ''), COALESCE(b.price, 0),
-	b.paid, COALESCE(b.note, ''), b.confirmed, b.recurrence_id
+	c.id, c.surname, c.name, c.phone, c.email, COALESCE(t.id, 0), COALESCE(t.name, ''),
+	COALESCE(p.id, 0), COALESCE(p.surname, ''), COALESCE(p.name, ''), COALESCE(p.phone, ''), COALESCE(p.email, ''), COALESCE(p.timezone, ''),
+	COALESCE(b.address, ''), COALESCE(rm.id, 0), COALESCE(rm.name, ''), COALESCE(rm.address_id, 0), COALESCE(b.price, 0), COALESCE(b.booking_motive_id, 0),
+	b.paid, COALESCE(b.note, ''), b.confirmed, b.recurrence_id, b.no_show, b.payment_deadline
 	FROM clinician_booking b
 	LEFT JOIN patient p ON b.patient_id = p.id
-	LEFT JOIN person c ON b.clinician_person_id = c.id `
+	LEFT JOIN person c ON b.clinician_person_id = c.id
+	LEFT JOIN calendar_settings cs ON cs.person_id = c.id
+	LEFT JOIN timezone t ON t.id = cs.timezone_id
+	LEFT JOIN room rm ON rm.id = b.room_id `
 
 func scanBookingRow(row pgx.Row) (deiz.Booking, error) {
 	var b deiz.Booking
+	var paymentDeadline *time.Time
 	err := row.Scan(&b.ID, &b.Description, &b.DeleteID, &b.Start, &b.End, &b.BookingType, &b.MeetingMode,
 		&b.Clinician.ID, &b.Clinician.Surname, &b.Clinician.Name, &b.Clinician.Phone, &b.Clinician.Email,
-		&b.Patient.ID, &b.Patient.Surname, &b.Patient.Name, &b.Patient.Phone, &b.Patient.Email,
-		&b.Address, &b.Price,
-		&b.Paid, &b.Note, &b.Confirmed, &b.Recurrence)
+		&b.Clinician.Timezone.ID, &b.Clinician.Timezone.Name,
+		&b.Patient.ID, &b.Patient.Surname, &b.Patient.Name, &b.Patient.Phone, &b.Patient.Email, &b.Patient.Timezone,
+		&b.Address, &b.Room.ID, &b.Room.Name, &b.Room.AddressID, &b.Price, &b.Motive.ID,
+		&b.Paid, &b.Note, &b.Confirmed, &b.Recurrence, &b.NoShow, &paymentDeadline)
+	if paymentDeadline != nil {
+		b.PaymentDeadline = *paymentDeadline
+	}
 	return b, err
 }
 
-func updateBookingPaidStatus(ctx context.Context, db db, paid bool, bookingID int, clinicianID int) error {
-	const query = `UPDATE clinician_booking SET paid = $1 WHERE clinician_person_id = $2 AND id = $3`
-	cmdTag, err := db.Exec(ctx, query, paid, clinicianID, bookingID)
+//nullTime stores zero time as NULL
+func nullTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+//refreshBookingsPaidStatus derives paid flag of every given bookings from payments recorded,
+//failing if one of them is not found so that caller transaction is rolled back
+func refreshBookingsPaidStatus(ctx context.Context, db db, bookingIDs []int, clinicianID int) error {
+	if len(bookingIDs) == 0 {
+		return nil
+	}
+	const query = `UPDATE clinician_booking b
+	SET paid = COALESCE((SELECT SUM(p.amount) FROM payment p WHERE p.booking_id = b.id), 0) >= COALESCE(b.price, 0)
+	WHERE b.clinician_person_id = $1 AND b.id = ANY($2)`
+	cmdTag, err := db.Exec(ctx, query, clinicianID, bookingIDs)
 	if err != nil {
 		return err
 	}
-	if cmdTag.RowsAffected() == 0 {
+	if cmdTag.RowsAffected() != int64(len(bookingIDs)) {
 		return errNoRowsUpdated
 	}
 	return nil
 }
 
-func (r *Repo) GetUnpaidBookings(ctx context.Context, clinicianID int) ([]deiz.Booking, error) {
+//GetUnpaidBookings returns outstanding balances of past bookings not fully paid
+func (r *Repo) GetUnpaidBookings(ctx context.Context, clinicianID int) ([]deiz.BookingBalance, error) {
 	const query = bookingSelect + `WHERE b.paid = false AND b.confirmed = true AND LOWER(during) <= NOW() AND b.booking_type_id = 1 AND b.clinician_person_id = $1`
-	return r.queryBookingRows(ctx, query, clinicianID)
+	bookings, err := r.queryBookingRows(ctx, query, clinicianID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int, len(bookings))
+	for n, b := range bookings {
+		ids[n] = b.ID
+	}
+	amountsPaid, err := getBookingsAmountPaid(ctx, r.conn, ids)
+	if err != nil {
+		return nil, err
+	}
+	balances := make([]deiz.BookingBalance, len(bookings))
+	for n, b := range bookings {
+		balances[n].Booking = b
+		balances[n].SetBalance(amountsPaid[b.ID])
+	}
+	return balances, nil
 }
 
+//DeleteBooking removes a booking, a confirmed appointment deleted being recorded as cancelled.
+//Payments stay in the ledger, detached from the booking.
 func (r *Repo) DeleteBooking(ctx context.Context, bookingID int, clinicianID int) error {
-	const query = `DELETE FROM clinician_booking WHERE clinician_person_id = $1 AND id = $2`
-	cmdTag, err := r.conn.Exec(ctx, query, clinicianID, bookingID)
+	tx, err := r.conn.Begin(ctx)
+	defer tx.Rollback(ctx)
 	if err != nil {
 		return err
 	}
-	if cmdTag.RowsAffected() == 0 {
+	const detachQuery = `UPDATE payment SET booking_id = NULL WHERE booking_id = $1 AND person_id = $2`
+	if _, err := tx.Exec(ctx, detachQuery, bookingID, clinicianID); err != nil {
+		return err
+	}
+	const query = `WITH deleted AS (
+		DELETE FROM clinician_booking WHERE clinician_person_id = $1 AND id = $2
+		RETURNING clinician_person_id, during, booking_motive_id, confirmed, booking_type_id
+	), cancellation AS (
+		INSERT INTO booking_cancellation(person_id, during, booking_motive_id)
+		SELECT clinician_person_id, during, booking_motive_id FROM deleted
+		WHERE confirmed AND booking_type_id = 1
+	)
+	SELECT COUNT(*) FROM deleted`
+	var deleted int
+	if err := tx.QueryRow(ctx, query, clinicianID, bookingID).Scan(&deleted); err != nil {
+		return err
+	}
+	if deleted == 0 {
 		return errNothingDeleted
 	}
+	return tx.Commit(ctx)
+}
+
+func (r *Repo) GetPeriodBookingCancellations(ctx context.Context, start, end time.Time, clinicianID int) ([]deiz.BookingCancellation, error) {
+	const query = `SELECT lower(c.during), c.canceled_at, COALESCE(m.id, 0), COALESCE(m.name, '')
+	FROM booking_cancellation c LEFT JOIN booking_motive m ON m.id = c.booking_motive_id
+	WHERE c.person_id = $1 AND lower(c.during) >= $2 AND lower(c.during) < $3`
+	rows, err := r.conn.Query(ctx, query, clinicianID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	cancellations := []deiz.BookingCancellation{}
+	for rows.Next() {
+		var c deiz.BookingCancellation
+		if err := rows.Scan(&c.Start, &c.CanceledAt, &c.Motive.ID, &c.Motive.Name); err != nil {
+			return nil, err
+		}
+		cancellations = append(cancellations, c)
+	}
+	return cancellations, rows.Err()
+}
+
+//UpdateBookingNoShow flags a past confirmed appointment as not attended, or clears the flag
+func (r *Repo) UpdateBookingNoShow(ctx context.Context, bookingID int, noShow bool, clinicianID int) error {
+	const query = `UPDATE clinician_booking SET no_show = $1
+	WHERE id = $2 AND clinician_person_id = $3 AND confirmed = true AND booking_type_id = 1 AND lower(during) <= NOW()`
+	cmdTag, err := r.conn.Exec(ctx, query, noShow, bookingID, clinicianID)
+	if err != nil {
+		return err
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return errNoRowsUpdated
+	}
 	return nil
 }
 
 func (r *Repo) CreateBooking(ctx context.Context, b *deiz.Booking) error {
-	const query = `INSERT INTO clinician_booking(address, price, description, booking_type_id, meeting_mode_id, clinician_person_id, patient_id, during, paid, note, confirmed, recurrence_id)
-	VALUES(NULLIF($1, ''), $2, NULLIF($3, ''), $4, NULLIF($5, 0), $6, NULLIF($7, 0), tsrange($8, $9, '()'), $10, NULLIF($11, ''), $12, $13)
+	const query = `INSERT INTO clinician_booking(address, price, description, booking_type_id, meeting_mode_id, clinician_person_id, patient_id, during, note, confirmed, recurrence_id, booking_motive_id, room_id, payment_deadline)
+	VALUES(NULLIF($1, ''), $2, NULLIF($3, ''), $4, NULLIF($5, 0), $6, NULLIF($7, 0), tsrange($8, $9, '()'), NULLIF($10, ''), $11, $12, NULLIF($13, 0), NULLIF($14, 0), $15)
 	RETURNING id, delete_id`
-	row := r.conn.QueryRow(ctx, query, b.Address, b.Price, b.Description, b.BookingType, b.MeetingMode, b.Clinician.ID, b.Patient.ID, b.Start, b.End, b.Paid, b.Note, b.Confirmed, b.Recurrence)
+	row := r.conn.QueryRow(ctx, query, b.Address, b.Price, b.Description, b.BookingType, b.MeetingMode, b.Clinician.ID, b.Patient.ID, b.Start, b.End, b.Note, b.Confirmed, b.Recurrence, b.Motive.ID, b.Room.ID,
+		nullTime(b.PaymentDeadline))
 	err := row.Scan(&b.ID, &b.DeleteID)
 	if err != nil {
 		return err
@@ -80,7 +175,11 @@ func (r *Repo) GetBookingByDeleteID(ctx context.Context, deleteID string) (deiz.
 }
 
 func (r *Repo) GetBookingByID(ctx context.Context, bookingID int) (deiz.Booking, error) {
-	return r.queryBookingRow(ctx, bookingSelect+`WHERE b.id = $1`, bookingID)
+	b, err := r.queryBookingRow(ctx, bookingSelect+`WHERE b.id = $1`, bookingID)
+	if err == pgx.ErrNoRows {
+		return deiz.Booking{}, deiz.ErrorBookingNotFound
+	}
+	return b, err
 }
 
 func (r *Repo) queryBookingRow(ctx context.Context, query string, args ...interface{}) (deiz.Booking, error) {
@@ -129,9 +228,11 @@ func (r *Repo) GetPatientBookings(ctx context.Context, clinicianID int, patientI
 func (r *Repo) UpdateBooking(ctx context.Context, b *deiz.Booking) error {
 	const query = `UPDATE clinician_booking 
 	SET address = NULLIF($1, ''), price = COALESCE($2, 0), description = NULLIF($3, ''), booking_type_id = $4, clinician_person_id = $5, patient_id = $6,
-	during = tsrange($7, $8, '()'), paid = $9, note = NULLIF($10, ''), confirmed = $11, meeting_mode_id = $12, recurrence_id = $13 WHERE id = $14`
+	during = tsrange($7, $8, '()'), note = NULLIF($9, ''), confirmed = $10, meeting_mode_id = $11, recurrence_id = $12,
+	booking_motive_id = NULLIF($13, 0), room_id = NULLIF($14, 0),
+	payment_deadline = CASE WHEN $10 THEN NULL ELSE payment_deadline END WHERE id = $15`
 	cmdTag, err := r.conn.Exec(ctx, query, b.Address, b.Price, b.Description, b.BookingType, b.Clinician.ID, b.Patient.ID,
-		b.Start, b.End, b.Paid, b.Note, b.Confirmed, b.MeetingMode, b.Recurrence, b.ID)
+		b.Start, b.End, b.Note, b.Confirmed, b.MeetingMode, b.Recurrence, b.Motive.ID, b.Room.ID, b.ID)
 	if err != nil {
 		return err
 	}
@@ -141,6 +242,29 @@ func (r *Repo) UpdateBooking(ctx context.Context, b *deiz.Booking) error {
 	return nil
 }
 
+//ConfirmHeldBooking confirms a slot held until patient prepays it
+func (r *Repo) ConfirmHeldBooking(ctx context.Context, bookingID int, clinicianID int) error {
+	const query = `UPDATE clinician_booking SET confirmed = true, payment_deadline = NULL
+	WHERE id = $1 AND clinician_person_id = $2 AND confirmed = false AND payment_deadline IS NOT NULL`
+	cmdTag, err := r.conn.Exec(ctx, query, bookingID, clinicianID)
+	if err != nil {
+		return err
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return errNoRowsUpdated
+	}
+	return nil
+}
+
+//DeleteExpiredBookingHolds releases slots held for a prepayment never made before deadline.
+//A hold with a payment recorded is kept, its payment webhook confirming it.
+func (r *Repo) DeleteExpiredBookingHolds(ctx context.Context, d time.Time) error {
+	const query = `DELETE FROM clinician_booking b WHERE b.confirmed = false AND b.payment_deadline < $1
+	AND NOT EXISTS (SELECT 1 FROM payment p WHERE p.booking_id = b.id)`
+	_, err := r.conn.Exec(ctx, query, d)
+	return err
+}
+
 func (r *Repo) DeleteBlockedBookingPrior(ctx context.Context, d time.Time) error {
 	const query = `DELETE FROM clinician_booking WHERE booking_type_id = 0 AND upper(during) < $1`
 	_, err := r.conn.Exec(ctx, query, d)
@@ -36,6 +36,10 @@ func getBookingMotivesByPersonID(ctx context.Context, db db, clinicianID int) ([
 	return motives, nil
 }
 
+func (r *Repo) GetClinicianBookingMotives(ctx context.Context, clinicianID int) ([]deiz.BookingMotive, error) {
+	return getBookingMotivesByPersonID(ctx, r.conn, clinicianID)
+}
+
 func (r *Repo) UpdateBookingMotive(ctx context.Context, m *deiz.BookingMotive, clinicianID int) error {
 	const query = `UPDATE booking_motive SET duration = $1, price = $2, name = $3, public = $4 WHERE id = $5 AND person_id = $6`
 	tag, err := r.conn.Exec(ctx, query, m.Duration, m.Price, m.Name, m.Public, m.ID, clinicianID)
@@ -17,6 +17,7 @@ func insertBusiness(ctx context.Context, db db, b *deiz.Business, personID int)
 
 func getBusinessByPersonID(ctx context.Context, db db, personID int) (deiz.Business, error) {
 	const query = `SELECT b.id, COALESCE(b.name, ''), COALESCE(b.identifier, ''),
+	COALESCE(b.invoice_prefix, ''), b.invoice_yearly_reset,
 	COALESCE(t.id, 0), COALESCE(t.code, ''),
     COALESCE(a.id, 0), COALESCE(a.line, ''), COALESCE(a.post_code, 0), COALESCE(a.city, '')
 	FROM business b
@@ -25,7 +26,9 @@ func getBusinessByPersonID(ctx context.Context, db db, personID int) (deiz.Busin
 	WHERE b.person_id = $1`
 	row := db.QueryRow(ctx, query, personID)
 	b := deiz.Business{}
-	err := row.Scan(&b.ID, &b.Name, &b.Identifier, &b.TaxExemption.ID, &b.TaxExemption.Code,
+	err := row.Scan(&b.ID, &b.Name, &b.Identifier,
+		&b.InvoiceNumbering.Prefix, &b.InvoiceNumbering.YearlyReset,
+		&b.TaxExemption.ID, &b.TaxExemption.Code,
 		&b.Address.ID, &b.Address.Line, &b.Address.PostCode, &b.Address.City)
 	if err != nil {
 		return deiz.Business{}, err
@@ -38,8 +41,10 @@ func (r *Repo) GetClinicianBusiness(ctx context.Context, clinicianID int) (deiz.
 }
 
 func (r *Repo) UpdateClinicianBusiness(ctx context.Context, b *deiz.Business, clinicianID int) error {
-	const query = `UPDATE business SET name = $1, identifier = $2, tax_exemption_id = NULLIF($3, 0) WHERE person_id = $4`
-	cmdTag, err := r.conn.Exec(ctx, query, b.Name, b.Identifier, b.TaxExemption.ID, clinicianID)
+	const query = `UPDATE business SET name = $1, identifier = $2, tax_exemption_id = NULLIF($3, 0),
+	invoice_prefix = NULLIF($4, ''), invoice_yearly_reset = $5 WHERE person_id = $6`
+	cmdTag, err := r.conn.Exec(ctx, query, b.Name, b.Identifier, b.TaxExemption.ID,
+		b.InvoiceNumbering.Prefix, b.InvoiceNumbering.YearlyReset, clinicianID)
 	if err != nil {
 		return err
 	}
@@ -6,7 +6,8 @@ import (
 )
 
 func getCalendarSettingsByPersonID(ctx context.Context, db db, personID int) (deiz.CalendarSettings, error) {
-	const query = `SELECT s.id, s.remote_allowed, s.new_patient_allowed,
+	const query = `SELECT s.id, s.remote_allowed, s.new_patient_allowed, s.slot_granularity, s.listed, s.prepayment_mode, s.deposit_percent,
+	s.cancellation_notice_hours, s.late_refund_percent,
 	COALESCE(m.id, 0), COALESCE(m.duration, 60), COALESCE(m.price, 5000), COALESCE(m.name, 'Défaut'), COALESCE(m.public, false),
 	t.id, t.name
 	FROM calendar_settings s
@@ -15,7 +16,8 @@ func getCalendarSettingsByPersonID(ctx context.Context, db db, personID int) (de
 	WHERE s.person_id = $1`
 	row := db.QueryRow(ctx, query, personID)
 	var s deiz.CalendarSettings
-	err := row.Scan(&s.ID, &s.RemoteAllowed, &s.NewPatientAllowed,
+	err := row.Scan(&s.ID, &s.RemoteAllowed, &s.NewPatientAllowed, &s.SlotGranularity, &s.Listed, &s.Prepayment.Mode, &s.Prepayment.DepositPercent,
+		&s.Cancellation.NoticeHours, &s.Cancellation.LateRefundPercent,
 		&s.DefaultMotive.ID, &s.DefaultMotive.Duration, &s.DefaultMotive.Price, &s.DefaultMotive.Name, &s.DefaultMotive.Public,
 		&s.Timezone.ID, &s.Timezone.Name)
 	if err != nil {
@@ -31,8 +33,11 @@ func insertCalendarSettings(ctx context.Context, db db, s *deiz.CalendarSettings
 }
 
 func (r *Repo) UpdateCalendarSettings(ctx context.Context, s *deiz.CalendarSettings, clinicianID int) error {
-	const query = `UPDATE calendar_settings SET default_booking_motive_id = NULLIF($1, 0), remote_allowed = $2, new_patient_allowed = $3 WHERE person_id = $4`
-	tag, err := r.conn.Exec(ctx, query, s.DefaultMotive.ID, s.RemoteAllowed, s.NewPatientAllowed, clinicianID)
+	const query = `UPDATE calendar_settings SET default_booking_motive_id = NULLIF($1, 0), remote_allowed = $2, new_patient_allowed = $3,
+	slot_granularity = $4, timezone_id = $5, listed = $6, prepayment_mode = $7, deposit_percent = $8,
+	cancellation_notice_hours = $9, late_refund_percent = $10 WHERE person_id = $11`
+	tag, err := r.conn.Exec(ctx, query, s.DefaultMotive.ID, s.RemoteAllowed, s.NewPatientAllowed, s.SlotGranularity, s.Timezone.ID, s.Listed,
+		s.Prepayment.Mode, s.Prepayment.DepositPercent, s.Cancellation.NoticeHours, s.Cancellation.LateRefundPercent, clinicianID)
 	if err != nil {
 		return err
 	}
@@ -79,10 +79,18 @@ func (r *Repo) GetClinicianAccount(ctx context.Context, clinicianID int) (deiz.C
 		return deiz.ClinicianAccount{}, fmt.Errorf("unable to get person stripe keys: %s", err)
 	}
 	acc.StripePublicKey = keys.public
+	acc.StripeAccount, err = getStripeAccountByPersonID(ctx, r.conn, clinicianID)
+	if err != nil {
+		return deiz.ClinicianAccount{}, fmt.Errorf("unable to get person stripe account: %s", err)
+	}
 	acc.OfficeHours, err = r.GetClinicianOfficeHours(ctx, clinicianID)
 	if err != nil {
 		return deiz.ClinicianAccount{}, fmt.Errorf("unable to get clinician office hours: %s", err)
 	}
+	acc.ExtraAvailabilities, err = getUpcomingExtraAvailabilitiesByPersonID(ctx, r.conn, clinicianID)
+	if err != nil {
+		return deiz.ClinicianAccount{}, fmt.Errorf("unable to get clinician extra availabilities: %s", err)
+	}
 	acc.BookingMotives, err = getBookingMotivesByPersonID(ctx, r.conn, clinicianID)
 	if err != nil {
 		return deiz.ClinicianAccount{}, fmt.Errorf("unable to get booking motives: %s", err)
@@ -0,0 +1,51 @@
+package psql
+
+import (
+	"context"
+	"github.com/audrenbdb/deiz"
+)
+
+//SearchDirectory lists clinicians who opted in to the public directory and match given filter
+func (r *Repo) SearchDirectory(ctx context.Context, f deiz.DirectoryFilter) ([]deiz.DirectoryEntry, error) {
+	const query = `SELECT p.id, p.name, p.surname, COALESCE(p.profession, ''),
+	COALESCE(t.id, 0), COALESCE(t.name, ''),
+	COALESCE(m.id, 0), COALESCE(m.duration, 60), COALESCE(m.price, 5000), COALESCE(m.name, 'Défaut')
+	FROM person p
+	INNER JOIN calendar_settings cs ON cs.person_id = p.id AND cs.listed = true
+	LEFT JOIN timezone t ON t.id = cs.timezone_id
+	LEFT JOIN booking_motive m ON m.id = cs.default_booking_motive_id
+	WHERE ($1 = '' OR p.profession ILIKE '%' || $1 || '%')
+	AND ($2 = '' OR EXISTS(SELECT 1 FROM office_address o INNER JOIN address a ON a.id = o.address_id
+		WHERE o.person_id = p.id AND a.city ILIKE $2))
+	AND ($3 = 0 OR EXISTS(SELECT 1 FROM office_address o INNER JOIN address a ON a.id = o.address_id
+		WHERE o.person_id = p.id AND a.post_code = $3))
+	AND (NOT $4 OR EXISTS(SELECT 1 FROM office_hours h WHERE h.person_id = p.id AND h.meeting_mode_id = $5))
+	ORDER BY p.surname, p.name, p.id LIMIT $6 OFFSET $7`
+	rows, err := r.conn.Query(ctx, query, f.Profession, f.City, f.PostCode, f.FilterMeetingMode, f.MeetingMode,
+		deiz.DirectoryPageSize, f.Page*deiz.DirectoryPageSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	entries := []deiz.DirectoryEntry{}
+	for rows.Next() {
+		var e deiz.DirectoryEntry
+		err := rows.Scan(&e.Clinician.ID, &e.Clinician.Name, &e.Clinician.Surname, &e.Clinician.Profession,
+			&e.Clinician.Timezone.ID, &e.Clinician.Timezone.Name,
+			&e.DefaultMotive.ID, &e.DefaultMotive.Duration, &e.DefaultMotive.Price, &e.DefaultMotive.Name)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		entries[i].OfficeAddresses, err = getOfficeAddressesByPersonID(ctx, r.conn, entries[i].Clinician.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
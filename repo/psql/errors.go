@@ -1,5 +1,10 @@
 package psql
 
+import (
+	"errors"
+	"github.com/jackc/pgconn"
+)
+
 type Error string
 
 func (e Error) Error() string {
@@ -10,3 +15,11 @@ const errNoRowsUpdated Error = "no rows updated"
 const errNothingDeleted Error = "nothing deleted"
 const errUnauthorized Error = "unauthorized"
 const errNoRowsCreated Error = "no rows created"
+
+//uniqueViolationCode is the postgres error code of a row breaking a unique constraint
+const uniqueViolationCode = "23505"
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode
+}
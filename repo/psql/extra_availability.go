@@ -0,0 +1,64 @@
+package psql
+
+import (
+	"context"
+	"github.com/audrenbdb/deiz"
+	"time"
+)
+
+const extraAvailabilitySelect = `SELECT e.id, lower(e.during), upper(e.during), e.meeting_mode_id,
+	COALESCE(a.id, 0), COALESCE(a.line, ''), COALESCE(a.post_code, 0), COALESCE(a.city, '')
+	FROM extra_availability e
+	LEFT JOIN address a ON e.address_id = a.id `
+
+func queryExtraAvailabilities(ctx context.Context, db db, query string, args ...interface{}) ([]deiz.ExtraAvailability, error) {
+	rows, err := db.Query(ctx, query, args...)
+	defer rows.Close()
+	if err != nil {
+		return nil, err
+	}
+	availabilities := []deiz.ExtraAvailability{}
+	for rows.Next() {
+		var e deiz.ExtraAvailability
+		err := rows.Scan(&e.ID, &e.Start, &e.End, &e.MeetingMode,
+			&e.Address.ID, &e.Address.Line, &e.Address.PostCode, &e.Address.City)
+		if err != nil {
+			return nil, err
+		}
+		availabilities = append(availabilities, e)
+	}
+	return availabilities, nil
+}
+
+func getUpcomingExtraAvailabilitiesByPersonID(ctx context.Context, db db, personID int) ([]deiz.ExtraAvailability, error) {
+	const query = extraAvailabilitySelect + `WHERE e.person_id = $1 AND upper(e.during) > NOW() ORDER BY lower(e.during) ASC`
+	return queryExtraAvailabilities(ctx, db, query, personID)
+}
+
+func (r *Repo) GetClinicianExtraAvailabilitiesInTimeRange(ctx context.Context, start, end time.Time, clinicianID int) ([]deiz.ExtraAvailability, error) {
+	const query = extraAvailabilitySelect + `WHERE e.person_id = $1 AND $2 < upper(e.during) AND lower(e.during) < $3 ORDER BY lower(e.during) ASC`
+	return queryExtraAvailabilities(ctx, r.conn, query, clinicianID, start, end)
+}
+
+func (r *Repo) GetClinicianUpcomingExtraAvailabilities(ctx context.Context, clinicianID int) ([]deiz.ExtraAvailability, error) {
+	return getUpcomingExtraAvailabilitiesByPersonID(ctx, r.conn, clinicianID)
+}
+
+func (r *Repo) CreateExtraAvailability(ctx context.Context, e *deiz.ExtraAvailability, clinicianID int) error {
+	const query = `INSERT INTO extra_availability(person_id, during, address_id, meeting_mode_id)
+	VALUES($1, tsrange($2, $3, '[)'), NULLIF($4, 0), $5) RETURNING id`
+	row := r.conn.QueryRow(ctx, query, clinicianID, e.Start, e.End, e.Address.ID, e.MeetingMode)
+	return row.Scan(&e.ID)
+}
+
+func (r *Repo) DeleteExtraAvailability(ctx context.Context, availabilityID, clinicianID int) error {
+	const query = `DELETE FROM extra_availability WHERE id = $1 AND person_id = $2`
+	cmdTag, err := r.conn.Exec(ctx, query, availabilityID, clinicianID)
+	if err != nil {
+		return err
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return errNothingDeleted
+	}
+	return nil
+}
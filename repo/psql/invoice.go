@@ -3,11 +3,12 @@ package psql
 import (
 	"context"
 	"github.com/audrenbdb/deiz"
+	"github.com/jackc/pgx/v4"
 	"time"
 )
 
 func setInvoiceCanceled(ctx context.Context, db db, invoiceID int, clinicianID int) error {
-	const query = `UPDATE booking_invoice SET canceled = true WHERE id = $1 AND person_id = $2`
+	const query = `UPDATE booking_invoice SET canceled = true WHERE id = $1 AND person_id = $2 AND canceled = false`
 	cmdTag, err := db.Exec(ctx, query, invoiceID, clinicianID)
 	if err != nil {
 		return err
@@ -48,18 +49,51 @@ func (r *Repo) GetBookingsPendingPayment(ctx context.Context, clinicianID int) (
 	return bookings, nil
 }
 
-func (r *Repo) GetPeriodBookingInvoices(ctx context.Context, start time.Time, end time.Time, clinicianID int) ([]deiz.BookingInvoice, error) {
-	const query = `SELECT
-	i.id, i.created_at, i.identifier, i.sender, i.recipient,
+//SaveInvoiceArchive records where the PDF issued for an invoice is archived.
+//An archive once set is never replaced.
+func (r *Repo) SaveInvoiceArchive(ctx context.Context, invoiceID int, clinicianID int, a deiz.InvoiceArchive) error {
+	return setInvoiceArchive(ctx, r.conn, invoiceID, clinicianID, a)
+}
+
+func setInvoiceArchive(ctx context.Context, db db, invoiceID int, clinicianID int, a deiz.InvoiceArchive) error {
+	const query = `UPDATE booking_invoice SET archive_key = $1, archive_sha256 = $2
+	WHERE id = $3 AND person_id = $4 AND archive_key IS NULL`
+	cmdTag, err := db.Exec(ctx, query, a.Key, a.SHA256, invoiceID, clinicianID)
+	if err != nil {
+		return err
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return errNoRowsUpdated
+	}
+	return nil
+}
+
+const invoiceSelect = `SELECT
+	i.id, i.person_id, i.created_at, i.identifier, i.sender, i.recipient,
 	i.city_and_date, i.delivery_date,
 	i.delivery_date_str, i.label, i.price_before_tax, i.price_after_tax, i.tax_fee,
-	COALESCE(i.exemption, ''), i.canceled,
-	pm.id, pm.name,
-	COALESCE(bp.id, 0), COALESCE(bp.name, ''), COALESCE(bp.surname, ''), COALESCE(bp.phone, ''), COALESCE(bp.email, '')
+	COALESCE(i.exemption, ''), i.canceled, i.kind,
+	COALESCE(ci.id, 0), COALESCE(ci.identifier, ''),
+	COALESCE(i.archive_key, ''), COALESCE(i.archive_sha256, ''),
+	pm.id, pm.name, pm.account,
+	COALESCE(b.id, 0), COALESCE(bp.id, 0), COALESCE(bp.name, ''), COALESCE(bp.surname, ''), COALESCE(bp.phone, ''), COALESCE(bp.email, '')
 	FROM booking_invoice i INNER JOIN payment_method pm ON i.payment_method_id = pm.id
+	LEFT JOIN booking_invoice ci ON ci.id = i.canceled_invoice_id
 	LEFT JOIN clinician_booking b ON i.booking_id = b.id
-	LEFT JOIN patient bp ON bp.id = b.patient_id
-	WHERE i.person_id = $3 AND i.delivery_date >= $1 AND i.delivery_date <= $2`
+	LEFT JOIN patient bp ON bp.id = b.patient_id `
+
+func scanInvoiceRow(row pgx.Row) (deiz.BookingInvoice, error) {
+	var i deiz.BookingInvoice
+	err := row.Scan(&i.ID, &i.ClinicianID, &i.CreatedAt, &i.Identifier, &i.Sender, &i.Recipient, &i.CityAndDate, &i.DeliveryDate, &i.DeliveryDateStr,
+		&i.Label, &i.PriceBeforeTax, &i.PriceAfterTax, &i.TaxFee, &i.Exemption, &i.Canceled, &i.Kind,
+		&i.CanceledInvoice.ID, &i.CanceledInvoice.Identifier, &i.Archive.Key, &i.Archive.SHA256, &i.PaymentMethod.ID, &i.PaymentMethod.Name, &i.PaymentMethod.Account,
+		&i.Booking.ID, &i.Booking.Patient.ID, &i.Booking.Patient.Name, &i.Booking.Patient.Surname, &i.Booking.Patient.Phone, &i.Booking.Patient.Email)
+	return i, err
+}
+
+//GetPeriodBookingInvoices returns invoices issued within period, as journal entries are dated by issue
+func (r *Repo) GetPeriodBookingInvoices(ctx context.Context, start time.Time, end time.Time, clinicianID int) ([]deiz.BookingInvoice, error) {
+	const query = invoiceSelect + `WHERE i.person_id = $3 AND i.created_at >= $1 AND i.created_at <= $2`
 	rows, err := r.conn.Query(ctx, query, start, end, clinicianID)
 	defer rows.Close()
 	if err != nil {
@@ -67,73 +101,206 @@ func (r *Repo) GetPeriodBookingInvoices(ctx context.Context, start time.Time, en
 	}
 	var invoices []deiz.BookingInvoice
 	for rows.Next() {
-		var i deiz.BookingInvoice
-		err := rows.Scan(&i.ID, &i.CreatedAt, &i.Identifier, &i.Sender, &i.Recipient, &i.CityAndDate, &i.DeliveryDate, &i.DeliveryDateStr,
-			&i.Label, &i.PriceBeforeTax, &i.PriceAfterTax, &i.TaxFee, &i.Exemption, &i.Canceled, &i.PaymentMethod.ID, &i.PaymentMethod.Name,
-			&i.Booking.Patient.ID, &i.Booking.Patient.Name, &i.Booking.Patient.Surname, &i.Booking.Patient.Phone, &i.Booking.Patient.Email)
+		i, err := scanInvoiceRow(rows)
 		if err != nil {
 			return nil, err
 		}
 		invoices = append(invoices, i)
 	}
-	return invoices, nil
+	return invoices, setInvoicesLines(ctx, r.conn, invoices)
+}
+
+//GetBookingInvoiceByID returns a stored invoice of given clinician
+func (r *Repo) GetBookingInvoiceByID(ctx context.Context, invoiceID int, clinicianID int) (deiz.BookingInvoice, error) {
+	const query = invoiceSelect + `WHERE i.id = $1 AND i.person_id = $2`
+	i, err := scanInvoiceRow(r.conn.QueryRow(ctx, query, invoiceID, clinicianID))
+	if err != nil {
+		return deiz.BookingInvoice{}, err
+	}
+	invoices := []deiz.BookingInvoice{i}
+	if err := setInvoicesLines(ctx, r.conn, invoices); err != nil {
+		return deiz.BookingInvoice{}, err
+	}
+	return invoices[0], nil
 }
 
 func insertBookingInvoice(ctx context.Context, db db, i *deiz.BookingInvoice) error {
 	const query = `INSERT INTO booking_invoice
 	(person_id, booking_id, created_at, identifier, sender, recipient,
 	city_and_date, label, price_before_tax, price_after_tax, delivery_date,
-	delivery_date_str, tax_fee, exemption, payment_method_id)
-	VALUES($1, NULLIF($2, 0), $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15) RETURNING id`
+	delivery_date_str, tax_fee, exemption, payment_method_id, kind, canceled_invoice_id)
+	VALUES($1, NULLIF($2, 0), $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, NULLIF($17, 0)) RETURNING id`
 	row := db.QueryRow(ctx, query, i.ClinicianID, i.Booking.ID, i.CreatedAt, i.Identifier, i.Sender,
 		i.Recipient, i.CityAndDate, i.Label, i.PriceBeforeTax, i.PriceAfterTax, i.DeliveryDate,
-		i.DeliveryDateStr, i.TaxFee, i.Exemption, i.PaymentMethod.ID)
-	err := row.Scan(&i.ID)
-	return err
+		i.DeliveryDateStr, i.TaxFee, i.Exemption, i.PaymentMethod.ID, i.Kind, i.CanceledInvoice.ID)
+	if err := row.Scan(&i.ID); err != nil {
+		return err
+	}
+	return insertInvoiceLines(ctx, db, i)
+}
+
+func insertInvoiceLines(ctx context.Context, db db, i *deiz.BookingInvoice) error {
+	const query = `INSERT INTO invoice_line
+	(invoice_id, booking_id, position, label, delivery_date, delivery_date_str, price_before_tax, price_after_tax)
+	VALUES($1, NULLIF($2, 0), $3, $4, $5, $6, $7, $8) RETURNING id`
+	for n := range i.Lines {
+		l := &i.Lines[n]
+		row := db.QueryRow(ctx, query, i.ID, l.Booking.ID, n, l.Label, l.DeliveryDate,
+			l.DeliveryDateStr, l.PriceBeforeTax, l.PriceAfterTax)
+		if err := row.Scan(&l.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//getInvoicesLines maps lines of given invoices to their invoice id.
+//Patient billed is resolved from line bookings, invoices of several lines being related to no booking.
+func getInvoicesLines(ctx context.Context, db db, invoiceIDs []int) (map[int][]deiz.InvoiceLine, error) {
+	const query = `SELECT l.invoice_id, l.id, COALESCE(l.booking_id, 0),
+	COALESCE(b.meeting_mode_id, 0), COALESCE(m.id, 0), COALESCE(m.name, ''),
+	COALESCE(bp.id, 0), COALESCE(bp.name, ''), COALESCE(bp.surname, ''), COALESCE(bp.phone, ''), COALESCE(bp.email, ''),
+	l.label, l.delivery_date, l.delivery_date_str, l.price_before_tax, l.price_after_tax
+	FROM invoice_line l
+	LEFT JOIN clinician_booking b ON b.id = l.booking_id
+	LEFT JOIN booking_motive m ON m.id = b.booking_motive_id
+	LEFT JOIN patient bp ON bp.id = b.patient_id
+	WHERE l.invoice_id = ANY($1) ORDER BY l.invoice_id, l.position`
+	rows, err := db.Query(ctx, query, invoiceIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	lines := map[int][]deiz.InvoiceLine{}
+	for rows.Next() {
+		var invoiceID int
+		var l deiz.InvoiceLine
+		err := rows.Scan(&invoiceID, &l.ID, &l.Booking.ID,
+			&l.Booking.MeetingMode, &l.Booking.Motive.ID, &l.Booking.Motive.Name,
+			&l.Booking.Patient.ID, &l.Booking.Patient.Name, &l.Booking.Patient.Surname, &l.Booking.Patient.Phone, &l.Booking.Patient.Email, &l.Label,
+			&l.DeliveryDate, &l.DeliveryDateStr, &l.PriceBeforeTax, &l.PriceAfterTax)
+		if err != nil {
+			return nil, err
+		}
+		lines[invoiceID] = append(lines[invoiceID], l)
+	}
+	return lines, rows.Err()
 }
 
-func (r *Repo) SaveBookingInvoice(ctx context.Context, i *deiz.BookingInvoice) error {
+//SaveBookingInvoice records an invoice, archive having its PDF archived within the same transaction.
+//An invoice is never stored without its archive.
+func (r *Repo) SaveBookingInvoice(ctx context.Context, i *deiz.BookingInvoice, archive func(i *deiz.BookingInvoice) error) error {
 	tx, err := r.conn.Begin(ctx)
 	defer tx.Rollback(ctx)
 	if err != nil {
 		return err
 	}
+	if err := setNextInvoiceIdentifier(ctx, tx, i); err != nil {
+		return err
+	}
 	err = insertBookingInvoice(ctx, tx, i)
 	if err != nil {
 		return err
 	}
-	err = updateBookingPaidStatus(ctx, tx, true, i.Booking.ID, i.ClinicianID)
+	err = archiveInvoice(ctx, tx, i, archive)
+	if err != nil {
+		return err
+	}
+	err = insertInvoicePayments(ctx, tx, i)
+	if err != nil {
+		return err
+	}
+	err = refreshBookingsPaidStatus(ctx, tx, i.BookingIDs(), i.ClinicianID)
 	if err != nil {
 		return err
 	}
 	return tx.Commit(ctx)
 }
 
-func (r *Repo) SaveCorrectingBookingInvoice(ctx context.Context, i *deiz.BookingInvoice) error {
-	originalInvoiceID := i.ID
+//SaveCreditNote records a credit note and marks the invoice it cancels as such.
+//Payments recorded with cancelled invoice are reversed, its bookings going back to unpaid.
+//Credit note PDF is archived within the same transaction.
+func (r *Repo) SaveCreditNote(ctx context.Context, c *deiz.BookingInvoice, archive func(c *deiz.BookingInvoice) error) error {
 	tx, err := r.conn.Begin(ctx)
 	defer tx.Rollback(ctx)
 	if err != nil {
 		return err
 	}
-	err = insertBookingInvoice(ctx, tx, i)
+	err = setInvoiceCanceled(ctx, tx, c.CanceledInvoice.ID, c.ClinicianID)
+	if err != nil {
+		return err
+	}
+	if err := setNextInvoiceIdentifier(ctx, tx, c); err != nil {
+		return err
+	}
+	err = insertBookingInvoice(ctx, tx, c)
+	if err != nil {
+		return err
+	}
+	err = archiveInvoice(ctx, tx, c, archive)
+	if err != nil {
+		return err
+	}
+	err = reverseInvoicePayments(ctx, tx, c)
 	if err != nil {
 		return err
 	}
-	err = setInvoiceCanceled(ctx, tx, originalInvoiceID, i.ClinicianID)
+	err = refreshBookingsPaidStatus(ctx, tx, c.BookingIDs(), c.ClinicianID)
 	if err != nil {
 		return err
 	}
 	return tx.Commit(ctx)
 }
 
-func (r *Repo) CountClinicianInvoices(ctx context.Context, clinicianID int) (int, error) {
-	const query = `SELECT COUNT(*) FROM booking_invoice WHERE person_id = $1`
-	var count int
-	row := r.conn.QueryRow(ctx, query, clinicianID)
-	err := row.Scan(&count)
+//archiveInvoice has PDF of an inserted invoice archived, recording its archive within the transaction
+func archiveInvoice(ctx context.Context, tx pgx.Tx, i *deiz.BookingInvoice, archive func(i *deiz.BookingInvoice) error) error {
+	if err := archive(i); err != nil {
+		return err
+	}
+	return setInvoiceArchive(ctx, tx, i.ID, i.ClinicianID, i.Archive)
+}
+
+//setNextInvoiceIdentifier takes next number of clinician invoice sequence, one sequence per prefix and year.
+//Sequence row stays locked until the transaction inserting the invoice ends,
+//a rollback releasing the number.
+func setNextInvoiceIdentifier(ctx context.Context, tx pgx.Tx, i *deiz.BookingInvoice) error {
+	b, err := getBusinessByPersonID(ctx, tx, i.ClinicianID)
+	if err != nil && err != pgx.ErrNoRows {
+		return err
+	}
+	const query = `INSERT INTO invoice_sequence(person_id, prefix, year, last_number) VALUES($1, $2, $3, 1)
+	ON CONFLICT (person_id, prefix, year) DO UPDATE SET last_number = invoice_sequence.last_number + 1
+	RETURNING last_number`
+	year := invoiceYear(i)
+	prefix, sequenceYear := b.InvoiceNumbering.Sequence(i.ClinicianID, year)
+	var number int
+	err = tx.QueryRow(ctx, query, i.ClinicianID, prefix, sequenceYear).Scan(&number)
+	if err != nil {
+		return err
+	}
+	i.Identifier = b.InvoiceNumbering.Identifier(i.ClinicianID, year, number)
+	return nil
+}
+
+//invoiceYear is the civil year an invoice is numbered in
+func invoiceYear(i *deiz.BookingInvoice) int {
+	if i.CreatedAt.IsZero() {
+		return time.Now().UTC().Year()
+	}
+	return i.CreatedAt.Year()
+}
+
+func setInvoicesLines(ctx context.Context, db db, invoices []deiz.BookingInvoice) error {
+	ids := make([]int, len(invoices))
+	for n, i := range invoices {
+		ids[n] = i.ID
+	}
+	lines, err := getInvoicesLines(ctx, db, ids)
 	if err != nil {
-		return 0, err
+		return err
 	}
-	return count, nil
+	for n := range invoices {
+		invoices[n].Lines = lines[invoices[n].ID]
+	}
+	return nil
 }
@@ -7,9 +7,13 @@ import (
 
 func (r *Repo) GetClinicianOfficeHours(ctx context.Context, clinicianID int) ([]deiz.OfficeHours, error) {
 	const query = `SELECT h.id, h.start_mn, h.end_mn, h.week_day, h.meeting_mode_id,
-	COALESCE(a.id, 0), COALESCE(a.line, ''), COALESCE(a.post_code, 0), COALESCE(a.city, '')
+	COALESCE(h.valid_from, '0001-01-01'), COALESCE(h.valid_until, '0001-01-01'),
+	ARRAY(SELECT m.booking_motive_id FROM office_hours_motive m WHERE m.office_hours_id = h.id),
+	COALESCE(a.id, 0), COALESCE(a.line, ''), COALESCE(a.post_code, 0), COALESCE(a.city, ''),
+	COALESCE(rm.id, 0), COALESCE(rm.name, ''), COALESCE(rm.address_id, 0)
 	FROM office_hours h
 	LEFT JOIN address a ON h.address_id = a.id
+	LEFT JOIN room rm ON h.room_id = rm.id
 	WHERE h.person_id = $1`
 	rows, err := r.conn.Query(ctx, query, clinicianID)
 	defer rows.Close()
@@ -20,7 +24,9 @@ func (r *Repo) GetClinicianOfficeHours(ctx context.Context, clinicianID int) ([]
 	for rows.Next() {
 		var h deiz.OfficeHours
 		err := rows.Scan(&h.ID, &h.StartMn, &h.EndMn, &h.WeekDay, &h.MeetingMode,
-			&h.Address.ID, &h.Address.Line, &h.Address.PostCode, &h.Address.City)
+			&h.ValidFrom, &h.ValidUntil, &h.MotiveIDs,
+			&h.Address.ID, &h.Address.Line, &h.Address.PostCode, &h.Address.City,
+			&h.Room.ID, &h.Room.Name, &h.Room.AddressID)
 		if err != nil {
 			return nil, err
 		}
@@ -29,11 +35,97 @@ func (r *Repo) GetClinicianOfficeHours(ctx context.Context, clinicianID int) ([]
 	return hours, nil
 }
 
+func insertOfficeHours(ctx context.Context, db db, h *deiz.OfficeHours, clinicianID int) error {
+	const query = `INSERT INTO office_hours(start_mn, end_mn, week_day, address_id, person_id, meeting_mode_id, valid_from, valid_until, room_id)
+	VALUES($1, $2, $3, NULLIF($4, 0), $5, $6, NULLIF($7::date, '0001-01-01'), NULLIF($8::date, '0001-01-01'),
+	(SELECT id FROM room WHERE id = $9 AND address_id = $4)) RETURNING id`
+	row := db.QueryRow(ctx, query, h.StartMn, h.EndMn, h.WeekDay, h.Address.ID, clinicianID, h.MeetingMode, h.ValidFrom, h.ValidUntil, h.Room.ID)
+	if err := row.Scan(&h.ID); err != nil {
+		return err
+	}
+	return insertOfficeHoursMotives(ctx, db, h, clinicianID)
+}
+
+//insertOfficeHoursMotives restricts office hours to given motives, ignoring motives not owned by the clinician
+func insertOfficeHoursMotives(ctx context.Context, db db, h *deiz.OfficeHours, clinicianID int) error {
+	if len(h.MotiveIDs) == 0 {
+		return nil
+	}
+	const query = `INSERT INTO office_hours_motive(office_hours_id, booking_motive_id)
+	SELECT $1, id FROM booking_motive WHERE id = ANY($2) AND person_id = $3`
+	_, err := db.Exec(ctx, query, h.ID, h.MotiveIDs, clinicianID)
+	return err
+}
+
+func deleteOfficeHoursMotives(ctx context.Context, db db, hoursID int) error {
+	const query = `DELETE FROM office_hours_motive WHERE office_hours_id = $1`
+	_, err := db.Exec(ctx, query, hoursID)
+	return err
+}
+
+func updateOfficeHours(ctx context.Context, db db, h *deiz.OfficeHours, clinicianID int) error {
+	const query = `UPDATE office_hours SET start_mn = $1, end_mn = $2, week_day = $3, address_id = NULLIF($4, 0), meeting_mode_id = $5,
+	valid_from = NULLIF($6::date, '0001-01-01'), valid_until = NULLIF($7::date, '0001-01-01'),
+	room_id = (SELECT id FROM room WHERE id = $10 AND address_id = $4)
+	WHERE id = $8 AND person_id = $9`
+	cmdTag, err := db.Exec(ctx, query, h.StartMn, h.EndMn, h.WeekDay, h.Address.ID, h.MeetingMode,
+		h.ValidFrom, h.ValidUntil, h.ID, clinicianID, h.Room.ID)
+	if err != nil {
+		return err
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return errNoRowsUpdated
+	}
+	return nil
+}
+
 func (r *Repo) CreateOfficeHours(ctx context.Context, h *deiz.OfficeHours, clinicianID int) error {
-	const query = `INSERT INTO office_hours(start_mn, end_mn, week_day, address_id, person_id, meeting_mode_id)
-	VALUES($1, $2, $3, NULLIF($4, 0), $5, $6) RETURNING id`
-	row := r.conn.QueryRow(ctx, query, h.StartMn, h.EndMn, h.WeekDay, h.Address.ID, clinicianID, h.MeetingMode)
-	return row.Scan(&h.ID)
+	tx, err := r.conn.Begin(ctx)
+	defer tx.Rollback(ctx)
+	if err != nil {
+		return err
+	}
+	if err := insertOfficeHours(ctx, tx, h, clinicianID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *Repo) UpdateOfficeHours(ctx context.Context, h *deiz.OfficeHours, clinicianID int) error {
+	tx, err := r.conn.Begin(ctx)
+	defer tx.Rollback(ctx)
+	if err != nil {
+		return err
+	}
+	if err := updateOfficeHours(ctx, tx, h, clinicianID); err != nil {
+		return err
+	}
+	if err := deleteOfficeHoursMotives(ctx, tx, h.ID); err != nil {
+		return err
+	}
+	if err := insertOfficeHoursMotives(ctx, tx, h, clinicianID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+//ReplaceClinicianOfficeHours deletes all clinician office hours and inserts given ones in a single transaction
+func (r *Repo) ReplaceClinicianOfficeHours(ctx context.Context, hours []*deiz.OfficeHours, clinicianID int) error {
+	tx, err := r.conn.Begin(ctx)
+	defer tx.Rollback(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `DELETE FROM office_hours WHERE person_id = $1`, clinicianID)
+	if err != nil {
+		return err
+	}
+	for _, h := range hours {
+		if err := insertOfficeHours(ctx, tx, h, clinicianID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
 }
 
 func (r *Repo) DeleteOfficeHours(ctx context.Context, hoursID, clinicianID int) error {
@@ -18,29 +18,34 @@ func (r *Repo) IsPatientTiedToClinician(ctx context.Context, p *deiz.Patient, cl
 }
 
 func (r *Repo) CreatePatient(ctx context.Context, p *deiz.Patient, clinicianID int) error {
-	const query = `INSERT INTO patient(clinician_person_id, email, name, surname, phone, address_id)
-	VALUES($1, NULLIF($2, ''), $3, $4, $5, NULLIF($6, 0)) RETURNING id`
-	row := r.conn.QueryRow(ctx, query, clinicianID, p.Email, p.Name, p.Surname, p.Phone, p.Address.ID)
+	const query = `INSERT INTO patient(clinician_person_id, email, name, surname, phone, address_id, timezone)
+	VALUES($1, NULLIF($2, ''), $3, $4, $5, NULLIF($6, 0), NULLIF($7, '')) RETURNING id`
+	row := r.conn.QueryRow(ctx, query, clinicianID, p.Email, p.Name, p.Surname, p.Phone, p.Address.ID, p.Timezone)
 	return row.Scan(&p.ID)
 }
 
 func (r *Repo) GetPatientByEmail(ctx context.Context, email string, clinicianID int) (deiz.Patient, error) {
-	const query = `SELECT id, name, surname, phone, COALESCE(email, '') FROM patient WHERE clinician_person_id = $1 AND email = $2`
+	const query = `SELECT id, name, surname, phone, COALESCE(email, ''), COALESCE(timezone, '') FROM patient WHERE clinician_person_id = $1 AND email = $2`
 	row := r.conn.QueryRow(ctx, query, clinicianID, email)
 	var p deiz.Patient
-	err := row.Scan(&p.ID, &p.Name, &p.Surname, &p.Phone, &p.Email)
+	err := row.Scan(&p.ID, &p.Name, &p.Surname, &p.Phone, &p.Email, &p.Timezone)
 	if err != nil && err != pgx.ErrNoRows {
 		return deiz.Patient{}, err
 	}
 	return p, nil
 }
 
+//SearchPatient looks for patients of given clinician, or of any member of their practice.
+//Patients are shared between members who both accepted to join the practice.
 func (r *Repo) SearchPatient(ctx context.Context, search string, clinicianID int) ([]deiz.Patient, error) {
 	const query = `SELECT p.id, COALESCE(p.email, ''), p.name, p.surname, p.phone, COALESCE(p.note, ''),
 		COALESCE(a.id, 0) address_id, COALESCE(a.line, '') address_line, COALESCE(a.post_code, 0) address_post_code, COALESCE(a.city, '') address_city,
 		similarity(p.name, $1) AS name_sml
 		FROM patient p LEFT JOIN address a ON p.address_id = a.id
-		WHERE p.name % $1 AND p.clinician_person_id = $2
+		WHERE p.name % $1 AND (p.clinician_person_id = $2 OR p.clinician_person_id IN (
+			SELECT peer.person_id FROM practice_member peer
+			INNER JOIN practice_member m ON m.practice_id = peer.practice_id
+			WHERE m.person_id = $2 AND m.accepted_at IS NOT NULL AND peer.accepted_at IS NOT NULL))
 		ORDER BY name_sml DESC LIMIT 5`
 	rows, err := r.conn.Query(ctx, query, search, clinicianID)
 	defer rows.Close()
@@ -72,6 +77,19 @@ func (r *Repo) CountPatients(ctx context.Context, clinicianID int) (int, error)
 	return count, nil
 }
 
+//UpdatePatientTimezone stores the timezone a patient last booked from
+func (r *Repo) UpdatePatientTimezone(ctx context.Context, patientID int, timezone string, clinicianID int) error {
+	const query = `UPDATE patient SET timezone = NULLIF($1, '') WHERE clinician_person_id = $2 AND id = $3`
+	cmdTag, err := r.conn.Exec(ctx, query, timezone, clinicianID, patientID)
+	if err != nil {
+		return err
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return errNoRowsUpdated
+	}
+	return nil
+}
+
 func (r *Repo) UpdatePatient(ctx context.Context, p *deiz.Patient, clinicianID int) error {
 	const query = `UPDATE patient SET name = $1, surname = $2, phone = $3, email = NULLIF($4, ''), note = NULLIF($5, '') WHERE clinician_person_id = $6 AND id = $7`
 	cmdTag, err := r.conn.Exec(ctx, query, p.Name, p.Surname, p.Phone, p.Email, p.Note, clinicianID, p.ID)
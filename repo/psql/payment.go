@@ -0,0 +1,206 @@
+package psql
+
+import (
+	"context"
+	"github.com/audrenbdb/deiz"
+)
+
+//SavePayment records a payment in clinician ledger, updating paid status of its booking.
+//Payment booking or invoice must belong to clinician.
+//A payment referenced at its provider is recorded once, providers notifying payments more than once,
+//reference being unique in the ledger.
+func (r *Repo) SavePayment(ctx context.Context, p *deiz.Payment, clinicianID int) error {
+	tx, err := r.conn.Begin(ctx)
+	defer tx.Rollback(ctx)
+	if err != nil {
+		return err
+	}
+	const query = `INSERT INTO payment(person_id, booking_id, invoice_id, amount, payment_method_id, paid_at, reference, stripe_account_id)
+	SELECT $1, NULLIF($2, 0), NULLIF($3, 0), $4, $5, $6, NULLIF($7, ''), NULLIF($8, '')
+	WHERE ($2 = 0 OR EXISTS (SELECT 1 FROM clinician_booking WHERE id = $2 AND clinician_person_id = $1))
+	AND ($3 = 0 OR EXISTS (SELECT 1 FROM booking_invoice WHERE id = $3 AND person_id = $1))
+	RETURNING id`
+	row := tx.QueryRow(ctx, query, clinicianID, p.BookingID, p.InvoiceID, p.Amount, p.Method.ID, p.PaidAt, p.Reference, p.AccountID)
+	err = row.Scan(&p.ID)
+	if isUniqueViolation(err) {
+		return deiz.ErrorPaymentAlreadyRecorded
+	}
+	if err != nil {
+		return err
+	}
+	if p.BookingID != 0 {
+		if err := refreshBookingsPaidStatus(ctx, tx, []int{p.BookingID}, clinicianID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+//DeletePayment removes a payment recorded by mistake, updating paid status of its booking
+func (r *Repo) DeletePayment(ctx context.Context, paymentID int, clinicianID int) error {
+	tx, err := r.conn.Begin(ctx)
+	defer tx.Rollback(ctx)
+	if err != nil {
+		return err
+	}
+	const query = `DELETE FROM payment WHERE id = $1 AND person_id = $2 RETURNING COALESCE(booking_id, 0)`
+	var bookingID int
+	if err := tx.QueryRow(ctx, query, paymentID, clinicianID).Scan(&bookingID); err != nil {
+		return err
+	}
+	if bookingID != 0 {
+		if err := refreshBookingsPaidStatus(ctx, tx, []int{bookingID}, clinicianID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *Repo) GetBookingPayments(ctx context.Context, bookingID int, clinicianID int) ([]deiz.Payment, error) {
+	const query = `SELECT p.id, COALESCE(p.booking_id, 0), COALESCE(p.invoice_id, 0), p.amount,
+	COALESCE(pm.id, 0), COALESCE(pm.name, ''), p.paid_at, COALESCE(p.reference, ''), COALESCE(p.stripe_account_id, '')
+	FROM payment p LEFT JOIN payment_method pm ON pm.id = p.payment_method_id
+	WHERE p.booking_id = $1 AND p.person_id = $2 ORDER BY p.paid_at`
+	rows, err := r.conn.Query(ctx, query, bookingID, clinicianID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	payments := []deiz.Payment{}
+	for rows.Next() {
+		var p deiz.Payment
+		err := rows.Scan(&p.ID, &p.BookingID, &p.InvoiceID, &p.Amount, &p.Method.ID, &p.Method.Name, &p.PaidAt, &p.Reference, &p.AccountID)
+		if err != nil {
+			return nil, err
+		}
+		payments = append(payments, p)
+	}
+	return payments, rows.Err()
+}
+
+//getBookingsAmountPaid maps amount paid to booking id
+func getBookingsAmountPaid(ctx context.Context, db db, bookingIDs []int) (map[int]int64, error) {
+	const query = `SELECT booking_id, SUM(amount) FROM payment WHERE booking_id = ANY($1) GROUP BY booking_id`
+	rows, err := db.Query(ctx, query, bookingIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	paid := map[int]int64{}
+	for rows.Next() {
+		var bookingID int
+		var amount int64
+		if err := rows.Scan(&bookingID, &amount); err != nil {
+			return nil, err
+		}
+		paid[bookingID] = amount
+	}
+	return paid, rows.Err()
+}
+
+//insertInvoicePayments records invoice as paid with its payment method.
+//A line related to a booking is charged its remaining balance only,
+//previous instalments being already recorded.
+//Provider reference is held by the first payment recorded only, a single provider payment settling every line.
+//A provider payment already recorded with another invoice fails with deiz.ErrorPaymentAlreadyRecorded.
+func insertInvoicePayments(ctx context.Context, db db, i *deiz.BookingInvoice) error {
+	const query = `INSERT INTO payment(person_id, booking_id, invoice_id, amount, payment_method_id, reference, stripe_account_id)
+	SELECT $1, NULLIF($2, 0), $3, due, $5, NULLIF($6, ''), NULLIF($7, '')
+	FROM (SELECT $4 - COALESCE((SELECT SUM(amount) FROM payment WHERE booking_id = $2), 0) AS due) d
+	WHERE due > 0`
+	reference := i.PaymentReference
+	for _, l := range i.Lines {
+		cmdTag, err := db.Exec(ctx, query, i.ClinicianID, l.Booking.ID, i.ID, l.PriceAfterTax, i.PaymentMethod.ID, reference, i.PaymentAccountID)
+		if isUniqueViolation(err) {
+			return deiz.ErrorPaymentAlreadyRecorded
+		}
+		if err != nil {
+			return err
+		}
+		if cmdTag.RowsAffected() > 0 {
+			reference = ""
+		}
+	}
+	return nil
+}
+
+//reverseInvoicePayments records negative payments offsetting the ones recorded with invoice a credit note cancels,
+//up to the amount credited on each booking. Ledger keeps both entries,
+//instalments recorded apart from the invoice being left untouched.
+//Refunds already recorded for credited bookings are linked to the credit note,
+//money given back being reversed once.
+func reverseInvoicePayments(ctx context.Context, db db, c *deiz.BookingInvoice) error {
+	toReverse := map[int]int64{}
+	for _, l := range c.Lines {
+		toReverse[l.Booking.ID] -= l.PriceAfterTax
+	}
+	refunded, err := linkRefundsToCreditNote(ctx, db, c)
+	if err != nil {
+		return err
+	}
+	for bookingID, amount := range refunded {
+		toReverse[bookingID] -= amount
+	}
+	payments, err := getInvoicePayments(ctx, db, c.CanceledInvoice.ID, c.ClinicianID)
+	if err != nil {
+		return err
+	}
+	const query = `INSERT INTO payment(person_id, booking_id, invoice_id, amount, payment_method_id)
+	VALUES($1, NULLIF($2, 0), $3, $4, NULLIF($5, 0))`
+	for _, p := range payments {
+		amount := p.Amount
+		if amount > toReverse[p.BookingID] {
+			amount = toReverse[p.BookingID]
+		}
+		if amount <= 0 {
+			continue
+		}
+		if _, err := db.Exec(ctx, query, c.ClinicianID, p.BookingID, c.ID, -amount, p.Method.ID); err != nil {
+			return err
+		}
+		toReverse[p.BookingID] -= amount
+	}
+	return nil
+}
+
+//linkRefundsToCreditNote attaches refunds of credited bookings not attached to an invoice yet to the credit note,
+//mapping amount refunded to booking id
+func linkRefundsToCreditNote(ctx context.Context, db db, c *deiz.BookingInvoice) (map[int]int64, error) {
+	const query = `UPDATE payment SET invoice_id = $1
+	WHERE person_id = $2 AND booking_id = ANY($3) AND invoice_id IS NULL AND amount < 0 AND reference IS NOT NULL
+	RETURNING booking_id, -amount`
+	rows, err := db.Query(ctx, query, c.ID, c.ClinicianID, c.BookingIDs())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	refunded := map[int]int64{}
+	for rows.Next() {
+		var bookingID int
+		var amount int64
+		if err := rows.Scan(&bookingID, &amount); err != nil {
+			return nil, err
+		}
+		refunded[bookingID] += amount
+	}
+	return refunded, rows.Err()
+}
+
+func getInvoicePayments(ctx context.Context, db db, invoiceID int, clinicianID int) ([]deiz.Payment, error) {
+	const query = `SELECT id, COALESCE(booking_id, 0), amount, COALESCE(payment_method_id, 0)
+	FROM payment WHERE invoice_id = $1 AND person_id = $2 ORDER BY id`
+	rows, err := db.Query(ctx, query, invoiceID, clinicianID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	payments := []deiz.Payment{}
+	for rows.Next() {
+		var p deiz.Payment
+		if err := rows.Scan(&p.ID, &p.BookingID, &p.Amount, &p.Method.ID); err != nil {
+			return nil, err
+		}
+		payments = append(payments, p)
+	}
+	return payments, rows.Err()
+}
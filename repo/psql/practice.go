@@ -0,0 +1,111 @@
+package psql
+
+import (
+	"context"
+	"github.com/audrenbdb/deiz"
+	"github.com/jackc/pgx/v4"
+)
+
+//CreatePractice creates a new practice, given clinician being its owner and first member
+func (r *Repo) CreatePractice(ctx context.Context, p *deiz.Practice, clinicianID int) error {
+	tx, err := r.conn.Begin(ctx)
+	defer tx.Rollback(ctx)
+	if err != nil {
+		return err
+	}
+	const query = `INSERT INTO practice(name) VALUES($1) RETURNING id`
+	if err := tx.QueryRow(ctx, query, p.Name).Scan(&p.ID); err != nil {
+		return err
+	}
+	const ownerQuery = `INSERT INTO practice_member(practice_id, person_id, owner, accepted_at)
+	VALUES($1, $2, true, timezone('utc', NOW()))`
+	if _, err := tx.Exec(ctx, ownerQuery, p.ID, clinicianID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+//AddPracticeMember invites given person to the practice, membership pending until accepted
+func (r *Repo) AddPracticeMember(ctx context.Context, practiceID, personID int) error {
+	const query = `INSERT INTO practice_member(practice_id, person_id) VALUES($1, $2)`
+	_, err := r.conn.Exec(ctx, query, practiceID, personID)
+	return err
+}
+
+func (r *Repo) AcceptPracticeInvitation(ctx context.Context, practiceID, personID int) error {
+	const query = `UPDATE practice_member SET accepted_at = timezone('utc', NOW())
+	WHERE practice_id = $1 AND person_id = $2 AND accepted_at IS NULL`
+	cmdTag, err := r.conn.Exec(ctx, query, practiceID, personID)
+	if err != nil {
+		return err
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return errNoRowsUpdated
+	}
+	return nil
+}
+
+func (r *Repo) RemovePracticeMember(ctx context.Context, practiceID, personID int) error {
+	const query = `DELETE FROM practice_member WHERE practice_id = $1 AND person_id = $2`
+	tag, err := r.conn.Exec(ctx, query, practiceID, personID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return errNothingDeleted
+	}
+	return nil
+}
+
+//GetPersonPractice returns the practice a person belongs to or is invited to, a zero practice if none
+func (r *Repo) GetPersonPractice(ctx context.Context, personID int) (deiz.Practice, error) {
+	const query = `SELECT p.id, p.name FROM practice p
+	INNER JOIN practice_member m ON m.practice_id = p.id WHERE m.person_id = $1`
+	var p deiz.Practice
+	err := r.conn.QueryRow(ctx, query, personID).Scan(&p.ID, &p.Name)
+	if err == pgx.ErrNoRows {
+		return deiz.Practice{}, nil
+	}
+	if err != nil {
+		return deiz.Practice{}, err
+	}
+	p.Members, err = getPracticeMembers(ctx, r.conn, p.ID)
+	if err != nil {
+		return deiz.Practice{}, err
+	}
+	return p, nil
+}
+
+func getPracticeMembers(ctx context.Context, db db, practiceID int) ([]deiz.PracticeMember, error) {
+	const query = `SELECT p.id, p.role, p.name, p.surname, p.email, p.phone, COALESCE(p.profession, ''),
+	m.owner, m.accepted_at IS NOT NULL
+	FROM practice_member m INNER JOIN person p ON p.id = m.person_id
+	WHERE m.practice_id = $1 ORDER BY p.surname, p.name`
+	rows, err := db.Query(ctx, query, practiceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	members := []deiz.PracticeMember{}
+	for rows.Next() {
+		var m deiz.PracticeMember
+		err := rows.Scan(&m.Clinician.ID, &m.Role, &m.Clinician.Name, &m.Clinician.Surname,
+			&m.Clinician.Email, &m.Clinician.Phone, &m.Clinician.Profession, &m.Owner, &m.Accepted)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+//ArePracticeMembers checks if both persons joined the same practice, invitations pending being left out
+func (r *Repo) ArePracticeMembers(ctx context.Context, personID, otherPersonID int) (bool, error) {
+	const query = `SELECT EXISTS(SELECT 1 FROM practice_member m1
+	INNER JOIN practice_member m2 ON m2.practice_id = m1.practice_id
+	WHERE m1.person_id = $1 AND m2.person_id = $2
+	AND m1.accepted_at IS NOT NULL AND m2.accepted_at IS NOT NULL)`
+	var same bool
+	err := r.conn.QueryRow(ctx, query, personID, otherPersonID).Scan(&same)
+	return same, err
+}
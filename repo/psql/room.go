@@ -0,0 +1,54 @@
+package psql
+
+import (
+	"context"
+	"github.com/audrenbdb/deiz"
+	"time"
+)
+
+func (r *Repo) CreateRoom(ctx context.Context, room *deiz.Room) error {
+	const query = `INSERT INTO room(address_id, name) VALUES($1, $2) RETURNING id`
+	return r.conn.QueryRow(ctx, query, room.AddressID, room.Name).Scan(&room.ID)
+}
+
+func (r *Repo) DeleteRoom(ctx context.Context, roomID, addressID int) error {
+	const query = `DELETE FROM room WHERE id = $1 AND address_id = $2`
+	tag, err := r.conn.Exec(ctx, query, roomID, addressID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return errNothingDeleted
+	}
+	return nil
+}
+
+func (r *Repo) GetAddressRooms(ctx context.Context, addressID int) ([]deiz.Room, error) {
+	const query = `SELECT id, name, address_id FROM room WHERE address_id = $1 ORDER BY name`
+	rows, err := r.conn.Query(ctx, query, addressID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	rooms := []deiz.Room{}
+	for rows.Next() {
+		var room deiz.Room
+		if err := rows.Scan(&room.ID, &room.Name, &room.AddressID); err != nil {
+			return nil, err
+		}
+		rooms = append(rooms, room)
+	}
+	return rooms, rows.Err()
+}
+
+//GetRoomBookingsInTimeRange returns in office bookings of any clinician holding given room
+func (r *Repo) GetRoomBookingsInTimeRange(ctx context.Context, from, to time.Time, roomID int) ([]deiz.Booking, error) {
+	const query = bookingSelect + `WHERE b.room_id = $1 AND b.meeting_mode_id = 1 AND b.recurrence_id = 0 AND $2 <= upper(b.during) AND lower(b.during) <= $3`
+	return r.queryBookingRows(ctx, query, roomID, from, to)
+}
+
+//GetRoomWeeklyRecurrentBookings returns weekly recurrent in office bookings of any clinician holding given room
+func (r *Repo) GetRoomWeeklyRecurrentBookings(ctx context.Context, roomID int) ([]deiz.Booking, error) {
+	const query = bookingSelect + `WHERE b.room_id = $1 AND b.meeting_mode_id = 1 AND b.recurrence_id = 2`
+	return r.queryBookingRows(ctx, query, roomID)
+}
@@ -0,0 +1,10 @@
+ALTER TABLE person ADD COLUMN disabled BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE audit_log (
+                           id SERIAL PRIMARY KEY,
+                           admin_person_id INT REFERENCES person(id) ON DELETE SET NULL,
+                           action VARCHAR(50) NOT NULL,
+                           target_person_id INT REFERENCES person(id) ON DELETE SET NULL,
+                           created_at TIMESTAMP NOT NULL DEFAULT timezone('utc', NOW())
+);
+CREATE INDEX audit_log_created_at ON audit_log(created_at DESC);
@@ -10,4 +10,6 @@ CREATE TABLE tax_exemption (
                                id SERIAL PRIMARY KEY,
                                discount_percent INT NOT NULL,
                                code VARCHAR(100)
-);
\ No newline at end of file
+);
+ALTER TABLE business ADD COLUMN invoice_prefix VARCHAR(20) DEFAULT NULL;
+ALTER TABLE business ADD COLUMN invoice_yearly_reset BOOLEAN NOT NULL DEFAULT false;
@@ -13,4 +13,23 @@ CREATE TABLE timezone (
 
 ALTER TABLE OFFICE_HOURS ADD COLUMN allow_remote bool NOT NULL DEFAULT FALSE;
 ALTER TABLE OFFICE_HOURS ADD COLUMN allow_booking_to_patient_home bool NOT NULL DEFAULT FALSE;
-ALTER TABLE OFFICE_HOURS ADD COLUMN allow_new_patient bool NOT NULL DEFAULT TRUE;
\ No newline at end of file
+ALTER TABLE OFFICE_HOURS ADD COLUMN allow_new_patient bool NOT NULL DEFAULT TRUE;
+
+ALTER TABLE calendar_settings ADD COLUMN slot_granularity INT NOT NULL DEFAULT 0
+    CONSTRAINT slot_granularity_val CHECK(slot_granularity >= 0 AND slot_granularity <= 120);
+
+COMMENT ON COLUMN timezone.name IS 'IANA time zone name, such as Europe/Paris or Indian/Reunion';
+
+/* clinicians opt in to the public directory */
+ALTER TABLE calendar_settings ADD COLUMN listed BOOL NOT NULL DEFAULT false;
+
+/* prepayment required from patients booking online: 0 none, 1 full price, 2 deposit */
+ALTER TABLE calendar_settings ADD COLUMN prepayment_mode SMALLINT NOT NULL DEFAULT 0;
+ALTER TABLE calendar_settings ADD COLUMN deposit_percent INT NOT NULL DEFAULT 0
+    CONSTRAINT deposit_percent_val CHECK(deposit_percent >= 0 AND deposit_percent < 100);
+
+/* refund of patients who prepaid online and cancel, full when cancelling at least notice hours ahead */
+ALTER TABLE calendar_settings ADD COLUMN cancellation_notice_hours INT NOT NULL DEFAULT 24
+    CONSTRAINT cancellation_notice_hours_val CHECK(cancellation_notice_hours >= 0);
+ALTER TABLE calendar_settings ADD COLUMN late_refund_percent INT NOT NULL DEFAULT 100
+    CONSTRAINT late_refund_percent_val CHECK(late_refund_percent >= 0 AND late_refund_percent <= 100);
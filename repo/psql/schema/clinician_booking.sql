@@ -15,4 +15,20 @@ CREATE TABLE clinician_booking (
                                    FOREIGN KEY (clinician_person_id, patient_id) REFERENCES patient(clinician_person_id, id) ON DELETE CASCADE,
                                    EXCLUDE USING gist (clinician_person_id WITH =, during WITH &&)
 );
-CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
\ No newline at end of file
+CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+/* patient who did not attend a confirmed appointment */
+ALTER TABLE clinician_booking ADD COLUMN no_show BOOLEAN NOT NULL DEFAULT false;
+
+/* confirmed appointments deleted are recorded as cancellations for clinician statistics */
+CREATE TABLE booking_cancellation (
+                                      id SERIAL PRIMARY KEY,
+                                      person_id INT NOT NULL REFERENCES person(id) ON DELETE CASCADE,
+                                      during TSRANGE NOT NULL,
+                                      booking_motive_id INT REFERENCES booking_motive(id) ON DELETE SET NULL,
+                                      canceled_at TIMESTAMP NOT NULL DEFAULT timezone('utc', NOW())
+);
+CREATE INDEX booking_cancellation_person_idx ON booking_cancellation(person_id, lower(during));
+
+/* slots held from public calendar until patient prepays them, released past deadline */
+ALTER TABLE clinician_booking ADD COLUMN payment_deadline TIMESTAMP DEFAULT NULL;
+CREATE INDEX clinician_booking_payment_deadline_idx ON clinician_booking(payment_deadline) WHERE payment_deadline IS NOT NULL;
@@ -0,0 +1,8 @@
+CREATE TABLE extra_availability (
+                                    id SERIAL PRIMARY KEY,
+                                    person_id INT NOT NULL REFERENCES person(id) ON DELETE CASCADE,
+                                    during TSRANGE NOT NULL,
+                                    address_id INT REFERENCES address(id) ON DELETE SET NULL,
+                                    meeting_mode_id INT NOT NULL DEFAULT 0,
+                                    EXCLUDE USING gist (person_id WITH =, during WITH &&)
+);
@@ -23,4 +23,68 @@ CREATE TABLE booking_invoice (
 CREATE TABLE payment_method (
                                 id SERIAL PRIMARY KEY,
                                 name VARCHAR(50) NOT NULL
-);
\ No newline at end of file
+);
+/* invoice numbers are taken from a sequence row per clinician, prefix and year (0 without yearly reset),
+   locked and incremented in the transaction inserting the invoice, so that numbering has no gap nor duplicate */
+CREATE TABLE invoice_sequence (
+                                  person_id INT NOT NULL REFERENCES person(id) ON DELETE CASCADE,
+                                  prefix VARCHAR(50) NOT NULL,
+                                  year INT NOT NULL DEFAULT 0,
+                                  last_number INT NOT NULL DEFAULT 0
+                                      CONSTRAINT last_number_min CHECK (last_number >= 0),
+                                  PRIMARY KEY (person_id, prefix, year)
+);
+
+/* identifiers duplicated by concurrent creations, counted from existing invoices, are told apart by a suffix,
+   the earliest invoice keeping its identifier */
+UPDATE booking_invoice i SET identifier = i.identifier || '-' || d.rank
+FROM (SELECT id, ROW_NUMBER() OVER (PARTITION BY person_id, identifier ORDER BY created_at, id) AS rank
+      FROM booking_invoice) d
+WHERE d.id = i.id AND d.rank > 1;
+ALTER TABLE booking_invoice ADD CONSTRAINT booking_invoice_identifier_unique UNIQUE (person_id, identifier);
+
+/* sequences start after the highest number issued with each prefix and year,
+   identifiers ending with -<8 digits> or, reset yearly, with -<year>-<6 digits> */
+INSERT INTO invoice_sequence(person_id, prefix, year, last_number)
+SELECT person_id, m[1], COALESCE(NULLIF(m[2], '')::INT, 0), MAX(m[3]::INT)
+FROM (SELECT person_id, COALESCE(regexp_match(identifier, '^(.+)-(\d{4})-(\d{6})$'),
+                                 regexp_match(identifier, '^(.+)-()(\d{8})$')) AS m
+      FROM booking_invoice WHERE person_id IS NOT NULL) parsed
+WHERE m IS NOT NULL
+GROUP BY person_id, m[1], m[2];
+
+/* an invoice may cover several sessions, each line being related to a booking if any */
+CREATE TABLE invoice_line (
+                              id SERIAL PRIMARY KEY,
+                              invoice_id INT NOT NULL REFERENCES booking_invoice(id) ON DELETE CASCADE,
+                              booking_id INT REFERENCES clinician_booking(id) ON DELETE SET NULL,
+                              position INT NOT NULL DEFAULT 0,
+                              label VARCHAR(50) NOT NULL,
+                              delivery_date TIMESTAMP NOT NULL,
+                              delivery_date_str VARCHAR(50) NOT NULL,
+                              price_before_tax INT NOT NULL,
+                              price_after_tax INT NOT NULL
+);
+INSERT INTO invoice_line(invoice_id, booking_id, label, delivery_date, delivery_date_str, price_before_tax, price_after_tax)
+SELECT id, booking_id, label, delivery_date, delivery_date_str, price_before_tax, price_after_tax FROM booking_invoice;
+
+/* exact PDF issued is archived in a blob store, hash guarding its integrity */
+ALTER TABLE booking_invoice ADD COLUMN archive_key VARCHAR(200) DEFAULT NULL;
+ALTER TABLE booking_invoice ADD COLUMN archive_sha256 CHAR(64) DEFAULT NULL;
+
+/* cancelled invoices are offset by credit notes with negative amounts */
+ALTER TABLE booking_invoice ADD COLUMN kind SMALLINT NOT NULL DEFAULT 0;
+ALTER TABLE booking_invoice ADD COLUMN canceled_invoice_id INT UNIQUE REFERENCES booking_invoice(id);
+/* a cancelled invoice keeps its booking, which may be invoiced again */
+ALTER TABLE booking_invoice DROP CONSTRAINT booking_invoice_booking_id_key;
+CREATE UNIQUE INDEX booking_invoice_booking_idx ON booking_invoice(booking_id) WHERE canceled = false;
+
+/* treasury account payments of each method are booked to in accounting exports */
+ALTER TABLE payment_method ADD COLUMN account VARCHAR(10) NOT NULL DEFAULT '512000';
+UPDATE payment_method SET account = '530000' WHERE name ILIKE 'esp%ces';
+
+/* bookings paid through a Stripe checkout are invoiced with this method */
+INSERT INTO payment_method(name) VALUES ('Carte bancaire (en ligne)');
+
+/* invoices are selected by issue date to match journal entries of an export period */
+CREATE INDEX booking_invoice_created_at_idx ON booking_invoice(person_id, created_at);
@@ -15,4 +15,18 @@ CREATE TABLE office_hours (
 ALTER TABLE office_hours ADD COLUMN booking_type_id INT REFERENCES booking_type(id) NOT NULL DEFAULT 0;
 
 CREATE EXTENSION IF NOT EXISTS intarray;
-CREATE EXTENSION IF NOT EXISTS btree_gist;
\ No newline at end of file
+CREATE EXTENSION IF NOT EXISTS btree_gist;
+
+ALTER TABLE office_hours ADD COLUMN valid_from DATE DEFAULT NULL;
+ALTER TABLE office_hours ADD COLUMN valid_until DATE DEFAULT NULL
+    CONSTRAINT valid_period CHECK(valid_from IS NULL OR valid_until IS NULL OR valid_from <= valid_until);
+/* seasonal office hours may share a weekday window as long as their validity periods do not overlap */
+ALTER TABLE office_hours DROP CONSTRAINT IF EXISTS office_hours_person_id_week_day_array_excl;
+ALTER TABLE office_hours ADD EXCLUDE USING gist (person_id WITH =, week_day WITH =, (array[start_mn, end_mn]) WITH &&,
+    daterange(valid_from, valid_until, '[]') WITH &&);
+
+CREATE TABLE office_hours_motive (
+                                     office_hours_id INT NOT NULL REFERENCES office_hours(id) ON DELETE CASCADE,
+                                     booking_motive_id INT NOT NULL REFERENCES booking_motive(id) ON DELETE CASCADE,
+                                     PRIMARY KEY (office_hours_id, booking_motive_id)
+);
@@ -17,4 +17,6 @@ CREATE TABLE patient (
                          UNIQUE (email, clinician_person_id)
 );
 CREATE UNIQUE index clinician_patient_unique ON patient(id, clinician_person_id);
-CREATE INDEX trgm_idx_patient ON patient USING GIST (name gist_trgm_ops);
\ No newline at end of file
+CREATE INDEX trgm_idx_patient ON patient USING GIST (name gist_trgm_ops);
+
+ALTER TABLE patient ADD COLUMN timezone VARCHAR(80) DEFAULT NULL;
@@ -0,0 +1,43 @@
+/* payment ledger, booking paid flag being derived from it */
+CREATE TABLE payment (
+                         id SERIAL PRIMARY KEY,
+                         person_id INT NOT NULL REFERENCES person(id) ON DELETE CASCADE,
+                         booking_id INT REFERENCES clinician_booking(id) ON DELETE CASCADE,
+                         invoice_id INT REFERENCES booking_invoice(id) ON DELETE SET NULL,
+                         amount INT NOT NULL,
+                         payment_method_id INT REFERENCES payment_method(id),
+                         paid_at TIMESTAMP NOT NULL DEFAULT timezone('utc', NOW()),
+                         CONSTRAINT payment_target CHECK (booking_id IS NOT NULL OR invoice_id IS NOT NULL)
+);
+CREATE INDEX payment_booking_idx ON payment(booking_id);
+
+/* bookings flagged paid before the ledger are recorded as fully paid */
+INSERT INTO payment(person_id, booking_id, invoice_id, amount, payment_method_id, paid_at)
+SELECT b.clinician_person_id, b.id, i.id, b.price, i.payment_method_id, COALESCE(i.created_at, lower(b.during))
+FROM clinician_booking b
+LEFT JOIN booking_invoice i ON i.booking_id = b.id AND i.canceled = false
+WHERE b.paid = true AND b.price > 0;
+
+/* reference of a payment at its provider, such as a Stripe payment intent */
+ALTER TABLE payment ADD COLUMN reference VARCHAR(255) DEFAULT NULL;
+CREATE INDEX payment_reference_idx ON payment(reference);
+
+/* payments and refunds made through a provider outlive their booking, mirroring money actually moved */
+ALTER TABLE payment DROP CONSTRAINT payment_target;
+ALTER TABLE payment ADD CONSTRAINT payment_target CHECK (booking_id IS NOT NULL OR invoice_id IS NOT NULL OR reference IS NOT NULL);
+
+/* a provider payment is recorded once, notifications of the same payment racing each other */
+DELETE FROM payment p USING payment d
+WHERE p.reference IS NOT NULL AND p.reference = d.reference AND p.id > d.id;
+DROP INDEX payment_reference_idx;
+CREATE UNIQUE INDEX payment_reference_idx ON payment(reference) WHERE reference IS NOT NULL;
+
+/* Stripe connected account a payment was made on, refunds going through it.
+NULL for payments made with keys a clinician pasted before Stripe Connect */
+ALTER TABLE payment ADD COLUMN stripe_account_id VARCHAR(255) DEFAULT NULL;
+
+/* every ledger entry outlives its booking, payments in cash, by cheque or through an invoice included.
+Entries detached from both booking and invoice stay in the ledger of their clinician */
+ALTER TABLE payment DROP CONSTRAINT payment_booking_id_fkey;
+ALTER TABLE payment ADD CONSTRAINT payment_booking_id_fkey FOREIGN KEY (booking_id) REFERENCES clinician_booking(id) ON DELETE SET NULL;
+ALTER TABLE payment DROP CONSTRAINT payment_target;
@@ -20,3 +20,6 @@ CREATE TABLE role (
                       level INT UNIQUE NOT NULL,
                       name VARCHAR(50) NOT NULL
 );
+
+/* staff level comes after admin level, existing admin claims keeping their value */
+INSERT INTO role (level, name) VALUES (4, 'staff');
@@ -0,0 +1,20 @@
+CREATE TABLE practice (
+                          id SERIAL PRIMARY KEY,
+                          name VARCHAR(100) NOT NULL
+                              CONSTRAINT practice_name_length CHECK (CHAR_LENGTH(name) > 1),
+                          created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE TABLE practice_member (
+                                 practice_id INT NOT NULL REFERENCES practice(id) ON DELETE CASCADE,
+                                 person_id INT UNIQUE NOT NULL REFERENCES person(id) ON DELETE CASCADE,
+                                 PRIMARY KEY (practice_id, person_id)
+);
+
+/* members are invited by the practice owner, joining once they accept.
+Members of existing practices joined already, practices being owned by their earliest account */
+ALTER TABLE practice_member ADD COLUMN owner BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE practice_member ADD COLUMN accepted_at TIMESTAMP DEFAULT NULL;
+UPDATE practice_member SET accepted_at = timezone('utc', NOW());
+UPDATE practice_member m SET owner = true
+WHERE m.person_id = (SELECT MIN(o.person_id) FROM practice_member o WHERE o.practice_id = m.practice_id);
@@ -0,0 +1,11 @@
+CREATE TABLE room (
+                      id SERIAL PRIMARY KEY,
+                      address_id INT NOT NULL REFERENCES address(id) ON DELETE CASCADE,
+                      name VARCHAR(80) NOT NULL
+                          CONSTRAINT room_name_length CHECK (CHAR_LENGTH(name) > 0)
+);
+CREATE UNIQUE INDEX address_room_name ON room(address_id, name);
+
+ALTER TABLE office_hours ADD COLUMN room_id INT REFERENCES room(id) ON DELETE SET NULL;
+ALTER TABLE clinician_booking ADD COLUMN room_id INT REFERENCES room(id) ON DELETE SET NULL;
+CREATE INDEX clinician_booking_room ON clinician_booking(room_id) WHERE room_id IS NOT NULL;
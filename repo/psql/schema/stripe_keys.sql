@@ -6,4 +6,14 @@ CREATE TABLE stripe_keys (
 );
 /* Requirement */
 CREATE EXTENSION "pgcrypto";
-CREATE EXTENSION pg_trgm;
\ No newline at end of file
+CREATE EXTENSION pg_trgm;
+/* signing secret of clinician Stripe webhook endpoint, encrypted as secret key is */
+ALTER TABLE stripe_keys ADD COLUMN webhook_secret BYTEA DEFAULT NULL;
+/* Stripe Connect account clinicians are paid on, platform charging it on their behalf.
+keys pasted before Stripe Connect are kept until clinician connects, then erased */
+ALTER TABLE stripe_keys ADD COLUMN account_id VARCHAR(255) UNIQUE DEFAULT NULL;
+ALTER TABLE stripe_keys ADD COLUMN charges_enabled BOOL NOT NULL DEFAULT false;
+/* keys pasted before Stripe Connect are kept once connected while legacy payments may still be refunded,
+charges_enabled_at telling since when clinician is connected */
+ALTER TABLE stripe_keys ADD COLUMN charges_enabled_at TIMESTAMPTZ DEFAULT NULL;
+UPDATE stripe_keys SET charges_enabled_at = now() WHERE charges_enabled = true;
@@ -2,6 +2,8 @@ package psql
 
 import (
 	"context"
+	"github.com/audrenbdb/deiz"
+	"time"
 )
 
 type stripeKeys struct {
@@ -27,16 +29,14 @@ func insertStripeKeys(ctx context.Context, db db, keys *stripeKeys, personID int
 	return row.Scan(&keys.id)
 }
 
-func updatePersonStripeKeys(ctx context.Context, db db, k stripeKeys, personID int) error {
-	const query = `UPDATE stripe_keys SET public = $1, secret = $2 WHERE person_id = $3`
-	cmdTag, err := db.Exec(ctx, query, k.public, k.secret, personID)
+func getStripeAccountByPersonID(ctx context.Context, db db, personID int) (deiz.StripeAccount, error) {
+	const query = `SELECT COALESCE(account_id, ''), charges_enabled FROM stripe_keys WHERE person_id = $1`
+	var a deiz.StripeAccount
+	err := db.QueryRow(ctx, query, personID).Scan(&a.ID, &a.ChargesEnabled)
 	if err != nil {
-		return err
+		return deiz.StripeAccount{}, err
 	}
-	if cmdTag.RowsAffected() == 0 {
-		return errNoRowsUpdated
-	}
-	return nil
+	return a, nil
 }
 
 func (r *Repo) GetClinicianStripeSecretKey(ctx context.Context, clinicianID int) ([]byte, error) {
@@ -47,10 +47,48 @@ func (r *Repo) GetClinicianStripeSecretKey(ctx context.Context, clinicianID int)
 	return k.secret, nil
 }
 
-func (r *Repo) UpdateClinicianStripeKeys(ctx context.Context, pk string, sk []byte, clinicianID int) error {
-	k := stripeKeys{
-		public: pk,
-		secret: sk,
+func (r *Repo) GetClinicianStripeWebhookSecret(ctx context.Context, clinicianID int) ([]byte, error) {
+	const query = `SELECT webhook_secret FROM stripe_keys WHERE person_id = $1`
+	var secret []byte
+	err := r.conn.QueryRow(ctx, query, clinicianID).Scan(&secret)
+	return secret, err
+}
+
+func (r *Repo) GetClinicianStripeAccount(ctx context.Context, clinicianID int) (deiz.StripeAccount, error) {
+	return getStripeAccountByPersonID(ctx, r.conn, clinicianID)
+}
+
+func (r *Repo) GetClinicianIDByStripeAccount(ctx context.Context, accountID string) (int, error) {
+	const query = `SELECT person_id FROM stripe_keys WHERE account_id = $1`
+	var clinicianID int
+	err := r.conn.QueryRow(ctx, query, accountID).Scan(&clinicianID)
+	return clinicianID, err
+}
+
+//UpdateClinicianStripeAccount records clinician connected account, and since when it may be charged
+func (r *Repo) UpdateClinicianStripeAccount(ctx context.Context, a deiz.StripeAccount, clinicianID int) error {
+	const query = `UPDATE stripe_keys SET account_id = NULLIF($1, ''), charges_enabled = $2,
+	charges_enabled_at = CASE WHEN $2 THEN COALESCE(charges_enabled_at, now()) END
+	WHERE person_id = $3`
+	cmdTag, err := r.conn.Exec(ctx, query, a.ID, a.ChargesEnabled, clinicianID)
+	if err != nil {
+		return err
 	}
-	return updatePersonStripeKeys(ctx, r.conn, k, clinicianID)
+	if cmdTag.RowsAffected() == 0 {
+		return errNoRowsUpdated
+	}
+	return nil
+}
+
+//RemoveSettledStripeKeys erases keys clinicians pasted before connecting their Stripe account
+//once connected before connectedBefore, and no booking paid with the keys takes place after now,
+//such bookings being refunded through the keys if cancelled
+func (r *Repo) RemoveSettledStripeKeys(ctx context.Context, connectedBefore time.Time, now time.Time) error {
+	const query = `UPDATE stripe_keys k SET public = NULL, secret = NULL, webhook_secret = NULL
+	WHERE k.secret IS NOT NULL AND k.charges_enabled = true AND k.charges_enabled_at < $1
+	AND NOT EXISTS (SELECT 1 FROM payment p INNER JOIN clinician_booking b ON b.id = p.booking_id
+		WHERE p.person_id = k.person_id AND p.reference IS NOT NULL AND p.stripe_account_id IS NULL
+		AND p.amount > 0 AND upper(b.during) > $2)`
+	_, err := r.conn.Exec(ctx, query, connectedBefore, now)
+	return err
 }
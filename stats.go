@@ -0,0 +1,104 @@
+package deiz
+
+import (
+	"github.com/audrenbdb/deiz/interval"
+	"time"
+)
+
+//StatsBucket is the calendar period revenue dashboard statistics are grouped by
+type StatsBucket string
+
+const (
+	DayBucket   StatsBucket = "day"
+	WeekBucket  StatsBucket = "week"
+	MonthBucket StatsBucket = "month"
+	YearBucket  StatsBucket = "year"
+)
+
+//MaxStatsPeriods limits periods a dashboard request may span
+const MaxStatsPeriods = 366
+
+func (b StatsBucket) IsValid() bool {
+	return b == DayBucket || b == WeekBucket || b == MonthBucket || b == YearBucket
+}
+
+//Periods cuts time range in consecutive calendar periods of given location.
+//First and last periods extend to their calendar limits, weeks starting on monday.
+//Walking is stopped past MaxStatsPeriods.
+func (b StatsBucket) Periods(start, end time.Time, loc *time.Location) []interval.Range {
+	periods := []interval.Range{}
+	for p := b.floor(start.In(loc)); p.Before(end) && len(periods) <= MaxStatsPeriods; {
+		next := b.next(p)
+		periods = append(periods, interval.Range{Start: p.UTC(), End: next.UTC()})
+		p = next
+	}
+	return periods
+}
+
+func (b StatsBucket) floor(t time.Time) time.Time {
+	y, m, d := t.Date()
+	switch b {
+	case WeekBucket:
+		return time.Date(y, m, d-(int(t.Weekday())+6)%7, 0, 0, 0, 0, t.Location())
+	case MonthBucket:
+		return time.Date(y, m, 1, 0, 0, 0, 0, t.Location())
+	case YearBucket:
+		return time.Date(y, time.January, 1, 0, 0, 0, 0, t.Location())
+	default:
+		return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+	}
+}
+
+func (b StatsBucket) next(t time.Time) time.Time {
+	y, m, d := t.Date()
+	switch b {
+	case WeekBucket:
+		return time.Date(y, m, d+7, 0, 0, 0, 0, t.Location())
+	case MonthBucket:
+		return time.Date(y, m+1, d, 0, 0, 0, 0, t.Location())
+	case YearBucket:
+		return time.Date(y+1, m, d, 0, 0, 0, 0, t.Location())
+	default:
+		return time.Date(y, m, d+1, 0, 0, 0, 0, t.Location())
+	}
+}
+
+//PeriodStats sums clinician activity over a period of the revenue dashboard.
+//Amounts are in cents, tax included unless stated otherwise.
+type PeriodStats struct {
+	Start            time.Time `json:"start"`
+	End              time.Time `json:"end"`
+	Revenue          int64     `json:"revenue"`
+	RevenueBeforeTax int64     `json:"revenueBeforeTax"`
+	//revenue shares, credit notes being deducted
+	ByMotive        []RevenueShare `json:"byMotive"`
+	ByPaymentMethod []RevenueShare `json:"byPaymentMethod"`
+	ByMeetingMode   []RevenueShare `json:"byMeetingMode"`
+	//Outstanding is the balance left to pay on past appointments of the period
+	Outstanding   int64 `json:"outstanding"`
+	Appointments  int   `json:"appointments"`
+	NoShows       int   `json:"noShows"`
+	Cancellations int   `json:"cancellations"`
+	//BookedMn and CapacityMn are minutes of appointments and office hours
+	BookedMn   int `json:"bookedMn"`
+	CapacityMn int `json:"capacityMn"`
+	//OccupancyRate is the share of office hours capacity booked, 0 without capacity
+	OccupancyRate float64 `json:"occupancyRate"`
+}
+
+//RevenueShare is the revenue of a motive, payment method or meeting mode
+type RevenueShare struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	Revenue int64  `json:"revenue"`
+	//Count of invoice lines
+	Count int `json:"count"`
+}
+
+//SetOccupancyRate derives occupancy rate from minutes booked and capacity
+func (s *PeriodStats) SetOccupancyRate() {
+	s.OccupancyRate = 0
+	if s.CapacityMn > 0 {
+		s.OccupancyRate = float64(s.BookedMn) / float64(s.CapacityMn)
+	}
+}
@@ -0,0 +1,71 @@
+package deiz_test
+
+import (
+	"github.com/audrenbdb/deiz"
+	"github.com/audrenbdb/deiz/interval"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestStatsBucketPeriods(t *testing.T) {
+	paris, _ := time.LoadLocation("Europe/Paris")
+	at := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, 0, 0, 0, 0, paris).UTC()
+	}
+	var tests = []struct {
+		description string
+
+		bucket     deiz.StatsBucket
+		start, end time.Time
+
+		periodsOutput []interval.Range
+	}{
+		{
+			description: "should cut time range in calendar days",
+			bucket:      deiz.DayBucket,
+			start:       at(2021, 3, 27).Add(10 * time.Hour),
+			end:         at(2021, 3, 29),
+			periodsOutput: []interval.Range{
+				{Start: at(2021, 3, 27), End: at(2021, 3, 28)},
+				{Start: at(2021, 3, 28), End: at(2021, 3, 29)},
+			},
+		},
+		{
+			description: "should start weeks on monday",
+			bucket:      deiz.WeekBucket,
+			start:       at(2021, 6, 3),
+			end:         at(2021, 6, 8),
+			periodsOutput: []interval.Range{
+				{Start: at(2021, 5, 31), End: at(2021, 6, 7)},
+				{Start: at(2021, 6, 7), End: at(2021, 6, 14)},
+			},
+		},
+		{
+			description: "should extend to whole months",
+			bucket:      deiz.MonthBucket,
+			start:       at(2021, 1, 31),
+			end:         at(2021, 2, 2),
+			periodsOutput: []interval.Range{
+				{Start: at(2021, 1, 1), End: at(2021, 2, 1)},
+				{Start: at(2021, 2, 1), End: at(2021, 3, 1)},
+			},
+		},
+		{
+			description:   "should extend to whole year",
+			bucket:        deiz.YearBucket,
+			start:         at(2021, 5, 1),
+			end:           at(2021, 6, 1),
+			periodsOutput: []interval.Range{{Start: at(2021, 1, 1), End: at(2022, 1, 1)}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			assert.Equal(t, test.periodsOutput, test.bucket.Periods(test.start, test.end, paris))
+		})
+	}
+	t.Run("should stop past maximum periods", func(t *testing.T) {
+		periods := deiz.DayBucket.Periods(at(2000, 1, 1), at(2021, 1, 1), paris)
+		assert.Len(t, periods, deiz.MaxStatsPeriods+1)
+	})
+}
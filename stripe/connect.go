@@ -0,0 +1,67 @@
+package stripe
+
+import (
+	"context"
+	"github.com/audrenbdb/deiz"
+	"github.com/stripe/stripe-go/v72"
+)
+
+const (
+	onboardingRefreshURL = "https://deiz.fr/stripe/onboarding/refresh/"
+	onboardingReturnURL  = "https://deiz.fr/stripe/onboarding/return/"
+)
+
+//CreateConnectedAccount creates the Stripe express account a clinician will be paid on, returning its id
+func (s *service) CreateConnectedAccount(ctx context.Context, email string) (string, error) {
+	if s.platformKey == "" {
+		return "", errPlatformKeyNotProvided
+	}
+	params := &stripe.AccountParams{
+		Type:    stripe.String(string(stripe.AccountTypeExpress)),
+		Country: stripe.String("FR"),
+		Email:   stripe.String(email),
+		Capabilities: &stripe.AccountCapabilitiesParams{
+			CardPayments: &stripe.AccountCapabilitiesCardPaymentsParams{Requested: stripe.Bool(true)},
+			Transfers:    &stripe.AccountCapabilitiesTransfersParams{Requested: stripe.Bool(true)},
+		},
+	}
+	params.Context = ctx
+	a, err := s.client(s.platformKey).Account.New(params)
+	if err != nil {
+		return "", err
+	}
+	return a.ID, nil
+}
+
+//CreateOnboardingLink returns the url, valid a few minutes, where clinician fills its connected account details on Stripe
+func (s *service) CreateOnboardingLink(ctx context.Context, accountID string) (string, error) {
+	if s.platformKey == "" {
+		return "", errPlatformKeyNotProvided
+	}
+	params := &stripe.AccountLinkParams{
+		Account:    stripe.String(accountID),
+		RefreshURL: stripe.String(onboardingRefreshURL),
+		ReturnURL:  stripe.String(onboardingReturnURL),
+		Type:       stripe.String(string(stripe.AccountLinkTypeAccountOnboarding)),
+	}
+	params.Context = ctx
+	link, err := s.client(s.platformKey).AccountLinks.New(params)
+	if err != nil {
+		return "", err
+	}
+	return link.URL, nil
+}
+
+//GetConnectedAccount reads onboarding status of a connected account
+func (s *service) GetConnectedAccount(ctx context.Context, accountID string) (deiz.StripeAccount, error) {
+	if s.platformKey == "" {
+		return deiz.StripeAccount{}, errPlatformKeyNotProvided
+	}
+	params := &stripe.AccountParams{}
+	params.Context = ctx
+	a, err := s.client(s.platformKey).Account.GetByID(accountID, params)
+	if err != nil {
+		return deiz.StripeAccount{}, err
+	}
+	return deiz.StripeAccount{ID: a.ID, ChargesEnabled: a.ChargesEnabled}, nil
+}
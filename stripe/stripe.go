@@ -2,8 +2,13 @@ package stripe
 
 import (
 	"context"
+	"encoding/json"
+	"github.com/audrenbdb/deiz"
 	"github.com/stripe/stripe-go/v72"
 	"github.com/stripe/stripe-go/v72/client"
+	"github.com/stripe/stripe-go/v72/webhook"
+	"os"
+	"strconv"
 )
 
 const (
@@ -11,12 +16,61 @@ const (
 	failPaymentURL    = "https://deiz.fr/payment/cancel/"
 )
 
-type service struct{}
+//checkout session metadata linking a payment back to its booking
+const (
+	bookingIDMetadata   = "booking_id"
+	clinicianIDMetadata = "clinician_id"
+)
+
+const checkoutCompletedEvent = "checkout.session.completed"
 
-//CreateSession creates stripe session token that can be used by a client to open a stripe payment checkout form
-func (s *service) CreateSession(ctx context.Context, amount int64, sk string) (string, error) {
+const errPlatformKeyNotProvided Error = "stripe platform key not found"
+
+type Error string
+
+func (e Error) Error() string {
+	return string(e)
+}
+
+type service struct {
+	//apiURL of Stripe API, default one if empty
+	apiURL string
+	//platformKey is the secret key of deiz platform account, charging connected accounts on their behalf
+	platformKey string
+	//connectWebhookSecret signs events of connected accounts sent to platform webhook endpoint
+	connectWebhookSecret string
+}
+
+func (s *service) client(sk string) *client.API {
 	sc := &client.API{}
-	sc.Init(sk, nil)
+	if s.apiURL == "" {
+		sc.Init(sk, nil)
+		return sc
+	}
+	sc.Init(sk, &stripe.Backends{
+		API:     stripe.GetBackendWithConfig(stripe.APIBackend, &stripe.BackendConfig{URL: stripe.String(s.apiURL)}),
+		Connect: stripe.GetBackendWithConfig(stripe.ConnectBackend, &stripe.BackendConfig{URL: stripe.String(s.apiURL)}),
+		Uploads: stripe.GetBackendWithConfig(stripe.UploadsBackend, &stripe.BackendConfig{URL: stripe.String(s.apiURL)}),
+	})
+	return sc
+}
+
+//payeeClient returns a client charging payee.
+//A connected account is charged with platform key, request params being made on its behalf.
+func (s *service) payeeClient(payee deiz.StripePayee, params *stripe.Params) (*client.API, error) {
+	if payee.AccountID == "" {
+		return s.client(payee.LegacySecretKey), nil
+	}
+	if s.platformKey == "" {
+		return nil, errPlatformKeyNotProvided
+	}
+	params.SetStripeAccount(payee.AccountID)
+	return s.client(s.platformKey), nil
+}
+
+//CreateSession creates stripe session token that can be used by a client to open a stripe payment checkout form.
+//Booking and clinician ids are attached as metadata, webhook events reporting them back.
+func (s *service) CreateSession(ctx context.Context, p deiz.CheckoutPayment, payee deiz.StripePayee) (string, error) {
 	params := &stripe.CheckoutSessionParams{
 		PaymentMethodTypes: stripe.StringSlice([]string{"card"}),
 		Mode:               stripe.String("payment"),
@@ -27,19 +81,122 @@ func (s *service) CreateSession(ctx context.Context, amount int64, sk string) (s
 					ProductData: &stripe.CheckoutSessionLineItemPriceDataProductDataParams{
 						Name: stripe.String("Entretien"),
 					},
-					UnitAmount: &amount,
+					UnitAmount: stripe.Int64(p.Amount),
 				},
 				Quantity: stripe.Int64(1),
 			},
 		},
-		SuccessURL: stripe.String(successPaymentURL),
-		CancelURL:  stripe.String(failPaymentURL),
-		Locale:     stripe.String("auto"),
+		ClientReferenceID: stripe.String(strconv.Itoa(p.BookingID)),
+		SuccessURL:        stripe.String(successPaymentURL),
+		CancelURL:         stripe.String(failPaymentURL),
+		Locale:            stripe.String("auto"),
+	}
+	params.Context = ctx
+	params.AddMetadata(bookingIDMetadata, strconv.Itoa(p.BookingID))
+	params.AddMetadata(clinicianIDMetadata, strconv.Itoa(p.ClinicianID))
+	if !p.ExpiresAt.IsZero() {
+		//expires_at is not part of session params of the stripe-go version used
+		params.AddExtra("expires_at", strconv.FormatInt(p.ExpiresAt.Unix(), 10))
+	}
+	sc, err := s.payeeClient(payee, &params.Params)
+	if err != nil {
+		return "", err
 	}
 	session, err := sc.CheckoutSessions.New(params)
-	return session.ID, err
+	if err != nil {
+		return "", err
+	}
+	return session.ID, nil
+}
+
+//Refund gives back part or all of a payment intent to patient, returning refund id.
+//A refund is issued once per payment intent, retries replaying the first one.
+func (s *service) Refund(ctx context.Context, paymentIntentID string, amount int64, payee deiz.StripePayee) (string, error) {
+	params := &stripe.RefundParams{
+		PaymentIntent: stripe.String(paymentIntentID),
+		Amount:        stripe.Int64(amount),
+		Reason:        stripe.String(string(stripe.RefundReasonRequestedByCustomer)),
+	}
+	params.Context = ctx
+	params.SetIdempotencyKey("refund-" + paymentIntentID)
+	sc, err := s.payeeClient(payee, &params.Params)
+	if err != nil {
+		return "", err
+	}
+	r, err := sc.Refunds.New(params)
+	if err != nil {
+		return "", err
+	}
+	return r.ID, nil
+}
+
+//ParseCheckoutEvent verifies webhook payload signature with endpoint secret and reads the checkout it reports.
+//Events other than a completed checkout return a checkout not paid.
+func (s *service) ParseCheckoutEvent(payload []byte, signature string, webhookSecret string) (deiz.CheckoutPayment, error) {
+	event, err := webhook.ConstructEvent(payload, signature, webhookSecret)
+	if err != nil {
+		return deiz.CheckoutPayment{}, deiz.ErrorUnauthorized
+	}
+	return readCheckoutEvent(event)
 }
 
+//ParseConnectCheckoutEvent verifies payload of an event of a connected account with platform webhook secret
+//and reads the checkout it reports, along with the account it was made on.
+//Events of platform own account are ignored.
+func (s *service) ParseConnectCheckoutEvent(payload []byte, signature string) (deiz.CheckoutPayment, error) {
+	if s.connectWebhookSecret == "" {
+		return deiz.CheckoutPayment{}, deiz.ErrorUnauthorized
+	}
+	event, err := webhook.ConstructEvent(payload, signature, s.connectWebhookSecret)
+	if err != nil {
+		return deiz.CheckoutPayment{}, deiz.ErrorUnauthorized
+	}
+	if event.Account == "" {
+		return deiz.CheckoutPayment{}, nil
+	}
+	p, err := readCheckoutEvent(event)
+	if err != nil {
+		return deiz.CheckoutPayment{}, err
+	}
+	p.AccountID = event.Account
+	return p, nil
+}
+
+func readCheckoutEvent(event stripe.Event) (deiz.CheckoutPayment, error) {
+	if event.Type != checkoutCompletedEvent {
+		return deiz.CheckoutPayment{}, nil
+	}
+	var session stripe.CheckoutSession
+	if err := json.Unmarshal(event.Data.Raw, &session); err != nil {
+		return deiz.CheckoutPayment{}, err
+	}
+	bookingID, err := strconv.Atoi(session.Metadata[bookingIDMetadata])
+	if err != nil {
+		return deiz.CheckoutPayment{}, deiz.ErrorStructValidation
+	}
+	clinicianID, err := strconv.Atoi(session.Metadata[clinicianIDMetadata])
+	if err != nil {
+		return deiz.CheckoutPayment{}, deiz.ErrorStructValidation
+	}
+	p := deiz.CheckoutPayment{
+		SessionID:   session.ID,
+		BookingID:   bookingID,
+		ClinicianID: clinicianID,
+		Amount:      session.AmountTotal,
+		Paid:        session.PaymentStatus == stripe.CheckoutSessionPaymentStatusPaid,
+	}
+	if session.PaymentIntent != nil {
+		p.PaymentIntentID = session.PaymentIntent.ID
+	}
+	return p, nil
+}
+
+//NewService reads platform secrets from environment.
+//STRIPE_API_URL points the service to another Stripe API, such as a local fake.
 func NewService() *service {
-	return &service{}
+	return &service{
+		apiURL:               os.Getenv("STRIPE_API_URL"),
+		platformKey:          os.Getenv("STRIPE_SECRET_KEY"),
+		connectWebhookSecret: os.Getenv("STRIPE_CONNECT_WEBHOOK_SECRET"),
+	}
 }
@@ -0,0 +1,270 @@
+package stripe
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"github.com/audrenbdb/deiz"
+	"github.com/stretchr/testify/assert"
+	"github.com/stripe/stripe-go/v72/webhook"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+//fakeStripeAPI records checkout sessions, refunds, connected accounts and onboarding links created
+type fakeStripeAPI struct {
+	sessions []map[string]string
+	refunds  []map[string]string
+	accounts []map[string]string
+	links    []map[string]string
+}
+
+func (f *fakeStripeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	form := map[string]string{
+		"key":           r.Header.Get("Authorization"),
+		"stripeAccount": r.Header.Get("Stripe-Account"),
+		"idempotency":   r.Header.Get("Idempotency-Key"),
+	}
+	for k := range r.PostForm {
+		form[k] = r.PostForm.Get(k)
+	}
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/v1/checkout/sessions":
+		f.sessions = append(f.sessions, form)
+		fmt.Fprintf(w, `{"id": "cs_test_%d", "object": "checkout.session"}`, len(f.sessions))
+	case r.Method == http.MethodPost && r.URL.Path == "/v1/refunds":
+		f.refunds = append(f.refunds, form)
+		fmt.Fprintf(w, `{"id": "re_test_%d", "object": "refund"}`, len(f.refunds))
+	case r.Method == http.MethodPost && r.URL.Path == "/v1/accounts":
+		f.accounts = append(f.accounts, form)
+		fmt.Fprintf(w, `{"id": "acct_test_%d", "object": "account", "charges_enabled": false}`, len(f.accounts))
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/v1/accounts/"):
+		fmt.Fprintf(w, `{"id": "%s", "object": "account", "charges_enabled": true}`, strings.TrimPrefix(r.URL.Path, "/v1/accounts/"))
+	case r.Method == http.MethodPost && r.URL.Path == "/v1/account_links":
+		f.links = append(f.links, form)
+		fmt.Fprintf(w, `{"object": "account_link", "url": "https://connect.stripe.com/setup/e/%s"}`, form["account"])
+	default:
+		notFound(w)
+	}
+}
+
+func notFound(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusNotFound)
+	fmt.Fprint(w, `{"error": {"type": "invalid_request_error", "message": "not found"}}`)
+}
+
+func TestCreateSession(t *testing.T) {
+	api := &fakeStripeAPI{}
+	server := httptest.NewServer(api)
+	defer server.Close()
+	s := &service{apiURL: server.URL, platformKey: "sk_platform"}
+
+	id, err := s.CreateSession(context.Background(), deiz.CheckoutPayment{BookingID: 12, ClinicianID: 3, Amount: 5000}, deiz.StripePayee{AccountID: "acct_1"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "cs_test_1", id)
+	assert.Len(t, api.sessions, 1)
+	session := api.sessions[0]
+	assert.Equal(t, "Bearer sk_platform", session["key"])
+	assert.Equal(t, "acct_1", session["stripeAccount"])
+	assert.Equal(t, "12", session["metadata[booking_id]"])
+	assert.Equal(t, "3", session["metadata[clinician_id]"])
+	assert.Equal(t, "12", session["client_reference_id"])
+	assert.Equal(t, "5000", session["line_items[0][price_data][unit_amount]"])
+	assert.Equal(t, "", session["expires_at"])
+}
+
+func TestCreateSessionExpiring(t *testing.T) {
+	api := &fakeStripeAPI{}
+	server := httptest.NewServer(api)
+	defer server.Close()
+	s := &service{apiURL: server.URL, platformKey: "sk_platform"}
+	expiresAt := time.Date(2021, 3, 1, 10, 31, 0, 0, time.UTC)
+
+	_, err := s.CreateSession(context.Background(), deiz.CheckoutPayment{BookingID: 12, ClinicianID: 3, Amount: 5000, ExpiresAt: expiresAt}, deiz.StripePayee{AccountID: "acct_1"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "1614594660", api.sessions[0]["expires_at"])
+}
+
+func TestCreateSessionWithLegacyKey(t *testing.T) {
+	api := &fakeStripeAPI{}
+	server := httptest.NewServer(api)
+	defer server.Close()
+	s := &service{apiURL: server.URL, platformKey: "sk_platform"}
+
+	_, err := s.CreateSession(context.Background(), deiz.CheckoutPayment{BookingID: 12, ClinicianID: 3, Amount: 5000}, deiz.StripePayee{LegacySecretKey: "sk_test_1"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer sk_test_1", api.sessions[0]["key"])
+	assert.Equal(t, "", api.sessions[0]["stripeAccount"])
+}
+
+func TestCreateSessionWithoutPlatformKey(t *testing.T) {
+	s := &service{}
+	_, err := s.CreateSession(context.Background(), deiz.CheckoutPayment{BookingID: 12, ClinicianID: 3, Amount: 5000}, deiz.StripePayee{AccountID: "acct_1"})
+	assert.Equal(t, errPlatformKeyNotProvided, err)
+}
+
+func TestConnectOnboarding(t *testing.T) {
+	api := &fakeStripeAPI{}
+	server := httptest.NewServer(api)
+	defer server.Close()
+	s := &service{apiURL: server.URL, platformKey: "sk_platform"}
+	ctx := context.Background()
+
+	accountID, err := s.CreateConnectedAccount(ctx, "jean@dupont.fr")
+	assert.NoError(t, err)
+	assert.Equal(t, "acct_test_1", accountID)
+	assert.Equal(t, "Bearer sk_platform", api.accounts[0]["key"])
+	assert.Equal(t, "express", api.accounts[0]["type"])
+	assert.Equal(t, "jean@dupont.fr", api.accounts[0]["email"])
+
+	url, err := s.CreateOnboardingLink(ctx, accountID)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://connect.stripe.com/setup/e/acct_test_1", url)
+	assert.Equal(t, "acct_test_1", api.links[0]["account"])
+	assert.Equal(t, "account_onboarding", api.links[0]["type"])
+
+	account, err := s.GetConnectedAccount(ctx, accountID)
+	assert.NoError(t, err)
+	assert.Equal(t, deiz.StripeAccount{ID: "acct_test_1", ChargesEnabled: true}, account)
+}
+
+func TestRefund(t *testing.T) {
+	api := &fakeStripeAPI{}
+	server := httptest.NewServer(api)
+	defer server.Close()
+	s := &service{apiURL: server.URL, platformKey: "sk_platform"}
+
+	id, err := s.Refund(context.Background(), "pi_1", 2500, deiz.StripePayee{AccountID: "acct_1"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "re_test_1", id)
+	assert.Len(t, api.refunds, 1)
+	refund := api.refunds[0]
+	assert.Equal(t, "Bearer sk_platform", refund["key"])
+	assert.Equal(t, "acct_1", refund["stripeAccount"])
+	assert.Equal(t, "refund-pi_1", refund["idempotency"])
+	assert.Equal(t, "pi_1", refund["payment_intent"])
+	assert.Equal(t, "2500", refund["amount"])
+}
+
+func signedHeader(payload []byte, secret string) string {
+	now := time.Now()
+	return fmt.Sprintf("t=%d,v1=%s", now.Unix(), hex.EncodeToString(webhook.ComputeSignature(now, payload, secret)))
+}
+
+func TestParseCheckoutEvent(t *testing.T) {
+	const secret = "whsec_test"
+	completed := []byte(`{"id": "evt_1", "type": "checkout.session.completed", "data": {"object": {
+		"id": "cs_test_1", "object": "checkout.session", "amount_total": 5000, "payment_status": "paid", "payment_intent": "pi_1",
+		"metadata": {"booking_id": "12", "clinician_id": "3"}}}}`)
+	unpaid := []byte(`{"id": "evt_2", "type": "checkout.session.completed", "data": {"object": {
+		"id": "cs_test_2", "object": "checkout.session", "amount_total": 5000, "payment_status": "unpaid",
+		"metadata": {"booking_id": "12", "clinician_id": "3"}}}}`)
+	noMetadata := []byte(`{"id": "evt_3", "type": "checkout.session.completed", "data": {"object": {
+		"id": "cs_test_3", "object": "checkout.session", "payment_status": "paid"}}}`)
+	otherEvent := []byte(`{"id": "evt_4", "type": "payment_intent.created", "data": {"object": {"id": "pi_1"}}}`)
+
+	var tests = []struct {
+		description string
+
+		payload   []byte
+		signature string
+
+		paymentOutput deiz.CheckoutPayment
+		errorOutput   error
+	}{
+		{
+			description: "should reject a payload not signed with endpoint secret",
+			payload:     completed,
+			signature:   signedHeader(completed, "whsec_other"),
+			errorOutput: deiz.ErrorUnauthorized,
+		},
+		{
+			description: "should reject a payload altered after signature",
+			payload:     unpaid,
+			signature:   signedHeader(completed, secret),
+			errorOutput: deiz.ErrorUnauthorized,
+		},
+		{
+			description: "should ignore events other than a completed checkout",
+			payload:     otherEvent,
+			signature:   signedHeader(otherEvent, secret),
+		},
+		{
+			description: "should fail to read a checkout without booking metadata",
+			payload:     noMetadata,
+			signature:   signedHeader(noMetadata, secret),
+			errorOutput: deiz.ErrorStructValidation,
+		},
+		{
+			description: "should read a checkout not paid yet",
+			payload:     unpaid,
+			signature:   signedHeader(unpaid, secret),
+			paymentOutput: deiz.CheckoutPayment{
+				SessionID: "cs_test_2", BookingID: 12, ClinicianID: 3, Amount: 5000,
+			},
+		},
+		{
+			description: "should read a paid checkout",
+			payload:     completed,
+			signature:   signedHeader(completed, secret),
+			paymentOutput: deiz.CheckoutPayment{
+				SessionID: "cs_test_1", PaymentIntentID: "pi_1", BookingID: 12, ClinicianID: 3, Amount: 5000, Paid: true,
+			},
+		},
+	}
+
+	s := NewService()
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			p, err := s.ParseCheckoutEvent(test.payload, test.signature, secret)
+			assert.Equal(t, test.errorOutput, err)
+			assert.Equal(t, test.paymentOutput, p)
+		})
+	}
+}
+
+func TestParseConnectCheckoutEvent(t *testing.T) {
+	const secret = "whsec_platform"
+	connected := []byte(`{"id": "evt_1", "type": "checkout.session.completed", "account": "acct_1", "data": {"object": {
+		"id": "cs_test_1", "object": "checkout.session", "amount_total": 5000, "payment_status": "paid", "payment_intent": "pi_1",
+		"metadata": {"booking_id": "12", "clinician_id": "3"}}}}`)
+	platform := []byte(`{"id": "evt_2", "type": "checkout.session.completed", "data": {"object": {
+		"id": "cs_test_2", "object": "checkout.session", "amount_total": 5000, "payment_status": "paid", "payment_intent": "pi_2",
+		"metadata": {"booking_id": "12", "clinician_id": "3"}}}}`)
+
+	t.Run("should reject events when platform secret is not set", func(t *testing.T) {
+		s := &service{}
+		_, err := s.ParseConnectCheckoutEvent(connected, signedHeader(connected, ""))
+		assert.Equal(t, deiz.ErrorUnauthorized, err)
+	})
+	t.Run("should reject a payload not signed with platform secret", func(t *testing.T) {
+		s := &service{connectWebhookSecret: secret}
+		_, err := s.ParseConnectCheckoutEvent(connected, signedHeader(connected, "whsec_clinician"))
+		assert.Equal(t, deiz.ErrorUnauthorized, err)
+	})
+	t.Run("should ignore events of platform own account", func(t *testing.T) {
+		s := &service{connectWebhookSecret: secret}
+		p, err := s.ParseConnectCheckoutEvent(platform, signedHeader(platform, secret))
+		assert.NoError(t, err)
+		assert.Equal(t, deiz.CheckoutPayment{}, p)
+	})
+	t.Run("should read checkout of a connected account", func(t *testing.T) {
+		s := &service{connectWebhookSecret: secret}
+		p, err := s.ParseConnectCheckoutEvent(connected, signedHeader(connected, secret))
+		assert.NoError(t, err)
+		assert.Equal(t, deiz.CheckoutPayment{
+			SessionID: "cs_test_1", AccountID: "acct_1", PaymentIntentID: "pi_1", BookingID: 12, ClinicianID: 3, Amount: 5000, Paid: true,
+		}, p)
+	})
+}
@@ -0,0 +1,63 @@
+package deiz
+
+import "math"
+
+//TaxRate is a VAT rate in basis points, 2000 being 20%.
+//Integer rates keep amount computations exact to the cent.
+type TaxRate int64
+
+//french VAT rates table
+const (
+	NoTaxRate           TaxRate = 0
+	SuperReducedTaxRate TaxRate = 210
+	ReducedTaxRate      TaxRate = 550
+	IntermediateTaxRate TaxRate = 1000
+	StandardTaxRate     TaxRate = 2000
+)
+
+const fullRate = 10000
+
+var taxRates = []TaxRate{NoTaxRate, SuperReducedTaxRate, ReducedTaxRate, IntermediateTaxRate, StandardTaxRate}
+
+func (r TaxRate) IsValid() bool {
+	for _, rate := range taxRates {
+		if r == rate {
+			return true
+		}
+	}
+	return false
+}
+
+//Percent converts rate to a percentage, 5.5 for 550
+func (r TaxRate) Percent() float32 {
+	return float32(r) / 100
+}
+
+//TaxRateFromPercent converts a percentage to a rate, rounded to the nearest basis point
+func TaxRateFromPercent(percent float32) TaxRate {
+	return TaxRate(math.Round(float64(percent) * 100))
+}
+
+//BeforeTax splits a price tax included in its amount before tax.
+//Amount is rounded half up to the cent, tax being the remainder
+//so that amount before tax and tax always add up to the price paid.
+func (r TaxRate) BeforeTax(priceAfterTax int64) int64 {
+	return roundedDiv(priceAfterTax*fullRate, fullRate+int64(r))
+}
+
+//roundedDiv divides rounding half away from zero
+func roundedDiv(a, b int64) int64 {
+	if a < 0 {
+		return -roundedDiv(-a, b)
+	}
+	return (a + b/2) / b
+}
+
+//TaxRate applied to clinician invoices.
+//An exempted clinician charges no VAT, others the standard rate.
+func (b *Business) TaxRate() TaxRate {
+	if b.TaxExemption.ID != 0 {
+		return NoTaxRate
+	}
+	return StandardTaxRate
+}
@@ -20,12 +20,15 @@ type (
 		MotiveUsecases           MotiveUsecases
 		OfficeHoursUsecases      OfficeHoursUsecases
 		CalendarSettingsUsecases CalendarSettingsEditer
-		StripeKeysUsecases       StripeKeysSetter
+		StripeConnectUsecases    StripeAccountConnecter
 	}
 	AccountAddressUsecases struct {
 		OfficeAddressAdder OfficeAddressAdder
 		AddressDeleter     AddressDeleter
 		AddressEditer      AddressEditer
+		RoomAdder          RoomAdder
+		RoomRemover        RoomRemover
+		RoomsGetter        RoomsGetter
 	}
 	BusinessUsecases struct {
 		BusinessEditer        BusinessEditer
@@ -44,8 +47,12 @@ type (
 		MotiveRemover BookingMotiveRemover
 	}
 	OfficeHoursUsecases struct {
-		OfficeHoursAdder   OfficeHoursAdder
-		OfficeHoursRemover OfficeHoursRemover
+		OfficeHoursAdder         OfficeHoursAdder
+		OfficeHoursEditer        OfficeHoursEditer
+		OfficeHoursRemover       OfficeHoursRemover
+		WeeklyScheduleReplacer   WeeklyScheduleReplacer
+		ExtraAvailabilityAdder   ExtraAvailabilityAdder
+		ExtraAvailabilityRemover ExtraAvailabilityRemover
 	}
 )
 
@@ -69,6 +76,15 @@ type (
 	AddressDeleter interface {
 		DeleteAddress(ctx context.Context, addressID int, cred deiz.Credentials) error
 	}
+	RoomAdder interface {
+		AddRoom(ctx context.Context, room *deiz.Room, cred deiz.Credentials) error
+	}
+	RoomRemover interface {
+		RemoveRoom(ctx context.Context, roomID, addressID int, cred deiz.Credentials) error
+	}
+	RoomsGetter interface {
+		GetAddressRooms(ctx context.Context, addressID int, cred deiz.Credentials) ([]deiz.Room, error)
+	}
 )
 
 type (
@@ -105,14 +121,27 @@ type (
 	OfficeHoursAdder interface {
 		AddOfficeHours(ctx context.Context, h *deiz.OfficeHours, clinicianID int) error
 	}
+	OfficeHoursEditer interface {
+		EditOfficeHours(ctx context.Context, h *deiz.OfficeHours, clinicianID int) error
+	}
 	OfficeHoursRemover interface {
 		RemoveOfficeHours(ctx context.Context, hoursID int, clinicianID int) error
 	}
+	WeeklyScheduleReplacer interface {
+		ReplaceWeeklySchedule(ctx context.Context, hours []*deiz.OfficeHours, clinicianID int) error
+	}
+	ExtraAvailabilityAdder interface {
+		AddExtraAvailability(ctx context.Context, a *deiz.ExtraAvailability, clinicianID int) error
+	}
+	ExtraAvailabilityRemover interface {
+		RemoveExtraAvailability(ctx context.Context, availabilityID int, clinicianID int) error
+	}
 )
 
 type (
-	StripeKeysSetter interface {
-		SetClinicianStripeKeys(ctx context.Context, pk, sk string, clinicianID int) error
+	StripeAccountConnecter interface {
+		StartStripeOnboarding(ctx context.Context, clinicianID int) (string, error)
+		RefreshStripeAccount(ctx context.Context, clinicianID int) (deiz.StripeAccount, error)
 	}
 )
 
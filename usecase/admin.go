@@ -0,0 +1,40 @@
+/*
+Package usecase references all usecases to be implemented
+*/
+package usecase
+
+import (
+	"context"
+	"github.com/audrenbdb/deiz"
+	"time"
+)
+
+type (
+	AdminUsecases struct {
+		AccountsLister      AdminAccountsLister
+		AccountEnabler      AdminAccountEnabler
+		AccountImpersonator AdminAccountImpersonator
+		UsageStatsGetter    AdminUsageStatsGetter
+		AuditLogsGetter     AdminAuditLogsGetter
+	}
+)
+
+type (
+	AdminAccountsLister interface {
+		ListAccounts(ctx context.Context, adminID int) ([]deiz.AccountSummary, error)
+	}
+	AdminAccountEnabler interface {
+		EnableAccount(ctx context.Context, clinicianID, adminID int) error
+		DisableAccount(ctx context.Context, clinicianID, adminID int) error
+	}
+	AdminAccountImpersonator interface {
+		ImpersonateAccount(ctx context.Context, clinicianID, adminID int) (deiz.ClinicianAccount, error)
+		ImpersonateCalendar(ctx context.Context, start time.Time, defaultDuration int, clinicianID, adminID int) ([]deiz.Booking, error)
+	}
+	AdminUsageStatsGetter interface {
+		GetUsageStats(ctx context.Context, from, to time.Time, adminID int) (deiz.UsageStats, error)
+	}
+	AdminAuditLogsGetter interface {
+		GetAuditLogs(ctx context.Context, adminID int) ([]deiz.AuditLog, error)
+	}
+)
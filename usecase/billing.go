@@ -4,6 +4,7 @@ Package usecase references all usecases to be implemented
 package usecase
 
 import (
+	"bytes"
 	"context"
 	"github.com/audrenbdb/deiz"
 	"time"
@@ -11,12 +12,23 @@ import (
 
 type (
 	BillingUsecases struct {
-		InvoiceCreater       InvoiceCreater
-		InvoiceCanceler      InvoiceCanceler
-		InvoiceMailer        InvoiceMailer
-		InvoicesGetter       InvoicesGetter
-		StripeSessionCreater StripeSessionCreater
-		UnpaidBookingsGetter UnpaidBookingsGetter
+		InvoiceCreater        InvoiceCreater
+		InvoiceCanceler       InvoiceCanceler
+		InvoiceMailer         InvoiceMailer
+		InvoicePDFGetter      InvoicePDFGetter
+		InvoicesGetter        InvoicesGetter
+		InvoicesExporter      InvoicesExporter
+		RevenueStatsGetter    RevenueStatsGetter
+		StripeSessionCreater  StripeSessionCreater
+		StripeWebhookHandler  StripeWebhookHandler
+		UnpaidBookingsGetter  UnpaidBookingsGetter
+		PaymentReminderSender PaymentReminderSender
+		PaymentUsecases       PaymentUsecases
+	}
+	PaymentUsecases struct {
+		Recorder       PaymentRecorder
+		Remover        PaymentRemover
+		BookingsGetter BookingPaymentsGetter
 	}
 )
 
@@ -25,19 +37,46 @@ type (
 		CreateInvoice(ctx context.Context, invoice *deiz.BookingInvoice, sendToPatient bool) error
 	}
 	InvoiceCanceler interface {
-		CancelInvoice(ctx context.Context, invoice *deiz.BookingInvoice) error
+		CancelInvoice(ctx context.Context, invoiceID int, clinicianID int) (deiz.BookingInvoice, error)
 	}
 	InvoiceMailer interface {
-		MailInvoice(invoice *deiz.BookingInvoice, recipient string) error
-		MailInvoicesSummary(ctx context.Context, start, end time.Time, recipient string, clinicianID int) error
+		MailInvoice(ctx context.Context, invoiceID int, clinicianID int, recipient string) error
+		MailInvoicesSummary(ctx context.Context, start, end time.Time, recipient string, clinicianID int, format deiz.ExportFormat) error
+	}
+	InvoicePDFGetter interface {
+		GetInvoicePDF(ctx context.Context, invoiceID int, clinicianID int) (*bytes.Buffer, error)
 	}
 	InvoicesGetter interface {
 		GetPeriodInvoices(ctx context.Context, start, end time.Time, clinicianID int) ([]deiz.BookingInvoice, error)
 	}
+	InvoicesExporter interface {
+		ExportInvoices(ctx context.Context, start, end time.Time, clinicianID int, format deiz.ExportFormat) (deiz.AccountingExport, error)
+	}
+	RevenueStatsGetter interface {
+		GetRevenueStats(ctx context.Context, start, end time.Time, bucket deiz.StatsBucket, clinicianID int) ([]deiz.PeriodStats, error)
+	}
 	StripeSessionCreater interface {
-		CreateStripePaymentSession(ctx context.Context, amount int64, clinicianID int) (string, error)
+		CreateStripePaymentSession(ctx context.Context, bookingID int, clinicianID int) (string, error)
+		CreateStripePaymentLinkSession(ctx context.Context, token string) (string, error)
+	}
+	StripeWebhookHandler interface {
+		HandleStripeWebhook(ctx context.Context, payload []byte, signature string, clinicianID int) error
+		HandleStripeConnectWebhook(ctx context.Context, payload []byte, signature string) error
 	}
 	UnpaidBookingsGetter interface {
-		GetUnpaidBookings(ctx context.Context, clinicianID int) ([]deiz.Booking, error)
+		GetUnpaidBookings(ctx context.Context, clinicianID int) ([]deiz.BookingBalance, error)
+	}
+	PaymentReminderSender interface {
+		SendPaymentReminder(ctx context.Context, bookingID int, clinicianID int) error
+		SendPaymentReminders(ctx context.Context, clinicianID int) (int, error)
+	}
+	PaymentRecorder interface {
+		RecordPayment(ctx context.Context, p *deiz.Payment, clinicianID int) error
+	}
+	PaymentRemover interface {
+		RemovePayment(ctx context.Context, paymentID int, clinicianID int) error
+	}
+	BookingPaymentsGetter interface {
+		GetBookingPayments(ctx context.Context, bookingID int, clinicianID int) ([]deiz.Payment, error)
 	}
 )
@@ -16,13 +16,18 @@ type (
 		CalendarReader CalendarReader
 		SlotDeleter    BookingSlotDeleter
 		SlotBlocker    BookingSlotBlocker
+		NoShowMarker   BookingNoShowMarker
+		Directory      DirectorySearcher
 	}
 )
 
 type (
+	DirectorySearcher interface {
+		SearchDirectory(ctx context.Context, f deiz.DirectoryFilter, from time.Time) ([]deiz.DirectoryEntry, error)
+	}
 	BookingRegister interface {
 		RegisterBookingsFromClinician(ctx context.Context, b []*deiz.Booking, clinicianID int, notifyPatient bool) error
-		RegisterBookingFromPatient(ctx context.Context, b *deiz.Booking) error
+		RegisterBookingFromPatient(ctx context.Context, b *deiz.Booking) (string, error)
 		RegisterPreRegisteredBooking(ctx context.Context, b *deiz.Booking, clinicianID int, notifyPatient bool) error
 	}
 	BookingSlotDeleter interface {
@@ -31,6 +36,9 @@ type (
 		DeleteBookedSlotFromPatient(ctx context.Context, deleteID string) error
 		DeleteBookedSlotFromClinician(ctx context.Context, bookingID int, notifyPatient bool, clinicianID int) error
 	}
+	BookingNoShowMarker interface {
+		MarkNoShow(ctx context.Context, bookingID int, noShow bool, clinicianID int) error
+	}
 	BookingPreRegister interface {
 		PreRegisterBookings(ctx context.Context, slots []*deiz.Booking, clinicianID int) error
 	}
@@ -38,7 +46,7 @@ type (
 		BlockBookingSlots(ctx context.Context, slots []*deiz.Booking, credentials deiz.Credentials) error
 	}
 	CalendarReader interface {
-		GetCalendarFreeSlots(ctx context.Context, start time.Time, defaultDuration int, clinicianID int) ([]deiz.Booking, error)
+		GetCalendarFreeSlots(ctx context.Context, start time.Time, defaultDuration int, motiveID int, clinicianID int) ([]deiz.Booking, error)
 		GetCalendarSlots(ctx context.Context, start time.Time, defaultDuration int, clinicianID int) ([]deiz.Booking, error)
 	}
 )
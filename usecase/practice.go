@@ -0,0 +1,41 @@
+/*
+Package usecase references all usecases to be implemented
+*/
+package usecase
+
+import (
+	"context"
+	"github.com/audrenbdb/deiz"
+)
+
+type (
+	PracticeUsecases struct {
+		Creater                 PracticeCreater
+		Getter                  PracticeGetter
+		MemberAdder             PracticeMemberAdder
+		MemberRemover           PracticeMemberRemover
+		InvitationAccepter      PracticeInvitationAccepter
+		ActingClinicianResolver ActingClinicianResolver
+	}
+)
+
+type (
+	PracticeCreater interface {
+		CreatePractice(ctx context.Context, p *deiz.Practice, clinicianID int) error
+	}
+	PracticeGetter interface {
+		GetPractice(ctx context.Context, personID int) (deiz.Practice, error)
+	}
+	PracticeMemberAdder interface {
+		AddPracticeMember(ctx context.Context, email string, personID int) error
+	}
+	PracticeMemberRemover interface {
+		RemovePracticeMember(ctx context.Context, memberID, personID int) error
+	}
+	PracticeInvitationAccepter interface {
+		AcceptPracticeInvitation(ctx context.Context, personID int) error
+	}
+	ActingClinicianResolver interface {
+		ResolveActingClinician(ctx context.Context, cred deiz.Credentials, onBehalfOfID int) (int, error)
+	}
+)
@@ -7,8 +7,21 @@ const (
 	PatientRole
 	ClinicianRole
 	AdminRole
+	//StaffRole is given to practice secretaries acting on behalf of practice members.
+	//Role values being stored in firebase claims, privileges are ranked by rolePrivileges rather than by value.
+	StaffRole
 )
 
+//rolePrivileges ranks roles by privilege, staff ranking between clinician and admin.
+//A role missing from it has public privileges only.
+var rolePrivileges = map[Role]int{
+	PublicRole:    0,
+	PatientRole:   1,
+	ClinicianRole: 2,
+	StaffRole:     3,
+	AdminRole:     4,
+}
+
 type Credentials struct {
 	UserID int
 	Role   Role
@@ -17,3 +30,18 @@ type Credentials struct {
 func (c *Credentials) IsPatient() bool {
 	return c.Role == PatientRole
 }
+
+func (c *Credentials) IsStaff() bool {
+	return c.Role == StaffRole || c.Role == AdminRole
+}
+
+//ActsOnBehalfOnly tells if credentials reach clinician data only on behalf of a practice clinician,
+//staff having neither calendar nor patients of their own
+func (c *Credentials) ActsOnBehalfOnly() bool {
+	return c.Role == StaffRole
+}
+
+//HasRole tells if credentials grant at least the privileges of given role
+func (c *Credentials) HasRole(minRole Role) bool {
+	return rolePrivileges[c.Role] >= rolePrivileges[minRole]
+}
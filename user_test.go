@@ -0,0 +1,33 @@
+package deiz
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestHasRole(t *testing.T) {
+	var tests = []struct {
+		description string
+
+		role    Role
+		minRole Role
+
+		expected bool
+	}{
+		{description: "should grant clinician routes to a clinician", role: ClinicianRole, minRole: ClinicianRole, expected: true},
+		{description: "should grant clinician routes to staff", role: StaffRole, minRole: ClinicianRole, expected: true},
+		{description: "should grant clinician routes to an admin", role: AdminRole, minRole: ClinicianRole, expected: true},
+		{description: "should refuse admin routes to staff", role: StaffRole, minRole: AdminRole, expected: false},
+		{description: "should refuse staff routes to a clinician", role: ClinicianRole, minRole: StaffRole, expected: false},
+		{description: "should grant admin routes to an admin", role: AdminRole, minRole: AdminRole, expected: true},
+		{description: "should refuse clinician routes to a patient", role: PatientRole, minRole: ClinicianRole, expected: false},
+		{description: "should grant public routes only to an unknown role", role: Role(42), minRole: PublicRole, expected: true},
+		{description: "should refuse patient routes to an unknown role", role: Role(42), minRole: PatientRole, expected: false},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			c := Credentials{Role: test.role}
+			assert.Equal(t, test.expected, c.HasRole(test.minRole))
+		})
+	}
+}